@@ -31,6 +31,7 @@ type Request struct {
 	Session string `json:"session,omitempty"` // "" => resolve the single session
 	Path    string `json:"path,omitempty"`    // session_new: label for listings
 	Content []byte `json:"content,omitempty"` // session_new: diagram bytes (base64)
+	Dir     string `json:"dir,omitempty"`     // session_new: directory !include paths are relative to and confined to
 	Index   *int   `json:"index,omitempty"`   // select (optional) / jump (required)
 	Values  string `json:"values,omitempty"`  // statevar: JSON-array text
 }
@@ -5,6 +5,7 @@ import (
 	"encoding/csv"
 	"errors"
 	"fmt"
+	"path/filepath"
 	"strconv"
 	"sync"
 
@@ -78,7 +79,13 @@ func (s *Service) Handle(req Request) Response {
 }
 
 func (s *Service) handleSessionNew(req Request) Response {
-	diagram, err := csdf.ParseDiagram(req.Content)
+	path := ""
+	if req.Dir != "" {
+		path = filepath.Join(req.Dir, filepath.Base(req.Path))
+	}
+	// The content comes over the socket, so !include reads only files inside
+	// req.Dir, and none without it.
+	diagram, err := csdf.ParseDiagramFileWith(path, req.Content, csdf.ReadFileWithin(req.Dir))
 	if err != nil {
 		return s.errorFromErr(err)
 	}
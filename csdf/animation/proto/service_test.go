@@ -2,6 +2,8 @@ package proto
 
 import (
 	"encoding/json"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 )
@@ -74,6 +76,46 @@ func TestHandleSessionNewRejectsBadContent(t *testing.T) {
 	}
 }
 
+func TestHandleSessionNewConfinesIncludesToDir(t *testing.T) {
+	// Setup: states.puml is inside dir, secret.puml next to it.
+	parent := t.TempDir()
+	dir := filepath.Join(parent, "project")
+	if err := os.Mkdir(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "states.puml"), []byte("state \"A\" as a\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(parent, "secret.puml"), []byte("state \"A\" as a\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	diagram := func(include string) []byte {
+		return []byte("@startuml\n!include " + include + "\n[*] --> a\n@enduml\n")
+	}
+	tests := []struct {
+		name   string
+		req    Request
+		wantOK bool
+	}{
+		{name: "inside dir", req: Request{Command: CommandSessionNew, Path: "d.puml", Dir: dir, Content: diagram("states.puml")}, wantOK: true},
+		{name: "parent of dir", req: Request{Command: CommandSessionNew, Path: "d.puml", Dir: dir, Content: diagram("../secret.puml")}},
+		{name: "absolute path outside dir", req: Request{Command: CommandSessionNew, Path: "d.puml", Dir: dir, Content: diagram(filepath.Join(parent, "secret.puml"))}},
+		{name: "no dir", req: Request{Command: CommandSessionNew, Path: "d.puml", Content: diagram(filepath.Join(dir, "states.puml"))}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Execute
+			resp := NewService("dev", false).Handle(tt.req)
+
+			// Assert
+			if resp.OK != tt.wantOK {
+				t.Errorf("session_new OK = %v (error %q), want %v", resp.OK, resp.Error, tt.wantOK)
+			}
+		})
+	}
+}
+
 func TestHandleReadShowsValuePromptInValuesMode(t *testing.T) {
 	service, id := newSession(t)
 	resp := service.Handle(Request{Command: CommandRead, Session: id})
@@ -24,17 +24,36 @@ const True = "true"
 // τ-transition (docs/SYNTAX.md, docs/REFINEMENT_ALGORITHM.md §8).
 const Tau Event = "tau"
 
+// Annotations are the "@key value" pairs of annotation comments such as
+// /'@owner team-a'/ (docs/SYNTAX.md, "Annotations"). A key without a value
+// maps to "". They carry metadata for downstream tools and do not affect the
+// semantics.
+type Annotations map[string]string
+
+// Diagram is one "@startuml ... @enduml" block. Name is the optional diagram
+// name following @startuml, used to select a diagram from a file holding
+// several (see SplitDiagramRef).
 type Diagram struct {
-	States    map[StateID]State `json:"states"`
-	StartEdge StartEdge         `json:"start_edge"`
-	Edges     []Edge            `json:"edges"`
-	EndEdge   *EndEdge          `json:"end_edge"`
+	Name        string            `json:"name,omitempty"`
+	States      map[StateID]State `json:"states"`
+	StartEdge   StartEdge         `json:"start_edge"`
+	Edges       []Edge            `json:"edges"`
+	EndEdge     *EndEdge          `json:"end_edge"`
+	Annotations Annotations       `json:"annotations,omitempty"`
 }
 
+// State is a state declaration. Color, Stereotype and Descriptions carry the
+// PlantUML presentation metadata of "state X <<stereo>> #color" and "X : text"
+// lines; they do not affect the semantics. A description never reads as a
+// variable declaration (docs/SYNTAX.md), so String writes it back as is.
 type State struct {
-	ID   StateID    `json:"id"`
-	Name string     `json:"name"`
-	Vars []StateVar `json:"vars"`
+	ID           StateID     `json:"id"`
+	Name         string      `json:"name"`
+	Vars         []StateVar  `json:"vars"`
+	Color        string      `json:"color,omitempty"`
+	Stereotype   string      `json:"stereotype,omitempty"`
+	Descriptions []string    `json:"descriptions,omitempty"`
+	Annotations  Annotations `json:"annotations,omitempty"`
 }
 
 type StartEdge struct {
@@ -42,12 +61,16 @@ type StartEdge struct {
 	Post string  `json:"post"`
 }
 
+// Edge is a transition declaration. Style is the bracketed arrow style of a
+// PlantUML arrow such as "-[#red]->" ("#red"); it does not affect the semantics.
 type Edge struct {
-	Src   StateID `json:"src"`
-	Dst   StateID `json:"dst"`
-	Event Event   `json:"event"`
-	Guard string  `json:"guard"`
-	Post  string  `json:"post"`
+	Src         StateID     `json:"src"`
+	Dst         StateID     `json:"dst"`
+	Event       Event       `json:"event"`
+	Guard       string      `json:"guard"`
+	Post        string      `json:"post"`
+	Style       string      `json:"style,omitempty"`
+	Annotations Annotations `json:"annotations,omitempty"`
 }
 
 type EndEdge struct {
@@ -57,7 +80,16 @@ type EndEdge struct {
 
 func (d *Diagram) String() string {
 	var sb strings.Builder
-	sb.WriteString("@startuml\n")
+	sb.WriteString("@startuml")
+	if d.Name != "" {
+		sb.WriteString(" " + formatDiagramName(d.Name))
+	}
+	sb.WriteString("\n")
+	if len(d.Annotations) > 0 {
+		// The blank line keeps the annotations from attaching to the first state.
+		writeAnnotations(&sb, d.Annotations)
+		sb.WriteString("\n")
+	}
 
 	stateIDs := make([]StateID, 0, len(d.States))
 	for id := range d.States {
@@ -67,7 +99,15 @@ func (d *Diagram) String() string {
 
 	for _, id := range stateIDs {
 		state := d.States[id]
-		sb.WriteString(fmt.Sprintf("state \"%s\" as %s\n", state.Name, state.ID))
+		writeAnnotations(&sb, state.Annotations)
+		sb.WriteString(fmt.Sprintf("state \"%s\" as %s", state.Name, state.ID))
+		if state.Stereotype != "" {
+			sb.WriteString(fmt.Sprintf(" <<%s>>", state.Stereotype))
+		}
+		if state.Color != "" {
+			sb.WriteString(" " + state.Color)
+		}
+		sb.WriteString("\n")
 		for _, v := range state.Vars {
 			sb.WriteString(fmt.Sprintf("%s: %s", state.ID, v.Name))
 			if v.Type != "" {
@@ -75,6 +115,9 @@ func (d *Diagram) String() string {
 			}
 			sb.WriteString("\n")
 		}
+		for _, description := range state.Descriptions {
+			sb.WriteString(fmt.Sprintf("%s: %s\n", state.ID, description))
+		}
 	}
 
 	// StartEdge
@@ -86,16 +129,22 @@ func (d *Diagram) String() string {
 
 	// Regular edges
 	for _, edge := range d.Edges {
-		sb.WriteString(fmt.Sprintf("%s --> %s : %s", edge.Src, edge.Dst, edge.Event))
+		writeAnnotations(&sb, edge.Annotations)
+		arrow := "-->"
+		if edge.Style != "" {
+			arrow = "-[" + edge.Style + "]->"
+		}
+		sb.WriteString(fmt.Sprintf("%s %s %s : %s", edge.Src, arrow, edge.Dst, edge.Event))
 		if edge.Post == "" || edge.Post == True {
 			sb.WriteString("\n")
 			continue
 		}
-		if edge.Guard == "" || edge.Guard == True {
-			sb.WriteString(fmt.Sprintf(" ; %s\n", edge.Post))
-			continue
+		// The guard is written even when trivial: "e ; post" reads post as the guard.
+		guard := edge.Guard
+		if guard == "" {
+			guard = True
 		}
-		sb.WriteString(fmt.Sprintf(" ; %s ; %s\n", edge.Guard, edge.Post))
+		sb.WriteString(fmt.Sprintf(" ; %s ; %s\n", guard, edge.Post))
 	}
 
 	if d.EndEdge != nil {
@@ -109,3 +158,29 @@ func (d *Diagram) String() string {
 	sb.WriteString("@enduml\n")
 	return sb.String()
 }
+
+// formatDiagramName writes a diagram name bare when it is a single word and as
+// an escaped double-quoted string otherwise.
+func formatDiagramName(name string) string {
+	if !strings.ContainsAny(name, " \t\"\\'") {
+		return name
+	}
+	escaped := strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(name)
+	return `"` + escaped + `"`
+}
+
+// writeAnnotations writes one annotation comment line per key, in key order.
+func writeAnnotations(sb *strings.Builder, annotations Annotations) {
+	keys := make([]string, 0, len(annotations))
+	for key := range annotations {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if annotations[key] == "" {
+			sb.WriteString(fmt.Sprintf("/'@%s'/\n", key))
+			continue
+		}
+		sb.WriteString(fmt.Sprintf("/'@%s %s'/\n", key, annotations[key]))
+	}
+}
@@ -1,6 +1,11 @@
 package csdf
 
-import "testing"
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
 
 func TestDiagramStringOrdersStatesByID(t *testing.T) {
 	// Setup: a map literal whose iteration order is not stable across runs.
@@ -29,6 +34,30 @@ state "Third" as s2
 	}
 }
 
+func TestDiagramStringKeepsTrivialGuardBeforePost(t *testing.T) {
+	// Setup: "e ; post" would read the post-condition back as the guard.
+	diagram := Diagram{
+		States: map[StateID]State{
+			"s0": {ID: "s0", Name: "s0", Vars: []StateVar{}},
+		},
+		StartEdge: StartEdge{Dst: "s0", Post: True},
+		Edges:     []Edge{{Src: "s0", Dst: "s0", Event: "inc", Guard: True, Post: "n' = n + 1"}},
+	}
+
+	// Execute
+	got, err := ParseDiagram([]byte(diagram.String()))
+
+	// Assert
+	if err != nil {
+		t.Fatalf("ParseDiagram() error = %v", err)
+	}
+	if diff := cmp.Diff(diagram.Edges, got.Edges); diff != "" {
+		t.Error(diff)
+	}
+
+	// Teardown: no resources to release.
+}
+
 func TestDiagramStringIncludesEndEdge(t *testing.T) {
 	// Setup
 	diagram := Diagram{
@@ -113,3 +142,124 @@ s0 --> s0 : finish(result, status)
 		t.Errorf("Diagram.String() = %q, want %q", got, want)
 	}
 }
+
+func TestDiagramStringIncludesPlantUMLMetadata(t *testing.T) {
+	// Setup
+	diagram := Diagram{
+		States: map[StateID]State{
+			"s0": {
+				ID:           "s0",
+				Name:         "Idle",
+				Vars:         []StateVar{{Name: "coins"}},
+				Color:        "#pink",
+				Stereotype:   "waiting",
+				Descriptions: []string{"Waiting for a coin"},
+			},
+		},
+		StartEdge: StartEdge{Dst: "s0", Post: True},
+		Edges: []Edge{
+			{Src: "s0", Dst: "s0", Event: "insert", Guard: True, Post: True, Style: "#red"},
+		},
+	}
+	want := `@startuml
+state "Idle" as s0 <<waiting>> #pink
+s0: coins
+s0: Waiting for a coin
+[*] --> s0
+s0 -[#red]-> s0 : insert
+@enduml
+`
+
+	// Execute
+	got := diagram.String()
+
+	// Assert
+	if got != want {
+		t.Errorf("Diagram.String() = %q, want %q", got, want)
+	}
+
+	// Teardown: no resources to release.
+}
+
+func TestDiagramStringIncludesName(t *testing.T) {
+	tests := []struct {
+		name     string
+		diagram  string
+		wantHead string
+	}{
+		{name: "bare", diagram: "vending", wantHead: "@startuml vending\n"},
+		{name: "quoted", diagram: `a "b" c`, wantHead: `@startuml "a \"b\" c"` + "\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Setup
+			diagram := Diagram{
+				Name:      tt.diagram,
+				States:    map[StateID]State{"s0": {ID: "s0", Name: "s0"}},
+				StartEdge: StartEdge{Dst: "s0", Post: True},
+			}
+
+			// Execute
+			got := diagram.String()
+
+			// Assert
+			if !strings.HasPrefix(got, tt.wantHead) {
+				t.Errorf("Diagram.String() = %q, want prefix %q", got, tt.wantHead)
+			}
+			parsed, err := NewParser(got).Parse()
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if parsed.Name != tt.diagram {
+				t.Errorf("Parse() name = %q, want %q", parsed.Name, tt.diagram)
+			}
+
+			// Teardown: no resources to release.
+		})
+	}
+}
+
+func TestDiagramStringIncludesAnnotations(t *testing.T) {
+	// Setup
+	diagram := &Diagram{
+		States: map[StateID]State{
+			"s0": {ID: "s0", Name: "s0", Vars: []StateVar{}, Annotations: Annotations{"progress": ""}},
+			"s1": {ID: "s1", Name: "s1", Vars: []StateVar{}},
+		},
+		StartEdge: StartEdge{Dst: "s0", Post: True},
+		Edges: []Edge{
+			{Src: "s0", Dst: "s1", Event: "go", Guard: True, Post: True, Annotations: Annotations{"formal": "x > 0", "owner": "team-a"}},
+		},
+		Annotations: Annotations{"owner": "team-b"},
+	}
+	want := `@startuml
+/'@owner team-b'/
+
+/'@progress'/
+state "s0" as s0
+state "s1" as s1
+[*] --> s0
+/'@formal x > 0'/
+/'@owner team-a'/
+s0 --> s1 : go
+@enduml
+`
+
+	// Execute
+	got := diagram.String()
+
+	// Assert
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Error(diff)
+	}
+	parsed, err := NewParser(got).Parse()
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if diff := cmp.Diff(diagram, parsed); diff != "" {
+		t.Error(diff)
+	}
+
+	// Teardown: no resources to release.
+}
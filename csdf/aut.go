@@ -0,0 +1,237 @@
+package csdf
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// AutStateTable is the side table of an Aldebaran (.aut) file: what a
+// diagram has beyond numbered states and labeled transitions. States[n] is
+// state number n. It is stored as JSON next to the .aut file (see
+// AutStateTablePath).
+type AutStateTable struct {
+	Name      string   `json:"name,omitempty"`
+	States    []State  `json:"states"`
+	StartPost string   `json:"start_post,omitempty"`
+	EndEdge   *EndEdge `json:"end_edge,omitempty"`
+}
+
+// AutStateTablePath is the side table path of the .aut file at path:
+// "model.aut" has its table in "model.states.json".
+func AutStateTablePath(path string) string {
+	return strings.TrimSuffix(path, ".aut") + ".states.json"
+}
+
+// ParseAut reads an Aldebaran file, the LTS format of CADP and mCRL2:
+//
+//	des (initial, transitions, states)
+//	(from, "label", to)
+//	...
+//
+// A label is an event optionally followed by "; guard ; post" as in an edge
+// declaration, and the labels "i" and "tau" are τ. Without a table, state n
+// gets the ID and name "s<n>"; with one, the states, start post, end edge and
+// diagram name come from the table, which must describe every state.
+func ParseAut(input string, table *AutStateTable) (*Diagram, error) {
+	lines := strings.Split(input, "\n")
+	header := -1
+	for i, line := range lines {
+		if strings.TrimSpace(line) != "" {
+			header = i
+			break
+		}
+	}
+	if header < 0 {
+		return nil, fmt.Errorf("csdf.ParseAut: expected 'des (initial, transitions, states)' header")
+	}
+	tuple, ok := strings.CutPrefix(strings.TrimSpace(lines[header]), "des")
+	if !ok {
+		return nil, fmt.Errorf("csdf.ParseAut: expected 'des (initial, transitions, states)' header at line %d", header+1)
+	}
+	fields, err := splitAutTuple(tuple)
+	if err != nil {
+		return nil, fmt.Errorf("csdf.ParseAut: header at line %d: %w", header+1, err)
+	}
+	var counts [3]int
+	for i, field := range fields {
+		counts[i], err = strconv.Atoi(field)
+		if err != nil || counts[i] < 0 {
+			return nil, fmt.Errorf("csdf.ParseAut: header at line %d: expected a natural number, got %q", header+1, field)
+		}
+	}
+	initial, numTransitions, numStates := counts[0], counts[1], counts[2]
+	if initial >= numStates {
+		return nil, fmt.Errorf("csdf.ParseAut: initial state %d out of range 0..%d", initial, numStates-1)
+	}
+
+	ids, diagram, err := autStates(numStates, table)
+	if err != nil {
+		return nil, fmt.Errorf("csdf.ParseAut: %w", err)
+	}
+	diagram.StartEdge.Dst = ids[initial]
+
+	for i := header + 1; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+		if line == "" {
+			continue
+		}
+		edge, err := parseAutTransition(line, ids)
+		if err != nil {
+			return nil, fmt.Errorf("csdf.ParseAut: transition at line %d: %w", i+1, err)
+		}
+		diagram.Edges = append(diagram.Edges, edge)
+	}
+	if len(diagram.Edges) != numTransitions {
+		return nil, fmt.Errorf("csdf.ParseAut: header declares %d transitions, found %d", numTransitions, len(diagram.Edges))
+	}
+	return diagram, nil
+}
+
+// autStates returns the IDs of the numbered states and a diagram holding
+// them, using table when it is not nil.
+func autStates(numStates int, table *AutStateTable) ([]StateID, *Diagram, error) {
+	diagram := &Diagram{
+		States:    make(map[StateID]State, numStates),
+		StartEdge: StartEdge{Post: True},
+		Edges:     make([]Edge, 0),
+	}
+	ids := make([]StateID, numStates)
+	if table == nil {
+		for n := range ids {
+			ids[n] = StateID("s" + strconv.Itoa(n))
+			diagram.States[ids[n]] = State{ID: ids[n], Name: string(ids[n]), Vars: []StateVar{}}
+		}
+		return ids, diagram, nil
+	}
+
+	if len(table.States) != numStates {
+		return nil, nil, fmt.Errorf("the state table describes %d states, but the LTS has %d", len(table.States), numStates)
+	}
+	for n, state := range table.States {
+		if state.ID == "" {
+			return nil, nil, fmt.Errorf("state %d has no ID in the state table", n)
+		}
+		if _, ok := diagram.States[state.ID]; ok {
+			return nil, nil, fmt.Errorf("duplicate state ID %q in the state table", state.ID)
+		}
+		if state.Vars == nil {
+			state.Vars = []StateVar{}
+		}
+		ids[n] = state.ID
+		diagram.States[state.ID] = state
+	}
+	diagram.Name = table.Name
+	if table.StartPost != "" {
+		diagram.StartEdge.Post = table.StartPost
+	}
+	if table.EndEdge != nil {
+		if _, ok := diagram.States[table.EndEdge.Src]; !ok {
+			return nil, nil, fmt.Errorf("the end edge leaves unknown state %q", table.EndEdge.Src)
+		}
+		end := *table.EndEdge
+		diagram.EndEdge = &end
+	}
+	return ids, diagram, nil
+}
+
+func parseAutTransition(line string, ids []StateID) (Edge, error) {
+	fields, err := splitAutTuple(line)
+	if err != nil {
+		return Edge{}, err
+	}
+	var ends [2]StateID
+	for i, field := range []string{fields[0], fields[2]} {
+		n, err := strconv.Atoi(field)
+		if err != nil || n < 0 || n >= len(ids) {
+			return Edge{}, fmt.Errorf("expected a state number in 0..%d, got %q", len(ids)-1, field)
+		}
+		ends[i] = ids[n]
+	}
+
+	label := fields[1]
+	if strings.HasPrefix(label, `"`) {
+		if len(label) < 2 || !strings.HasSuffix(label, `"`) {
+			return Edge{}, fmt.Errorf("unterminated label %s", label)
+		}
+		label = label[1 : len(label)-1]
+	}
+	parts := strings.SplitN(label, ";", 3)
+	for i := range parts {
+		parts[i] = strings.TrimSpace(parts[i])
+	}
+	if parts[0] == "" {
+		return Edge{}, fmt.Errorf("empty label")
+	}
+	edge := Edge{Src: ends[0], Dst: ends[1], Event: Event(parts[0]), Guard: True, Post: True}
+	if edge.Event == "i" {
+		edge.Event = Tau
+	}
+	if len(parts) > 1 && parts[1] != "" {
+		edge.Guard = parts[1]
+	}
+	if len(parts) > 2 && parts[2] != "" {
+		edge.Post = parts[2]
+	}
+	return edge, nil
+}
+
+// splitAutTuple splits "(a, b, c)" into three trimmed fields. The middle field of
+// a transition may itself hold commas, so the first and the last comma
+// delimit it.
+func splitAutTuple(s string) ([]string, error) {
+	s = strings.TrimSpace(s)
+	if !strings.HasPrefix(s, "(") || !strings.HasSuffix(s, ")") {
+		return nil, fmt.Errorf("expected '(...)', got %q", s)
+	}
+	inner := s[1 : len(s)-1]
+	first := strings.IndexByte(inner, ',')
+	last := strings.LastIndexByte(inner, ',')
+	if first < 0 || first == last {
+		return nil, fmt.Errorf("expected three comma-separated fields, got %q", s)
+	}
+	fields := []string{inner[:first], inner[first+1 : last], inner[last+1:]}
+	for i := range fields {
+		fields[i] = strings.TrimSpace(fields[i])
+	}
+	return fields, nil
+}
+
+// isAutSource reports whether a source read from path is an Aldebaran file:
+// by the .aut extension, or, for other paths and standard input, by a leading
+// "des" header.
+func isAutSource(path, text string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".aut":
+		return true
+	case ".puml", ".plantuml", ".png", ".svg", ".csp", ".cspm":
+		return false
+	}
+	rest, ok := strings.CutPrefix(strings.TrimLeft(text, " \t\r\n"), "des")
+	return ok && strings.HasPrefix(strings.TrimLeft(rest, " \t"), "(")
+}
+
+// readAutStateTable reads the side table of the .aut file at path, returning
+// nil when there is none.
+func readAutStateTable(path string) (*AutStateTable, error) {
+	if path == "" {
+		return nil, nil
+	}
+	bs, err := os.ReadFile(AutStateTablePath(path))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("csdf.readAutStateTable: %w", err)
+	}
+	var table AutStateTable
+	if err := json.Unmarshal(bs, &table); err != nil {
+		return nil, fmt.Errorf("csdf.readAutStateTable: %s: %w", AutStateTablePath(path), err)
+	}
+	return &table, nil
+}
@@ -0,0 +1,124 @@
+// Package aut writes Composable State Diagrams in the Aldebaran (.aut) format
+// of CADP and the mCRL2 toolset. csdf.ParseAut reads them back.
+package aut
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Kuniwak/puml-parallel/csdf"
+)
+
+// ExportOptions selects how labels are written.
+type ExportOptions struct {
+	// Tau is the label of τ-edges: "i" for CADP or "tau" for mCRL2.
+	Tau string
+	// Conditions appends non-trivial guards and post-conditions to labels as
+	// "event ; guard ; post".
+	Conditions bool
+}
+
+// ParseTau parses the -tau option of csdf2aut: "i" or "tau".
+func ParseTau(s string) (string, error) {
+	switch s {
+	case "i", string(csdf.Tau):
+		return s, nil
+	default:
+		return "", fmt.Errorf("aut.ParseTau: unknown internal action label %q (want i or tau)", s)
+	}
+}
+
+// Export writes d as an Aldebaran file and returns it with the side table that
+// keeps the state IDs, names and variables, the start post-condition and the
+// end edge. The start state is number 0 and the others follow in ID order.
+func Export(d *csdf.Diagram, opts *ExportOptions) (string, *csdf.AutStateTable, error) {
+	tau, err := ParseTau(opts.Tau)
+	if err != nil {
+		return "", nil, fmt.Errorf("aut.Export: %w", err)
+	}
+
+	// A state only referred to by edges gets a number too.
+	seen := map[csdf.StateID]bool{d.StartEdge.Dst: true}
+	stateIDs := make([]csdf.StateID, 0, len(d.States))
+	add := func(id csdf.StateID) {
+		if !seen[id] {
+			seen[id] = true
+			stateIDs = append(stateIDs, id)
+		}
+	}
+	for id := range d.States {
+		add(id)
+	}
+	for _, e := range d.Edges {
+		add(e.Src)
+		add(e.Dst)
+	}
+	if d.EndEdge != nil {
+		add(d.EndEdge.Src)
+	}
+	sort.Slice(stateIDs, func(i, j int) bool { return stateIDs[i] < stateIDs[j] })
+	stateIDs = append([]csdf.StateID{d.StartEdge.Dst}, stateIDs...)
+
+	numbers := make(map[csdf.StateID]int, len(stateIDs))
+	table := &csdf.AutStateTable{Name: d.Name, States: make([]csdf.State, len(stateIDs))}
+	for n, id := range stateIDs {
+		numbers[id] = n
+		state, ok := d.States[id]
+		if !ok {
+			state = csdf.State{ID: id, Name: string(id)}
+		}
+		table.States[n] = state
+	}
+	if d.StartEdge.Post != "" && d.StartEdge.Post != csdf.True {
+		table.StartPost = d.StartEdge.Post
+	}
+	if d.EndEdge != nil {
+		end := *d.EndEdge
+		table.EndEdge = &end
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("des (0, %d, %d)\n", len(d.Edges), len(stateIDs)))
+	for _, e := range d.Edges {
+		label, err := edgeLabel(e, tau, opts.Conditions)
+		if err != nil {
+			return "", nil, fmt.Errorf("aut.Export: %w", err)
+		}
+		sb.WriteString(fmt.Sprintf("(%d, \"%s\", %d)\n", numbers[e.Src], label, numbers[e.Dst]))
+	}
+	return sb.String(), table, nil
+}
+
+func edgeLabel(e csdf.Edge, tau string, conditions bool) (string, error) {
+	label := string(e.Event)
+	if e.Event == csdf.Tau {
+		label = tau
+	} else if e.Event == "i" {
+		return "", fmt.Errorf("event %q of edge %s -> %s would read back as the internal action", e.Event, e.Src, e.Dst)
+	}
+	if strings.ContainsAny(label, "\"\n") {
+		return "", fmt.Errorf("event %q of edge %s -> %s cannot be an Aldebaran label", e.Event, e.Src, e.Dst)
+	}
+	if !conditions {
+		return label, nil
+	}
+	guard := e.Guard
+	if guard == "" {
+		guard = csdf.True
+	}
+	post := e.Post
+	if post == "" {
+		post = csdf.True
+	}
+	switch {
+	case post != csdf.True:
+		label += " ; " + guard + " ; " + post
+	case guard != csdf.True:
+		label += " ; " + guard
+	}
+	if strings.ContainsAny(label, "\"\n") {
+		return "", fmt.Errorf("the conditions of edge %s -> %s cannot be part of an Aldebaran label", e.Src, e.Dst)
+	}
+	return label, nil
+}
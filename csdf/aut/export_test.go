@@ -0,0 +1,145 @@
+package aut
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Kuniwak/puml-parallel/csdf"
+	"github.com/google/go-cmp/cmp"
+)
+
+func mustParse(t *testing.T, src string) *csdf.Diagram {
+	t.Helper()
+	d, err := csdf.ParseDiagram([]byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+const teaMachine = `@startuml tea
+state "Ready" as ready
+ready: cups ; int
+state "Brewing" as brewing
+[*] --> ready : cups = 0
+ready --> brewing : coin
+brewing --> ready : tea ; hot ; cups' = cups + 1
+brewing --> brewing : tau
+ready --> [*]
+@enduml
+`
+
+func TestExportNumbersStatesFromStart(t *testing.T) {
+	// Setup
+	d := mustParse(t, teaMachine)
+	wantAut := `des (0, 3, 2)
+(0, "coin", 1)
+(1, "tea", 0)
+(1, "i", 1)
+`
+	wantTable := &csdf.AutStateTable{
+		Name: "tea",
+		States: []csdf.State{
+			{ID: "ready", Name: "Ready", Vars: []csdf.StateVar{{Name: "cups", Type: "int"}}},
+			{ID: "brewing", Name: "Brewing", Vars: []csdf.StateVar{}},
+		},
+		StartPost: "cups = 0",
+		EndEdge:   &csdf.EndEdge{Src: "ready"},
+	}
+
+	// Execute
+	gotAut, gotTable, err := Export(d, &ExportOptions{Tau: "i"})
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+	if diff := cmp.Diff(wantAut, gotAut); diff != "" {
+		t.Error(diff)
+	}
+	if diff := cmp.Diff(wantTable, gotTable); diff != "" {
+		t.Error(diff)
+	}
+
+	// Teardown: no resources to release.
+}
+
+func TestExportWithConditionsRoundTrips(t *testing.T) {
+	// Setup
+	d := mustParse(t, teaMachine)
+
+	// Execute
+	lts, table, err := Export(d, &ExportOptions{Tau: "tau", Conditions: true})
+	if err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+	got, err := csdf.ParseAut(lts, table)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("ParseAut() error = %v", err)
+	}
+	if !strings.Contains(lts, `(1, "tea ; hot ; cups' = cups + 1", 0)`) {
+		t.Errorf("want conditions in labels, got %q", lts)
+	}
+	if diff := cmp.Diff(d, got); diff != "" {
+		t.Error(diff)
+	}
+
+	// Teardown: no resources to release.
+}
+
+func TestExportRejectsEventNamedI(t *testing.T) {
+	// Setup: "i" is the internal action of CADP.
+	d := mustParse(t, `@startuml
+state "s0" as s0
+[*] --> s0
+s0 --> s0 : i
+@enduml
+`)
+
+	// Execute
+	_, _, err := Export(d, &ExportOptions{Tau: "tau"})
+
+	// Assert
+	if err == nil {
+		t.Error("Export() error = nil, want error")
+	}
+
+	// Teardown: no resources to release.
+}
+
+func TestExportNumbersStatesOnlyReferredToByEdges(t *testing.T) {
+	// Setup: b is not declared.
+	d := mustParse(t, `@startuml
+state "A" as a
+[*] --> a
+a --> b : go
+b --> a : back
+@enduml
+`)
+	wantAut := `des (0, 2, 2)
+(0, "go", 1)
+(1, "back", 0)
+`
+	wantStates := []csdf.State{
+		{ID: "a", Name: "A", Vars: []csdf.StateVar{}},
+		{ID: "b", Name: "b"},
+	}
+
+	// Execute
+	gotAut, gotTable, err := Export(d, &ExportOptions{Tau: "i"})
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+	if diff := cmp.Diff(wantAut, gotAut); diff != "" {
+		t.Error(diff)
+	}
+	if diff := cmp.Diff(wantStates, gotTable.States); diff != "" {
+		t.Error(diff)
+	}
+
+	// Teardown: no resources to release.
+}
@@ -0,0 +1,146 @@
+package csdf
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseAutWithoutStateTable(t *testing.T) {
+	// Setup
+	input := `des (1, 3, 3)
+(1, "coin", 2)
+(2, "tea ; hot ; cups' = cups + 1", 1)
+(2, i, 0)
+`
+	want := &Diagram{
+		States: map[StateID]State{
+			"s0": {ID: "s0", Name: "s0", Vars: []StateVar{}},
+			"s1": {ID: "s1", Name: "s1", Vars: []StateVar{}},
+			"s2": {ID: "s2", Name: "s2", Vars: []StateVar{}},
+		},
+		StartEdge: StartEdge{Dst: "s1", Post: True},
+		Edges: []Edge{
+			{Src: "s1", Dst: "s2", Event: "coin", Guard: True, Post: True},
+			{Src: "s2", Dst: "s1", Event: "tea", Guard: "hot", Post: "cups' = cups + 1"},
+			{Src: "s2", Dst: "s0", Event: Tau, Guard: True, Post: True},
+		},
+	}
+
+	// Execute
+	got, err := ParseAut(input, nil)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("ParseAut() error = %v", err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Error(diff)
+	}
+
+	// Teardown: no resources to release.
+}
+
+func TestParseAutRejects(t *testing.T) {
+	testCases := map[string]struct {
+		input string
+		table *AutStateTable
+		want  string
+	}{
+		"missing header": {
+			input: `(0, "a", 0)` + "\n",
+			want:  "expected 'des (initial, transitions, states)' header",
+		},
+		"initial state out of range": {
+			input: "des (2, 0, 2)\n",
+			want:  "initial state 2 out of range",
+		},
+		"wrong transition count": {
+			input: "des (0, 2, 1)\n(0, \"a\", 0)\n",
+			want:  "header declares 2 transitions, found 1",
+		},
+		"state out of range": {
+			input: "des (0, 1, 1)\n(0, \"a\", 1)\n",
+			want:  "expected a state number in 0..0",
+		},
+		"empty label": {
+			input: "des (0, 1, 1)\n(0, \"\", 0)\n",
+			want:  "empty label",
+		},
+		"state table of another LTS": {
+			input: "des (0, 0, 2)\n",
+			table: &AutStateTable{States: []State{{ID: "a"}}},
+			want:  "the state table describes 1 states, but the LTS has 2",
+		},
+		"duplicate state IDs": {
+			input: "des (0, 0, 2)\n",
+			table: &AutStateTable{States: []State{{ID: "a"}, {ID: "a"}}},
+			want:  `duplicate state ID "a"`,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Execute
+			_, err := ParseAut(tc.input, tc.table)
+
+			// Assert
+			if err == nil {
+				t.Fatal("ParseAut() error = nil, want error")
+			}
+			if !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("ParseAut() error = %q, want it to contain %q", err.Error(), tc.want)
+			}
+		})
+	}
+}
+
+func TestLoadDiagramsReadsAutWithStateTable(t *testing.T) {
+	// Setup: vending_machine.aut and vending_machine.states.json were written by
+	// csdf2aut -conditions from vending_machine.puml.
+	want := MustLoadDiagrams("../examples/valid/vending_machine.puml")[0]
+
+	// Execute
+	got, err := LoadDiagrams([]string{"../examples/valid/vending_machine.aut"})
+
+	// Assert
+	if err != nil {
+		t.Fatalf("LoadDiagrams() error = %v", err)
+	}
+	if diff := cmp.Diff(want, got[0]); diff != "" {
+		t.Error(diff)
+	}
+
+	// Teardown: no resources to release.
+}
+
+func TestLoadDiagramsRejectsNamesAutDiagramsDoNotHave(t *testing.T) {
+	// Execute: the side table of vending_machine.aut names no diagram.
+	_, err := LoadDiagrams([]string{"../examples/valid/vending_machine.aut#vm"})
+
+	// Assert
+	if err == nil || !strings.Contains(err.Error(), `no diagram named "vm"`) {
+		t.Errorf("LoadDiagrams() error = %v, want no diagram named \"vm\"", err)
+	}
+
+	// Teardown: no resources to release.
+}
+
+func TestParseDiagramDetectsAutByContent(t *testing.T) {
+	// Setup
+	input := []byte("des (0, 1, 1)\n(0, \"tau\", 0)\n")
+
+	// Execute
+	d, err := ParseDiagram(input)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("ParseDiagram() error = %v", err)
+	}
+	if len(d.Edges) != 1 || d.Edges[0].Event != Tau {
+		t.Errorf("ParseDiagram() edges = %+v, want one tau edge", d.Edges)
+	}
+
+	// Teardown: no resources to release.
+}
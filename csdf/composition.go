@@ -2,6 +2,11 @@ package csdf
 
 import (
 	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
 )
 
 type StatePair struct {
@@ -27,206 +32,737 @@ func (s StatePair) State() State {
 	}
 }
 
+// ComposeParallel composes diagrams in interface parallel over syncEvents:
+// events in syncEvents are taken by all the diagrams together, other events by
+// any one of them alone. Only the product states reachable from the start are
+// built. A single diagram is returned with its variables renamed as below,
+// without the states that neither the start edge nor any edge refers to.
+//
+// The product states are flat tuples of component states in argument order:
+// the product of a, b and c has the ID a_b_c (see ComposeStateIDs) and the name
+// "(a, b, c)". The variables of the i-th diagram (counting from 1) are renamed
+// to pi_<name>, in the states as well as in the guards and post-conditions, so
+// that variables of the same name in different diagrams stay apart.
+//
+// Edges are written in the order the product states are discovered, and for
+// each state in the order of the component edges: the first diagram's edges
+// first, each synchronized with the other diagrams' edges of the same event in
+// turn, then the unsynchronized edges of the other diagrams in argument order.
 func ComposeParallel(diagrams []*Diagram, syncEvents []Event) (*Diagram, error) {
-	if len(diagrams) < 1 {
-		return nil, fmt.Errorf("csdf.ComposeParallel: at least one diagrams are required for interface parallel")
-	}
+	return (&Explorer{}).ComposeParallel(diagrams, syncEvents)
+}
 
+// ComposeParallel is the function ComposeParallel run on the workers of x.
+func (x *Explorer) ComposeParallel(diagrams []*Diagram, syncEvents []Event) (*Diagram, error) {
 	if len(diagrams) == 1 {
-		return diagrams[0], nil
+		return namespaced(diagrams[0], "p1"), nil
 	}
+	p, err := x.explore(diagrams, syncEvents, noReduction, true)
+	if err != nil {
+		return nil, fmt.Errorf("csdf.Explorer.ComposeParallel: %w", err)
+	}
+	return p.diagram(), nil
+}
 
-	dL := diagrams[0]
-	dR := diagrams[1]
+// Composition is a composed diagram together with where its states and edges
+// come from.
+type Composition struct {
+	Diagram *Diagram `json:"diagram"`
+	// States maps each state of Diagram to the IDs of its component states, in
+	// argument order.
+	States map[StateID][]StateID `json:"states"`
+	// Edges holds, for the edge of Diagram at the same index, the component
+	// edges taken together, in argument order.
+	Edges [][]ComponentEdge `json:"edges"`
+}
 
-	if len(diagrams) > 2 {
-		for _, d := range diagrams[2:] {
-			var err error
-			dL, err = ComposeParallel2(dL, d, syncEvents)
-			if err != nil {
-				return nil, fmt.Errorf("csdf.ComposeParallel: %w", err)
-			}
+// ComponentEdge is an edge of one of the composed diagrams.
+type ComponentEdge struct {
+	// Component is the index of the diagram in the arguments of Compose.
+	Component int `json:"component"`
+	// Edge is the index of the edge in the Edges of the diagram.
+	Edge  int     `json:"edge"`
+	Src   StateID `json:"src"`
+	Dst   StateID `json:"dst"`
+	Event Event   `json:"event"`
+}
+
+// Compose is ComposeParallel that also returns the provenance of the composite
+// states and edges. A single diagram is its own composition, with its variables
+// renamed as by ComposeParallel.
+func Compose(diagrams []*Diagram, syncEvents []Event) (*Composition, error) {
+	return (&Explorer{}).Compose(diagrams, syncEvents)
+}
+
+// Compose is the function Compose run on the workers of x.
+func (x *Explorer) Compose(diagrams []*Diagram, syncEvents []Event) (*Composition, error) {
+	if len(diagrams) == 1 {
+		d := namespaced(diagrams[0], "p1")
+		c := &Composition{
+			Diagram: d,
+			States:  make(map[StateID][]StateID, len(d.States)),
+			Edges:   make([][]ComponentEdge, len(d.Edges)),
+		}
+		for id := range d.States {
+			c.States[id] = []StateID{id}
+		}
+		for i, e := range d.Edges {
+			c.Edges[i] = []ComponentEdge{{Component: 0, Edge: i, Src: e.Src, Dst: e.Dst, Event: e.Event}}
 		}
+		return c, nil
 	}
+	p, err := x.explore(diagrams, syncEvents, noReduction, true)
+	if err != nil {
+		return nil, fmt.Errorf("csdf.Explorer.Compose: %w", err)
+	}
+	return p.composition(), nil
+}
 
-	return ComposeParallel2(dL, dR, syncEvents)
+// product is the reachable part of the product of some components, with the
+// product states and edges referring to the components by number.
+type product struct {
+	components []*component
+	// tuples holds the component state numbers of the k-th product state at
+	// tuples[k*n : (k+1)*n], where n is the number of components.
+	tuples []int32
+	edges  []bfsEdge[move]
+	// ids holds the final ID of each product state.
+	ids []StateID
 }
 
-func ComposeParallel2(dL, dR *Diagram, syncEvents []Event) (*Diagram, error) {
-	if dL.EndEdge != nil || dR.EndEdge != nil {
-		return nil, fmt.Errorf("csdf.ComposeParallel2: end edges are not supported for interface parallel")
+// move is the label of a product edge: the edge e of the component taken
+// alone, or the synchronized edge sync when component is -1.
+type move struct {
+	component, e int32
+	sync         *syncMove
+}
+
+// syncMove is an edge taken by all the components together: edges[i] is the
+// edge of the i-th component.
+type syncMove struct {
+	event       Event
+	guard, post string
+	edges       []int32
+}
+
+// reduction is the partial-order reduction explore applies to the product.
+//
+// Edges of different components that are not synchronized commute, so the
+// product holds every interleaving of them although the checks below need only
+// some. In each state, explore looks for a component whose edges out of its
+// current state are all unsynchronized: only that component can take them or
+// disable them, so they form an ample set, and explore follows them alone
+// instead of every edge of the state. The reduced product keeps every
+// reachable deadlock. To keep every reachable τ-cycle too, the ample edges
+// must be τ, and none may lead back to a state numbered no later than the
+// state (the cycle proviso), so that no edge is put off along a cycle forever.
+// States without an ample set are fully expanded.
+type reduction int
+
+const (
+	noReduction reduction = iota
+	preserveDeadlocks
+	preserveDivergences
+)
+
+// explore builds the product of diagrams under r. Unless namespaced is false,
+// the variables of the i-th diagram are renamed to pi_<name> (see
+// ComposeParallel).
+func (x *Explorer) explore(diagrams []*Diagram, syncEvents []Event, r reduction, namespaced bool) (_ *product, err error) {
+	if len(diagrams) < 1 {
+		return nil, fmt.Errorf("at least one diagrams are required for interface parallel")
+	}
+	for _, d := range diagrams {
+		if d.EndEdge != nil {
+			return nil, fmt.Errorf("end edges are not supported for interface parallel")
+		}
 	}
 
-	ss := make(map[Event]struct{})
+	ss := make(map[Event]struct{}, len(syncEvents))
 	for _, event := range syncEvents {
 		ss[event] = struct{}{}
 	}
+	n := len(diagrams)
+	p := &product{components: make([]*component, n)}
+	for i, d := range diagrams {
+		namespace := ""
+		if namespaced {
+			namespace = fmt.Sprintf("p%d", i+1)
+		}
+		p.components[i] = newComponent(d, namespace)
+	}
 
-	initStatePair := StatePair{
-		Left:  dL.States[dL.StartEdge.Dst],
-		Right: dR.States[dR.StartEdge.Dst],
+	start := make([]int32, n)
+	for i, c := range p.components {
+		start[i] = c.start
 	}
+	codec := newTupleCodec(p.components)
+	store, err := x.newStore()
+	if err != nil {
+		return nil, err
+	}
+	defer closeStore(store, &err)
+	edges, err := bfs(store, codec.encode(nil, start), func() expandFunc[move] {
+		current := make([]int32, n)
+		next := make([]int32, n)
+		var key []byte
+		matches := make([][]int32, n)
+		guards := make([]string, n)
+		posts := make([]string, n)
+		choice := make([]int, n)
+		// ample returns the component whose edges out of current make an
+		// ample set, or -1 if the state must be fully expanded.
+		ample := func(visited func([]byte) bool) int {
+		candidates:
+			for i, c := range p.components {
+				out := c.out[current[i]]
+				if len(out) == 0 {
+					continue
+				}
+				for _, e := range out {
+					event := c.d.Edges[e].Event
+					if _, ok := ss[event]; ok {
+						continue candidates
+					}
+					if r == preserveDivergences {
+						if event != Tau {
+							continue candidates
+						}
+						copy(next, current)
+						next[i] = c.dst[e]
+						if visited(codec.encode(key[:0], next)) {
+							continue candidates
+						}
+					}
+				}
+				return i
+			}
+			return -1
+		}
+		return func(k []byte, visited func([]byte) bool, emit func([]byte, move)) {
+			codec.decode(current, k)
+			if r != noReduction {
+				if i := ample(visited); i >= 0 {
+					c := p.components[i]
+					for _, e := range c.out[current[i]] {
+						copy(next, current)
+						next[i] = c.dst[e]
+						emit(codec.encode(key[:0], next), move{component: int32(i), e: e})
+					}
+					return
+				}
+			}
 
-	states := make(map[StateID]State)
-	states[initStatePair.ID()] = initStatePair.State()
+			first := p.components[0]
+			for _, e := range first.out[current[0]] {
+				event := first.d.Edges[e].Event
+				if _, ok := ss[event]; !ok {
+					// Para1
+					copy(next, current)
+					next[0] = first.dst[e]
+					emit(codec.encode(key[:0], next), move{component: 0, e: e})
+					continue
+				}
+				// Para3
+				matches[0] = append(matches[0][:0], e)
+				blocked := false
+				for i, c := range p.components[1:] {
+					matches[i+1] = matches[i+1][:0]
+					for _, f := range c.out[current[i+1]] {
+						if c.d.Edges[f].Event == event {
+							matches[i+1] = append(matches[i+1], f)
+						}
+					}
+					if len(matches[i+1]) == 0 {
+						blocked = true
+						break
+					}
+				}
+				if blocked {
+					continue
+				}
+				// choice[i] walks matches[i]; the last component varies fastest.
+				clear(choice)
+				for {
+					s := &syncMove{event: event, edges: make([]int32, n)}
+					for i, c := range p.components {
+						f := matches[i][choice[i]]
+						next[i] = c.dst[f]
+						guards[i], posts[i] = c.guards[f], c.posts[f]
+						s.edges[i] = f
+					}
+					s.guard, s.post = ComposeGuard(guards...), ComposePostConditions(posts...)
+					emit(codec.encode(key[:0], next), move{component: -1, sync: s})
+					i := n - 1
+					for ; i >= 0; i-- {
+						choice[i]++
+						if choice[i] < len(matches[i]) {
+							break
+						}
+						choice[i] = 0
+					}
+					if i < 0 {
+						break
+					}
+				}
+			}
+			for i, c := range p.components[1:] {
+				for _, e := range c.out[current[i+1]] {
+					if _, ok := ss[c.d.Edges[e].Event]; !ok {
+						// Para2
+						copy(next, current)
+						next[i+1] = c.dst[e]
+						emit(codec.encode(key[:0], next), move{component: int32(i + 1), e: e})
+					}
+				}
+			}
+		}
+	}, x.workers())
+	if err != nil {
+		return nil, err
+	}
+	p.edges = edges
+	p.tuples = make([]int32, n*store.len())
+	var key []byte
+	for k := range store.len() {
+		if key, err = store.key(key[:0], int32(k)); err != nil {
+			return nil, err
+		}
+		codec.decode(p.tuples[k*n:(k+1)*n], key)
+	}
+
+	allocator := newStateIDAllocator()
+	ids := make([]StateID, store.len())
+	stateIDs := make([]StateID, n)
+	for k := range ids {
+		for i, c := range p.components {
+			stateIDs[i] = c.ids[p.tuples[k*n+i]]
+		}
+		ids[k] = allocator.id(stateKey(stateIDs...), ComposeStateIDs(stateIDs...))
+	}
+	final := allocator.final()
+	p.ids = make([]StateID, len(ids))
+	for k, id := range ids {
+		p.ids[k] = final[id]
+	}
+	return p, nil
+}
 
+// diagram builds the composed diagram.
+func (p *product) diagram() *Diagram {
+	n := len(p.components)
+	startPosts := make([]string, n)
+	for i, c := range p.components {
+		startPosts[i] = c.startPost
+	}
 	out := &Diagram{
-		States: states,
+		States: make(map[StateID]State, len(p.ids)),
 		StartEdge: StartEdge{
-			Dst:  initStatePair.ID(),
-			Post: ComposePostConditions(dL.StartEdge.Post, dR.StartEdge.Post),
+			Dst:  p.ids[0],
+			Post: ComposePostConditions(startPosts...),
 		},
-		Edges: make([]Edge, 0),
+		Edges: make([]Edge, len(p.edges)),
+	}
+	names := make([]string, n)
+	for k, id := range p.ids {
+		size := 0
+		for i, c := range p.components {
+			size += len(c.vars[p.tuples[k*n+i]])
+		}
+		vars := make([]StateVar, 0, size)
+		for i, c := range p.components {
+			state := p.tuples[k*n+i]
+			names[i] = c.names[state]
+			vars = append(vars, c.vars[state]...)
+		}
+		out.States[id] = State{ID: id, Name: ComposeStateNames(names...), Vars: vars}
+	}
+	for i, e := range p.edges {
+		if s := e.label.sync; s != nil {
+			out.Edges[i] = Edge{Event: s.event, Guard: s.guard, Post: s.post}
+		} else {
+			out.Edges[i] = p.components[e.label.component].edge(e.label.e)
+		}
+		out.Edges[i].Src = p.ids[e.src]
+		out.Edges[i].Dst = p.ids[e.dst]
 	}
+	return out
+}
 
-	marked := make(map[StateID]struct{})
-	marked[initStatePair.ID()] = struct{}{}
-	queue := []StatePair{initStatePair}
-	for len(queue) > 0 {
-		if err := composeParallel2(dL, dR, dL.Edges, dR.Edges, &queue, &marked, ss, out); err != nil {
-			return nil, fmt.Errorf("csdf.ComposeParallel2: %w", err)
+// composition builds the composed diagram with its provenance.
+func (p *product) composition() *Composition {
+	n := len(p.components)
+	c := &Composition{
+		Diagram: p.diagram(),
+		States:  make(map[StateID][]StateID, len(p.ids)),
+		Edges:   make([][]ComponentEdge, len(p.edges)),
+	}
+	for k, id := range p.ids {
+		tuple := make([]StateID, n)
+		for i, comp := range p.components {
+			tuple[i] = comp.ids[p.tuples[k*n+i]]
+		}
+		c.States[id] = tuple
+	}
+	for i, e := range p.edges {
+		s := e.label.sync
+		if s == nil {
+			c.Edges[i] = []ComponentEdge{p.components[e.label.component].componentEdge(int(e.label.component), e.label.e)}
+			continue
 		}
+		moves := make([]ComponentEdge, n)
+		for j, comp := range p.components {
+			moves[j] = comp.componentEdge(j, s.edges[j])
+		}
+		c.Edges[i] = moves
 	}
-	return out, nil
+	return c
 }
 
-func composeParallel2(dL, dR *Diagram, tsL, tsR []Edge, queue *[]StatePair, marked *map[StateID]struct{}, syncEvents map[Event]struct{}, out *Diagram) error {
-	currentPair := (*queue)[0]
-	currentPairID := currentPair.ID()
-	*queue = (*queue)[1:]
+// ComposeParallel2 is ComposeParallel for the two diagrams dL and dR.
+func ComposeParallel2(dL, dR *Diagram, syncEvents []Event) (*Diagram, error) {
+	composite, err := ComposeParallel([]*Diagram{dL, dR}, syncEvents)
+	if err != nil {
+		return nil, fmt.Errorf("csdf.ComposeParallel2: %w", err)
+	}
+	return composite, nil
+}
 
-	evs := make(map[Event]struct{})
-	evL := make(map[Event]map[StateID][]Edge)
-	evR := make(map[Event]map[StateID][]Edge)
-	for _, tL := range tsL {
-		if tL.Src == currentPair.Left.ID {
-			evs[tL.Event] = struct{}{}
-			if _, ok := evL[tL.Event]; !ok {
-				evL[tL.Event] = make(map[StateID][]Edge)
-			}
-			evL[tL.Event][tL.Dst] = append(evL[tL.Event][tL.Dst], tL)
+// tupleCodec encodes product states, the tuples of the state numbers of the
+// components, into keys. Each number takes as few bytes as the number of states
+// of its component needs, so that the keys of most products are a few bytes
+// long.
+type tupleCodec struct {
+	widths []int
+}
+
+func newTupleCodec(components []*component) tupleCodec {
+	widths := make([]int, len(components))
+	for i, c := range components {
+		widths[i] = 1
+		for m := len(c.ids) - 1; m > 0xff; m >>= 8 {
+			widths[i]++
+		}
+	}
+	return tupleCodec{widths: widths}
+}
+
+// encode appends the key of tuple to key.
+func (c tupleCodec) encode(key []byte, tuple []int32) []byte {
+	for i, n := range tuple {
+		for b := 0; b < c.widths[i]; b++ {
+			key = append(key, byte(n>>(8*b)))
+		}
+	}
+	return key
+}
+
+// decode decodes the key made by encode into tuple.
+func (c tupleCodec) decode(tuple []int32, key []byte) {
+	for i, w := range c.widths {
+		var n int32
+		for b := 0; b < w; b++ {
+			n |= int32(key[b]) << (8 * b)
 		}
+		tuple[i] = n
+		key = key[w:]
+	}
+}
+
+// component is a diagram prepared for ComposeParallel. It numbers the states
+// and lists the outgoing edges of each, so that the edges leaving a state are
+// found without scanning the whole diagram, and holds the state variables,
+// guards and post-conditions with the variables renamed into the namespace of
+// the component.
+type component struct {
+	d     *Diagram
+	start int32
+	// ids maps state numbers to state IDs, and numbers the reverse.
+	ids     []StateID
+	numbers map[StateID]int32
+	// out lists, per state number, the indexes into d.Edges of the edges
+	// leaving the state, in declaration order.
+	out [][]int32
+	// dst is the state number of the destination of each edge.
+	dst []int32
+
+	names     []string
+	vars      [][]StateVar
+	guards    []string
+	posts     []string
+	startPost string
+}
+
+// newComponent prepares d with its variables renamed to namespace_<name>, or
+// kept as they are if namespace is "".
+func newComponent(d *Diagram, namespace string) *component {
+	c := &component{
+		d:       d,
+		numbers: make(map[StateID]int32, len(d.States)),
+		dst:     make([]int32, len(d.Edges)),
+	}
+	c.start = c.number(d.StartEdge.Dst)
+	for i, e := range d.Edges {
+		src := c.number(e.Src)
+		c.dst[i] = c.number(e.Dst)
+		c.out[src] = append(c.out[src], int32(i))
 	}
 
-	for _, tR := range tsR {
-		if tR.Src == currentPair.Right.ID {
-			evs[tR.Event] = struct{}{}
-			if _, ok := evR[tR.Event]; !ok {
-				evR[tR.Event] = make(map[StateID][]Edge)
+	renames := make(map[Var]Var)
+	c.names = make([]string, len(c.ids))
+	c.vars = make([][]StateVar, len(c.ids))
+	for n, id := range c.ids {
+		state, ok := d.States[id]
+		if !ok {
+			// A state only referred to by edges has just an ID.
+			state = State{ID: id, Name: string(id)}
+		}
+		c.names[n] = state.Name
+		for _, v := range state.Vars {
+			renamed := v.Name
+			if namespace != "" {
+				renamed = Var(namespace + "_" + string(v.Name))
+				renames[v.Name] = renamed
 			}
-			evR[tR.Event][tR.Dst] = append(evR[tR.Event][tR.Dst], tR)
-		}
-	}
-
-	for ev := range evs {
-		// Para3
-		if _, ok := syncEvents[ev]; ok {
-			if dstLs, ok := evL[ev]; ok {
-				if dstRs, ok := evR[ev]; ok {
-					for dstL, esL := range dstLs {
-						for dstR, esR := range dstRs {
-							for _, eL := range esL {
-								for _, eR := range esR {
-									nextStatePair := StatePair{
-										Left:  dL.States[dstL],
-										Right: dR.States[dstR],
-									}
-									out.States[nextStatePair.ID()] = nextStatePair.State()
-									out.Edges = append(out.Edges, Edge{
-										Src:   currentPairID,
-										Dst:   nextStatePair.ID(),
-										Event: ev,
-										Guard: ComposeGuard(eL.Guard, eR.Guard),
-										Post:  ComposePostConditions(eL.Post, eR.Post),
-									})
-									if _, ok := (*marked)[nextStatePair.ID()]; !ok {
-										*queue = append(*queue, nextStatePair)
-										(*marked)[nextStatePair.ID()] = struct{}{}
-									}
-								}
-							}
-						}
-					}
-				}
+			c.vars[n] = append(c.vars[n], StateVar{Name: renamed, Type: v.Type})
+		}
+	}
+	r := newVarRenamer(renames)
+	c.guards = make([]string, len(d.Edges))
+	c.posts = make([]string, len(d.Edges))
+	for i, e := range d.Edges {
+		c.guards[i] = r.rename(e.Guard)
+		c.posts[i] = r.rename(e.Post)
+	}
+	c.startPost = r.rename(d.StartEdge.Post)
+	return c
+}
+
+func (c *component) number(id StateID) int32 {
+	if n, ok := c.numbers[id]; ok {
+		return n
+	}
+	n := int32(len(c.ids))
+	c.numbers[id] = n
+	c.ids = append(c.ids, id)
+	c.out = append(c.out, nil)
+	return n
+}
+
+// edge returns the e-th edge of the component, taken alone, without its
+// source and destination.
+func (c *component) edge(e int32) Edge {
+	return Edge{Event: c.d.Edges[e].Event, Guard: c.guards[e], Post: c.posts[e]}
+}
+
+// componentEdge returns the e-th edge of the component, the i-th one.
+func (c *component) componentEdge(i int, e int32) ComponentEdge {
+	edge := c.d.Edges[e]
+	return ComponentEdge{Component: i, Edge: int(e), Src: edge.Src, Dst: edge.Dst, Event: edge.Event}
+}
+
+// varRenamer renames variables in guards and post-conditions. Guards and
+// post-conditions are free text, so a variable is any occurrence of its name
+// that is not part of a longer word; a primed variable x' is renamed too.
+type varRenamer struct {
+	renames map[Var]Var
+	// names lists the variables to rename, longest first, so that a-b is
+	// preferred over a.
+	names []Var
+}
+
+func newVarRenamer(renames map[Var]Var) *varRenamer {
+	r := &varRenamer{renames: renames}
+	for name := range renames {
+		r.names = append(r.names, name)
+	}
+	sort.Slice(r.names, func(i, j int) bool {
+		if len(r.names[i]) != len(r.names[j]) {
+			return len(r.names[i]) > len(r.names[j])
+		}
+		return r.names[i] < r.names[j]
+	})
+	return r
+}
+
+func (r *varRenamer) rename(text string) string {
+	if len(r.names) == 0 {
+		return text
+	}
+	var sb strings.Builder
+	prev := rune(-1)
+	for i := 0; i < len(text); {
+		if !isWordRune(prev) {
+			if name, ok := r.match(text[i:]); ok {
+				sb.WriteString(string(r.renames[name]))
+				i += len(name)
+				prev, _ = utf8.DecodeLastRuneInString(string(name))
+				continue
 			}
+		}
+		c, size := utf8.DecodeRuneInString(text[i:])
+		sb.WriteString(text[i : i+size])
+		i += size
+		prev = c
+	}
+	return sb.String()
+}
+
+// match returns the variable text starts with, if it is not followed by more
+// of a word.
+func (r *varRenamer) match(text string) (Var, bool) {
+	for _, name := range r.names {
+		if !strings.HasPrefix(text, string(name)) {
 			continue
 		}
+		c, _ := utf8.DecodeRuneInString(text[len(name):])
+		if len(text) == len(name) || !isWordRune(c) {
+			return name, true
+		}
+	}
+	return "", false
+}
 
-		// Para1
-		if dstLs, ok := evL[ev]; ok {
-			for dstL, esL := range dstLs {
-				for _, eL := range esL {
-					nextStatePair := StatePair{
-						Left:  dL.States[dstL],
-						Right: currentPair.Right,
-					}
-					out.States[nextStatePair.ID()] = nextStatePair.State()
-					out.Edges = append(out.Edges, Edge{
-						Src:   currentPairID,
-						Dst:   nextStatePair.ID(),
-						Event: ev,
-						Guard: eL.Guard,
-						Post:  eL.Post,
-					})
-					if _, ok := (*marked)[nextStatePair.ID()]; !ok {
-						*queue = append(*queue, nextStatePair)
-						(*marked)[nextStatePair.ID()] = struct{}{}
-					}
-				}
-			}
+// isWordRune reports whether c continues a word in guards and
+// post-conditions. - is an ID character but usually means minus there.
+func isWordRune(c rune) bool {
+	return c == '_' || unicode.IsLetter(c) || unicode.IsDigit(c)
+}
+
+// ComposeStateIDs is the preferred ID of the product of the states ids. Joining
+// with "_" can map different tuples to the same ID (("a_b", "c") and ("a",
+// "b_c")), so ComposeParallel numbers colliding IDs apart (see
+// stateIDAllocator).
+func ComposeStateIDs(ids ...StateID) StateID {
+	var sb strings.Builder
+	for i, id := range ids {
+		if i > 0 {
+			sb.WriteByte('_')
 		}
+		sb.WriteString(string(id))
+	}
+	return StateID(sb.String())
+}
 
-		// Para2
-		if dstRs, ok := evR[ev]; ok {
-			for dstR, esR := range dstRs {
-				for _, eR := range esR {
-					nextStatePair := StatePair{
-						Left:  currentPair.Left,
-						Right: dR.States[dstR],
-					}
-					out.States[nextStatePair.ID()] = nextStatePair.State()
-					out.Edges = append(out.Edges, Edge{
-						Src:   currentPairID,
-						Dst:   nextStatePair.ID(),
-						Event: ev,
-						Guard: eR.Guard,
-						Post:  eR.Post,
-					})
-					if _, ok := (*marked)[nextStatePair.ID()]; !ok {
-						*queue = append(*queue, nextStatePair)
-						(*marked)[nextStatePair.ID()] = struct{}{}
-					}
-				}
-			}
+func ComposeStateNames(names ...string) string {
+	return "(" + strings.Join(names, ", ") + ")"
+}
+
+func ComposeGuard(guards ...string) string {
+	return conjoin(guards)
+}
+
+func ComposePostConditions(posts ...string) string {
+	return conjoin(posts)
+}
+
+// conjoin joins the conditions that are not empty or true with " ∧ ".
+func conjoin(conditions []string) string {
+	var kept []string
+	for _, c := range conditions {
+		if c != "" && c != True {
+			kept = append(kept, c)
 		}
 	}
-	return nil
+	if len(kept) == 0 {
+		// Keep the trivial condition, so that true and true is true.
+		if len(conditions) == 0 {
+			return ""
+		}
+		return conditions[len(conditions)-1]
+	}
+	return strings.Join(kept, " ∧ ")
+}
+
+// stateIDAllocator assigns distinct StateIDs to distinct generated states.
+// While a diagram is built, each state is referred to by its unambiguous key;
+// assign then renames every state to its preferred ID (e.g. ComposeStateIDs),
+// falling back to "<preferred>_2", "<preferred>_3", ... when several states
+// prefer the same ID. Colliding states are numbered in key order, so the
+// result does not depend on the order the states were discovered in.
+type stateIDAllocator struct {
+	preferred map[StateID]StateID
+}
+
+func newStateIDAllocator() *stateIDAllocator {
+	return &stateIDAllocator{preferred: make(map[StateID]StateID)}
 }
 
-func ComposeStateIDs(s1, s2 StateID) StateID {
-	return s1 + "_" + s2
+// id returns the provisional ID of the state identified by key. key must
+// identify the state unambiguously.
+func (a *stateIDAllocator) id(key string, preferred StateID) StateID {
+	a.preferred[StateID(key)] = preferred
+	return StateID(key)
 }
 
-func ComposeStateNames(s1, s2 string) string {
-	return "(" + s1 + ", " + s2 + ")"
+// assign replaces the provisional IDs in d with the final ones.
+func (a *stateIDAllocator) assign(d *Diagram) {
+	final := a.final()
+	states := make(map[StateID]State, len(d.States))
+	for key, state := range d.States {
+		state.ID = final[key]
+		states[state.ID] = state
+	}
+	d.States = states
+	d.StartEdge.Dst = final[d.StartEdge.Dst]
+	for i := range d.Edges {
+		d.Edges[i].Src = final[d.Edges[i].Src]
+		d.Edges[i].Dst = final[d.Edges[i].Dst]
+	}
+	if d.EndEdge != nil {
+		d.EndEdge.Src = final[d.EndEdge.Src]
+	}
 }
 
-func ComposeGuard(g1, g2 string) string {
-	if g1 == "" || g1 == True {
-		return g2
+// final maps the provisional IDs to the final ones.
+func (a *stateIDAllocator) final() map[StateID]StateID {
+	keys := make([]StateID, 0, len(a.preferred))
+	for key := range a.preferred {
+		keys = append(keys, key)
 	}
-	if g2 == "" || g2 == True {
-		return g1
+	sort.Slice(keys, func(i, j int) bool {
+		if a.preferred[keys[i]] != a.preferred[keys[j]] {
+			return a.preferred[keys[i]] < a.preferred[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+
+	final := make(map[StateID]StateID, len(keys))
+	taken := make(map[StateID]struct{}, len(keys))
+	for _, key := range keys {
+		id := a.preferred[key]
+		for n := 2; ; n++ {
+			if _, ok := taken[id]; !ok {
+				break
+			}
+			id = StateID(fmt.Sprintf("%s_%d", a.preferred[key], n))
+		}
+		final[key] = id
+		taken[id] = struct{}{}
 	}
-	return g1 + " ∧ " + g2
+	return final
 }
 
-func ComposePostConditions(p1, p2 string) string {
-	if p1 == "" || p1 == True {
-		return p2
+// stateKey is an unambiguous encoding of a tuple of state IDs: each ID is
+// prefixed with its byte length.
+func stateKey(ids ...StateID) string {
+	var sb strings.Builder
+	for _, id := range ids {
+		sb.WriteString(strconv.Itoa(len(id)))
+		sb.WriteByte(':')
+		sb.WriteString(string(id))
 	}
-	if p2 == "" || p2 == True {
-		return p1
+	return sb.String()
+}
+
+// parseStateKey decodes the state IDs encoded by stateKey.
+func parseStateKey(key string) []StateID {
+	var ids []StateID
+	for key != "" {
+		length, rest, _ := strings.Cut(key, ":")
+		n, _ := strconv.Atoi(length)
+		ids = append(ids, StateID(rest[:n]))
+		key = rest[n:]
 	}
-	return p1 + " ∧ " + p2
+	return ids
 }
@@ -1,6 +1,7 @@
 package csdf
 
 import (
+	"fmt"
 	"strings"
 	"testing"
 
@@ -28,6 +29,36 @@ s0 --> [*] : true
 	}
 }
 
+func TestComposeParallelNamespacesSingleDiagram(t *testing.T) {
+	// Setup: a single diagram gets the variable names it would get as the
+	// first of several, as in ComposeMinimized.
+	d := mustParse(t, `@startuml
+state "c" as c
+c : n ; int
+[*] --> c : n' = 0
+c --> c : inc ; n < 10 ; n' = n + 1
+@enduml
+`)
+	want := `@startuml
+state "c" as c
+c: p1_n ; int
+[*] --> c : p1_n' = 0
+c --> c : inc ; p1_n < 10 ; p1_n' = p1_n + 1
+@enduml
+`
+
+	// Execute
+	composite, err := ComposeParallel([]*Diagram{d}, nil)
+	if err != nil {
+		t.Fatalf("ComposeParallel() error = %v", err)
+	}
+
+	// Assert
+	if diff := cmp.Diff(want, composite.String()); diff != "" {
+		t.Error(diff)
+	}
+}
+
 func TestComposeParallelComposesDiagrams(t *testing.T) {
 	// Setup
 	want := `@startuml
@@ -170,3 +201,315 @@ func TestComposeParallelMatchesWholeEvent(t *testing.T) {
 		t.Errorf("ComposeParallel2() synchronized destination = %q, want l1_r1", composite.Edges[0].Dst)
 	}
 }
+
+func TestComposeParallelKeepsCollidingStateIDsDistinct(t *testing.T) {
+	// Setup: ("a_b", "c") and ("a", "b_c") both prefer the ID a_b_c.
+	left := mustParse(t, `@startuml
+state "a_b" as a_b
+state "a" as a
+[*] --> a_b
+a_b --> a : l
+@enduml
+`)
+	right := mustParse(t, `@startuml
+state "c" as c
+state "b_c" as b_c
+[*] --> c
+c --> b_c : r
+@enduml
+`)
+
+	// Execute
+	composite, err := ComposeParallel2(left, right, nil)
+	if err != nil {
+		t.Fatalf("ComposeParallel2() error = %v", err)
+	}
+
+	// Assert
+	if len(composite.States) != 4 {
+		t.Fatalf("ComposeParallel2() states = %d, want 4:\n%s", len(composite.States), composite.String())
+	}
+	for _, want := range []struct {
+		id   StateID
+		name string
+	}{
+		{"a_b_c", "(a, b_c)"},
+		{"a_b_c_2", "(a_b, c)"},
+		{"a_b_b_c", "(a_b, b_c)"},
+		{"a_c", "(a, c)"},
+	} {
+		if got := composite.States[want.id].Name; got != want.name {
+			t.Errorf("ComposeParallel2() state %s = %q, want %q", want.id, got, want.name)
+		}
+	}
+	if composite.StartEdge.Dst != "a_b_c_2" {
+		t.Errorf("ComposeParallel2() start = %s, want a_b_c_2", composite.StartEdge.Dst)
+	}
+}
+
+func TestComposeParallel2OrdersEdgesByDiscovery(t *testing.T) {
+	// Setup
+	left := mustParse(t, `@startuml
+state "l0" as l0
+state "l1" as l1
+[*] --> l0
+l0 --> l1 : a
+l0 --> l1 : sync
+@enduml
+`)
+	right := mustParse(t, `@startuml
+state "r0" as r0
+state "r1" as r1
+[*] --> r0
+r0 --> r1 : sync
+r0 --> r1 : b
+@enduml
+`)
+	want := []Edge{
+		{Src: "l0_r0", Dst: "l1_r0", Event: "a", Guard: True, Post: True},
+		{Src: "l0_r0", Dst: "l1_r1", Event: "sync", Guard: True, Post: True},
+		{Src: "l0_r0", Dst: "l0_r1", Event: "b", Guard: True, Post: True},
+		{Src: "l1_r0", Dst: "l1_r1", Event: "b", Guard: True, Post: True},
+		{Src: "l0_r1", Dst: "l1_r1", Event: "a", Guard: True, Post: True},
+	}
+
+	// Execute
+	composite, err := ComposeParallel2(left, right, []Event{"sync"})
+	if err != nil {
+		t.Fatalf("ComposeParallel2() error = %v", err)
+	}
+
+	// Assert
+	if diff := cmp.Diff(want, composite.Edges); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestComposeParallelBuildsFlatTuples(t *testing.T) {
+	// Setup: sync needs all three diagrams, which take their own events in
+	// argument order.
+	a := mustParse(t, `@startuml
+state "a0" as a0
+state "a1" as a1
+[*] --> a0
+a0 --> a1 : sync
+@enduml
+`)
+	b := mustParse(t, `@startuml
+state "b0" as b0
+state "b1" as b1
+[*] --> b0
+b0 --> b1 : sync
+b0 --> b0 : b
+@enduml
+`)
+	c := mustParse(t, `@startuml
+state "c0" as c0
+state "c1" as c1
+[*] --> c0
+c0 --> c1 : c
+c1 --> c1 : sync
+@enduml
+`)
+	want := `@startuml
+state "(a0, b0, c0)" as a0_b0_c0
+state "(a0, b0, c1)" as a0_b0_c1
+state "(a1, b1, c1)" as a1_b1_c1
+[*] --> a0_b0_c0
+a0_b0_c0 --> a0_b0_c0 : b
+a0_b0_c0 --> a0_b0_c1 : c
+a0_b0_c1 --> a1_b1_c1 : sync
+a0_b0_c1 --> a0_b0_c1 : b
+@enduml
+`
+
+	// Execute
+	composite, err := ComposeParallel([]*Diagram{a, b, c}, []Event{"sync"})
+	if err != nil {
+		t.Fatalf("ComposeParallel() error = %v", err)
+	}
+
+	// Assert
+	if diff := cmp.Diff(want, composite.String()); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestComposeParallelNamespacesVariables(t *testing.T) {
+	// Setup: both diagrams have a variable n; counter also has n2, which must
+	// not be renamed as n followed by 2.
+	counter := mustParse(t, `@startuml
+state "c" as c
+c : n ; int
+c : n2 ; int
+[*] --> c : n' = 0 ∧ n2' = 0
+c --> c : inc ; n < n2 ; n' = n + 1
+@enduml
+`)
+	buffer := mustParse(t, `@startuml
+state "b" as b
+b : n ; int
+[*] --> b : n' = 0
+b --> b : inc ; n-1 < 10 ; n' = n
+@enduml
+`)
+
+	// Execute
+	composite, err := ComposeParallel([]*Diagram{counter, buffer}, []Event{"inc"})
+	if err != nil {
+		t.Fatalf("ComposeParallel() error = %v", err)
+	}
+
+	// Assert
+	wantVars := []StateVar{
+		{Name: "p1_n", Type: "int"},
+		{Name: "p1_n2", Type: "int"},
+		{Name: "p2_n", Type: "int"},
+	}
+	if diff := cmp.Diff(wantVars, composite.States["c_b"].Vars); diff != "" {
+		t.Errorf("vars:\n%s", diff)
+	}
+	if want := "p1_n' = 0 ∧ p1_n2' = 0 ∧ p2_n' = 0"; composite.StartEdge.Post != want {
+		t.Errorf("start post = %q, want %q", composite.StartEdge.Post, want)
+	}
+	wantEdges := []Edge{{
+		Src:   "c_b",
+		Dst:   "c_b",
+		Event: "inc",
+		Guard: "p1_n < p1_n2 ∧ p2_n-1 < 10",
+		Post:  "p1_n' = p1_n + 1 ∧ p2_n' = p2_n",
+	}}
+	if diff := cmp.Diff(wantEdges, composite.Edges); diff != "" {
+		t.Errorf("edges:\n%s", diff)
+	}
+}
+
+func TestComposeRecordsProvenance(t *testing.T) {
+	// Setup
+	left := mustParse(t, `@startuml
+state "l0" as l0
+state "l1" as l1
+[*] --> l0
+l0 --> l1 : a
+l0 --> l1 : sync
+@enduml
+`)
+	right := mustParse(t, `@startuml
+state "r0" as r0
+state "r1" as r1
+[*] --> r0
+r0 --> r1 : b
+r0 --> r1 : sync
+@enduml
+`)
+
+	// Execute
+	composition, err := Compose([]*Diagram{left, right}, []Event{"sync"})
+	if err != nil {
+		t.Fatalf("Compose() error = %v", err)
+	}
+
+	// Assert
+	wantStates := map[StateID][]StateID{
+		"l0_r0": {"l0", "r0"},
+		"l1_r0": {"l1", "r0"},
+		"l1_r1": {"l1", "r1"},
+		"l0_r1": {"l0", "r1"},
+	}
+	if diff := cmp.Diff(wantStates, composition.States); diff != "" {
+		t.Errorf("states:\n%s", diff)
+	}
+	if len(composition.Edges) != len(composition.Diagram.Edges) {
+		t.Fatalf("Compose() edges = %d, want one per diagram edge (%d)", len(composition.Edges), len(composition.Diagram.Edges))
+	}
+	for i, edge := range composition.Diagram.Edges {
+		src, dst := composition.States[edge.Src], composition.States[edge.Dst]
+		for _, ce := range composition.Edges[i] {
+			component := []*Diagram{left, right}[ce.Component]
+			if component.Edges[ce.Edge].Event != edge.Event {
+				t.Errorf("edge %d: component %d edge %d has event %s, want %s", i, ce.Component, ce.Edge, component.Edges[ce.Edge].Event, edge.Event)
+			}
+			if ce.Src != src[ce.Component] || ce.Dst != dst[ce.Component] {
+				t.Errorf("edge %d: component edge %s -> %s, want %s -> %s", i, ce.Src, ce.Dst, src[ce.Component], dst[ce.Component])
+			}
+		}
+	}
+	wantSync := []ComponentEdge{
+		{Component: 0, Edge: 1, Src: "l0", Dst: "l1", Event: "sync"},
+		{Component: 1, Edge: 1, Src: "r0", Dst: "r1", Event: "sync"},
+	}
+	if diff := cmp.Diff(wantSync, composition.Edges[1]); diff != "" {
+		t.Errorf("sync edge:\n%s", diff)
+	}
+}
+
+func TestComposeSingleDiagramIsItsOwnComposition(t *testing.T) {
+	// Setup
+	d := MustLoadDiagrams("../examples/valid/in.puml")[0]
+
+	// Execute
+	composition, err := Compose([]*Diagram{d}, nil)
+	if err != nil {
+		t.Fatalf("Compose() error = %v", err)
+	}
+
+	// Assert
+	if diff := cmp.Diff(d.String(), composition.Diagram.String()); diff != "" {
+		t.Errorf("diagram:\n%s", diff)
+	}
+	if diff := cmp.Diff([]StateID{"s1"}, composition.States["s1"]); diff != "" {
+		t.Errorf("states[s1]:\n%s", diff)
+	}
+	want := []ComponentEdge{{Component: 0, Edge: 1, Src: "s1", Dst: "s2", Event: "sync"}}
+	if diff := cmp.Diff(want, composition.Edges[1]); diff != "" {
+		t.Errorf("edges[1]:\n%s", diff)
+	}
+}
+
+// generateDiagram returns a diagram with n states s0...s<n-1> in which every
+// state has fanout outgoing edges, labeled with events in turn.
+func generateDiagram(n, fanout int, events []Event) *Diagram {
+	d := &Diagram{
+		States:    make(map[StateID]State, n),
+		StartEdge: StartEdge{Dst: "s0", Post: "x' = 0"},
+		Edges:     make([]Edge, 0, n*fanout),
+	}
+	for i := 0; i < n; i++ {
+		id := StateID(fmt.Sprintf("s%d", i))
+		d.States[id] = State{ID: id, Name: string(id), Vars: []StateVar{{Name: "x", Type: "int"}}}
+		for j := 0; j < fanout; j++ {
+			d.Edges = append(d.Edges, Edge{
+				Src:   id,
+				Dst:   StateID(fmt.Sprintf("s%d", (i*7+j+1)%n)),
+				Event: events[(i+j)%len(events)],
+				Guard: "x < 10",
+				Post:  "x' = x + 1",
+			})
+		}
+	}
+	return d
+}
+
+func BenchmarkComposeParallel2(b *testing.B) {
+	left := generateDiagram(200, 4, []Event{"a", "b", "sync"})
+	right := generateDiagram(200, 4, []Event{"c", "d", "sync"})
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err := ComposeParallel2(left, right, []Event{"sync"}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkComposeParallelManyComponents(b *testing.B) {
+	diagrams := make([]*Diagram, 6)
+	for i := range diagrams {
+		diagrams[i] = generateDiagram(4, 2, []Event{Event(fmt.Sprintf("e%d", i)), "tick"})
+	}
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err := ComposeParallel(diagrams, []Event{"tick"}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
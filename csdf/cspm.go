@@ -0,0 +1,765 @@
+package csdf
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// maxCSPmStates bounds the state space explored by ParseCSPm, so that a
+// process that is not finite-state is reported instead of exhausting memory.
+const maxCSPmStates = 10000
+
+// ParseCSPm builds a diagram from a script in a finite-state subset of CSPm
+// (docs/SYNTAX.md, "CSPm input"):
+//
+//   - "channel a, b, c" declarations of channels without data,
+//   - process definitions "P = expr" where expr is built from prefix "a -> P",
+//     external choice "[]", internal choice "|~|", interface parallel
+//     "[| {a} |]", interleaving "|||", hiding "P \ {a, b}", process names
+//     (recursion), STOP, SKIP and parentheses,
+//   - "--" and "{- -}" comments; assert lines are skipped.
+//
+// The diagram is the labelled transition system of process, or of the first
+// defined process when process is "". Each reachable process term becomes a
+// state: named processes keep their name as the state ID, other terms get the
+// ID "<process>_<n>" and their CSPm text as the state name. Internal choice and
+// hidden events become τ-edges. SKIP is a single state with the end edge;
+// SKIP as an alternative of an external choice is not supported.
+func ParseCSPm(input, process string) (*Diagram, error) {
+	script, err := parseCSPmScript(input)
+	if err != nil {
+		return nil, fmt.Errorf("csdf.ParseCSPm: %w", err)
+	}
+	if process == "" {
+		if len(script.order) == 0 {
+			return nil, fmt.Errorf("csdf.ParseCSPm: no process definitions")
+		}
+		process = script.order[0]
+	}
+	if _, ok := script.defs[process]; !ok {
+		return nil, fmt.Errorf("csdf.ParseCSPm: no process named %q (defined processes: %s)", process, strings.Join(script.order, ", "))
+	}
+	d, err := script.explore(process)
+	if err != nil {
+		return nil, fmt.Errorf("csdf.ParseCSPm: %w", err)
+	}
+	return d, nil
+}
+
+// isCSPmSource reports whether a source read from path is CSPm rather than
+// PlantUML: by the .csp/.cspm extension, or, for other paths and standard
+// input, by content without @startuml that starts like a CSPm script.
+func isCSPmSource(path, text string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csp", ".cspm":
+		return true
+	case ".puml", ".plantuml", ".png", ".svg":
+		return false
+	}
+	if strings.Contains(text, "@startuml") {
+		return false
+	}
+	tokens, err := tokenizeCSPm(text)
+	if err != nil || len(tokens) < 2 {
+		return false
+	}
+	return tokens[0].text == "channel" || (tokens[0].kind == cspmIdent && tokens[1].text == "=")
+}
+
+type cspmTokenKind int
+
+const (
+	cspmIdent cspmTokenKind = iota
+	cspmSymbol
+)
+
+type cspmToken struct {
+	kind cspmTokenKind
+	text string
+	line int
+	col  int
+}
+
+// cspmSymbols are the supported operators, longest first.
+var cspmSymbols = []string{"|||", "|~|", "[|", "|]", "{|", "|}", "->", "[]", "(", ")", "{", "}", "=", ",", ":", "\\"}
+
+func tokenizeCSPm(input string) ([]cspmToken, error) {
+	var tokens []cspmToken
+	line, col := 1, 1
+	advance := func(n int) {
+		for _, r := range input[:n] {
+			if r == '\n' {
+				line++
+				col = 1
+			} else {
+				col++
+			}
+		}
+		input = input[n:]
+	}
+	for len(input) > 0 {
+		c := input[0]
+		switch {
+		case c == '\n' || c == ' ' || c == '\t' || c == '\r':
+			advance(1)
+			continue
+		case strings.HasPrefix(input, "--"):
+			end := strings.IndexByte(input, '\n')
+			if end < 0 {
+				end = len(input)
+			}
+			advance(end)
+			continue
+		case strings.HasPrefix(input, "{-"):
+			startLine, startCol := line, col
+			depth := 0
+			n := 0
+			for n < len(input) {
+				if strings.HasPrefix(input[n:], "{-") {
+					depth++
+					n += 2
+					continue
+				}
+				if strings.HasPrefix(input[n:], "-}") {
+					depth--
+					n += 2
+					if depth == 0 {
+						break
+					}
+					continue
+				}
+				n++
+			}
+			if depth != 0 {
+				return nil, fmt.Errorf("csdf.tokenizeCSPm: unterminated comment at line %d, col %d", startLine, startCol)
+			}
+			advance(n)
+			continue
+		case isCSPmIdentStart(c):
+			n := 1
+			for n < len(input) && isCSPmIdentChar(input[n]) {
+				n++
+			}
+			if input[:n] == "assert" {
+				// Assertions are checks for FDR, not part of the model.
+				end := strings.IndexByte(input, '\n')
+				if end < 0 {
+					end = len(input)
+				}
+				advance(end)
+				continue
+			}
+			tokens = append(tokens, cspmToken{kind: cspmIdent, text: input[:n], line: line, col: col})
+			advance(n)
+			continue
+		}
+		matched := false
+		for _, symbol := range cspmSymbols {
+			if strings.HasPrefix(input, symbol) {
+				tokens = append(tokens, cspmToken{kind: cspmSymbol, text: symbol, line: line, col: col})
+				advance(len(symbol))
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return nil, fmt.Errorf("csdf.tokenizeCSPm: unsupported CSPm syntax %q at line %d, col %d", firstCSPmWord(input), line, col)
+		}
+	}
+	return tokens, nil
+}
+
+func isCSPmIdentStart(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isCSPmIdentChar(c byte) bool {
+	return isCSPmIdentStart(c) || (c >= '0' && c <= '9') || c == '_' || c == '\''
+}
+
+// firstCSPmWord returns the run of non-space characters input starts with,
+// for error messages.
+func firstCSPmWord(input string) string {
+	end := strings.IndexAny(input, " \t\r\n")
+	if end < 0 {
+		return input
+	}
+	return input[:end]
+}
+
+type cspmKind int
+
+const (
+	cspmStop cspmKind = iota
+	cspmSkip
+	cspmRef
+	cspmPrefix
+	cspmExternal
+	cspmInternal
+	cspmHide
+	cspmParallel
+)
+
+// cspmProc is a process term. Terms are immutable; key identifies a term
+// structurally and doubles as its CSPm text.
+type cspmProc struct {
+	kind  cspmKind
+	name  string // cspmRef: process name; cspmPrefix: event
+	left  *cspmProc
+	right *cspmProc
+	set   []string // cspmHide, cspmParallel: sorted channel names
+	key   string
+}
+
+func newCSPmProc(kind cspmKind, name string, left, right *cspmProc, set []string) *cspmProc {
+	p := &cspmProc{kind: kind, name: name, left: left, right: right, set: set}
+	switch kind {
+	case cspmStop:
+		p.key = "STOP"
+	case cspmSkip:
+		p.key = "SKIP"
+	case cspmRef:
+		p.key = name
+	case cspmPrefix:
+		p.key = name + " -> " + left.operand()
+	case cspmExternal:
+		p.key = left.operand() + " [] " + right.operand()
+	case cspmInternal:
+		p.key = left.operand() + " |~| " + right.operand()
+	case cspmHide:
+		p.key = left.operand() + " \\ {" + strings.Join(set, ", ") + "}"
+	case cspmParallel:
+		if len(set) == 0 {
+			p.key = left.operand() + " ||| " + right.operand()
+		} else {
+			p.key = left.operand() + " [| {" + strings.Join(set, ", ") + "} |] " + right.operand()
+		}
+	}
+	return p
+}
+
+// operand is the text of p as an operand of another operator.
+func (p *cspmProc) operand() string {
+	switch p.kind {
+	case cspmStop, cspmSkip, cspmRef:
+		return p.key
+	default:
+		return "(" + p.key + ")"
+	}
+}
+
+// hide returns p \ hidden, merging nested hiding and dropping it where it has
+// no effect.
+func hideCSPm(p *cspmProc, hidden []string) *cspmProc {
+	if len(hidden) == 0 || p.kind == cspmStop || p.kind == cspmSkip {
+		return p
+	}
+	if p.kind == cspmHide {
+		seen := make(map[string]struct{})
+		var merged []string
+		for _, name := range append(append([]string{}, p.set...), hidden...) {
+			if _, ok := seen[name]; !ok {
+				seen[name] = struct{}{}
+				merged = append(merged, name)
+			}
+		}
+		sort.Strings(merged)
+		return newCSPmProc(cspmHide, "", p.left, nil, merged)
+	}
+	return newCSPmProc(cspmHide, "", p, nil, hidden)
+}
+
+type cspmScript struct {
+	channels map[string]struct{}
+	defs     map[string]*cspmProc
+	order    []string
+}
+
+type cspmParser struct {
+	tokens []cspmToken
+	pos    int
+	script *cspmScript
+}
+
+func parseCSPmScript(input string) (*cspmScript, error) {
+	tokens, err := tokenizeCSPm(input)
+	if err != nil {
+		return nil, fmt.Errorf("csdf.parseCSPmScript: %w", err)
+	}
+	p := &cspmParser{
+		tokens: tokens,
+		script: &cspmScript{channels: make(map[string]struct{}), defs: make(map[string]*cspmProc)},
+	}
+	for !p.isAtEnd() {
+		if err := p.parseDeclaration(); err != nil {
+			return nil, fmt.Errorf("csdf.parseCSPmScript: %w", err)
+		}
+	}
+	if err := p.script.checkNames(); err != nil {
+		return nil, fmt.Errorf("csdf.parseCSPmScript: %w", err)
+	}
+	return p.script, nil
+}
+
+func (p *cspmParser) parseDeclaration() error {
+	tok := p.next()
+	switch {
+	case tok.text == "channel":
+		for {
+			name := p.next()
+			if name.kind != cspmIdent {
+				return fmt.Errorf("csdf.cspmParser.parseDeclaration: expected channel name at %s", name.position())
+			}
+			p.script.channels[name.text] = struct{}{}
+			if !p.peekIs(",") {
+				break
+			}
+			p.next()
+		}
+		if p.peekIs(":") {
+			return fmt.Errorf("csdf.cspmParser.parseDeclaration: channels carrying data are not supported at %s", p.peek().position())
+		}
+		return nil
+	case tok.kind == cspmIdent && isCSPmKeyword(tok.text):
+		return fmt.Errorf("csdf.cspmParser.parseDeclaration: unsupported CSPm declaration %q at %s", tok.text, tok.position())
+	case tok.kind == cspmIdent && p.peekIs("="):
+		p.next()
+		if _, ok := p.script.defs[tok.text]; ok {
+			return fmt.Errorf("csdf.cspmParser.parseDeclaration: process %s is defined twice at %s", tok.text, tok.position())
+		}
+		body, err := p.parseHide()
+		if err != nil {
+			return fmt.Errorf("csdf.cspmParser.parseDeclaration: %w", err)
+		}
+		p.script.defs[tok.text] = body
+		p.script.order = append(p.script.order, tok.text)
+		return nil
+	default:
+		return fmt.Errorf("csdf.cspmParser.parseDeclaration: expected a channel declaration or a process definition at %s", tok.position())
+	}
+}
+
+// parseHide parses "A \ {a, b}", the loosest binding operator.
+func (p *cspmParser) parseHide() (*cspmProc, error) {
+	proc, err := p.parseParallel()
+	if err != nil {
+		return nil, fmt.Errorf("csdf.cspmParser.parseHide: %w", err)
+	}
+	for p.peekIs("\\") {
+		p.next()
+		hidden, err := p.parseChannelSet()
+		if err != nil {
+			return nil, fmt.Errorf("csdf.cspmParser.parseHide: %w", err)
+		}
+		proc = hideCSPm(proc, hidden)
+	}
+	return proc, nil
+}
+
+// parseParallel parses "A [| {a, b} |] B" and "A ||| B".
+func (p *cspmParser) parseParallel() (*cspmProc, error) {
+	left, err := p.parseInternal()
+	if err != nil {
+		return nil, fmt.Errorf("csdf.cspmParser.parseParallel: %w", err)
+	}
+	for p.peekIs("|||") || p.peekIs("[|") {
+		var sync []string
+		if p.next().text == "[|" {
+			sync, err = p.parseChannelSet()
+			if err != nil {
+				return nil, fmt.Errorf("csdf.cspmParser.parseParallel: %w", err)
+			}
+			if tok := p.next(); tok.text != "|]" {
+				return nil, fmt.Errorf("csdf.cspmParser.parseParallel: expected '|]' at %s", tok.position())
+			}
+		}
+		right, err := p.parseInternal()
+		if err != nil {
+			return nil, fmt.Errorf("csdf.cspmParser.parseParallel: %w", err)
+		}
+		left = newCSPmProc(cspmParallel, "", left, right, sync)
+	}
+	return left, nil
+}
+
+func (p *cspmParser) parseInternal() (*cspmProc, error) {
+	left, err := p.parseExternal()
+	if err != nil {
+		return nil, fmt.Errorf("csdf.cspmParser.parseInternal: %w", err)
+	}
+	for p.peekIs("|~|") {
+		p.next()
+		right, err := p.parseExternal()
+		if err != nil {
+			return nil, fmt.Errorf("csdf.cspmParser.parseInternal: %w", err)
+		}
+		left = newCSPmProc(cspmInternal, "", left, right, nil)
+	}
+	return left, nil
+}
+
+func (p *cspmParser) parseExternal() (*cspmProc, error) {
+	left, err := p.parsePrefix()
+	if err != nil {
+		return nil, fmt.Errorf("csdf.cspmParser.parseExternal: %w", err)
+	}
+	for p.peekIs("[]") {
+		p.next()
+		right, err := p.parsePrefix()
+		if err != nil {
+			return nil, fmt.Errorf("csdf.cspmParser.parseExternal: %w", err)
+		}
+		left = newCSPmProc(cspmExternal, "", left, right, nil)
+	}
+	return left, nil
+}
+
+// parseChannelSet parses "{a, b}" (or "{| a, b |}") into sorted channel names.
+func (p *cspmParser) parseChannelSet() ([]string, error) {
+	open := p.next()
+	if open.text != "{" && open.text != "{|" {
+		return nil, fmt.Errorf("csdf.cspmParser.parseChannelSet: expected '{' at %s", open.position())
+	}
+	closing := "}"
+	if open.text == "{|" {
+		closing = "|}"
+	}
+	var set []string
+	for !p.peekIs(closing) {
+		name := p.next()
+		if name.kind != cspmIdent {
+			return nil, fmt.Errorf("csdf.cspmParser.parseChannelSet: expected channel name at %s", name.position())
+		}
+		if err := p.checkChannel(name); err != nil {
+			return nil, fmt.Errorf("csdf.cspmParser.parseChannelSet: %w", err)
+		}
+		set = append(set, name.text)
+		if !p.peekIs(",") {
+			break
+		}
+		p.next()
+	}
+	if tok := p.next(); tok.text != closing {
+		return nil, fmt.Errorf("csdf.cspmParser.parseChannelSet: expected '%s' at %s", closing, tok.position())
+	}
+	sort.Strings(set)
+	return set, nil
+}
+
+func (p *cspmParser) parsePrefix() (*cspmProc, error) {
+	tok := p.next()
+	switch {
+	case tok.text == "(":
+		proc, err := p.parseHide()
+		if err != nil {
+			return nil, fmt.Errorf("csdf.cspmParser.parsePrefix: %w", err)
+		}
+		if closing := p.next(); closing.text != ")" {
+			return nil, fmt.Errorf("csdf.cspmParser.parsePrefix: expected ')' at %s", closing.position())
+		}
+		return proc, nil
+	case tok.text == "STOP":
+		return newCSPmProc(cspmStop, "", nil, nil, nil), nil
+	case tok.text == "SKIP":
+		return newCSPmProc(cspmSkip, "", nil, nil, nil), nil
+	case tok.kind == cspmIdent && !isCSPmKeyword(tok.text):
+		if !p.peekIs("->") {
+			return newCSPmProc(cspmRef, tok.text, nil, nil, nil), nil
+		}
+		if err := p.checkChannel(tok); err != nil {
+			return nil, fmt.Errorf("csdf.cspmParser.parsePrefix: %w", err)
+		}
+		p.next()
+		rest, err := p.parsePrefix()
+		if err != nil {
+			return nil, fmt.Errorf("csdf.cspmParser.parsePrefix: %w", err)
+		}
+		return newCSPmProc(cspmPrefix, tok.text, rest, nil, nil), nil
+	default:
+		return nil, fmt.Errorf("csdf.cspmParser.parsePrefix: expected a process at %s", tok.position())
+	}
+}
+
+func (p *cspmParser) checkChannel(tok cspmToken) error {
+	if _, ok := p.script.channels[tok.text]; !ok {
+		return fmt.Errorf("csdf.cspmParser.checkChannel: undeclared channel %s at %s", tok.text, tok.position())
+	}
+	return nil
+}
+
+func (p *cspmParser) isAtEnd() bool {
+	return p.pos >= len(p.tokens)
+}
+
+func (p *cspmParser) peek() cspmToken {
+	if p.isAtEnd() {
+		return cspmToken{kind: cspmSymbol, line: -1}
+	}
+	return p.tokens[p.pos]
+}
+
+func (p *cspmParser) peekIs(text string) bool {
+	return !p.isAtEnd() && p.tokens[p.pos].text == text
+}
+
+func (p *cspmParser) next() cspmToken {
+	tok := p.peek()
+	if !p.isAtEnd() {
+		p.pos++
+	}
+	return tok
+}
+
+func (t cspmToken) position() string {
+	if t.line < 0 {
+		return "end of input"
+	}
+	return fmt.Sprintf("line %d, col %d", t.line, t.col)
+}
+
+// cspmKeywords are CSPm words outside the supported subset.
+var cspmKeywords = map[string]struct{}{
+	"datatype": {}, "nametype": {}, "subtype": {}, "let": {}, "within": {},
+	"if": {}, "then": {}, "else": {}, "include": {}, "transparent": {},
+	"external": {}, "module": {}, "instance": {}, "timed": {},
+	"channel": {}, "STOP": {}, "SKIP": {},
+}
+
+func isCSPmKeyword(word string) bool {
+	_, ok := cspmKeywords[word]
+	return ok
+}
+
+// checkNames reports references to undefined processes.
+func (s *cspmScript) checkNames() error {
+	var check func(p *cspmProc) error
+	check = func(p *cspmProc) error {
+		if p == nil {
+			return nil
+		}
+		if p.kind == cspmRef {
+			if _, ok := s.defs[p.name]; !ok {
+				return fmt.Errorf("csdf.cspmScript.checkNames: undefined process %s", p.name)
+			}
+		}
+		if err := check(p.left); err != nil {
+			return err
+		}
+		return check(p.right)
+	}
+	for _, name := range s.order {
+		if err := check(s.defs[name]); err != nil {
+			return fmt.Errorf("csdf.cspmScript.checkNames: in %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+type cspmTransition struct {
+	event Event
+	dst   *cspmProc
+}
+
+// unfold replaces process names at the top of p by their definitions. It
+// fails on unguarded recursion such as "P = P [] a -> P".
+func (s *cspmScript) unfold(p *cspmProc, unfolding map[string]struct{}) (*cspmProc, error) {
+	for p.kind == cspmRef {
+		if _, ok := unfolding[p.name]; ok {
+			return nil, fmt.Errorf("csdf.cspmScript.unfold: unguarded recursion through %s", p.name)
+		}
+		unfolding[p.name] = struct{}{}
+		p = s.defs[p.name]
+	}
+	return p, nil
+}
+
+// transitions returns the operational semantics of p: its outgoing events and
+// successor terms, in source order.
+func (s *cspmScript) transitions(p *cspmProc, unfolding map[string]struct{}) ([]cspmTransition, error) {
+	p, err := s.unfold(p, unfolding)
+	if err != nil {
+		return nil, fmt.Errorf("csdf.cspmScript.transitions: %w", err)
+	}
+	switch p.kind {
+	case cspmPrefix:
+		return []cspmTransition{{event: Event(p.name), dst: p.left}}, nil
+	case cspmInternal:
+		return []cspmTransition{{event: Tau, dst: p.left}, {event: Tau, dst: p.right}}, nil
+	case cspmExternal:
+		var result []cspmTransition
+		for _, side := range []struct{ this, other *cspmProc }{{p.left, p.right}, {p.right, p.left}} {
+			if isSkip, err := s.isSkip(side.this); err != nil {
+				return nil, fmt.Errorf("csdf.cspmScript.transitions: %w", err)
+			} else if isSkip {
+				return nil, fmt.Errorf("csdf.cspmScript.transitions: SKIP as an alternative of an external choice is not supported: %s", p.key)
+			}
+			ts, err := s.transitions(side.this, copyCSPmSet(unfolding))
+			if err != nil {
+				return nil, fmt.Errorf("csdf.cspmScript.transitions: %w", err)
+			}
+			for _, t := range ts {
+				if t.event == Tau {
+					// τ does not resolve the choice.
+					if side.this == p.left {
+						t.dst = newCSPmProc(cspmExternal, "", t.dst, side.other, nil)
+					} else {
+						t.dst = newCSPmProc(cspmExternal, "", side.other, t.dst, nil)
+					}
+				}
+				result = append(result, t)
+			}
+		}
+		return result, nil
+	case cspmHide:
+		ts, err := s.transitions(p.left, unfolding)
+		if err != nil {
+			return nil, fmt.Errorf("csdf.cspmScript.transitions: %w", err)
+		}
+		result := make([]cspmTransition, len(ts))
+		for i, t := range ts {
+			event := t.event
+			if containsCSPmName(p.set, string(event)) {
+				event = Tau
+			}
+			result[i] = cspmTransition{event: event, dst: hideCSPm(t.dst, p.set)}
+		}
+		return result, nil
+	case cspmParallel:
+		left, err := s.transitions(p.left, copyCSPmSet(unfolding))
+		if err != nil {
+			return nil, fmt.Errorf("csdf.cspmScript.transitions: %w", err)
+		}
+		right, err := s.transitions(p.right, unfolding)
+		if err != nil {
+			return nil, fmt.Errorf("csdf.cspmScript.transitions: %w", err)
+		}
+		var result []cspmTransition
+		for _, t := range left {
+			if t.event == Tau || !containsCSPmName(p.set, string(t.event)) {
+				result = append(result, cspmTransition{event: t.event, dst: newCSPmProc(cspmParallel, "", t.dst, p.right, p.set)})
+			}
+		}
+		for _, t := range right {
+			if t.event == Tau || !containsCSPmName(p.set, string(t.event)) {
+				result = append(result, cspmTransition{event: t.event, dst: newCSPmProc(cspmParallel, "", p.left, t.dst, p.set)})
+			}
+		}
+		for _, tL := range left {
+			if tL.event == Tau || !containsCSPmName(p.set, string(tL.event)) {
+				continue
+			}
+			for _, tR := range right {
+				if tR.event == tL.event {
+					result = append(result, cspmTransition{event: tL.event, dst: newCSPmProc(cspmParallel, "", tL.dst, tR.dst, p.set)})
+				}
+			}
+		}
+		return result, nil
+	default:
+		return nil, nil
+	}
+}
+
+func containsCSPmName(sorted []string, name string) bool {
+	i := sort.SearchStrings(sorted, name)
+	return i < len(sorted) && sorted[i] == name
+}
+
+// isSkip reports whether p has terminated: it is SKIP, or a parallel
+// composition of terminated processes.
+func (s *cspmScript) isSkip(p *cspmProc) (bool, error) {
+	p, err := s.unfold(p, make(map[string]struct{}))
+	if err != nil {
+		return false, fmt.Errorf("csdf.cspmScript.isSkip: %w", err)
+	}
+	if p.kind == cspmParallel {
+		left, err := s.isSkip(p.left)
+		if err != nil || !left {
+			return false, err
+		}
+		return s.isSkip(p.right)
+	}
+	return p.kind == cspmSkip, nil
+}
+
+func copyCSPmSet(set map[string]struct{}) map[string]struct{} {
+	copied := make(map[string]struct{}, len(set))
+	for k := range set {
+		copied[k] = struct{}{}
+	}
+	return copied
+}
+
+// explore builds the diagram of the named process by breadth-first search
+// over process terms.
+func (s *cspmScript) explore(process string) (*Diagram, error) {
+	// Primes are not ID characters: P' prefers the ID P_.
+	ids := newStateIDAllocator()
+	known := make(map[string]StateID)
+	anonymous := 0
+	stateID := func(p *cspmProc) StateID {
+		if id, ok := known[p.key]; ok {
+			return id
+		}
+		preferred := strings.ReplaceAll(p.key, "'", "_")
+		switch p.kind {
+		case cspmRef, cspmStop, cspmSkip:
+		default:
+			anonymous++
+			preferred = fmt.Sprintf("%s_%d", strings.ReplaceAll(process, "'", "_"), anonymous)
+		}
+		id := ids.id(p.key, StateID(preferred))
+		known[p.key] = id
+		return id
+	}
+
+	root := newCSPmProc(cspmRef, process, nil, nil, nil)
+	rootID := stateID(root)
+	d := &Diagram{
+		Name:      process,
+		States:    map[StateID]State{rootID: {ID: rootID, Name: root.key, Vars: []StateVar{}}},
+		StartEdge: StartEdge{Dst: rootID, Post: True},
+		Edges:     []Edge{},
+	}
+	queue := []*cspmProc{root}
+	for len(queue) > 0 {
+		p := queue[0]
+		queue = queue[1:]
+		srcID := stateID(p)
+
+		isSkip, err := s.isSkip(p)
+		if err != nil {
+			return nil, fmt.Errorf("csdf.cspmScript.explore: %w", err)
+		}
+		if isSkip {
+			d.EndEdge = &EndEdge{Src: srcID}
+			continue
+		}
+		ts, err := s.transitions(p, make(map[string]struct{}))
+		if err != nil {
+			return nil, fmt.Errorf("csdf.cspmScript.explore: %w", err)
+		}
+		for _, t := range ts {
+			dst := t.dst
+			if isSkip, err := s.isSkip(dst); err != nil {
+				return nil, fmt.Errorf("csdf.cspmScript.explore: %w", err)
+			} else if isSkip {
+				// Every way of terminating shares the single end edge.
+				dst = newCSPmProc(cspmSkip, "", nil, nil, nil)
+			}
+			dstID := stateID(dst)
+			if _, ok := d.States[dstID]; !ok {
+				if len(d.States) >= maxCSPmStates {
+					return nil, fmt.Errorf("csdf.cspmScript.explore: %s has more than %d states; only finite-state processes are supported", process, maxCSPmStates)
+				}
+				d.States[dstID] = State{ID: dstID, Name: dst.key, Vars: []StateVar{}}
+				queue = append(queue, dst)
+			}
+			d.Edges = append(d.Edges, Edge{Src: srcID, Dst: dstID, Event: t.event, Guard: True, Post: True})
+		}
+	}
+	ids.assign(d)
+	return d, nil
+}
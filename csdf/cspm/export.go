@@ -0,0 +1,336 @@
+// Package cspm writes Composable State Diagrams as CSPm, the machine-readable
+// CSP dialect of FDR. csdf.ParseCSPm reads a subset of CSPm back.
+package cspm
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Kuniwak/puml-parallel/csdf"
+)
+
+// Model is an FDR semantic model for refinement assertions.
+type Model string
+
+const (
+	Traces              Model = "T"
+	Failures            Model = "F"
+	FailuresDivergences Model = "FD"
+)
+
+// ParseModel parses "T", "F" or "FD".
+func ParseModel(s string) (Model, error) {
+	switch Model(s) {
+	case Traces, Failures, FailuresDivergences:
+		return Model(s), nil
+	default:
+		return "", fmt.Errorf("cspm.ParseModel: unknown semantic model %q (want T, F or FD)", s)
+	}
+}
+
+// ExportOptions selects the synchronization set and the assertions of an
+// exported script.
+type ExportOptions struct {
+	// Sync is the interface of the parallel composition, as in
+	// csdf.ComposeParallel.
+	Sync []csdf.Event
+	// DeadlockFree adds "assert SYSTEM :[deadlock free [F]]".
+	DeadlockFree bool
+	// DivergenceFree adds "assert SYSTEM :[divergence free]", FDR's livelock
+	// check.
+	DivergenceFree bool
+	// Spec, when not nil, is exported as SPEC and "assert SPEC [Model= SYSTEM"
+	// is added.
+	Spec  *csdf.Diagram
+	Model Model
+}
+
+// Export writes a CSPm script for the interface parallel composition of
+// diagrams over opts.Sync:
+//
+//   - every visible event becomes a channel without data; events that are not
+//     CSPm identifiers are renamed (a comment lists the original text),
+//   - every state s of diagram i becomes a process Di_s offering its outgoing
+//     edges in external choice; a state with an end edge also offers SKIP and a
+//     state without outgoing edges is STOP,
+//   - τ-edges are performed on an extra channel that Di hides,
+//   - SYSTEM composes D0, D1, ... with [| {sync} |] (||| when Sync is empty).
+//
+// Guards and post-conditions are natural language and are kept as comments.
+func Export(diagrams []*csdf.Diagram, opts *ExportOptions) (string, error) {
+	if len(diagrams) == 0 {
+		return "", fmt.Errorf("cspm.Export: no diagrams")
+	}
+	for _, event := range opts.Sync {
+		if event == csdf.Tau {
+			return "", fmt.Errorf("cspm.Export: tau cannot be a synchronization event")
+		}
+	}
+	if opts.Spec != nil && opts.Model == "" {
+		return "", fmt.Errorf("cspm.Export: a refinement assertion needs a semantic model")
+	}
+
+	names := newNamer()
+	names.reserve("SYSTEM", "SPEC")
+	all := append([]*csdf.Diagram{}, diagrams...)
+	if opts.Spec != nil {
+		all = append(all, opts.Spec)
+	}
+	events, hasTau := collectEvents(all, opts.Sync)
+	channels := make(map[csdf.Event]string, len(events))
+	for _, event := range events {
+		channels[event] = names.name(string(event), "e")
+	}
+	tauChannel := ""
+	if hasTau {
+		tauChannel = names.name("tau", "e")
+	}
+
+	var sb strings.Builder
+	sb.WriteString("-- Channels\n")
+	for _, event := range events {
+		if channels[event] != string(event) {
+			sb.WriteString(fmt.Sprintf("-- %s: %s\n", channels[event], event))
+		}
+	}
+	if len(events) > 0 {
+		sb.WriteString("channel " + strings.Join(channelList(events, channels), ", ") + "\n")
+	}
+	if hasTau {
+		sb.WriteString("-- Internal transitions (tau) happen on this channel, which is hidden.\n")
+		sb.WriteString("channel " + tauChannel + "\n")
+	}
+
+	processes := make([]string, len(diagrams))
+	for i, d := range diagrams {
+		processes[i] = names.name(fmt.Sprintf("D%d", i), "P")
+		writeDiagram(&sb, d, processes[i], names, channels, tauChannel)
+	}
+
+	sb.WriteString("\n")
+	if len(processes) == 1 {
+		sb.WriteString("SYSTEM = " + processes[0] + "\n")
+	} else {
+		operator := " ||| "
+		if len(opts.Sync) > 0 {
+			sync := make([]csdf.Event, len(opts.Sync))
+			copy(sync, opts.Sync)
+			sortEvents(sync)
+			operator = " [| {" + strings.Join(channelList(sync, channels), ", ") + "} |] "
+		}
+		system := processes[0]
+		for i, process := range processes[1:] {
+			if i > 0 {
+				system = "(" + system + ")"
+			}
+			system += operator + process
+		}
+		sb.WriteString("SYSTEM = " + system + "\n")
+	}
+
+	if opts.Spec != nil {
+		writeDiagram(&sb, opts.Spec, "SPEC", names, channels, tauChannel)
+	}
+
+	if opts.DeadlockFree || opts.DivergenceFree || opts.Spec != nil {
+		sb.WriteString("\n")
+	}
+	if opts.DeadlockFree {
+		sb.WriteString("assert SYSTEM :[deadlock free [F]]\n")
+	}
+	if opts.DivergenceFree {
+		sb.WriteString("assert SYSTEM :[divergence free]\n")
+	}
+	if opts.Spec != nil {
+		sb.WriteString(fmt.Sprintf("assert SPEC [%s= SYSTEM\n", opts.Model))
+	}
+	return sb.String(), nil
+}
+
+// writeDiagram writes the definition of process, the behavior of d, and of
+// its state processes, named process_<state ID>.
+func writeDiagram(sb *strings.Builder, d *csdf.Diagram, process string, names *namer, channels map[csdf.Event]string, tauChannel string) {
+	sb.WriteString("\n")
+	if d.Name != "" {
+		sb.WriteString(fmt.Sprintf("-- %s: %s\n", process, d.Name))
+	}
+
+	// A state only referred to by edges is a process too.
+	seen := make(map[csdf.StateID]bool, len(d.States))
+	stateIDs := make([]csdf.StateID, 0, len(d.States))
+	add := func(id csdf.StateID) {
+		if !seen[id] {
+			seen[id] = true
+			stateIDs = append(stateIDs, id)
+		}
+	}
+	for id := range d.States {
+		add(id)
+	}
+	add(d.StartEdge.Dst)
+	for _, e := range d.Edges {
+		add(e.Src)
+		add(e.Dst)
+	}
+	if d.EndEdge != nil {
+		add(d.EndEdge.Src)
+	}
+	sort.Slice(stateIDs, func(i, j int) bool { return stateIDs[i] < stateIDs[j] })
+	processes := make(map[csdf.StateID]string, len(stateIDs))
+	for _, id := range stateIDs {
+		processes[id] = names.name(process+"_"+string(id), "P")
+	}
+
+	outgoing := make(map[csdf.StateID][]csdf.Edge)
+	usesTau := false
+	for _, e := range d.Edges {
+		outgoing[e.Src] = append(outgoing[e.Src], e)
+		if e.Event == csdf.Tau {
+			usesTau = true
+		}
+	}
+
+	for _, id := range stateIDs {
+		if state, ok := d.States[id]; ok && state.Name != string(id) {
+			sb.WriteString(fmt.Sprintf("-- %s: %s\n", processes[id], state.Name))
+		}
+		var choices []string
+		for _, e := range outgoing[id] {
+			channel := channels[e.Event]
+			if e.Event == csdf.Tau {
+				channel = tauChannel
+			}
+			choices = append(choices, channel+" -> "+processes[e.Dst]+conditionComment(e.Guard, e.Post))
+		}
+		if d.EndEdge != nil && d.EndEdge.Src == id {
+			choices = append(choices, "SKIP"+conditionComment(d.EndEdge.Guard, ""))
+		}
+		if len(choices) == 0 {
+			choices = []string{"STOP"}
+		}
+		sb.WriteString(processes[id] + " =\n    " + strings.Join(choices, "\n    [] ") + "\n")
+	}
+
+	body := processes[d.StartEdge.Dst]
+	if usesTau {
+		body += " \\ {" + tauChannel + "}"
+	}
+	sb.WriteString(process + " = " + body + conditionComment("", d.StartEdge.Post) + "\n")
+}
+
+// conditionComment renders a guard and a post-condition as a CSPm block
+// comment, omitting trivial ones.
+func conditionComment(guard, post string) string {
+	var parts []string
+	if guard != "" && guard != csdf.True {
+		parts = append(parts, "["+guard+"]")
+	}
+	if post != "" && post != csdf.True {
+		parts = append(parts, "/ "+post)
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	text := strings.NewReplacer("{-", "{ -", "-}", "- }").Replace(strings.Join(parts, " "))
+	return " {- " + text + " -}"
+}
+
+// collectEvents returns the visible events of diagrams and sync, sorted, and
+// whether any diagram has a τ-edge.
+func collectEvents(diagrams []*csdf.Diagram, sync []csdf.Event) ([]csdf.Event, bool) {
+	seen := make(map[csdf.Event]struct{})
+	hasTau := false
+	for _, d := range diagrams {
+		for _, e := range d.Edges {
+			if e.Event == csdf.Tau {
+				hasTau = true
+				continue
+			}
+			seen[e.Event] = struct{}{}
+		}
+	}
+	for _, event := range sync {
+		seen[event] = struct{}{}
+	}
+	events := make([]csdf.Event, 0, len(seen))
+	for event := range seen {
+		events = append(events, event)
+	}
+	sortEvents(events)
+	return events, hasTau
+}
+
+func sortEvents(events []csdf.Event) {
+	sort.Slice(events, func(i, j int) bool { return events[i] < events[j] })
+}
+
+func channelList(events []csdf.Event, channels map[csdf.Event]string) []string {
+	list := make([]string, len(events))
+	for i, event := range events {
+		list[i] = channels[event]
+	}
+	return list
+}
+
+// keywords are the CSPm reserved words and the built-in names that generated
+// identifiers must avoid.
+var keywords = []string{
+	"and", "assert", "channel", "datatype", "else", "false", "if", "include",
+	"let", "nametype", "not", "or", "subtype", "then", "transparent", "true",
+	"within", "external", "timed", "module", "exports", "endmodule", "instance",
+	"STOP", "SKIP", "CHAOS", "DIV", "RUN", "WAIT", "Bool", "Int", "Char",
+	"Events", "Proc", "Set", "Seq",
+}
+
+// namer allocates distinct CSPm identifiers.
+type namer struct {
+	taken map[string]struct{}
+}
+
+func newNamer() *namer {
+	n := &namer{taken: make(map[string]struct{})}
+	n.reserve(keywords...)
+	return n
+}
+
+func (n *namer) reserve(names ...string) {
+	for _, name := range names {
+		n.taken[name] = struct{}{}
+	}
+}
+
+// name returns an unused identifier close to text. Characters other than ASCII
+// letters, digits and '_' become '_', and prefix is prepended when the result
+// would not start with a letter.
+func (n *namer) name(text, prefix string) string {
+	var sb strings.Builder
+	for _, r := range text {
+		if isIdentRune(r) {
+			sb.WriteRune(r)
+		} else {
+			sb.WriteByte('_')
+		}
+	}
+	base := strings.TrimRight(sb.String(), "_")
+	if base == "" || !isLetter(rune(base[0])) {
+		base = prefix + "_" + base
+	}
+	candidate := base
+	for i := 2; ; i++ {
+		if _, ok := n.taken[candidate]; !ok {
+			break
+		}
+		candidate = fmt.Sprintf("%s_%d", base, i)
+	}
+	n.taken[candidate] = struct{}{}
+	return candidate
+}
+
+func isLetter(r rune) bool {
+	return (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z')
+}
+
+func isIdentRune(r rune) bool {
+	return isLetter(r) || (r >= '0' && r <= '9') || r == '_'
+}
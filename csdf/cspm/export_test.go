@@ -0,0 +1,201 @@
+package cspm
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Kuniwak/puml-parallel/csdf"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestExportComposesDiagrams(t *testing.T) {
+	// Setup
+	diagrams := csdf.MustLoadDiagrams("../../examples/valid/in.puml", "../../examples/valid/out.puml")
+	want := `-- Channels
+channel in, out, sync
+
+D0_s0 =
+    in -> D0_s1
+D0_s1 =
+    sync -> D0_s2
+D0_s2 =
+    STOP
+D0 = D0_s0
+
+D1_s0 =
+    sync -> D1_s1
+D1_s1 =
+    out -> D1_s2
+D1_s2 =
+    STOP
+D1 = D1_s0
+
+SYSTEM = D0 [| {sync} |] D1
+
+assert SYSTEM :[deadlock free [F]]
+assert SYSTEM :[divergence free]
+`
+
+	// Execute
+	got, err := Export(diagrams, &ExportOptions{
+		Sync:           []csdf.Event{"sync"},
+		DeadlockFree:   true,
+		DivergenceFree: true,
+	})
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestExportHidesTauAndRenamesEvents(t *testing.T) {
+	// Setup
+	d, err := csdf.ParseDiagram([]byte(`@startuml machine
+state "Idle" as idle
+state "Busy" as busy
+[*] --> idle : count = 0
+idle --> busy : insert coin ; count < 3 ; count' = count + 1
+busy --> idle : tau
+busy --> STOP : finish(result)
+state STOP
+STOP --> [*]
+@enduml
+`))
+	if err != nil {
+		t.Fatalf("ParseDiagram() error = %v", err)
+	}
+	want := `-- Channels
+-- finish_result: finish(result)
+-- insert_coin: insert coin
+channel finish_result, insert_coin
+-- Internal transitions (tau) happen on this channel, which is hidden.
+channel tau
+
+-- D0: machine
+D0_STOP =
+    SKIP
+-- D0_busy: Busy
+D0_busy =
+    tau -> D0_idle
+    [] finish_result -> D0_STOP
+-- D0_idle: Idle
+D0_idle =
+    insert_coin -> D0_busy {- [count < 3] / count' = count + 1 -}
+D0 = D0_idle \ {tau} {- / count = 0 -}
+
+SYSTEM = D0
+`
+
+	// Execute
+	got, err := Export([]*csdf.Diagram{d}, &ExportOptions{})
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestExportAddsRefinementAssertion(t *testing.T) {
+	// Setup
+	diagrams := csdf.MustLoadDiagrams("../../examples/valid/in.puml", "../../examples/valid/out.puml")
+	spec := csdf.MustLoadDiagrams("../../examples/valid/in.puml")[0]
+
+	// Execute
+	got, err := Export(diagrams, &ExportOptions{Sync: []csdf.Event{"sync"}, Spec: spec, Model: FailuresDivergences})
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+	for _, want := range []string{"SPEC_s0 =\n    in -> SPEC_s1\n", "SPEC = SPEC_s0\n", "assert SPEC [FD= SYSTEM\n"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Export() = %q, want it to contain %q", got, want)
+		}
+	}
+}
+
+func TestExportDefinesStatesOnlyReferredToByEdges(t *testing.T) {
+	// Setup: b is not declared.
+	d, err := csdf.ParseDiagram([]byte(`@startuml
+state "A" as a
+[*] --> a
+a --> b : go
+b --> a : back
+@enduml
+`))
+	if err != nil {
+		t.Fatalf("ParseDiagram() error = %v", err)
+	}
+	want := `-- Channels
+channel back, go
+
+-- D0_a: A
+D0_a =
+    go -> D0_b
+D0_b =
+    back -> D0_a
+D0 = D0_a
+
+SYSTEM = D0
+`
+
+	// Execute
+	got, err := Export([]*csdf.Diagram{d}, &ExportOptions{})
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestExportRejectsTauInSyncSet(t *testing.T) {
+	// Setup
+	diagrams := csdf.MustLoadDiagrams("../../examples/valid/in.puml")
+
+	// Execute
+	_, err := Export(diagrams, &ExportOptions{Sync: []csdf.Event{csdf.Tau}})
+
+	// Assert
+	if err == nil {
+		t.Fatal("Export() error = nil, want error")
+	}
+}
+
+func TestExportedScriptCanBeImported(t *testing.T) {
+	// Setup
+	diagrams := csdf.MustLoadDiagrams("../../examples/valid/in.puml", "../../examples/valid/out.puml")
+	script, err := Export(diagrams, &ExportOptions{Sync: []csdf.Event{"sync"}, DeadlockFree: true})
+	if err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+	want := `@startuml D1
+state "D1" as D1
+state "D1_s1" as D1_s1
+state "D1_s2" as D1_s2
+[*] --> D1
+D1 --> D1_s1 : sync
+D1_s1 --> D1_s2 : out
+@enduml
+`
+
+	// Execute
+	imported, err := csdf.ParseCSPm(script, "D1")
+
+	// Assert
+	if err != nil {
+		t.Fatalf("ParseCSPm() error = %v", err)
+	}
+	if diff := cmp.Diff(want, imported.String()); diff != "" {
+		t.Error(diff)
+	}
+}
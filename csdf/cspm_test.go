@@ -0,0 +1,232 @@
+package csdf
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseCSPm(t *testing.T) {
+	// Setup
+	input := `-- comments {- and block comments -} are skipped
+channel coin, tea, coffee, refill, done
+{- nested {- block -} comment -}
+VM = coin -> (tea -> VM [] coffee -> VM) |~| Broken
+Broken = refill -> VM
+   [] done -> Stop'
+Stop' = SKIP
+assert VM :[deadlock free [F]]
+`
+	want := `@startuml VM
+state "Broken" as Broken
+state "SKIP" as SKIP
+state "VM" as VM
+state "coin -> ((tea -> VM) [] (coffee -> VM))" as VM_1
+state "(tea -> VM) [] (coffee -> VM)" as VM_2
+[*] --> VM
+VM --> VM_1 : tau
+VM --> Broken : tau
+VM_1 --> VM_2 : coin
+Broken --> VM : refill
+Broken --> SKIP : done
+VM_2 --> VM : tea
+VM_2 --> VM : coffee
+SKIP --> [*]
+@enduml
+`
+
+	// Execute
+	d, err := ParseCSPm(input, "")
+
+	// Assert
+	if err != nil {
+		t.Fatalf("ParseCSPm() error = %v", err)
+	}
+	if diff := cmp.Diff(want, d.String()); diff != "" {
+		t.Error(diff)
+	}
+
+	// Teardown: no resources to release.
+}
+
+func TestParseCSPmHidesEvents(t *testing.T) {
+	// Setup: τ inside an external choice does not resolve the choice.
+	input := `channel a, b, h
+P = ((h -> a -> P) \ {h}) [] b -> STOP
+`
+	want := `@startuml P
+state "P" as P
+state "((a -> P) \ {h}) [] (b -> STOP)" as P_1
+state "P \ {h}" as P_2
+state "(((a -> P) \ {h}) [] (b -> STOP)) \ {h}" as P_3
+state "STOP" as STOP
+[*] --> P
+P --> P_1 : tau
+P --> STOP : b
+P_1 --> P_2 : a
+P_1 --> STOP : b
+P_2 --> P_3 : tau
+P_2 --> STOP : b
+P_3 --> P_2 : a
+P_3 --> STOP : b
+@enduml
+`
+
+	// Execute
+	d, err := ParseCSPm(input, "P")
+
+	// Assert
+	if err != nil {
+		t.Fatalf("ParseCSPm() error = %v", err)
+	}
+	if diff := cmp.Diff(want, d.String()); diff != "" {
+		t.Error(diff)
+	}
+
+	// Teardown: no resources to release.
+}
+
+func TestParseCSPmComposesInParallel(t *testing.T) {
+	// Setup: termination is distributed, so SKIP needs both sides to terminate.
+	input := `channel a, b, c
+P = (a -> b -> SKIP) [| {a} |] (a -> SKIP) ||| c -> SKIP
+`
+	want := `@startuml P
+state "P" as P
+state "((b -> SKIP) [| {a} |] SKIP) ||| (c -> SKIP)" as P_1
+state "((a -> (b -> SKIP)) [| {a} |] (a -> SKIP)) ||| SKIP" as P_2
+state "(SKIP [| {a} |] SKIP) ||| (c -> SKIP)" as P_3
+state "((b -> SKIP) [| {a} |] SKIP) ||| SKIP" as P_4
+state "SKIP" as SKIP
+[*] --> P
+P --> P_1 : a
+P --> P_2 : c
+P_1 --> P_3 : b
+P_1 --> P_4 : c
+P_2 --> P_4 : a
+P_3 --> SKIP : c
+P_4 --> SKIP : b
+SKIP --> [*]
+@enduml
+`
+
+	// Execute
+	d, err := ParseCSPm(input, "")
+
+	// Assert
+	if err != nil {
+		t.Fatalf("ParseCSPm() error = %v", err)
+	}
+	if diff := cmp.Diff(want, d.String()); diff != "" {
+		t.Error(diff)
+	}
+
+	// Teardown: no resources to release.
+}
+
+func TestParseCSPmRejects(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		process string
+		wantErr string
+	}{
+		{
+			name:    "channel with data",
+			input:   "channel c : Bool\nP = STOP\n",
+			wantErr: "channels carrying data are not supported at line 1, col 11",
+		},
+		{
+			name:    "sequential composition",
+			input:   "channel a\nP = a -> SKIP ; P\n",
+			wantErr: "unsupported CSPm syntax \";\" at line 2, col 15",
+		},
+		{
+			name:    "undeclared channel",
+			input:   "P = a -> STOP\n",
+			wantErr: "undeclared channel a at line 1, col 5",
+		},
+		{
+			name:    "undefined process",
+			input:   "channel a\nP = a -> Q\n",
+			wantErr: "in P: csdf.cspmScript.checkNames: undefined process Q",
+		},
+		{
+			name:    "unguarded recursion",
+			input:   "channel a\nP = P [] a -> P\n",
+			wantErr: "unguarded recursion through P",
+		},
+		{
+			name:    "SKIP in external choice",
+			input:   "channel a\nP = a -> P [] SKIP\n",
+			wantErr: "SKIP as an alternative of an external choice is not supported",
+		},
+		{
+			name:    "unknown process",
+			input:   "channel a\nP = a -> P\n",
+			process: "Q",
+			wantErr: "no process named \"Q\" (defined processes: P)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Execute
+			_, err := ParseCSPm(tt.input, tt.process)
+
+			// Assert
+			if err == nil {
+				t.Fatal("ParseCSPm() error = nil, want error")
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ParseCSPm() error = %q, want %q", err, tt.wantErr)
+			}
+
+			// Teardown: no resources to release.
+		})
+	}
+}
+
+func TestLoadDiagramsReadsCSPm(t *testing.T) {
+	// Setup
+	refs := []string{"../examples/valid/tea_machine.csp#Customer", "../examples/valid/tea_machine.csp"}
+
+	// Execute
+	diagrams, err := LoadDiagrams(refs)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("LoadDiagrams() error = %v", err)
+	}
+	if diagrams[0].Name != "Customer" || diagrams[1].Name != "VM" {
+		t.Errorf("LoadDiagrams() names = %q, %q, want Customer, VM", diagrams[0].Name, diagrams[1].Name)
+	}
+	composite, err := ComposeParallel(diagrams, []Event{"coin", "tea"})
+	if err != nil {
+		t.Fatalf("ComposeParallel() error = %v", err)
+	}
+	if len(composite.States) == 0 {
+		t.Error("ComposeParallel() has no states")
+	}
+
+	// Teardown: no resources to release.
+}
+
+func TestParseDiagramDetectsCSPmByContent(t *testing.T) {
+	// Setup
+	input := []byte("channel a\nP = a -> P\n")
+
+	// Execute
+	d, err := ParseDiagram(input)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("ParseDiagram() error = %v", err)
+	}
+	if d.Name != "P" || len(d.Edges) != 1 || d.Edges[0].Event != "a" {
+		t.Errorf("ParseDiagram() = %#v", d)
+	}
+
+	// Teardown: no resources to release.
+}
@@ -0,0 +1,93 @@
+package csdf
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Deadlock is a reachable state without outgoing edges: a deadlock witness.
+// Trace is a shortest path of edges from the start state to State.
+type Deadlock struct {
+	Trace []Edge
+	State StateID
+}
+
+// CheckDeadlockFree reports whether d is deadlock free, i.e. every state
+// reachable from the start state has an outgoing edge. When a deadlock exists it
+// returns the first deadlocked state found breadth first, with a shortest trace
+// to it, and ok == false; otherwise it returns (nil, true).
+//
+// Like CheckLivelockFree, the analysis is purely structural: guards are not
+// evaluated, so an edge counts as enabled whatever its guard.
+func CheckDeadlockFree(d *Diagram) (witness *Deadlock, ok bool) {
+	out := make(map[StateID][]Edge)
+	for _, e := range d.Edges {
+		out[e.Src] = append(out[e.Src], e)
+	}
+
+	// Breadth first over the edges in diagram order, so that the witness is
+	// reproducible.
+	prev := make(map[StateID]Edge)
+	seen := map[StateID]struct{}{d.StartEdge.Dst: {}}
+	queue := []StateID{d.StartEdge.Dst}
+	for len(queue) > 0 {
+		s := queue[0]
+		queue = queue[1:]
+		if len(out[s]) == 0 {
+			return &Deadlock{Trace: buildPath(prev, d.StartEdge.Dst, s), State: s}, false
+		}
+		for _, e := range out[s] {
+			if _, ok := seen[e.Dst]; ok {
+				continue
+			}
+			seen[e.Dst] = struct{}{}
+			prev[e.Dst] = e
+			queue = append(queue, e.Dst)
+		}
+	}
+	return nil, true
+}
+
+// CheckComposedDeadlockFree is CheckDeadlockFree on ComposeParallel(diagrams,
+// syncEvents). It builds the reachable product under a partial-order
+// reduction, which may leave out many of its states, and then checks it; it
+// does not stop at the first deadlock.
+func CheckComposedDeadlockFree(diagrams []*Diagram, syncEvents []Event) (witness *Deadlock, ok bool, err error) {
+	return (&Explorer{}).CheckComposedDeadlockFree(diagrams, syncEvents)
+}
+
+// CheckComposedDeadlockFree is the function CheckComposedDeadlockFree run on the
+// workers of x. Unless x.NoReduction, it explores the composition under a
+// partial-order reduction that keeps every reachable deadlock, so the witness
+// may differ from the one of the whole composition, but the verdict does not.
+func (x *Explorer) CheckComposedDeadlockFree(diagrams []*Diagram, syncEvents []Event) (witness *Deadlock, ok bool, err error) {
+	if len(diagrams) == 1 {
+		witness, ok = CheckDeadlockFree(diagrams[0])
+		return witness, ok, nil
+	}
+	p, err := x.explore(diagrams, syncEvents, x.reduction(preserveDeadlocks), true)
+	if err != nil {
+		return nil, false, fmt.Errorf("csdf.Explorer.CheckComposedDeadlockFree: %w", err)
+	}
+	witness, ok = CheckDeadlockFree(p.diagram())
+	return witness, ok, nil
+}
+
+func (x *Explorer) reduction(r reduction) reduction {
+	if x != nil && x.NoReduction {
+		return noReduction
+	}
+	return r
+}
+
+// RenderDeadlock renders a witness as human-readable lines: the trace, one
+// transition per line as "Src --event--> Dst", then a "deadlock:" line naming
+// the deadlocked state.
+func RenderDeadlock(w *Deadlock) string {
+	var sb strings.Builder
+	for _, e := range w.Trace {
+		sb.WriteString(renderEdge(e))
+	}
+	fmt.Fprintf(&sb, "deadlock: %s\n", w.State)
+	return sb.String()
+}
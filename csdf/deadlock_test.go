@@ -0,0 +1,250 @@
+package csdf
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"pgregory.net/rapid"
+)
+
+func TestCheckDeadlockFreeReportsFreeWhenEveryStateMoves(t *testing.T) {
+	// Setup: a two-state cycle never gets stuck.
+	d := mustParse(t, `@startuml
+state "a" as a
+state "b" as b
+[*] --> a
+a --> b : x
+b --> a : y
+@enduml
+`)
+
+	// Execute
+	witness, ok := CheckDeadlockFree(d)
+
+	// Assert
+	if !ok {
+		t.Errorf("want deadlock free, got witness %+v", witness)
+	}
+}
+
+func TestCheckDeadlockFreeReturnsShortestTrace(t *testing.T) {
+	// Setup: d is reachable by a -> b -> d and by the shorter a -> d.
+	d := mustParse(t, `@startuml
+state "a" as a
+state "b" as b
+state "d" as d
+[*] --> a
+a --> b : x
+b --> d : y
+a --> d : z
+b --> a : w
+@enduml
+`)
+	want := &Deadlock{
+		Trace: []Edge{{Src: "a", Dst: "d", Event: "z", Guard: True, Post: True}},
+		State: "d",
+	}
+
+	// Execute
+	witness, ok := CheckDeadlockFree(d)
+
+	// Assert
+	if ok {
+		t.Error("want deadlock detected, got deadlock free")
+	}
+	if diff := cmp.Diff(want, witness); diff != "" {
+		t.Error(diff)
+	}
+	if got, want := RenderDeadlock(witness), "a --z--> d\ndeadlock: d\n"; got != want {
+		t.Errorf("RenderDeadlock() = %q, want %q", got, want)
+	}
+}
+
+func TestCheckComposedDeadlockFreeDetectsBlockedSync(t *testing.T) {
+	// Setup: the components disagree on the order of the sync events.
+	left := mustParse(t, `@startuml
+state "l0" as l0
+state "l1" as l1
+[*] --> l0
+l0 --> l1 : a
+l1 --> l0 : b
+@enduml
+`)
+	right := mustParse(t, `@startuml
+state "r0" as r0
+state "r1" as r1
+[*] --> r0
+r0 --> r1 : b
+r1 --> r0 : a
+@enduml
+`)
+	want := &Deadlock{State: "l0_r0"}
+
+	for _, noReduction := range []bool{false, true} {
+		t.Run(fmt.Sprintf("NoReduction=%v", noReduction), func(t *testing.T) {
+			// Execute
+			witness, ok, err := (&Explorer{NoReduction: noReduction}).CheckComposedDeadlockFree([]*Diagram{left, right}, []Event{"a", "b"})
+
+			// Assert
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ok {
+				t.Error("want deadlock detected, got deadlock free")
+			}
+			if diff := cmp.Diff(want, witness); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestComposedChecksDoNotDependOnReduction(t *testing.T) {
+	// Setup: the example pairs synchronized on their common events, and
+	// generated components with τ edges, blocking sync events and unsynchronized
+	// events.
+	systems := map[string][]*Diagram{
+		"in_out":                MustLoadDiagrams("../examples/valid/in.puml", "../examples/valid/out.puml"),
+		"client_server":         MustLoadDiagrams("../examples/valid/client.puml", "../examples/valid/server.puml"),
+		"user_vending_machine":  MustLoadDiagrams("../examples/valid/user.puml", "../examples/valid/vending_machine.puml"),
+		"test_sync1_test_sync2": MustLoadDiagrams("../examples/valid/test_sync1.puml", "../examples/valid/test_sync2.puml"),
+	}
+	syncs := make(map[string][]Event, len(systems)+3)
+	for name, diagrams := range systems {
+		for _, event := range CommonEvents(diagrams) {
+			syncs[name] = append(syncs[name], Event(event))
+		}
+	}
+	for i, events := range [][][]Event{
+		{{"a", Tau, "sync"}, {"b", "sync"}, {Tau, "c"}},
+		{{Tau, "sync", "a"}, {"sync", Tau}},
+		{{"a", "b"}, {"c", "sync", Tau}, {"sync", "d", "e"}},
+	} {
+		name := fmt.Sprintf("generated%d", i)
+		for j, es := range events {
+			systems[name] = append(systems[name], generateDiagram(7+3*j, 2, es))
+		}
+		syncs[name] = []Event{"sync"}
+	}
+
+	for name, diagrams := range systems {
+		t.Run(name, func(t *testing.T) {
+			full := &Explorer{NoReduction: true}
+			composite, err := full.ComposeParallel(diagrams, syncs[name])
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, wantDeadlockFree := CheckDeadlockFree(composite)
+			_, wantLivelockFree := CheckLivelockFree(composite)
+
+			for _, x := range []*Explorer{full, {}} {
+				// Execute
+				_, deadlockFree, err := x.CheckComposedDeadlockFree(diagrams, syncs[name])
+				if err != nil {
+					t.Fatal(err)
+				}
+				_, livelockFree, err := x.CheckComposedLivelockFree(diagrams, syncs[name])
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				// Assert
+				if deadlockFree != wantDeadlockFree {
+					t.Errorf("NoReduction=%v: deadlock free = %v, want %v", x.NoReduction, deadlockFree, wantDeadlockFree)
+				}
+				if livelockFree != wantLivelockFree {
+					t.Errorf("NoReduction=%v: livelock free = %v, want %v", x.NoReduction, livelockFree, wantLivelockFree)
+				}
+			}
+		})
+	}
+}
+
+func TestComposedChecksDoNotDependOnReductionOnRandomDiagrams(t *testing.T) {
+	rapid.Check(t, func(t *rapid.T) {
+		events := []Event{"a", "b", "c", "sync", Tau}
+		diagrams := make([]*Diagram, rapid.IntRange(2, 3).Draw(t, "components"))
+		for i := range diagrams {
+			n := rapid.IntRange(1, 4).Draw(t, "states")
+			d := &Diagram{States: make(map[StateID]State, n), StartEdge: StartEdge{Dst: "s0"}}
+			for j := 0; j < n; j++ {
+				id := StateID(fmt.Sprintf("s%d", j))
+				d.States[id] = State{ID: id, Name: string(id)}
+			}
+			for e := rapid.IntRange(0, 6).Draw(t, "edges"); e > 0; e-- {
+				d.Edges = append(d.Edges, Edge{
+					Src:   StateID(fmt.Sprintf("s%d", rapid.IntRange(0, n-1).Draw(t, "src"))),
+					Dst:   StateID(fmt.Sprintf("s%d", rapid.IntRange(0, n-1).Draw(t, "dst"))),
+					Event: rapid.SampledFrom(events).Draw(t, "event"),
+				})
+			}
+			diagrams[i] = d
+		}
+		sync := rapid.SliceOfDistinct(rapid.SampledFrom(events[:4]), func(e Event) Event { return e }).Draw(t, "sync")
+
+		full := &Explorer{NoReduction: true}
+		reduced := &Explorer{}
+		_, fullDeadlockFree, err := full.CheckComposedDeadlockFree(diagrams, sync)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, reducedDeadlockFree, err := reduced.CheckComposedDeadlockFree(diagrams, sync)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, fullLivelockFree, err := full.CheckComposedLivelockFree(diagrams, sync)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, reducedLivelockFree, err := reduced.CheckComposedLivelockFree(diagrams, sync)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if reducedDeadlockFree != fullDeadlockFree {
+			t.Errorf("deadlock free = %v with reduction, %v without", reducedDeadlockFree, fullDeadlockFree)
+		}
+		if reducedLivelockFree != fullLivelockFree {
+			t.Errorf("livelock free = %v with reduction, %v without", reducedLivelockFree, fullLivelockFree)
+		}
+	})
+}
+
+func TestReductionPrunesIndependentInterleavings(t *testing.T) {
+	// Setup: three components stepping through four states each on their own
+	// events have 4^3 interleaved states; following one component at a time
+	// reaches the final state along 3*3+1 states.
+	var components []*Diagram
+	for i := 0; i < 3; i++ {
+		components = append(components, mustParse(t, fmt.Sprintf(`@startuml
+state "s0" as s0
+state "s1" as s1
+state "s2" as s2
+state "s3" as s3
+[*] --> s0
+s0 --> s1 : e%[1]d
+s1 --> s2 : e%[1]d
+s2 --> s3 : e%[1]d
+@enduml
+`, i)))
+	}
+
+	// Execute
+	full, err := (&Explorer{}).explore(components, nil, noReduction, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reduced, err := (&Explorer{}).explore(components, nil, preserveDeadlocks, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Assert
+	if got, want := len(full.ids), 64; got != want {
+		t.Errorf("full product has %d states, want %d", got, want)
+	}
+	if got, want := len(reduced.ids), 10; got != want {
+		t.Errorf("reduced product has %d states, want %d", got, want)
+	}
+}
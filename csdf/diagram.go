@@ -1,35 +1,207 @@
 package csdf
 
 import (
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
+	"strconv"
+	"strings"
 
 	"github.com/Kuniwak/puml-parallel/pngsrc"
+	"github.com/Kuniwak/puml-parallel/pumlenc"
 )
 
-// ParseDiagram parses a Composable State Diagram from raw .puml text or .png
-// bytes (the embedded PlantUML source is extracted from PNG inputs).
+// ParseDiagram parses a Composable State Diagram from raw .puml text, .png or
+// .svg bytes (the embedded PlantUML source is extracted from PNG and SVG
+// inputs). When the source holds several diagrams the first one is returned.
+// !include directives are resolved relative to the working directory. CSPm
+// scripts, Aldebaran files, SCXML documents, Mermaid diagrams and the JSON
+// written by csdfparse are recognized by content and read with ParseCSPm,
+// ParseAut, ParseSCXML, ParseMermaid and ParseJSON.
 func ParseDiagram(content []byte) (*Diagram, error) {
-	source, err := pngsrc.Extract(content)
+	return ParseDiagramFile("", content)
+}
+
+// ParseDiagramFile is ParseDiagram for content read from ref, a file path
+// optionally followed by "#name" (see SplitDiagramRef). The format follows
+// from the file name, or else from the content:
+//
+//   - PlantUML (any other file, such as *.puml, *.png or *.svg): !include
+//     directives are resolved relative to the directory of the file, and
+//     "#name" selects the diagram by the name after @startuml.
+//   - CSPm (*.csp, *.cspm): "#name" selects the process.
+//   - Mermaid (*.mmd, or ```mermaid blocks in *.md): "#name" selects the
+//     diagram by its title.
+//   - JSON (*.json), Aldebaran (*.aut, read with the side table next to it if
+//     any) and SCXML (*.scxml): each file holds one diagram, which "#name"
+//     must name.
+//
+// Without "#name", the first diagram is returned.
+func ParseDiagramFile(ref string, content []byte) (*Diagram, error) {
+	return ParseDiagramFileWith(ref, content, os.ReadFile)
+}
+
+// ParseDiagramFileWith is ParseDiagramFile with the files of !include
+// directives read by readFile, such as one returned by ReadFileWithin.
+func ParseDiagramFileWith(ref string, content []byte, readFile ReadFileFunc) (*Diagram, error) {
+	path, name := SplitDiagramRef(ref)
+	text, err := pngsrc.Extract(content)
 	if err != nil {
-		return nil, fmt.Errorf("csdf.ParseDiagram: reading PlantUML source: %w", err)
+		return nil, fmt.Errorf("csdf.ParseDiagramFile: reading PlantUML source: %w", err)
+	}
+	if isJSONSource(path, text) {
+		diagram, err := ParseJSON(text)
+		if err != nil {
+			return nil, fmt.Errorf("csdf.ParseDiagramFile: %w", err)
+		}
+		diagram, err = SelectDiagram([]*Diagram{diagram}, name)
+		if err != nil {
+			return nil, fmt.Errorf("csdf.ParseDiagramFile: %w", err)
+		}
+		return diagram, nil
+	}
+	if isAutSource(path, text) {
+		table, err := readAutStateTable(path)
+		if err != nil {
+			return nil, fmt.Errorf("csdf.ParseDiagramFile: %w", err)
+		}
+		diagram, err := ParseAut(text, table)
+		if err != nil {
+			return nil, fmt.Errorf("csdf.ParseDiagramFile: %w", err)
+		}
+		diagram, err = SelectDiagram([]*Diagram{diagram}, name)
+		if err != nil {
+			return nil, fmt.Errorf("csdf.ParseDiagramFile: %w", err)
+		}
+		return diagram, nil
 	}
-	diagram, err := NewParser(source).Parse()
+	if isSCXMLSource(path, text) {
+		diagram, err := ParseSCXML(text)
+		if err != nil {
+			return nil, fmt.Errorf("csdf.ParseDiagramFile: %w", err)
+		}
+		diagram, err = SelectDiagram([]*Diagram{diagram}, name)
+		if err != nil {
+			return nil, fmt.Errorf("csdf.ParseDiagramFile: %w", err)
+		}
+		return diagram, nil
+	}
+	if isMermaidSource(path, text) {
+		diagram, err := ParseMermaid(text, name)
+		if err != nil {
+			return nil, fmt.Errorf("csdf.ParseDiagramFile: %w", err)
+		}
+		return diagram, nil
+	}
+	if isCSPmSource(path, text) {
+		diagram, err := ParseCSPm(text, name)
+		if err != nil {
+			return nil, fmt.Errorf("csdf.ParseDiagramFile: %w", err)
+		}
+		return diagram, nil
+	}
+	source, err := Preprocess(text, path, readFile)
+	if err != nil {
+		return nil, fmt.Errorf("csdf.ParseDiagramFile: preprocess: %w", err)
+	}
+	diagrams, err := NewSourceParser(source).ParseAll()
 	if err != nil {
-		return nil, fmt.Errorf("csdf.ParseDiagram: parse: %w", err)
+		return nil, fmt.Errorf("csdf.ParseDiagramFile: parse: %w", err)
+	}
+	diagram, err := SelectDiagram(diagrams, name)
+	if err != nil {
+		return nil, fmt.Errorf("csdf.ParseDiagramFile: %w", err)
 	}
 	return diagram, nil
 }
 
+// SplitDiagramRef splits a diagram reference "file.puml#name" into the file
+// path and the diagram name, which is "" when the reference has no "#name"
+// suffix. A reference naming an existing file is never split, so file names
+// containing '#' keep working.
+func SplitDiagramRef(ref string) (path, name string) {
+	i := strings.LastIndexByte(ref, '#')
+	if i < 0 {
+		return ref, ""
+	}
+	if _, err := os.Stat(ref); err == nil {
+		return ref, ""
+	}
+	return ref[:i], ref[i+1:]
+}
+
+// SelectDiagram returns the diagram called name, or the first diagram when
+// name is "".
+func SelectDiagram(diagrams []*Diagram, name string) (*Diagram, error) {
+	if len(diagrams) == 0 {
+		return nil, fmt.Errorf("csdf.SelectDiagram: no diagrams")
+	}
+	if name == "" {
+		return diagrams[0], nil
+	}
+	var found *Diagram
+	names := make([]string, 0, len(diagrams))
+	for _, diagram := range diagrams {
+		if diagram.Name != "" {
+			names = append(names, strconv.Quote(diagram.Name))
+		}
+		if diagram.Name != name {
+			continue
+		}
+		if found != nil {
+			return nil, fmt.Errorf("csdf.SelectDiagram: more than one diagram is named %q", name)
+		}
+		found = diagram
+	}
+	if found == nil {
+		return nil, fmt.Errorf("csdf.SelectDiagram: no diagram named %q (named diagrams: %s)", name, strings.Join(names, ", "))
+	}
+	return found, nil
+}
+
+// ReadDiagramRef reads the content of ref, a file path optionally followed by
+// "#name", and returns it with the reference to pass to ParseDiagramFile. A
+// ref that names no existing file but is a PlantUML server link or encoded
+// text (see pumlenc.ParseRef) is decoded instead, and the source is returned
+// with the reference "". When text that is not a link fails to decode, as a
+// mistyped file name does, the error is the one of reading the file.
+func ReadDiagramRef(ref string) (string, []byte, error) {
+	path, _ := SplitDiagramRef(ref)
+	bs, err := os.ReadFile(path)
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			return "", nil, err
+		}
+		encoded, ok := pumlenc.ParseRef(ref)
+		if !ok {
+			return "", nil, err
+		}
+		source, decodeErr := pumlenc.Decode(encoded)
+		if decodeErr != nil {
+			if !strings.Contains(ref, "://") {
+				return "", nil, err
+			}
+			return "", nil, fmt.Errorf("csdf.ReadDiagramRef: %w", decodeErr)
+		}
+		return "", []byte(source), nil
+	}
+	return ref, bs, nil
+}
+
+// LoadDiagrams reads and parses one diagram per reference. A reference is a
+// file path optionally followed by "#name" to pick a named diagram from a
+// file holding several (see SplitDiagramRef), or a PlantUML server link (see
+// ReadDiagramRef).
 func LoadDiagrams(files []string) ([]*Diagram, error) {
 	diagrams := make([]*Diagram, 0, len(files))
 	for _, file := range files {
-		bs, err := os.ReadFile(file)
+		ref, bs, err := ReadDiagramRef(file)
 		if err != nil {
 			return nil, fmt.Errorf("csdf.LoadDiagrams: cannot read file: %w: %q", err, file)
 		}
 
-		diagram, err := ParseDiagram(bs)
+		diagram, err := ParseDiagramFile(ref, bs)
 		if err != nil {
 			return nil, fmt.Errorf("csdf.LoadDiagrams: cannot parse file: %w: %q", err, file)
 		}
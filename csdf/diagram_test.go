@@ -1,8 +1,11 @@
 package csdf
 
 import (
+	"errors"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -21,3 +24,137 @@ func TestLoadDiagramReadsPlantUMLPNG(t *testing.T) {
 		t.Fatal("LoadDiagram() returned a diagram without states")
 	}
 }
+
+func TestParseDiagramFileReadsPlantUMLSVG(t *testing.T) {
+	// Setup: the SRC comment holds examples/valid/in.puml.
+	svg := `<?xml version="1.0" encoding="us-ascii" standalone="no"?><svg xmlns="http://www.w3.org/2000/svg"><defs/><g><!--SRC=[SoWkIImgAStDuU82iafI5PIA3PGK4eiLYWtW0eOG0KEuW154m8YBArehLa5NrmwisW32CbImKiZCumBIOAuHa5jScPVCnUMGcfS2iWW0]--></g></svg>`
+
+	// Execute
+	diagram, err := ParseDiagramFile("in.svg", []byte(svg))
+
+	// Assert
+	if err != nil {
+		t.Fatalf("ParseDiagramFile() error = %v", err)
+	}
+	want := MustLoadDiagrams("../examples/valid/in.puml")[0]
+	if diagram.String() != want.String() {
+		t.Errorf("ParseDiagramFile() = %s, want %s", diagram, want)
+	}
+}
+
+func TestReadDiagramRef(t *testing.T) {
+	// Setup: the link and the encoded text hold examples/valid/in.puml.
+	encoded := "SoWkIImgAStDuU82iafI5PIA3PGK4eiLYWtW0eOG0KEuW154m8YBArehLa5NrmwisW32CbImKiZCumBIOAuHa5jScPVCnUMGcfS2iWW0"
+	link := "https://www.plantuml.com/plantuml/png/" + encoded
+	source, err := os.ReadFile("../examples/valid/in.puml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name        string
+		ref         string
+		wantRef     string
+		wantContent string
+		wantErr     bool
+		// wantNotExist is whether the error is that of reading a missing file.
+		wantNotExist bool
+	}{
+		{name: "file with a diagram name", ref: "../examples/valid/multiple_diagrams.puml#receiver", wantRef: "../examples/valid/multiple_diagrams.puml#receiver"},
+		{name: "server link", ref: link, wantRef: "", wantContent: string(source)},
+		{name: "encoded text", ref: encoded, wantRef: "", wantContent: string(source)},
+		{name: "corrupt encoded text", ref: "SyfFKj2rKt3CoKnELR1Io4", wantErr: true, wantNotExist: true},
+		{name: "mistyped file name", ref: "diagram", wantErr: true, wantNotExist: true},
+		{name: "corrupt server link", ref: "https://www.plantuml.com/plantuml/png/SyfFKj2rKt3CoKnELR1Io4", wantErr: true},
+		{name: "missing file", ref: "../examples/valid/missing.puml", wantErr: true, wantNotExist: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Execute
+			ref, content, err := ReadDiagramRef(tt.ref)
+
+			// Assert
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("ReadDiagramRef() error = nil, want an error")
+				}
+				if errors.Is(err, fs.ErrNotExist) != tt.wantNotExist {
+					t.Errorf("ReadDiagramRef() error = %v, want not-exist %t", err, tt.wantNotExist)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ReadDiagramRef() error = %v", err)
+			}
+			if ref != tt.wantRef {
+				t.Errorf("ReadDiagramRef() ref = %q, want %q", ref, tt.wantRef)
+			}
+			if tt.wantContent != "" && string(content) != tt.wantContent {
+				t.Errorf("ReadDiagramRef() content = %q, want %q", content, tt.wantContent)
+			}
+		})
+	}
+}
+
+func TestLoadDiagramsSelectsDiagramByName(t *testing.T) {
+	tests := []struct {
+		name      string
+		ref       string
+		wantName  string
+		wantEdges int
+	}{
+		{name: "first by default", ref: "../examples/valid/multiple_diagrams.puml", wantName: "sender", wantEdges: 1},
+		{name: "by name", ref: "../examples/valid/multiple_diagrams.puml#receiver", wantName: "receiver", wantEdges: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Execute
+			diagrams, err := LoadDiagrams([]string{tt.ref})
+
+			// Assert
+			if err != nil {
+				t.Fatalf("LoadDiagrams() error = %v", err)
+			}
+			if diagrams[0].Name != tt.wantName {
+				t.Errorf("LoadDiagrams() name = %q, want %q", diagrams[0].Name, tt.wantName)
+			}
+			if len(diagrams[0].Edges) != tt.wantEdges {
+				t.Errorf("LoadDiagrams() edges = %#v, want %d edges", diagrams[0].Edges, tt.wantEdges)
+			}
+		})
+	}
+}
+
+func TestLoadDiagramsRejectsUnknownDiagramName(t *testing.T) {
+	// Execute
+	_, err := LoadDiagrams([]string{"../examples/valid/multiple_diagrams.puml#missing"})
+
+	// Assert
+	if err == nil {
+		t.Fatal("LoadDiagrams() error = nil, want unknown name error")
+	}
+	want := `no diagram named "missing" (named diagrams: "sender", "receiver")`
+	if !strings.Contains(err.Error(), want) {
+		t.Errorf("LoadDiagrams() error = %q, want %q", err, want)
+	}
+}
+
+func TestSplitDiagramRefKeepsExistingFileNames(t *testing.T) {
+	// Setup
+	dir := t.TempDir()
+	withHash := filepath.Join(dir, "a#b.puml")
+	if err := os.WriteFile(withHash, []byte("@startuml\n@enduml\n"), 0o644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	// Execute
+	path, name := SplitDiagramRef(withHash)
+
+	// Assert
+	if path != withHash || name != "" {
+		t.Errorf("SplitDiagramRef() = (%q, %q), want (%q, \"\")", path, name, withHash)
+	}
+
+	// Teardown: t.TempDir is removed automatically.
+}
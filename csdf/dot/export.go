@@ -0,0 +1,253 @@
+// Package dot renders Composable State Diagrams in the Graphviz DOT language,
+// optionally overlaying analysis results.
+package dot
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Kuniwak/puml-parallel/csdf"
+)
+
+// ExportOptions selects the overlays drawn over the diagram.
+type ExportOptions struct {
+	// Livelock, when not nil, is a witness of csdf.CheckLivelockFree. Its stem
+	// is drawn in red and its τ-cycle in bold red.
+	Livelock *csdf.Livelock
+	// Tau draws τ-edges dashed and in blue.
+	Tau bool
+	// Clusters groups the states of a composed diagram by the states of its
+	// components (see Components).
+	Clusters bool
+}
+
+// The start and end pseudo states use names that no state ID can take.
+const (
+	startNode = "[*]"
+	endNode   = "[*] end"
+)
+
+// Export renders d as a DOT digraph. States are labeled with their names and
+// variables, and edges with "event [guard] / post" as in PlantUML.
+func Export(d *csdf.Diagram, opts *ExportOptions) string {
+	var sb strings.Builder
+	sb.WriteString("digraph " + quote(d.Name) + " {\n")
+	sb.WriteString("  node [shape=box, style=rounded];\n")
+	sb.WriteString("  " + quote(startNode) + " [shape=point, width=0.2, label=\"\"];\n")
+	if d.EndEdge != nil {
+		sb.WriteString("  " + quote(endNode) + " [shape=doublecircle, width=0.15, label=\"\", style=filled, fillcolor=black];\n")
+	}
+
+	stemEdges, cycleEdges, cycleStates := livelockOverlay(opts.Livelock)
+
+	stateIDs := make([]csdf.StateID, 0, len(d.States))
+	for id := range d.States {
+		stateIDs = append(stateIDs, id)
+	}
+	sort.Slice(stateIDs, func(i, j int) bool { return stateIDs[i] < stateIDs[j] })
+
+	root := &cluster{}
+	for _, id := range stateIDs {
+		c := root
+		if opts.Clusters {
+			components := Components(d.States[id].Name)
+			for _, component := range components[:len(components)-1] {
+				c = c.child(component)
+			}
+		}
+		c.states = append(c.states, id)
+	}
+	var next int
+	root.write(&sb, "  ", &next, func(sb *strings.Builder, indent string, id csdf.StateID) {
+		attrs := []string{"label=" + quote(stateLabel(d.States[id]))}
+		if _, ok := cycleStates[id]; ok {
+			attrs = append(attrs, `color="red"`, "penwidth=2")
+		}
+		sb.WriteString(indent + quote(string(id)) + " [" + strings.Join(attrs, ", ") + "];\n")
+	})
+
+	startAttrs := ""
+	if d.StartEdge.Post != "" && d.StartEdge.Post != csdf.True {
+		startAttrs = " [label=" + quote("/ "+d.StartEdge.Post) + "]"
+	}
+	sb.WriteString("  " + quote(startNode) + " -> " + quote(string(d.StartEdge.Dst)) + startAttrs + ";\n")
+
+	for _, e := range d.Edges {
+		attrs := []string{"label=" + quote(edgeLabel(e))}
+		if opts.Tau && e.Event == csdf.Tau {
+			attrs = append(attrs, `style="dashed"`, `color="blue"`, `fontcolor="blue"`)
+		}
+		key := keyOf(e)
+		if _, ok := cycleEdges[key]; ok {
+			attrs = append(attrs, `color="red"`, `fontcolor="red"`, "penwidth=2")
+		} else if _, ok := stemEdges[key]; ok {
+			attrs = append(attrs, `color="red"`, `fontcolor="red"`)
+		}
+		sb.WriteString("  " + quote(string(e.Src)) + " -> " + quote(string(e.Dst)) + " [" + strings.Join(dedupe(attrs), ", ") + "];\n")
+	}
+
+	if d.EndEdge != nil {
+		endAttrs := ""
+		if d.EndEdge.Guard != "" && d.EndEdge.Guard != csdf.True {
+			endAttrs = " [label=" + quote("["+d.EndEdge.Guard+"]") + "]"
+		}
+		sb.WriteString("  " + quote(string(d.EndEdge.Src)) + " -> " + quote(endNode) + endAttrs + ";\n")
+	}
+
+	sb.WriteString("}\n")
+	return sb.String()
+}
+
+// Components splits a state name built by csdf.ComposeStateNames, such as
+// "(a, b, c)" or the nested "((a, b), c)", into the names of the component
+// states ("a", "b", "c").
+// A name that is not a composed tuple is its own single component.
+func Components(name string) []string {
+	parts, ok := splitTuple(name)
+	if !ok {
+		return []string{name}
+	}
+	var components []string
+	for _, part := range parts {
+		components = append(components, Components(part)...)
+	}
+	return components
+}
+
+// splitTuple splits "(x, y)" at its top-level ", ". It fails when the
+// parentheses around name do not enclose the whole of it.
+func splitTuple(name string) ([]string, bool) {
+	if !strings.HasPrefix(name, "(") || !strings.HasSuffix(name, ")") {
+		return nil, false
+	}
+	inner := name[1 : len(name)-1]
+	var parts []string
+	depth, start := 0, 0
+	for i := 0; i < len(inner); i++ {
+		switch inner[i] {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth < 0 {
+				return nil, false
+			}
+		case ',':
+			if depth == 0 && strings.HasPrefix(inner[i:], ", ") {
+				parts = append(parts, inner[start:i])
+				start = i + 2
+			}
+		}
+	}
+	if depth != 0 || len(parts) == 0 {
+		return nil, false
+	}
+	return append(parts, inner[start:]), true
+}
+
+// cluster is a node of the component tree: the states whose leading
+// components equal the labels on the path from the root.
+type cluster struct {
+	label    string
+	states   []csdf.StateID
+	children []*cluster
+}
+
+func (c *cluster) child(label string) *cluster {
+	for _, child := range c.children {
+		if child.label == label {
+			return child
+		}
+	}
+	child := &cluster{label: label}
+	c.children = append(c.children, child)
+	return child
+}
+
+func (c *cluster) write(sb *strings.Builder, indent string, next *int, writeState func(*strings.Builder, string, csdf.StateID)) {
+	for _, id := range c.states {
+		writeState(sb, indent, id)
+	}
+	for _, child := range c.children {
+		sb.WriteString(fmt.Sprintf("%ssubgraph cluster_%d {\n", indent, *next))
+		*next++
+		sb.WriteString(indent + "  label=" + quote(child.label) + ";\n")
+		child.write(sb, indent+"  ", next, writeState)
+		sb.WriteString(indent + "}\n")
+	}
+}
+
+// edgeKey identifies an edge by its semantics, ignoring presentation.
+type edgeKey struct {
+	src, dst    csdf.StateID
+	event       csdf.Event
+	guard, post string
+}
+
+func keyOf(e csdf.Edge) edgeKey {
+	return edgeKey{src: e.Src, dst: e.Dst, event: e.Event, guard: e.Guard, post: e.Post}
+}
+
+func livelockOverlay(w *csdf.Livelock) (stem, cycle map[edgeKey]struct{}, states map[csdf.StateID]struct{}) {
+	stem = make(map[edgeKey]struct{})
+	cycle = make(map[edgeKey]struct{})
+	states = make(map[csdf.StateID]struct{})
+	if w == nil {
+		return stem, cycle, states
+	}
+	for _, e := range w.Stem {
+		stem[keyOf(e)] = struct{}{}
+	}
+	for _, e := range w.Cycle {
+		cycle[keyOf(e)] = struct{}{}
+		states[e.Src] = struct{}{}
+	}
+	return stem, cycle, states
+}
+
+func stateLabel(s csdf.State) string {
+	lines := []string{s.Name}
+	for _, v := range s.Vars {
+		if v.Type == "" {
+			lines = append(lines, string(v.Name))
+			continue
+		}
+		lines = append(lines, string(v.Name)+" : "+v.Type)
+	}
+	return strings.Join(lines, "\n")
+}
+
+func edgeLabel(e csdf.Edge) string {
+	label := string(e.Event)
+	if e.Guard != "" && e.Guard != csdf.True {
+		label += " [" + e.Guard + "]"
+	}
+	if e.Post != "" && e.Post != csdf.True {
+		label += " / " + e.Post
+	}
+	return label
+}
+
+// dedupe keeps the last of the attributes sharing a name, so overlays drawn
+// later override earlier ones.
+func dedupe(attrs []string) []string {
+	index := make(map[string]int, len(attrs))
+	var out []string
+	for _, attr := range attrs {
+		name, _, _ := strings.Cut(attr, "=")
+		if i, ok := index[name]; ok {
+			out[i] = attr
+			continue
+		}
+		index[name] = len(out)
+		out = append(out, attr)
+	}
+	return out
+}
+
+// quote writes s as a DOT double-quoted string. Line breaks become "\n", which
+// Graphviz renders as centered lines.
+func quote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s) + `"`
+}
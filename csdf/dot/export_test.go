@@ -0,0 +1,162 @@
+package dot
+
+import (
+	"testing"
+
+	"github.com/Kuniwak/puml-parallel/csdf"
+	"github.com/google/go-cmp/cmp"
+)
+
+func mustParse(t *testing.T, src string) *csdf.Diagram {
+	t.Helper()
+	d, err := csdf.ParseDiagram([]byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+func TestExportWithoutOverlays(t *testing.T) {
+	// Setup
+	d := mustParse(t, `@startuml
+state "Idle" as s0
+s0: count ; int
+state "Say \"hi\"" as s1
+[*] --> s0 : count = 0
+s0 --> s1 : greet ; count < 3 ; count' = count + 1
+s1 --> s0 : tau
+s1 --> [*]
+@enduml
+`)
+	want := `digraph "" {
+  node [shape=box, style=rounded];
+  "[*]" [shape=point, width=0.2, label=""];
+  "[*] end" [shape=doublecircle, width=0.15, label="", style=filled, fillcolor=black];
+  "s0" [label="Idle\ncount : int"];
+  "s1" [label="Say \"hi\""];
+  "[*]" -> "s0" [label="/ count = 0"];
+  "s0" -> "s1" [label="greet [count < 3] / count' = count + 1"];
+  "s1" -> "s0" [label="tau"];
+  "s1" -> "[*] end";
+}
+`
+
+	// Execute
+	got := Export(d, &ExportOptions{})
+
+	// Assert
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Error(diff)
+	}
+
+	// Teardown: no resources to release.
+}
+
+func TestExportDrawsLivelockAndTauOverlays(t *testing.T) {
+	// Setup: s0 --a--> s1 is the stem of the τ-cycle s1 -> s2 -> s1.
+	d := mustParse(t, `@startuml
+state "s0" as s0
+state "s1" as s1
+state "s2" as s2
+[*] --> s0
+s0 --> s1 : a
+s1 --> s2 : tau
+s2 --> s1 : tau
+s2 --> s0 : b
+@enduml
+`)
+	witness, ok := csdf.CheckLivelockFree(d)
+	if ok {
+		t.Fatal("want a livelock witness")
+	}
+	want := `digraph "" {
+  node [shape=box, style=rounded];
+  "[*]" [shape=point, width=0.2, label=""];
+  "s0" [label="s0"];
+  "s1" [label="s1", color="red", penwidth=2];
+  "s2" [label="s2", color="red", penwidth=2];
+  "[*]" -> "s0";
+  "s0" -> "s1" [label="a", color="red", fontcolor="red"];
+  "s1" -> "s2" [label="tau", style="dashed", color="red", fontcolor="red", penwidth=2];
+  "s2" -> "s1" [label="tau", style="dashed", color="red", fontcolor="red", penwidth=2];
+  "s2" -> "s0" [label="b"];
+}
+`
+
+	// Execute
+	got := Export(d, &ExportOptions{Livelock: witness, Tau: true})
+
+	// Assert
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Error(diff)
+	}
+
+	// Teardown: no resources to release.
+}
+
+func TestExportClustersComposedStates(t *testing.T) {
+	// Setup
+	diagrams := csdf.MustLoadDiagrams("../../examples/valid/in.puml", "../../examples/valid/out.puml")
+	d, err := csdf.ComposeParallel(diagrams, []csdf.Event{"sync"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `digraph "" {
+  node [shape=box, style=rounded];
+  "[*]" [shape=point, width=0.2, label=""];
+  subgraph cluster_0 {
+    label="s0";
+    "s0_s0" [label="(s0, s0)"];
+  }
+  subgraph cluster_1 {
+    label="s1";
+    "s1_s0" [label="(s1, s0)"];
+  }
+  subgraph cluster_2 {
+    label="s2";
+    "s2_s1" [label="(s2, s1)"];
+    "s2_s2" [label="(s2, s2)"];
+  }
+  "[*]" -> "s0_s0";
+  "s0_s0" -> "s1_s0" [label="in"];
+  "s1_s0" -> "s2_s1" [label="sync"];
+  "s2_s1" -> "s2_s2" [label="out"];
+}
+`
+
+	// Execute
+	got := Export(d, &ExportOptions{Clusters: true})
+
+	// Assert
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Error(diff)
+	}
+
+	// Teardown: no resources to release.
+}
+
+func TestComponents(t *testing.T) {
+	testCases := map[string][]string{
+		"s0":                     {"s0"},
+		"(a, b)":                 {"a", "b"},
+		"((a, b), c)":            {"a", "b", "c"},
+		"(f(x, y), b)":           {"f(x, y)", "b"},
+		"(a) [] (b)":             {"(a) [] (b)"},
+		"(a,b)":                  {"(a,b)"},
+		"((tea -> VM), Cust)":    {"(tea -> VM)", "Cust"},
+		"(unbalanced (, paren)":  {"(unbalanced (, paren)"},
+		"(closes early), (late)": {"(closes early), (late)"},
+	}
+
+	for name, want := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Execute
+			got := Components(name)
+
+			// Assert
+			if diff := cmp.Diff(want, got); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,312 @@
+package csdf
+
+import (
+	"errors"
+	"fmt"
+	"hash/maphash"
+	"runtime"
+	"sync"
+)
+
+// Explorer explores the state spaces of composition (ComposeParallel,
+// Compose), normalization (Normalize), reachability (CheckLivelockFree) and the
+// checks on composed diagrams (CheckComposedDeadlockFree,
+// CheckComposedLivelockFree) on several goroutines. The results do not depend
+// on the number of goroutines. The zero value uses one goroutine per CPU, as
+// do the functions of the same names.
+type Explorer struct {
+	// Workers is the number of goroutines exploring states. Values below 1
+	// mean runtime.GOMAXPROCS(0).
+	Workers int
+	// Store is where the states found are kept; "" means MemoryStore.
+	Store StoreKind
+	// StoreDir is the directory of the temporary file of DiskStore; "" means
+	// os.TempDir().
+	StoreDir string
+	// NoReduction turns off the partial-order reduction of the checks on
+	// composed diagrams (CheckComposedDeadlockFree, CheckComposedLivelockFree).
+	// The verdicts do not depend on it.
+	NoReduction bool
+}
+
+func (x *Explorer) workers() int {
+	if x == nil || x.Workers < 1 {
+		return runtime.GOMAXPROCS(0)
+	}
+	return x.Workers
+}
+
+// bfsEdge is an edge found by bfs from the state numbered src to the state
+// numbered dst.
+type bfsEdge[L any] struct {
+	src, dst int32
+	label    L
+}
+
+// expandFunc calls emit for every successor of the state identified by key, in
+// a fixed order, with the key of the successor and the label of the edge to
+// it. emit copies next, so it may be reused. visited reports whether the state
+// with key next was numbered no later than the expanded state, which does not
+// depend on the number of workers.
+type expandFunc[L any] func(key []byte, visited func(next []byte) bool, emit func(next []byte, label L))
+
+// bfs explores breadth first the states reachable from the state identified by
+// start, and adds them to store, which must be empty. States are identified by
+// keys, byte strings that are equal exactly when the states are. newExpand is
+// called once per goroutine, so that the expand functions may keep scratch
+// space; they must not change shared data.
+//
+// The states are numbered, and the edges listed, in the order a sequential
+// breadth-first search finds them: start is numbered 0, and the edges are those
+// of state 0, then state 1, and so on, each in the order expand emits them. On
+// several workers, bfs expands each level of the search in parallel, with the
+// states of the level shared out by work stealing, and finds the first
+// occurrences of the new states in a set split into shards by hash, one
+// goroutine per shard. New states are numbered, and added to store, in the
+// order of their first occurrence in the level, so the result is the same as
+// on one worker.
+func bfs[L any](store stateStore, start []byte, newExpand func() expandFunc[L], workers int) ([]bfsEdge[L], error) {
+	if err := store.add(start); err != nil {
+		return nil, fmt.Errorf("csdf.bfs: %w", err)
+	}
+	if workers <= 1 {
+		edges, err := bfsSequential(store, newExpand())
+		if err != nil {
+			return nil, fmt.Errorf("csdf.bfs: %w", err)
+		}
+		return edges, nil
+	}
+
+	expanders := make([]expandFunc[L], workers)
+	for i := range expanders {
+		expanders[i] = newExpand()
+	}
+	seed := maphash.MakeSeed()
+	shards := 1
+	for shards < 4*workers {
+		shards *= 2
+	}
+
+	// successor is an edge out of the level, to the state numbered dst, or to
+	// a state that was not in store when the level was expanded (dst < 0).
+	type successor struct {
+		dst   int32
+		shard int32
+		key   string
+		label L
+	}
+	var edges []bfsEdge[L]
+	for lo, hi := 0, 1; lo < hi; lo, hi = hi, store.len() {
+		level := make([][]successor, hi-lo)
+		errs := make([]error, workers)
+		parallelFor(hi-lo, workers, func(worker, j int) {
+			if errs[worker] != nil {
+				return
+			}
+			key, err := store.key(nil, int32(lo+j))
+			if err != nil {
+				errs[worker] = err
+				return
+			}
+			var succs []successor
+			visited := func(next []byte) bool {
+				if errs[worker] != nil {
+					return false
+				}
+				dst, ok, err := store.find(next)
+				if err != nil {
+					errs[worker] = err
+					return false
+				}
+				return ok && dst <= int32(lo+j)
+			}
+			expanders[worker](key, visited, func(next []byte, label L) {
+				if errs[worker] != nil {
+					return
+				}
+				// store is only read until the level is expanded.
+				dst, ok, err := store.find(next)
+				if err != nil {
+					errs[worker] = err
+					return
+				}
+				if ok {
+					succs = append(succs, successor{dst: dst, label: label})
+					return
+				}
+				shard := maphash.Bytes(seed, next) & uint64(shards-1)
+				succs = append(succs, successor{dst: -1, shard: int32(shard), key: string(next), label: label})
+			})
+			level[j] = succs
+		})
+		if err := errors.Join(errs...); err != nil {
+			return nil, fmt.Errorf("csdf.bfs: %w", err)
+		}
+
+		// Number the new successors in order of first occurrence: each shard
+		// finds the first occurrences of its keys, then the new states are
+		// numbered and added to store in order.
+		var unknown []*successor
+		byShard := make([][]int32, shards)
+		for j := range level {
+			for t := range level[j] {
+				if s := &level[j][t]; s.dst < 0 {
+					byShard[s.shard] = append(byShard[s.shard], int32(len(unknown)))
+					unknown = append(unknown, s)
+				}
+			}
+		}
+		first := make([]int32, len(unknown))
+		parallelFor(shards, workers, func(_, shard int) {
+			seen := make(map[string]int32, len(byShard[shard]))
+			for _, u := range byShard[shard] {
+				f, ok := seen[unknown[u].key]
+				if !ok {
+					f = u
+					seen[unknown[u].key] = u
+				}
+				first[u] = f
+			}
+		})
+		for u, s := range unknown {
+			if first[u] != int32(u) {
+				s.dst = unknown[first[u]].dst
+				continue
+			}
+			s.dst = int32(store.len())
+			if err := store.add([]byte(s.key)); err != nil {
+				return nil, fmt.Errorf("csdf.bfs: %w", err)
+			}
+		}
+
+		for j, succs := range level {
+			for _, s := range succs {
+				edges = append(edges, bfsEdge[L]{src: int32(lo + j), dst: s.dst, label: s.label})
+			}
+		}
+	}
+	return edges, nil
+}
+
+func bfsSequential[L any](store stateStore, expand expandFunc[L]) ([]bfsEdge[L], error) {
+	var edges []bfsEdge[L]
+	var key []byte
+	var err error
+	for k := 0; k < store.len(); k++ {
+		key, err = store.key(key[:0], int32(k))
+		if err != nil {
+			return nil, err
+		}
+		visited := func(next []byte) bool {
+			if err != nil {
+				return false
+			}
+			dst, ok, findErr := store.find(next)
+			if findErr != nil {
+				err = findErr
+				return false
+			}
+			return ok && dst <= int32(k)
+		}
+		expand(key, visited, func(next []byte, label L) {
+			if err != nil {
+				return
+			}
+			dst, ok, findErr := store.find(next)
+			if findErr != nil {
+				err = findErr
+				return
+			}
+			if !ok {
+				dst = int32(store.len())
+				if err = store.add(next); err != nil {
+					return
+				}
+			}
+			edges = append(edges, bfsEdge[L]{src: int32(k), dst: dst, label: label})
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return edges, nil
+}
+
+// parallelFor calls fn(worker, i) for every i in [0, n) on up to workers
+// goroutines, worker being the index of the goroutine. Each goroutine starts
+// with an equal range of indexes and takes chunks from its front; one that
+// runs out steals the back half of the range of another.
+func parallelFor(n, workers int, fn func(worker, i int)) {
+	if workers > n {
+		workers = n
+	}
+	if workers <= 1 {
+		for i := 0; i < n; i++ {
+			fn(0, i)
+		}
+		return
+	}
+
+	chunk := 1 + n/(32*workers)
+	ranges := make([]indexRange, workers)
+	for w := range ranges {
+		ranges[w].lo, ranges[w].hi = n*w/workers, n*(w+1)/workers
+	}
+	var wg sync.WaitGroup
+	for w := range ranges {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			own := &ranges[w]
+			for {
+				if lo, hi := own.take(chunk); lo < hi {
+					for i := lo; i < hi; i++ {
+						fn(w, i)
+					}
+					continue
+				}
+				stolen := false
+				for v := 1; v < workers && !stolen; v++ {
+					if lo, hi := ranges[(w+v)%workers].stealHalf(); lo < hi {
+						own.put(lo, hi)
+						stolen = true
+					}
+				}
+				if !stolen {
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// indexRange is the range of indexes [lo, hi) a goroutine of parallelFor has
+// yet to process.
+type indexRange struct {
+	mu     sync.Mutex
+	lo, hi int
+}
+
+func (r *indexRange) take(chunk int) (lo, hi int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	lo, hi = r.lo, min(r.lo+chunk, r.hi)
+	r.lo = hi
+	return lo, hi
+}
+
+func (r *indexRange) stealHalf() (lo, hi int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	mid := r.hi - (r.hi-r.lo)/2
+	lo, hi = mid, r.hi
+	r.hi = mid
+	return lo, hi
+}
+
+func (r *indexRange) put(lo, hi int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.lo, r.hi = lo, hi
+}
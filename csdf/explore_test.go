@@ -0,0 +1,114 @@
+package csdf
+
+import (
+	"fmt"
+	"sync/atomic"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParallelForVisitsEveryIndexOnce(t *testing.T) {
+	for _, n := range []int{0, 1, 7, 1000} {
+		for _, workers := range []int{1, 2, 3, 8} {
+			t.Run(fmt.Sprintf("n=%d,workers=%d", n, workers), func(t *testing.T) {
+				// Setup
+				visits := make([]atomic.Int32, n)
+
+				// Execute
+				parallelFor(n, workers, func(worker, i int) {
+					if worker < 0 || worker >= workers {
+						t.Errorf("worker = %d, want in [0, %d)", worker, workers)
+					}
+					visits[i].Add(1)
+				})
+
+				// Assert
+				for i := range visits {
+					if got := visits[i].Load(); got != 1 {
+						t.Errorf("index %d visited %d times, want 1", i, got)
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestExplorerResultsDoNotDependOnWorkersOrStore(t *testing.T) {
+	// Setup
+	components := []*Diagram{
+		generateDiagram(30, 3, []Event{"a", "sync", "b"}),
+		generateDiagram(20, 2, []Event{"c", "sync"}),
+		generateDiagram(10, 2, []Event{"sync", "d"}),
+	}
+	nondeterministic := generateDiagram(60, 3, []Event{"a", Tau, "b", "a"})
+	sequential := &Explorer{Workers: 1}
+	wantComposite, err := sequential.ComposeParallel(components, []Event{"sync"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantNormal, err := sequential.Normalize(nondeterministic)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantWitness, wantOK, err := sequential.CheckLivelockFree(nondeterministic)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, x := range []*Explorer{
+		{Workers: 2},
+		{Workers: 3},
+		{Workers: 8},
+		{Workers: 1, Store: CompactStore},
+		{Workers: 3, Store: CompactStore},
+		{Workers: 1, Store: DiskStore, StoreDir: t.TempDir()},
+		{Workers: 3, Store: DiskStore, StoreDir: t.TempDir()},
+	} {
+		t.Run(fmt.Sprintf("workers=%d,store=%s", x.Workers, x.Store), func(t *testing.T) {
+			// Execute
+			composite, err := x.ComposeParallel(components, []Event{"sync"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			normal, err := x.Normalize(nondeterministic)
+			if err != nil {
+				t.Fatal(err)
+			}
+			witness, ok, err := x.CheckLivelockFree(nondeterministic)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// Assert
+			if diff := cmp.Diff(wantComposite, composite); diff != "" {
+				t.Errorf("ComposeParallel():\n%s", diff)
+			}
+			if diff := cmp.Diff(wantNormal, normal); diff != "" {
+				t.Errorf("Normalize():\n%s", diff)
+			}
+			if ok != wantOK {
+				t.Errorf("CheckLivelockFree() ok = %v, want %v", ok, wantOK)
+			}
+			if diff := cmp.Diff(wantWitness, witness); diff != "" {
+				t.Errorf("CheckLivelockFree():\n%s", diff)
+			}
+		})
+	}
+}
+
+func BenchmarkExplorerComposeParallel(b *testing.B) {
+	left := generateDiagram(200, 4, []Event{"a", "b", "sync"})
+	right := generateDiagram(200, 4, []Event{"c", "d", "sync"})
+	for _, workers := range []int{1, 2, 4, 8} {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			x := &Explorer{Workers: workers}
+			b.ReportAllocs()
+			for b.Loop() {
+				if _, err := x.ComposeParallel([]*Diagram{left, right}, []Event{"sync"}); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
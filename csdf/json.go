@@ -0,0 +1,265 @@
+package csdf
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// JSONFormatVersion is the format_version of the JSON written by csdfparse. It
+// changes when the format changes in a way older readers would misread.
+const JSONFormatVersion = "1"
+
+// JSONDocument is the JSON written by csdfparse: a diagram with the version of
+// its format.
+type JSONDocument struct {
+	FormatVersion string `json:"format_version"`
+	*Diagram
+}
+
+// NewJSONDocument returns the document of d in the current format.
+func NewJSONDocument(d *Diagram) *JSONDocument {
+	return &JSONDocument{FormatVersion: JSONFormatVersion, Diagram: d}
+}
+
+// CompositionDocument is the JSON written by csdfparallel -provenance: a
+// composition with the version of its format, which is JSONFormatVersion.
+type CompositionDocument struct {
+	FormatVersion string `json:"format_version"`
+	*Composition
+}
+
+// NewCompositionDocument returns the document of c in the current format.
+func NewCompositionDocument(c *Composition) *CompositionDocument {
+	return &CompositionDocument{FormatVersion: JSONFormatVersion, Composition: c}
+}
+
+// ParseJSON reads a diagram in the JSON form written by csdfparse (a
+// JSONDocument) and validates it:
+//
+//   - unknown fields and trailing data are errors, "states" and "start_edge"
+//     are required, and a "format_version" other than JSONFormatVersion is
+//     rejected; documents without it are read as the current version,
+//   - every state is keyed by its ID, and IDs and variable names consist of ID
+//     characters (docs/SYNTAX.md, "Identifiers"),
+//   - edges, the start edge and the end edge refer to declared states, and
+//     every edge has an event,
+//   - texts must fit on their line of Diagram.String; events and guards cannot
+//     contain ';'.
+//
+// Texts are kept as written, so an empty guard or post-condition is trivial
+// as in "e ; ;". An omitted state ID is its key, and omitted variables and
+// edges are none.
+func ParseJSON(input string) (*Diagram, error) {
+	decoder := json.NewDecoder(strings.NewReader(input))
+	decoder.DisallowUnknownFields()
+	var raw struct {
+		Diagram
+		FormatVersion *string            `json:"format_version"`
+		States        *map[StateID]State `json:"states"`
+		StartEdge     *StartEdge         `json:"start_edge"`
+	}
+	if err := decoder.Decode(&raw); err != nil {
+		return nil, fmt.Errorf("csdf.ParseJSON: %w", describeJSONError(input, err))
+	}
+	if _, err := decoder.Token(); !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("csdf.ParseJSON: unexpected data after the diagram at offset %d", decoder.InputOffset())
+	}
+	if raw.FormatVersion != nil && *raw.FormatVersion != JSONFormatVersion {
+		return nil, fmt.Errorf("csdf.ParseJSON: unsupported format_version %q (this version reads %q)", *raw.FormatVersion, JSONFormatVersion)
+	}
+	if raw.States == nil {
+		return nil, fmt.Errorf("csdf.ParseJSON: missing \"states\"")
+	}
+	if raw.StartEdge == nil {
+		return nil, fmt.Errorf("csdf.ParseJSON: missing \"start_edge\"")
+	}
+	d := raw.Diagram
+	d.States = *raw.States
+	d.StartEdge = *raw.StartEdge
+	if d.Edges == nil {
+		d.Edges = []Edge{}
+	}
+	if err := validateJSONDiagram(&d); err != nil {
+		return nil, fmt.Errorf("csdf.ParseJSON: %w", err)
+	}
+	return &d, nil
+}
+
+// describeJSONError adds the line and the column of a syntax or type error.
+func describeJSONError(input string, err error) error {
+	var offset int64
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &syntaxErr):
+		offset = syntaxErr.Offset
+	case errors.As(err, &typeErr):
+		if typeErr.Field != "" {
+			return fmt.Errorf("%q must be %s, got %s at %s", typeErr.Field, typeErr.Type, typeErr.Value, jsonPosition(input, typeErr.Offset))
+		}
+		offset = typeErr.Offset
+	default:
+		return err
+	}
+	return fmt.Errorf("%w at %s", err, jsonPosition(input, offset))
+}
+
+// jsonPosition is the line and column of the last byte the decoder read
+// before the error at offset.
+func jsonPosition(input string, offset int64) string {
+	offset = min(max(offset-1, 0), int64(len(input)))
+	before := input[:offset]
+	line := strings.Count(before, "\n") + 1
+	col := len(before) - strings.LastIndexByte(before, '\n')
+	return fmt.Sprintf("line %d, col %d", line, col)
+}
+
+func validateJSONDiagram(d *Diagram) error {
+	if strings.ContainsAny(d.Name, "\r\n") {
+		return fmt.Errorf("the diagram name %q contains a line break", d.Name)
+	}
+	if err := validateJSONAnnotations("the diagram", d.Annotations); err != nil {
+		return err
+	}
+
+	keys := make([]StateID, 0, len(d.States))
+	for key := range d.States {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+	for _, key := range keys {
+		state := d.States[key]
+		where := fmt.Sprintf("state %q", key)
+		if state.ID == "" {
+			state.ID = key
+		}
+		if state.ID != key {
+			return fmt.Errorf("%s has the id %q", where, state.ID)
+		}
+		if !isJSONIdentifier(string(state.ID)) {
+			return fmt.Errorf("%s: the id is not an identifier", where)
+		}
+		if strings.ContainsAny(state.Name, "\"\r\n") {
+			return fmt.Errorf("%s: the name %q contains a quote or a line break", where, state.Name)
+		}
+		if state.Vars == nil {
+			state.Vars = []StateVar{}
+		}
+		seen := make(map[Var]struct{}, len(state.Vars))
+		for _, v := range state.Vars {
+			if !isJSONIdentifier(string(v.Name)) {
+				return fmt.Errorf("%s: the variable name %q is not an identifier", where, v.Name)
+			}
+			if _, ok := seen[v.Name]; ok {
+				return fmt.Errorf("%s: duplicate variable %q", where, v.Name)
+			}
+			seen[v.Name] = struct{}{}
+			if strings.ContainsAny(v.Type, ";\r\n") {
+				return fmt.Errorf("%s: the type %q of %q contains ';' or a line break", where, v.Type, v.Name)
+			}
+		}
+		for _, text := range append([]string{state.Color, state.Stereotype}, state.Descriptions...) {
+			if strings.ContainsAny(text, "\r\n") {
+				return fmt.Errorf("%s: %q contains a line break", where, text)
+			}
+		}
+		for _, description := range state.Descriptions {
+			if readsAsVarDecl(description) {
+				return fmt.Errorf("%s: the description %q reads as a variable declaration", where, description)
+			}
+		}
+		if err := validateJSONAnnotations(where, state.Annotations); err != nil {
+			return err
+		}
+		d.States[key] = state
+	}
+
+	if err := checkJSONState(d, "the start edge", d.StartEdge.Dst); err != nil {
+		return err
+	}
+	if strings.ContainsAny(d.StartEdge.Post, "\r\n") {
+		return fmt.Errorf("the start edge: the post-condition %q contains a line break", d.StartEdge.Post)
+	}
+
+	for i := range d.Edges {
+		e := &d.Edges[i]
+		where := fmt.Sprintf("edge %d", i)
+		if err := checkJSONState(d, where, e.Src); err != nil {
+			return err
+		}
+		if err := checkJSONState(d, where, e.Dst); err != nil {
+			return err
+		}
+		if strings.TrimSpace(string(e.Event)) == "" {
+			return fmt.Errorf("%s (%s -> %s): missing event", where, e.Src, e.Dst)
+		}
+		for _, text := range []string{string(e.Event), e.Guard} {
+			if strings.ContainsAny(text, ";\r\n") {
+				return fmt.Errorf("%s (%s -> %s): %q contains ';' or a line break", where, e.Src, e.Dst, text)
+			}
+		}
+		if strings.ContainsAny(e.Post, "\r\n") {
+			return fmt.Errorf("%s (%s -> %s): the post-condition %q contains a line break", where, e.Src, e.Dst, e.Post)
+		}
+		if strings.ContainsAny(e.Style, "]\r\n") {
+			return fmt.Errorf("%s (%s -> %s): the style %q contains ']' or a line break", where, e.Src, e.Dst, e.Style)
+		}
+		if err := validateJSONAnnotations(where, e.Annotations); err != nil {
+			return err
+		}
+	}
+
+	if d.EndEdge != nil {
+		if err := checkJSONState(d, "the end edge", d.EndEdge.Src); err != nil {
+			return err
+		}
+		if strings.ContainsAny(d.EndEdge.Guard, "\r\n") {
+			return fmt.Errorf("the end edge: the guard %q contains a line break", d.EndEdge.Guard)
+		}
+	}
+	return nil
+}
+
+func checkJSONState(d *Diagram, where string, id StateID) error {
+	if id == "" {
+		return fmt.Errorf("%s: missing state", where)
+	}
+	if _, ok := d.States[id]; !ok {
+		return fmt.Errorf("%s: undeclared state %q", where, id)
+	}
+	return nil
+}
+
+func validateJSONAnnotations(where string, annotations Annotations) error {
+	for key, value := range annotations {
+		if key == "" || strings.IndexFunc(key, func(r rune) bool { return !isAnnotationKeyRune(r) }) >= 0 {
+			return fmt.Errorf("%s: the annotation key %q is not made of ID characters and '.'", where, key)
+		}
+		if strings.ContainsAny(value, "\r\n") || strings.Contains(value, "'/") {
+			return fmt.Errorf("%s: the annotation value %q contains a line break or \"'/\"", where, value)
+		}
+	}
+	return nil
+}
+
+func isJSONIdentifier(s string) bool {
+	return s != "" && strings.IndexFunc(s, func(r rune) bool { return !isIDRune(r) }) < 0
+}
+
+// isJSONSource reports whether a source read from path is a JSON diagram: by
+// the .json extension, or, for other paths and standard input, by content
+// starting with '{'.
+func isJSONSource(path, text string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return true
+	case ".puml", ".plantuml", ".png", ".svg", ".csp", ".cspm", ".aut", ".scxml", ".mmd", ".mermaid", ".md", ".markdown":
+		return false
+	}
+	return strings.HasPrefix(strings.TrimSpace(text), "{")
+}
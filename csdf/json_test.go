@@ -0,0 +1,167 @@
+package csdf
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseJSONRoundTripsCsdfparseOutput(t *testing.T) {
+	paths := []string{
+		"../examples/valid/vending_machine.puml",
+		"../examples/valid/test_input2.puml",
+		"../examples/valid/tea_machine.csp",
+	}
+
+	for _, path := range paths {
+		t.Run(path, func(t *testing.T) {
+			// Setup
+			want := MustLoadDiagrams(path)[0]
+			bs, err := json.Marshal(want)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// Execute
+			got, err := ParseJSON(string(bs))
+
+			// Assert
+			if err != nil {
+				t.Fatalf("ParseJSON() error = %v", err)
+			}
+			if diff := cmp.Diff(want, got); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestParseJSONFillsOmittedFields(t *testing.T) {
+	// Setup
+	input := `{"states": {"a": {"name": "A"}}, "start_edge": {"dst": "a", "post": "true"}}`
+	want := &Diagram{
+		States:    map[StateID]State{"a": {ID: "a", Name: "A", Vars: []StateVar{}}},
+		StartEdge: StartEdge{Dst: "a", Post: True},
+		Edges:     []Edge{},
+	}
+
+	// Execute
+	got, err := ParseJSON(input)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("ParseJSON() error = %v", err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Error(diff)
+	}
+
+	// Teardown: no resources to release.
+}
+
+func TestParseJSONRejects(t *testing.T) {
+	testCases := map[string]struct {
+		input string
+		want  string
+	}{
+		"syntax error": {
+			input: "{\n  \"states\": {,\n}",
+			want:  "at line 2, col 14",
+		},
+		"wrong type": {
+			input: `{"states": {}, "start_edge": {"dst": 1}}`,
+			want:  `"start_edge.dst" must be csdf.StateID, got number`,
+		},
+		"unknown field": {
+			input: `{"states": {}, "start_edge": {"dst": "a"}, "edge": []}`,
+			want:  `unknown field "edge"`,
+		},
+		"trailing data": {
+			input: `{"states": {"a": {}}, "start_edge": {"dst": "a"}} {}`,
+			want:  "unexpected data after the diagram",
+		},
+		"unknown format version": {
+			input: `{"format_version": "2", "states": {"a": {}}, "start_edge": {"dst": "a"}}`,
+			want:  `unsupported format_version "2" (this version reads "1")`,
+		},
+		"missing states": {
+			input: `{"start_edge": {"dst": "a"}}`,
+			want:  `missing "states"`,
+		},
+		"missing start edge": {
+			input: `{"states": {"a": {}}}`,
+			want:  `missing "start_edge"`,
+		},
+		"key and id differ": {
+			input: `{"states": {"a": {"id": "b"}}, "start_edge": {"dst": "a"}}`,
+			want:  `state "a" has the id "b"`,
+		},
+		"not an identifier": {
+			input: `{"states": {"a b": {}}, "start_edge": {"dst": "a b"}}`,
+			want:  `state "a b": the id is not an identifier`,
+		},
+		"undeclared start state": {
+			input: `{"states": {"a": {}}, "start_edge": {"dst": "b"}}`,
+			want:  `the start edge: undeclared state "b"`,
+		},
+		"undeclared edge state": {
+			input: `{"states": {"a": {}}, "start_edge": {"dst": "a"}, "edges": [{"src": "a", "dst": "c", "event": "e"}]}`,
+			want:  `edge 0: undeclared state "c"`,
+		},
+		"missing event": {
+			input: `{"states": {"a": {}}, "start_edge": {"dst": "a"}, "edges": [{"src": "a", "dst": "a"}]}`,
+			want:  "edge 0 (a -> a): missing event",
+		},
+		"description like a variable": {
+			input: `{"states": {"a": {"descriptions": ["foo ; bar"]}}, "start_edge": {"dst": "a"}}`,
+			want:  `state "a": the description "foo ; bar" reads as a variable declaration`,
+		},
+		"semicolon in guard": {
+			input: `{"states": {"a": {}}, "start_edge": {"dst": "a"}, "edges": [{"src": "a", "dst": "a", "event": "e", "guard": "x; y"}]}`,
+			want:  `"x; y" contains ';' or a line break`,
+		},
+		"duplicate variable": {
+			input: `{"states": {"a": {"vars": [{"name": "x"}, {"name": "x"}]}}, "start_edge": {"dst": "a"}}`,
+			want:  `state "a": duplicate variable "x"`,
+		},
+		"undeclared end state": {
+			input: `{"states": {"a": {}}, "start_edge": {"dst": "a"}, "end_edge": {"src": "z"}}`,
+			want:  `the end edge: undeclared state "z"`,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Execute
+			_, err := ParseJSON(tc.input)
+
+			// Assert
+			if err == nil {
+				t.Fatal("ParseJSON() error = nil, want error")
+			}
+			if !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("ParseJSON() error = %q, want it to contain %q", err.Error(), tc.want)
+			}
+		})
+	}
+}
+
+func TestParseDiagramDetectsJSONByContent(t *testing.T) {
+	// Setup
+	input := []byte(`  {"states": {"a": {"id": "a", "name": "a", "vars": []}}, "start_edge": {"dst": "a", "post": "true"}, "edges": [], "end_edge": null}`)
+
+	// Execute
+	d, err := ParseDiagram(input)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("ParseDiagram() error = %v", err)
+	}
+	if d.StartEdge.Dst != "a" {
+		t.Errorf("ParseDiagram() start = %q, want a", d.StartEdge.Dst)
+	}
+
+	// Teardown: no resources to release.
+}
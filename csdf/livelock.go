@@ -22,13 +22,24 @@ type Livelock struct {
 // The analysis is purely structural over event labels: natural-language Guard and
 // Post predicates are not evaluated. A diagram with no τ edges is livelock free.
 func CheckLivelockFree(d *Diagram) (witness *Livelock, ok bool) {
+	// The default store keeps the states in memory, so it does not fail.
+	witness, ok, _ = (&Explorer{}).CheckLivelockFree(d)
+	return witness, ok
+}
+
+// CheckLivelockFree is the function CheckLivelockFree run on the workers of x.
+// It fails only when the store of x does.
+func (x *Explorer) CheckLivelockFree(d *Diagram) (witness *Livelock, ok bool, err error) {
 	// Index all outgoing edges by source state.
 	out := make(map[StateID][]Edge)
 	for _, e := range d.Edges {
 		out[e.Src] = append(out[e.Src], e)
 	}
 
-	reachable := reachableStates(d.StartEdge.Dst, out)
+	reachable, err := x.reachableStates(d.StartEdge.Dst, out)
+	if err != nil {
+		return nil, false, fmt.Errorf("csdf.Explorer.CheckLivelockFree: %w", err)
+	}
 
 	// τ-only successor index restricted to reachable sources, deterministically
 	// ordered so the witness is reproducible.
@@ -48,10 +59,37 @@ func CheckLivelockFree(d *Diagram) (witness *Livelock, ok bool) {
 
 	cycle := findTauCycle(tauOut)
 	if cycle == nil {
-		return nil, true
+		return nil, true, nil
 	}
 	stem := stemTo(d.StartEdge.Dst, cycle[0].Src, out)
-	return &Livelock{Stem: stem, Cycle: cycle}, false
+	return &Livelock{Stem: stem, Cycle: cycle}, false, nil
+}
+
+// CheckComposedLivelockFree is CheckLivelockFree on ComposeParallel(diagrams,
+// syncEvents). It builds the reachable product under a partial-order
+// reduction, which may leave out many of its states, and then checks it; it
+// does not stop at the first τ-cycle.
+func CheckComposedLivelockFree(diagrams []*Diagram, syncEvents []Event) (witness *Livelock, ok bool, err error) {
+	return (&Explorer{}).CheckComposedLivelockFree(diagrams, syncEvents)
+}
+
+// CheckComposedLivelockFree is the function CheckComposedLivelockFree run on
+// the workers of x. Unless x.NoReduction, it explores the composition under a
+// partial-order reduction that keeps every reachable τ-cycle, so the witness
+// may differ from the one of the whole composition, but the verdict does not.
+func (x *Explorer) CheckComposedLivelockFree(diagrams []*Diagram, syncEvents []Event) (witness *Livelock, ok bool, err error) {
+	if len(diagrams) == 1 {
+		return x.CheckLivelockFree(diagrams[0])
+	}
+	p, err := x.explore(diagrams, syncEvents, x.reduction(preserveDivergences), true)
+	if err != nil {
+		return nil, false, fmt.Errorf("csdf.Explorer.CheckComposedLivelockFree: %w", err)
+	}
+	witness, ok, err = x.CheckLivelockFree(p.diagram())
+	if err != nil {
+		return nil, false, fmt.Errorf("csdf.Explorer.CheckComposedLivelockFree: %w", err)
+	}
+	return witness, ok, nil
 }
 
 // RenderLivelock renders a witness as human-readable lines, one transition per
@@ -75,20 +113,32 @@ func renderEdge(e Edge) string {
 }
 
 // reachableStates returns every state reachable from start over all edges.
-func reachableStates(start StateID, out map[StateID][]Edge) map[StateID]struct{} {
-	reachable := map[StateID]struct{}{start: {}}
-	queue := []StateID{start}
-	for len(queue) > 0 {
-		s := queue[0]
-		queue = queue[1:]
-		for _, e := range out[s] {
-			if _, ok := reachable[e.Dst]; !ok {
-				reachable[e.Dst] = struct{}{}
-				queue = append(queue, e.Dst)
+func (x *Explorer) reachableStates(start StateID, out map[StateID][]Edge) (_ map[StateID]struct{}, err error) {
+	store, err := x.newStore()
+	if err != nil {
+		return nil, err
+	}
+	defer closeStore(store, &err)
+	if _, err := bfs(store, []byte(start), func() expandFunc[struct{}] {
+		var buf []byte
+		return func(key []byte, _ func([]byte) bool, emit func([]byte, struct{})) {
+			for _, e := range out[StateID(key)] {
+				buf = append(buf[:0], e.Dst...)
+				emit(buf, struct{}{})
 			}
 		}
+	}, x.workers()); err != nil {
+		return nil, err
+	}
+	reachable := make(map[StateID]struct{}, store.len())
+	var key []byte
+	for k := range store.len() {
+		if key, err = store.key(key[:0], int32(k)); err != nil {
+			return nil, err
+		}
+		reachable[StateID(key)] = struct{}{}
 	}
-	return reachable
+	return reachable, nil
 }
 
 // dfs coloring states.
@@ -0,0 +1,435 @@
+package csdf
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// mermaidBlock is the text of one Mermaid diagram; first is the line number of
+// its first line in the input.
+type mermaidBlock struct {
+	lines []string
+	first int
+}
+
+// ParseMermaid reads Mermaid state diagrams (docs/SYNTAX.md, "Mermaid input")
+// from a Mermaid document or from the ```mermaid fenced blocks of a Markdown
+// file, and returns the one titled name, or the first when name is "":
+//
+//   - "stateDiagram-v2" (or "stateDiagram") starts the diagram and the title of
+//     the front matter is its name,
+//   - "state "Name" as id" declares a state; states are also declared by their
+//     first mention, and "id : text" lines are descriptions (a text that
+//     would read as a CSDF variable declaration, such as one word, is
+//     rejected),
+//   - "a --> b : event [guard] / post" is an edge; an edge without a label is τ,
+//   - "[*] --> a : / post" is the start edge and "a --> [*] : [guard]" the end
+//     edge,
+//   - every line of a note on a state is a variable, "name" or "name: type".
+//
+// Fenced blocks holding other kinds of Mermaid diagrams are skipped. Composite
+// and concurrent states, forks, joins and choices are rejected.
+func ParseMermaid(input, name string) (*Diagram, error) {
+	blocks, fenced := mermaidBlocks(input)
+	var diagrams []*Diagram
+	for _, block := range blocks {
+		diagram, ok, err := parseMermaidBlock(block)
+		if err != nil {
+			return nil, fmt.Errorf("csdf.ParseMermaid: %w", err)
+		}
+		if !ok {
+			if fenced {
+				continue
+			}
+			return nil, fmt.Errorf("csdf.ParseMermaid: expected stateDiagram-v2 at line %d", block.first)
+		}
+		diagrams = append(diagrams, diagram)
+	}
+	if len(diagrams) == 0 {
+		return nil, fmt.Errorf("csdf.ParseMermaid: no Mermaid state diagram")
+	}
+	diagram, err := SelectDiagram(diagrams, name)
+	if err != nil {
+		return nil, fmt.Errorf("csdf.ParseMermaid: %w", err)
+	}
+	return diagram, nil
+}
+
+// mermaidBlocks returns the ```mermaid and ~~~mermaid fenced blocks of a
+// Markdown input, or the whole input when it has no such block.
+func mermaidBlocks(input string) ([]mermaidBlock, bool) {
+	lines := strings.Split(strings.ReplaceAll(input, "\r\n", "\n"), "\n")
+	var blocks []mermaidBlock
+	for i := 0; i < len(lines); i++ {
+		fence, ok := mermaidFence(lines[i])
+		if !ok {
+			continue
+		}
+		block := mermaidBlock{first: i + 2}
+		for i++; i < len(lines) && !strings.HasPrefix(strings.TrimSpace(lines[i]), fence); i++ {
+			block.lines = append(block.lines, lines[i])
+		}
+		blocks = append(blocks, block)
+	}
+	if len(blocks) == 0 {
+		return []mermaidBlock{{lines: lines, first: 1}}, false
+	}
+	return blocks, true
+}
+
+// mermaidFence returns the run of backticks or tildes opening a ```mermaid
+// block on line.
+func mermaidFence(line string) (string, bool) {
+	trimmed := strings.TrimSpace(line)
+	for _, c := range []string{"`", "~"} {
+		info := strings.TrimLeft(trimmed, c)
+		fence := trimmed[:len(trimmed)-len(info)]
+		if len(fence) >= 3 && strings.TrimSpace(info) == "mermaid" {
+			return fence, true
+		}
+	}
+	return "", false
+}
+
+// mermaidParser builds a diagram from the statements of a block.
+type mermaidParser struct {
+	diagram *Diagram
+	start   bool
+	line    int
+}
+
+// parseMermaidBlock parses a block; ok is false when the block is not a state
+// diagram.
+func parseMermaidBlock(block mermaidBlock) (*Diagram, bool, error) {
+	p := &mermaidParser{diagram: &Diagram{States: make(map[StateID]State), Edges: make([]Edge, 0)}}
+	i := 0
+	skip := func() {
+		for i < len(block.lines) {
+			line := strings.TrimSpace(block.lines[i])
+			if line != "" && !strings.HasPrefix(line, "%%") {
+				return
+			}
+			i++
+		}
+	}
+
+	skip()
+	if i < len(block.lines) && strings.TrimSpace(block.lines[i]) == "---" {
+		for i++; i < len(block.lines) && strings.TrimSpace(block.lines[i]) != "---"; i++ {
+			if title, ok := strings.CutPrefix(strings.TrimSpace(block.lines[i]), "title:"); ok {
+				p.diagram.Name = unquoteMermaid(strings.TrimSpace(title))
+			}
+		}
+		i++
+		skip()
+	}
+	if i >= len(block.lines) {
+		return nil, false, nil
+	}
+	switch strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(block.lines[i]), ";")) {
+	case "stateDiagram-v2", "stateDiagram":
+	default:
+		return nil, false, nil
+	}
+
+	for i++; i < len(block.lines); i++ {
+		p.line = block.first + i
+		line := strings.TrimSpace(block.lines[i])
+		if line == "" || strings.HasPrefix(line, "%%") {
+			continue
+		}
+		if id, ok := mermaidNoteStart(line); ok {
+			var text []string
+			for i++; i < len(block.lines) && strings.TrimSpace(block.lines[i]) != "end note"; i++ {
+				text = append(text, block.lines[i])
+			}
+			if i >= len(block.lines) {
+				return nil, false, fmt.Errorf("note at line %d has no 'end note'", p.line)
+			}
+			p.note(id, text)
+			continue
+		}
+		if err := p.statement(strings.TrimSpace(strings.TrimSuffix(line, ";"))); err != nil {
+			return nil, false, fmt.Errorf("%w at line %d", err, p.line)
+		}
+	}
+	if !p.start {
+		return nil, false, fmt.Errorf("no start transition '[*] --> state' in the diagram at line %d", block.first)
+	}
+	return p.diagram, true, nil
+}
+
+// mermaidNoteStart returns the state of a multi-line "note right of id" line.
+func mermaidNoteStart(line string) (StateID, bool) {
+	target, ok := mermaidNoteTarget(line)
+	if !ok || strings.Contains(target, ":") {
+		return "", false
+	}
+	return StateID(strings.TrimSpace(target)), true
+}
+
+// mermaidNoteTarget returns what follows "note left of" or "note right of".
+func mermaidNoteTarget(line string) (string, bool) {
+	for _, side := range []string{"note left of ", "note right of "} {
+		if target, ok := strings.CutPrefix(line, side); ok {
+			return target, true
+		}
+	}
+	return "", false
+}
+
+func (p *mermaidParser) statement(s string) error {
+	keyword, _, _ := strings.Cut(s, " ")
+	switch keyword {
+	case "direction", "classDef", "class", "style", "accTitle:", "accDescr:", "accTitle", "accDescr":
+		return nil
+	case "--", "}":
+		return fmt.Errorf("concurrent and composite states are not supported")
+	case "note":
+		target, ok := mermaidNoteTarget(s)
+		if !ok {
+			return fmt.Errorf("expected 'note left of' or 'note right of'")
+		}
+		id, text, _ := strings.Cut(target, ":")
+		p.note(StateID(strings.TrimSpace(id)), []string{text})
+		return nil
+	case "state":
+		return p.stateStatement(strings.TrimSpace(strings.TrimPrefix(s, "state")))
+	}
+
+	if src, rest, ok := strings.Cut(s, "-->"); ok {
+		dst, label, _ := strings.Cut(rest, ":")
+		return p.transition(mermaidID(src), mermaidID(dst), strings.TrimSpace(label))
+	}
+	if id, text, ok := strings.Cut(s, ":"); ok {
+		state, err := p.state(mermaidID(id))
+		if err != nil {
+			return err
+		}
+		description := strings.TrimSpace(text)
+		if readsAsVarDecl(description) {
+			return fmt.Errorf("the description %q reads as a CSDF variable declaration", description)
+		}
+		state.Descriptions = append(state.Descriptions, description)
+		p.diagram.States[state.ID] = state
+		return nil
+	}
+	_, err := p.state(mermaidID(s))
+	return err
+}
+
+// stateStatement reads what follows "state".
+func (p *mermaidParser) stateStatement(s string) error {
+	if strings.HasSuffix(s, "{") {
+		return fmt.Errorf("composite states are not supported")
+	}
+	if strings.Contains(s, "<<") {
+		return fmt.Errorf("forks, joins and choices are not supported")
+	}
+	if !strings.HasPrefix(s, `"`) {
+		_, err := p.state(mermaidID(s))
+		return err
+	}
+	end := strings.IndexByte(s[1:], '"')
+	if end < 0 {
+		return fmt.Errorf("unterminated state name")
+	}
+	name := s[1 : end+1]
+	id, ok := strings.CutPrefix(strings.TrimSpace(s[end+2:]), "as ")
+	if !ok {
+		return fmt.Errorf("expected 'as' after the state name")
+	}
+	state, err := p.state(mermaidID(id))
+	if err != nil {
+		return err
+	}
+	state.Name = name
+	p.diagram.States[state.ID] = state
+	return nil
+}
+
+// state returns the state id, declaring it on its first mention.
+func (p *mermaidParser) state(id StateID) (State, error) {
+	if id == "" || strings.ContainsAny(string(id), " \t\"") {
+		return State{}, fmt.Errorf("invalid state ID %q", id)
+	}
+	if id == "[*]" {
+		return State{}, fmt.Errorf("[*] cannot be declared as a state")
+	}
+	if state, ok := p.diagram.States[id]; ok {
+		return state, nil
+	}
+	state := State{ID: id, Name: string(id), Vars: []StateVar{}}
+	p.diagram.States[id] = state
+	return state, nil
+}
+
+func (p *mermaidParser) note(id StateID, lines []string) {
+	state, err := p.state(id)
+	if err != nil {
+		return
+	}
+	for _, line := range lines {
+		name, varType, _ := strings.Cut(line, ":")
+		if name = strings.TrimSpace(name); name != "" {
+			state.Vars = append(state.Vars, StateVar{Name: Var(name), Type: strings.TrimSpace(varType)})
+		}
+	}
+	p.diagram.States[id] = state
+}
+
+func (p *mermaidParser) transition(src, dst StateID, label string) error {
+	switch {
+	case src == "[*]" && dst == "[*]":
+		return fmt.Errorf("a transition from [*] to [*] is not supported")
+	case src == "[*]":
+		if p.start {
+			return fmt.Errorf("a diagram has one start transition")
+		}
+		if _, err := p.state(dst); err != nil {
+			return err
+		}
+		post := label
+		if rest, ok := strings.CutPrefix(label, "/"); ok {
+			post = strings.TrimSpace(rest)
+		}
+		if post == "" {
+			post = True
+		}
+		p.start = true
+		p.diagram.StartEdge = StartEdge{Dst: dst, Post: post}
+		return nil
+	case dst == "[*]":
+		if p.diagram.EndEdge != nil {
+			return fmt.Errorf("a diagram has one end transition")
+		}
+		if _, err := p.state(src); err != nil {
+			return err
+		}
+		guard := label
+		if strings.HasPrefix(label, "[") && strings.HasSuffix(label, "]") {
+			guard = strings.TrimSpace(label[1 : len(label)-1])
+		}
+		p.diagram.EndEdge = &EndEdge{Src: src, Guard: guard}
+		return nil
+	}
+
+	if _, err := p.state(src); err != nil {
+		return err
+	}
+	if _, err := p.state(dst); err != nil {
+		return err
+	}
+	edge := Edge{Src: src, Dst: dst, Event: Tau, Guard: True, Post: True}
+	if strings.Contains(label, ";") {
+		return fmt.Errorf("';' ends a Mermaid statement; write the label %q as 'event [guard] / post'", label)
+	}
+	if label != "" {
+		event, guard, post, err := splitMermaidLabel(label)
+		if err != nil {
+			return err
+		}
+		if event == "" {
+			return fmt.Errorf("the label %q of the transition from %s to %s has no event", label, src, dst)
+		}
+		edge.Event = Event(event)
+		if guard != "" {
+			edge.Guard = guard
+		}
+		if post != "" {
+			edge.Post = post
+		}
+	}
+	p.diagram.Edges = append(p.diagram.Edges, edge)
+	return nil
+}
+
+// splitMermaidLabel splits "event [guard] / post". The guard and the post are
+// optional; a '[' or a '/' starts them only after a space, so events such as
+// "a/b" and "get[0]" are kept whole.
+func splitMermaidLabel(label string) (event, guard, post string, err error) {
+	i := 0
+	for ; i < len(label); i++ {
+		if (label[i] == '[' || label[i] == '/') && (i == 0 || label[i-1] == ' ') {
+			break
+		}
+	}
+	event = strings.TrimSpace(label[:i])
+	rest := label[i:]
+	if strings.HasPrefix(rest, "[") {
+		depth, end := 0, -1
+		for j := 0; j < len(rest) && end < 0; j++ {
+			switch rest[j] {
+			case '[':
+				depth++
+			case ']':
+				depth--
+				if depth == 0 {
+					end = j
+				}
+			}
+		}
+		if end < 0 {
+			return "", "", "", fmt.Errorf("unclosed guard in the label %q", label)
+		}
+		guard = strings.TrimSpace(rest[1:end])
+		rest = strings.TrimSpace(rest[end+1:])
+	}
+	if after, ok := strings.CutPrefix(rest, "/"); ok {
+		post = strings.TrimSpace(after)
+		rest = ""
+	}
+	if rest != "" {
+		return "", "", "", fmt.Errorf("unexpected %q after the guard in the label %q", rest, label)
+	}
+	return event, guard, post, nil
+}
+
+// mermaidID trims a state reference and drops a ":::class" suffix.
+func mermaidID(s string) StateID {
+	id, _, _ := strings.Cut(strings.TrimSpace(s), ":::")
+	return StateID(strings.TrimSpace(id))
+}
+
+func unquoteMermaid(s string) string {
+	if unquoted, err := strconv.Unquote(s); err == nil {
+		return unquoted
+	}
+	return strings.Trim(s, `'`)
+}
+
+// isMermaidSource reports whether a source read from path is Mermaid or
+// Markdown: by the .mmd, .mermaid, .md and .markdown extensions, or, for
+// other paths and standard input, by content without @startuml that starts
+// with a Mermaid state diagram or holds a ```mermaid block.
+func isMermaidSource(path, text string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".mmd", ".mermaid", ".md", ".markdown":
+		return true
+	case ".puml", ".plantuml", ".png", ".svg", ".csp", ".cspm", ".aut", ".scxml":
+		return false
+	}
+	if strings.Contains(text, "@startuml") {
+		return false
+	}
+	lines := strings.Split(text, "\n")
+	for _, line := range lines {
+		if _, ok := mermaidFence(line); ok {
+			return true
+		}
+	}
+	inFrontMatter := false
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "---":
+			inFrontMatter = !inFrontMatter
+		case inFrontMatter, line == "", strings.HasPrefix(line, "%%"):
+		default:
+			return strings.HasPrefix(line, "stateDiagram")
+		}
+	}
+	return false
+}
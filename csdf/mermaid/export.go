@@ -0,0 +1,169 @@
+// Package mermaid writes Composable State Diagrams as Mermaid state diagrams,
+// which GitHub renders in Markdown. csdf.ParseMermaid reads them back.
+package mermaid
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Kuniwak/puml-parallel/csdf"
+)
+
+// Export writes d as a Mermaid stateDiagram-v2:
+//
+//   - the diagram name is the title of the front matter,
+//   - every state is declared, with "state "Name" as id" when its name differs
+//     from its ID, and its variables are the lines of a note,
+//   - every edge is a transition labeled "event [guard] / post", leaving out
+//     trivial guards and post-conditions,
+//   - the start edge is "[*] --> id : / post" and the end edge
+//     "id --> [*] : [guard]".
+//
+// Mermaid ends a label at ';' and '#', so texts containing them or line breaks
+// are rejected, and so are events that would be read as a guard or a
+// post-condition.
+func Export(d *csdf.Diagram) (string, error) {
+	var sb strings.Builder
+	if d.Name != "" {
+		if err := check("diagram name", d.Name); err != nil {
+			return "", fmt.Errorf("mermaid.Export: %w", err)
+		}
+		sb.WriteString("---\ntitle: " + title(d.Name) + "\n---\n")
+	}
+	sb.WriteString("stateDiagram-v2\n")
+
+	stateIDs := make([]csdf.StateID, 0, len(d.States))
+	for id := range d.States {
+		stateIDs = append(stateIDs, id)
+	}
+	sort.Slice(stateIDs, func(i, j int) bool { return stateIDs[i] < stateIDs[j] })
+
+	for _, id := range stateIDs {
+		state := d.States[id]
+		if strings.ContainsAny(string(id), " \t\":") {
+			return "", fmt.Errorf("mermaid.Export: state ID %q is not a Mermaid state ID", id)
+		}
+		if state.Name != string(id) {
+			if strings.ContainsAny(state.Name, "\"\n") {
+				return "", fmt.Errorf("mermaid.Export: state name %q contains a quote or a line break", state.Name)
+			}
+			sb.WriteString(fmt.Sprintf("    state \"%s\" as %s\n", state.Name, id))
+		} else {
+			sb.WriteString("    " + string(id) + "\n")
+		}
+		for _, description := range state.Descriptions {
+			if err := check("description", description); err != nil {
+				return "", fmt.Errorf("mermaid.Export: %w", err)
+			}
+			sb.WriteString("    " + string(id) + " : " + description + "\n")
+		}
+		if len(state.Vars) > 0 {
+			sb.WriteString("    note right of " + string(id) + "\n")
+			for _, v := range state.Vars {
+				if strings.Contains(string(v.Name), ":") {
+					return "", fmt.Errorf("mermaid.Export: variable %q contains ':'", v.Name)
+				}
+				if err := check("variable", string(v.Name)+v.Type); err != nil {
+					return "", fmt.Errorf("mermaid.Export: %w", err)
+				}
+				line := string(v.Name)
+				if v.Type != "" {
+					line += ": " + v.Type
+				}
+				sb.WriteString("        " + line + "\n")
+			}
+			sb.WriteString("    end note\n")
+		}
+	}
+
+	start := "    [*] --> " + string(d.StartEdge.Dst)
+	if d.StartEdge.Post != "" && d.StartEdge.Post != csdf.True {
+		if err := check("start post-condition", d.StartEdge.Post); err != nil {
+			return "", fmt.Errorf("mermaid.Export: %w", err)
+		}
+		start += " : / " + d.StartEdge.Post
+	}
+	sb.WriteString(start + "\n")
+
+	for _, e := range d.Edges {
+		label, err := label(e)
+		if err != nil {
+			return "", fmt.Errorf("mermaid.Export: edge %s -> %s: %w", e.Src, e.Dst, err)
+		}
+		sb.WriteString("    " + string(e.Src) + " --> " + string(e.Dst) + " : " + label + "\n")
+	}
+
+	if d.EndEdge != nil {
+		end := "    " + string(d.EndEdge.Src) + " --> [*]"
+		if d.EndEdge.Guard != "" && d.EndEdge.Guard != csdf.True {
+			if err := check("end guard", d.EndEdge.Guard); err != nil {
+				return "", fmt.Errorf("mermaid.Export: %w", err)
+			}
+			end += " : [" + d.EndEdge.Guard + "]"
+		}
+		sb.WriteString(end + "\n")
+	}
+	return sb.String(), nil
+}
+
+// label writes "event [guard] / post".
+func label(e csdf.Edge) (string, error) {
+	event := string(e.Event)
+	if err := check("event", event); err != nil {
+		return "", err
+	}
+	if strings.HasPrefix(event, "[") || strings.HasPrefix(event, "/") || strings.Contains(event, " [") || strings.Contains(event, " /") {
+		return "", fmt.Errorf("event %q would be read as a guard or a post-condition", event)
+	}
+	text := event
+	if e.Guard != "" && e.Guard != csdf.True {
+		if err := check("guard", e.Guard); err != nil {
+			return "", err
+		}
+		if !balanced(e.Guard) {
+			return "", fmt.Errorf("guard %q has unbalanced brackets", e.Guard)
+		}
+		text += " [" + e.Guard + "]"
+	}
+	if e.Post != "" && e.Post != csdf.True {
+		if err := check("post-condition", e.Post); err != nil {
+			return "", err
+		}
+		text += " / " + e.Post
+	}
+	return text, nil
+}
+
+func check(what, text string) error {
+	if strings.ContainsAny(text, ";#\n") {
+		return fmt.Errorf("%s %q contains ';', '#' or a line break, which Mermaid cannot keep", what, text)
+	}
+	return nil
+}
+
+// balanced reports whether every '[' in s is closed by a later ']'.
+func balanced(s string) bool {
+	depth := 0
+	for _, r := range s {
+		switch r {
+		case '[':
+			depth++
+		case ']':
+			depth--
+			if depth < 0 {
+				return false
+			}
+		}
+	}
+	return depth == 0
+}
+
+// title writes name as a YAML scalar, quoted when YAML would read it otherwise.
+func title(name string) string {
+	if strings.TrimSpace(name) != name || strings.ContainsAny(name, ":'\"{}[],&*!|>%@`") {
+		return strconv.Quote(name)
+	}
+	return name
+}
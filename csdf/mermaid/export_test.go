@@ -0,0 +1,130 @@
+package mermaid
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Kuniwak/puml-parallel/csdf"
+	"github.com/google/go-cmp/cmp"
+)
+
+func mustParse(t *testing.T, src string) *csdf.Diagram {
+	t.Helper()
+	d, err := csdf.ParseDiagram([]byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+func TestExport(t *testing.T) {
+	// Setup
+	d := mustParse(t, `@startuml tea
+state "Ready" as ready
+ready : cups ; int
+state "brewing" as brewing
+[*] --> ready : cups' = 0
+ready --> brewing : coin
+brewing --> ready : tea ; cups < 3 ; cups' = cups + 1
+brewing --> brewing : tau
+ready --> [*] : cups >= 3
+@enduml
+`)
+	want := `---
+title: tea
+---
+stateDiagram-v2
+    brewing
+    state "Ready" as ready
+    note right of ready
+        cups: int
+    end note
+    [*] --> ready : / cups' = 0
+    ready --> brewing : coin
+    brewing --> ready : tea [cups < 3] / cups' = cups + 1
+    brewing --> brewing : tau
+    ready --> [*] : [cups >= 3]
+`
+
+	// Execute
+	got, err := Export(d)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Error(diff)
+	}
+
+	// Teardown: no resources to release.
+}
+
+func TestExportRoundTrips(t *testing.T) {
+	paths := []string{
+		"../../examples/valid/vending_machine.puml",
+		"../../examples/valid/tea_machine.csp",
+		"../../examples/valid/turnstile.md",
+	}
+
+	for _, path := range paths {
+		t.Run(path, func(t *testing.T) {
+			// Setup
+			want := csdf.MustLoadDiagrams(path)[0]
+
+			// Execute
+			text, err := Export(want)
+			if err != nil {
+				t.Fatalf("Export() error = %v", err)
+			}
+			got, err := csdf.ParseMermaid(text, "")
+
+			// Assert
+			if err != nil {
+				t.Fatalf("ParseMermaid() error = %v\n%s", err, text)
+			}
+			if diff := cmp.Diff(want, got); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestExportRejects(t *testing.T) {
+	testCases := map[string]struct {
+		edge csdf.Edge
+		want string
+	}{
+		"semicolon": {
+			edge: csdf.Edge{Src: "a", Dst: "a", Event: "e", Guard: "x; y", Post: csdf.True},
+			want: "contains ';', '#' or a line break",
+		},
+		"event read as a guard": {
+			edge: csdf.Edge{Src: "a", Dst: "a", Event: "e [x]", Guard: csdf.True, Post: csdf.True},
+			want: "would be read as a guard",
+		},
+		"unbalanced guard": {
+			edge: csdf.Edge{Src: "a", Dst: "a", Event: "e", Guard: "x]", Post: csdf.True},
+			want: "unbalanced brackets",
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Setup
+			d := &csdf.Diagram{
+				States:    map[csdf.StateID]csdf.State{"a": {ID: "a", Name: "a", Vars: []csdf.StateVar{}}},
+				StartEdge: csdf.StartEdge{Dst: "a", Post: csdf.True},
+				Edges:     []csdf.Edge{tc.edge},
+			}
+
+			// Execute
+			_, err := Export(d)
+
+			// Assert
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("Export() error = %v, want it to contain %q", err, tc.want)
+			}
+		})
+	}
+}
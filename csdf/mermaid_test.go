@@ -0,0 +1,167 @@
+package csdf
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseMermaid(t *testing.T) {
+	// Setup
+	input := `stateDiagram-v2
+    direction LR
+    state "Ready" as ready
+    ready : waits for a coin
+    [*] --> ready
+    ready --> brewing : coin
+    brewing --> ready : tea [cups[0] < 3] / cups' = cups + 1
+    brewing --> brewing
+    brewing:::hot --> [*] : cups >= 3
+    classDef hot fill:#f00
+`
+	want := &Diagram{
+		States: map[StateID]State{
+			"ready":   {ID: "ready", Name: "Ready", Vars: []StateVar{}, Descriptions: []string{"waits for a coin"}},
+			"brewing": {ID: "brewing", Name: "brewing", Vars: []StateVar{}},
+		},
+		StartEdge: StartEdge{Dst: "ready", Post: True},
+		Edges: []Edge{
+			{Src: "ready", Dst: "brewing", Event: "coin", Guard: True, Post: True},
+			{Src: "brewing", Dst: "ready", Event: "tea", Guard: "cups[0] < 3", Post: "cups' = cups + 1"},
+			{Src: "brewing", Dst: "brewing", Event: Tau, Guard: True, Post: True},
+		},
+		EndEdge: &EndEdge{Src: "brewing", Guard: "cups >= 3"},
+	}
+
+	// Execute
+	got, err := ParseMermaid(input, "")
+
+	// Assert
+	if err != nil {
+		t.Fatalf("ParseMermaid() error = %v", err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Error(diff)
+	}
+
+	// Teardown: no resources to release.
+}
+
+func TestParseMermaidRejects(t *testing.T) {
+	testCases := map[string]struct {
+		input string
+		want  string
+	}{
+		"not a state diagram": {
+			input: "flowchart LR\n    a --> b\n",
+			want:  "expected stateDiagram-v2 at line 1",
+		},
+		"no start": {
+			input: "stateDiagram-v2\n    a --> b : e\n",
+			want:  "no start transition",
+		},
+		"composite state": {
+			input: "stateDiagram-v2\n    [*] --> a\n    state a {\n",
+			want:  "composite states are not supported at line 3",
+		},
+		"choice": {
+			input: "stateDiagram-v2\n    [*] --> a\n    state c <<choice>>\n",
+			want:  "forks, joins and choices are not supported",
+		},
+		"semicolon label": {
+			input: "stateDiagram-v2\n    [*] --> a\n    a --> a : tea ; hot\n",
+			want:  "write the label \"tea ; hot\" as 'event [guard] / post'",
+		},
+		"label without event": {
+			input: "stateDiagram-v2\n    [*] --> a\n    a --> a : [hot]\n",
+			want:  "has no event",
+		},
+		"unclosed guard": {
+			input: "stateDiagram-v2\n    [*] --> a\n    a --> a : tea [hot\n",
+			want:  "unclosed guard",
+		},
+		"two end transitions": {
+			input: "stateDiagram-v2\n    [*] --> a\n    a --> [*]\n    a --> [*]\n",
+			want:  "a diagram has one end transition",
+		},
+		"one-word description": {
+			input: "stateDiagram-v2\n    [*] --> a\n    a : idle\n",
+			want:  "the description \"idle\" reads as a CSDF variable declaration",
+		},
+		"unterminated note": {
+			input: "stateDiagram-v2\n    [*] --> a\n    note right of a\n        x\n",
+			want:  "has no 'end note'",
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Execute
+			_, err := ParseMermaid(tc.input, "")
+
+			// Assert
+			if err == nil {
+				t.Fatal("ParseMermaid() error = nil, want error")
+			}
+			if !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("ParseMermaid() error = %q, want it to contain %q", err.Error(), tc.want)
+			}
+		})
+	}
+}
+
+func TestLoadDiagramsReadsMermaidFromMarkdown(t *testing.T) {
+	// Setup: the flowchart block before the state diagram is skipped.
+	want := &Diagram{
+		Name: "turnstile",
+		States: map[StateID]State{
+			"locked":   {ID: "locked", Name: "Locked", Vars: []StateVar{{Name: "passed", Type: "int"}}},
+			"unlocked": {ID: "unlocked", Name: "Unlocked", Vars: []StateVar{{Name: "passed", Type: "int"}}},
+		},
+		StartEdge: StartEdge{Dst: "locked", Post: "passed' = 0"},
+		Edges: []Edge{
+			{Src: "locked", Dst: "unlocked", Event: "coin", Guard: True, Post: True},
+			{Src: "unlocked", Dst: "locked", Event: "push", Guard: True, Post: "passed' = passed + 1"},
+			{Src: "unlocked", Dst: "unlocked", Event: "coin", Guard: True, Post: True},
+		},
+		EndEdge: &EndEdge{Src: "locked", Guard: "out of service"},
+	}
+
+	for _, ref := range []string{"../examples/valid/turnstile.md", "../examples/valid/turnstile.md#turnstile"} {
+		t.Run(ref, func(t *testing.T) {
+			// Execute
+			got, err := LoadDiagrams([]string{ref})
+
+			// Assert
+			if err != nil {
+				t.Fatalf("LoadDiagrams() error = %v", err)
+			}
+			if diff := cmp.Diff(want, got[0]); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestParseDiagramDetectsMermaidByContent(t *testing.T) {
+	testCases := map[string]string{
+		"Mermaid":  "%% tea\nstateDiagram-v2\n    [*] --> a\n",
+		"Markdown": "# Tea\n\n```mermaid\nstateDiagram-v2\n    [*] --> a\n```\n",
+	}
+
+	for name, input := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Execute
+			d, err := ParseDiagram([]byte(input))
+
+			// Assert
+			if err != nil {
+				t.Fatalf("ParseDiagram() error = %v", err)
+			}
+			if d.StartEdge.Dst != "a" {
+				t.Errorf("ParseDiagram() start = %q, want a", d.StartEdge.Dst)
+			}
+		})
+	}
+}
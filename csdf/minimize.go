@@ -0,0 +1,334 @@
+package csdf
+
+import (
+	"encoding/binary"
+	"fmt"
+	"slices"
+)
+
+// Hide returns d with the events in hidden renamed to τ, as the CSP hiding
+// operator d \ hidden.
+func Hide(d *Diagram, hidden []Event) *Diagram {
+	hide := make(map[Event]struct{}, len(hidden))
+	for _, event := range hidden {
+		hide[event] = struct{}{}
+	}
+	out := *d
+	out.Edges = make([]Edge, len(d.Edges))
+	for i, e := range d.Edges {
+		if _, ok := hide[e.Event]; ok {
+			e.Event = Tau
+		}
+		out.Edges[i] = e
+	}
+	return &out
+}
+
+// Minimize returns the quotient of the states of d reachable from the start
+// state by divergence-preserving branching bisimulation: states are merged
+// when they offer the same edges up to τ-edges between merged states, and can
+// either both or neither run forever on such τ-edges. The result has the same
+// deadlocks and τ-cycles as d, and it may replace d in ComposeParallel and Hide
+// without changing the result, up to this equivalence.
+//
+// The label of an edge is its event, guard and post-condition; a τ-edge
+// between merged states is dropped whatever its guard and post-condition, as
+// in Normalize. Each merged state keeps the ID, name and variables of its
+// first member found breadth first from the start state, and a state that can
+// run forever on dropped τ-edges gets a τ self-loop. End edges are not
+// supported.
+func Minimize(d *Diagram) (*Diagram, error) {
+	if d.EndEdge != nil {
+		return nil, fmt.Errorf("csdf.Minimize: end edges are not supported")
+	}
+	g := newLabeledGraph(d)
+	g.collapseTauCycles()
+	block := g.refine()
+	return g.quotient(d, block), nil
+}
+
+// labeledGraph is the reachable part of a diagram, with states numbered
+// breadth first from the start state (numbered 0) and edges labeled by number.
+type labeledGraph struct {
+	ids    []StateID
+	edges  []labeledEdge
+	labels []Edge // the event, guard and post-condition of each label
+	tau    []bool // whether each label is a τ label
+	// tauLoop tells the states having a τ-edge back to themselves, which
+	// collapseTauCycles drops.
+	tauLoop []bool
+}
+
+type labeledEdge struct {
+	src, dst, label int
+}
+
+func newLabeledGraph(d *Diagram) *labeledGraph {
+	out := make(map[StateID][]Edge)
+	for _, e := range d.Edges {
+		out[e.Src] = append(out[e.Src], e)
+	}
+	g := &labeledGraph{}
+	numbers := map[StateID]int{d.StartEdge.Dst: 0}
+	g.ids = append(g.ids, d.StartEdge.Dst)
+	type labelKey struct {
+		event       Event
+		guard, post string
+	}
+	labels := make(map[labelKey]int)
+	for n := 0; n < len(g.ids); n++ {
+		for _, e := range out[g.ids[n]] {
+			dst, ok := numbers[e.Dst]
+			if !ok {
+				dst = len(g.ids)
+				numbers[e.Dst] = dst
+				g.ids = append(g.ids, e.Dst)
+			}
+			key := labelKey{event: e.Event, guard: e.Guard, post: e.Post}
+			label, ok := labels[key]
+			if !ok {
+				label = len(g.labels)
+				labels[key] = label
+				g.labels = append(g.labels, Edge{Event: e.Event, Guard: e.Guard, Post: e.Post})
+				g.tau = append(g.tau, e.Event == Tau)
+			}
+			g.edges = append(g.edges, labeledEdge{src: n, dst: dst, label: label})
+		}
+	}
+	g.tauLoop = make([]bool, len(g.ids))
+	return g
+}
+
+// collapseTauCycles merges the states of each strongly connected component of
+// the τ-edges, which are branching bisimilar, into its first state, and drops
+// the τ-edges inside the components, marking their states in tauLoop. The
+// τ-edges left then form no cycle.
+func (g *labeledGraph) collapseTauCycles() {
+	n := len(g.ids)
+	tauOut := make([][]int, n)
+	for _, e := range g.edges {
+		if g.tau[e.label] {
+			tauOut[e.src] = append(tauOut[e.src], e.dst)
+		}
+	}
+	scc := tarjan(n, tauOut)
+
+	// Number the components by their first state, keeping breadth-first order.
+	rep := make([]int, n)
+	for i := range rep {
+		rep[i] = -1
+	}
+	number := make([]int, n)
+	var ids []StateID
+	for s := 0; s < n; s++ {
+		if rep[scc[s]] < 0 {
+			rep[scc[s]] = len(ids)
+			ids = append(ids, g.ids[s])
+		}
+		number[s] = rep[scc[s]]
+	}
+
+	tauLoop := make([]bool, len(ids))
+	seen := make(map[labeledEdge]struct{}, len(g.edges))
+	edges := g.edges[:0]
+	for _, e := range g.edges {
+		e.src, e.dst = number[e.src], number[e.dst]
+		if g.tau[e.label] && e.src == e.dst {
+			tauLoop[e.src] = true
+			continue
+		}
+		if _, ok := seen[e]; ok {
+			continue
+		}
+		seen[e] = struct{}{}
+		edges = append(edges, e)
+	}
+	g.ids, g.edges, g.tauLoop = ids, edges, tauLoop
+}
+
+// tarjan returns the strongly connected component of each of n states, the
+// components being numbered in reverse topological order.
+func tarjan(n int, out [][]int) []int {
+	index := make([]int, n)
+	low := make([]int, n)
+	onStack := make([]bool, n)
+	scc := make([]int, n)
+	for i := range index {
+		index[i] = -1
+	}
+	var stack []int
+	next, count := 0, 0
+
+	type frame struct{ s, i int }
+	for root := 0; root < n; root++ {
+		if index[root] >= 0 {
+			continue
+		}
+		frames := []frame{{s: root}}
+		index[root], low[root] = next, next
+		next++
+		stack = append(stack, root)
+		onStack[root] = true
+		for len(frames) > 0 {
+			f := &frames[len(frames)-1]
+			if f.i < len(out[f.s]) {
+				t := out[f.s][f.i]
+				f.i++
+				if index[t] < 0 {
+					index[t], low[t] = next, next
+					next++
+					stack = append(stack, t)
+					onStack[t] = true
+					frames = append(frames, frame{s: t})
+				} else if onStack[t] {
+					low[f.s] = min(low[f.s], index[t])
+				}
+				continue
+			}
+			s := f.s
+			frames = frames[:len(frames)-1]
+			if len(frames) > 0 {
+				parent := frames[len(frames)-1].s
+				low[parent] = min(low[parent], low[s])
+			}
+			if low[s] == index[s] {
+				for {
+					t := stack[len(stack)-1]
+					stack = stack[:len(stack)-1]
+					onStack[t] = false
+					scc[t] = count
+					if t == s {
+						break
+					}
+				}
+				count++
+			}
+		}
+	}
+	return scc
+}
+
+// refine computes divergence-preserving branching bisimilarity on g, whose
+// τ-edges must form no cycle, by signature refinement: starting from a single
+// block, it splits the blocks by the signatures of their states until no block
+// splits. The signature of a state is whether it can run forever on τ-edges
+// within its block, and the labels and destination blocks of the edges it can
+// take after such τ-edges, except τ-edges within the block. Blocks are numbered
+// by their first state, so block 0 holds the start state.
+func (g *labeledGraph) refine() []int {
+	n := len(g.ids)
+	out := make([][]labeledEdge, n)
+	for _, e := range g.edges {
+		out[e.src] = append(out[e.src], e)
+	}
+	// order lists the states so that the τ-successors of a state come before it.
+	tauOut := make([][]int, n)
+	for _, e := range g.edges {
+		if g.tau[e.label] {
+			tauOut[e.src] = append(tauOut[e.src], e.dst)
+		}
+	}
+	scc := tarjan(n, tauOut)
+	order := make([]int, n)
+	for s := range order {
+		order[scc[s]] = s
+	}
+
+	block := make([]int, n)
+	count := 1
+	signatures := make([][]uint64, n)
+	diverges := make([]bool, n)
+	for {
+		for _, s := range order {
+			var sig []uint64
+			div := g.tauLoop[s]
+			for _, e := range out[s] {
+				if g.tau[e.label] && block[e.dst] == block[s] {
+					sig = append(sig, signatures[e.dst]...)
+					div = div || diverges[e.dst]
+					continue
+				}
+				sig = append(sig, uint64(e.label)<<32|uint64(block[e.dst]))
+			}
+			slices.Sort(sig)
+			signatures[s], diverges[s] = slices.Compact(sig), div
+		}
+
+		type key struct {
+			block int
+			div   bool
+			sig   string
+		}
+		numbers := make(map[key]int)
+		next := make([]int, n)
+		for s := 0; s < n; s++ {
+			sig := make([]byte, 0, 8*len(signatures[s]))
+			for _, x := range signatures[s] {
+				sig = binary.LittleEndian.AppendUint64(sig, x)
+			}
+			k := key{block: block[s], div: diverges[s], sig: string(sig)}
+			b, ok := numbers[k]
+			if !ok {
+				b = len(numbers)
+				numbers[k] = b
+			}
+			next[s] = b
+		}
+		block = next
+		if len(numbers) == count {
+			return block
+		}
+		count = len(numbers)
+	}
+}
+
+// quotient builds the diagram of the blocks of g, taking the states of d.
+func (g *labeledGraph) quotient(d *Diagram, block []int) *Diagram {
+	count := 0
+	for _, b := range block {
+		count = max(count, b+1)
+	}
+	ids := make([]StateID, count)
+	diverges := make([]bool, count)
+	for s := len(block) - 1; s >= 0; s-- {
+		ids[block[s]] = g.ids[s]
+	}
+	for s, loop := range g.tauLoop {
+		diverges[block[s]] = diverges[block[s]] || loop
+	}
+
+	out := &Diagram{
+		Name:      d.Name,
+		States:    make(map[StateID]State, count),
+		StartEdge: StartEdge{Dst: ids[0], Post: d.StartEdge.Post},
+	}
+	for _, id := range ids {
+		state, ok := d.States[id]
+		if !ok {
+			state = State{ID: id, Name: string(id)}
+		}
+		out.States[id] = state
+	}
+	seen := make(map[labeledEdge]struct{}, len(g.edges))
+	for _, e := range g.edges {
+		e.src, e.dst = block[e.src], block[e.dst]
+		if g.tau[e.label] && e.src == e.dst {
+			// A τ-edge within the block; divergence gets a τ self-loop below.
+			continue
+		}
+		if _, ok := seen[e]; ok {
+			continue
+		}
+		seen[e] = struct{}{}
+		edge := g.labels[e.label]
+		edge.Src, edge.Dst = ids[e.src], ids[e.dst]
+		out.Edges = append(out.Edges, edge)
+	}
+	for b, div := range diverges {
+		if div {
+			out.Edges = append(out.Edges, Edge{Src: ids[b], Dst: ids[b], Event: Tau, Guard: True, Post: True})
+		}
+	}
+	sortEdges(out.Edges)
+	return out
+}
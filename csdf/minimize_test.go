@@ -0,0 +1,299 @@
+package csdf
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"pgregory.net/rapid"
+)
+
+func TestHideRenamesEventsToTau(t *testing.T) {
+	// Setup
+	d := mustParse(t, `@startuml
+state "s0" as s0
+state "s1" as s1
+[*] --> s0
+s0 --> s1 : a
+s1 --> s0 : b
+@enduml
+`)
+
+	// Execute
+	hidden := Hide(d, []Event{"a"})
+
+	// Assert
+	if got := []Event{hidden.Edges[0].Event, hidden.Edges[1].Event}; !cmp.Equal(got, []Event{Tau, "b"}) {
+		t.Errorf("events = %v, want [tau b]", got)
+	}
+	if d.Edges[0].Event != "a" {
+		t.Error("Hide() changed its argument")
+	}
+}
+
+func TestMinimizeDropsInertTau(t *testing.T) {
+	// Setup: s0 only moves silently to s1, so the two are merged, while s2
+	// offers nothing and stays apart.
+	d := mustParse(t, `@startuml
+state "s0" as s0
+state "s1" as s1
+state "s2" as s2
+[*] --> s0
+s0 --> s1 : tau
+s1 --> s2 : a
+@enduml
+`)
+	want := mustParse(t, `@startuml
+state "s0" as s0
+state "s2" as s2
+[*] --> s0
+s0 --> s2 : a
+@enduml
+`)
+
+	// Execute
+	got, err := Minimize(d)
+
+	// Assert
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestMinimizeKeepsChoosingTau(t *testing.T) {
+	// Setup: s0 can do a or silently give it up for b; the τ-edge is a choice
+	// and must stay.
+	d := mustParse(t, `@startuml
+state "s0" as s0
+state "s1" as s1
+state "s2" as s2
+[*] --> s0
+s0 --> s1 : a
+s0 --> s2 : tau
+s2 --> s1 : b
+@enduml
+`)
+
+	// Execute
+	got, err := Minimize(d)
+
+	// Assert
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.States) != 3 || len(got.Edges) != 3 {
+		t.Errorf("got %d states and %d edges, want 3 and 3:\n%s", len(got.States), len(got.Edges), got)
+	}
+}
+
+func TestMinimizeMergesBisimilarStatesAndKeepsDivergence(t *testing.T) {
+	// Setup: the two branches behave alike, and s3 and s4 spin silently.
+	d := mustParse(t, `@startuml
+state "s0" as s0
+state "s1" as s1
+state "s2" as s2
+state "s3" as s3
+state "s4" as s4
+[*] --> s0
+s0 --> s1 : a
+s0 --> s2 : a
+s1 --> s3 : b
+s2 --> s4 : b
+s3 --> s4 : tau
+s4 --> s3 : tau
+@enduml
+`)
+	want := mustParse(t, `@startuml
+state "s0" as s0
+state "s1" as s1
+state "s3" as s3
+[*] --> s0
+s0 --> s1 : a
+s1 --> s3 : b
+s3 --> s3 : tau
+@enduml
+`)
+
+	// Execute
+	got, err := Minimize(d)
+
+	// Assert
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestComposeMinimizedMatchesMinimizedComposition(t *testing.T) {
+	// Setup
+	systems := map[string][]*Diagram{
+		"in_out":               MustLoadDiagrams("../examples/valid/in.puml", "../examples/valid/out.puml"),
+		"client_server":        MustLoadDiagrams("../examples/valid/client.puml", "../examples/valid/server.puml"),
+		"user_vending_machine": MustLoadDiagrams("../examples/valid/user.puml", "../examples/valid/vending_machine.puml"),
+		"generated": {
+			generateDiagram(7, 2, []Event{"a", "sync", Tau}),
+			generateDiagram(5, 2, []Event{"sync", "b"}),
+			generateDiagram(6, 3, []Event{"c", "sync", "d"}),
+		},
+	}
+
+	for name, diagrams := range systems {
+		t.Run(name, func(t *testing.T) {
+			sync := []Event{"sync"}
+			if name != "generated" {
+				sync = nil
+				for _, event := range CommonEvents(diagrams) {
+					sync = append(sync, Event(event))
+				}
+			}
+			want := mustMinimizeComposition(t, diagrams, sync, nil)
+
+			// Execute
+			got, steps, err := ComposeMinimized(diagrams, sync, nil)
+
+			// Assert
+			if err != nil {
+				t.Fatal(err)
+			}
+			assertEquivalent(t, want, got)
+			if len(steps) != 2*len(diagrams)-1 {
+				t.Errorf("got %d steps, want %d", len(steps), 2*len(diagrams)-1)
+			}
+			if last := steps[len(steps)-1]; last.Minimized != len(got.States) || len(last.Components) != len(diagrams) {
+				t.Errorf("last step = %+v, want all components and %d states", last, len(got.States))
+			}
+		})
+	}
+}
+
+func TestComposeMinimizedReportsSteps(t *testing.T) {
+	// Setup
+	diagrams := MustLoadDiagrams("../examples/valid/in.puml", "../examples/valid/out.puml")
+	want := []CompositionStep{
+		{Components: []int{0}, States: 3, Minimized: 2},
+		{Components: []int{1}, States: 3, Minimized: 2},
+		{Components: []int{0, 1}, States: 2, Minimized: 2},
+	}
+
+	// Execute
+	_, steps, err := ComposeMinimized(diagrams, []Event{"sync"}, nil)
+
+	// Assert
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(want, steps); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestComposeMinimizedMatchesMinimizedCompositionOnRandomDiagrams(t *testing.T) {
+	rapid.Check(t, func(t *rapid.T) {
+		events := []Event{"a", "b", "c", "sync", Tau}
+		diagrams := make([]*Diagram, rapid.IntRange(1, 4).Draw(t, "components"))
+		for i := range diagrams {
+			n := rapid.IntRange(1, 4).Draw(t, "states")
+			d := &Diagram{States: make(map[StateID]State, n), StartEdge: StartEdge{Dst: "s0"}}
+			for j := 0; j < n; j++ {
+				id := StateID(fmt.Sprintf("s%d", j))
+				d.States[id] = State{ID: id, Name: string(id)}
+			}
+			for e := rapid.IntRange(0, 6).Draw(t, "edges"); e > 0; e-- {
+				d.Edges = append(d.Edges, Edge{
+					Src:   StateID(fmt.Sprintf("s%d", rapid.IntRange(0, n-1).Draw(t, "src"))),
+					Dst:   StateID(fmt.Sprintf("s%d", rapid.IntRange(0, n-1).Draw(t, "dst"))),
+					Event: rapid.SampledFrom(events).Draw(t, "event"),
+				})
+			}
+			diagrams[i] = d
+		}
+		sync := rapid.SliceOfDistinct(rapid.SampledFrom(events[:4]), func(e Event) Event { return e }).Draw(t, "sync")
+		visible := rapid.SliceOfDistinct(rapid.SampledFrom(events[:3]), func(e Event) Event { return e }).Draw(t, "visible")
+
+		want := mustMinimizeComposition(t, diagrams, sync, visible)
+		got, _, err := ComposeMinimized(diagrams, sync, visible)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assertEquivalent(t, want, got)
+	})
+}
+
+// tester is what the helpers need of *testing.T and *rapid.T.
+type tester interface {
+	Helper()
+	Fatal(args ...any)
+	Fatalf(format string, args ...any)
+	Errorf(format string, args ...any)
+}
+
+// mustMinimizeComposition composes diagrams at once, hides the events neither
+// in sync nor in visible, and minimizes, checking that minimization keeps the
+// deadlock and livelock verdicts.
+func mustMinimizeComposition(t tester, diagrams []*Diagram, sync, visible []Event) *Diagram {
+	t.Helper()
+	composite, err := ComposeParallel(diagrams, sync)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keep := make(map[Event]bool)
+	for _, event := range append(sync, visible...) {
+		keep[event] = true
+	}
+	var hidden []Event
+	for _, event := range AllEvents(diagrams) {
+		if !keep[Event(event)] {
+			hidden = append(hidden, Event(event))
+		}
+	}
+	hiddenComposite := Hide(composite, hidden)
+	minimized, err := Minimize(hiddenComposite)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Minimization keeps the verdicts.
+	_, wantDeadlockFree := CheckDeadlockFree(hiddenComposite)
+	_, wantLivelockFree := CheckLivelockFree(hiddenComposite)
+	_, gotDeadlockFree := CheckDeadlockFree(minimized)
+	_, gotLivelockFree := CheckLivelockFree(minimized)
+	if gotDeadlockFree != wantDeadlockFree || gotLivelockFree != wantLivelockFree {
+		t.Fatalf("Minimize() changed the verdicts (deadlock free, livelock free) from (%v, %v) to (%v, %v)",
+			wantDeadlockFree, wantLivelockFree, gotDeadlockFree, gotLivelockFree)
+	}
+	return minimized
+}
+
+// assertEquivalent checks that two minimized diagrams have the same shape: as
+// minimal quotients of equivalent diagrams, they are isomorphic.
+func assertEquivalent(t tester, want, got *Diagram) {
+	t.Helper()
+	if len(got.States) != len(want.States) || len(got.Edges) != len(want.Edges) {
+		t.Fatalf("got %d states and %d edges, want %d and %d", len(got.States), len(got.Edges), len(want.States), len(want.Edges))
+	}
+	events := func(d *Diagram) map[Event]int {
+		count := make(map[Event]int)
+		for _, e := range d.Edges {
+			count[e.Event]++
+		}
+		return count
+	}
+	if diff := cmp.Diff(events(want), events(got)); diff != "" {
+		t.Errorf("edges per event:\n%s", diff)
+	}
+	_, wantDeadlockFree := CheckDeadlockFree(want)
+	_, gotDeadlockFree := CheckDeadlockFree(got)
+	if gotDeadlockFree != wantDeadlockFree {
+		t.Errorf("deadlock free = %v, want %v", gotDeadlockFree, wantDeadlockFree)
+	}
+	_, wantLivelockFree := CheckLivelockFree(want)
+	_, gotLivelockFree := CheckLivelockFree(got)
+	if gotLivelockFree != wantLivelockFree {
+		t.Errorf("livelock free = %v, want %v", gotLivelockFree, wantLivelockFree)
+	}
+}
@@ -16,8 +16,13 @@ import (
 // predicates as a true-aware disjunction. The empty sink state ∅ (a trace not in
 // the diagram) is omitted from the output. End edges are not supported.
 func Normalize(d *Diagram) (*Diagram, error) {
+	return (&Explorer{}).Normalize(d)
+}
+
+// Normalize is the function Normalize run on the workers of x.
+func (x *Explorer) Normalize(d *Diagram) (_ *Diagram, err error) {
 	if d.EndEdge != nil {
-		return nil, fmt.Errorf("csdf.Normalize: end edges are not supported")
+		return nil, fmt.Errorf("csdf.Explorer.Normalize: end edges are not supported")
 	}
 
 	// Index outgoing edges by source state.
@@ -26,70 +31,93 @@ func Normalize(d *Diagram) (*Diagram, error) {
 		out[e.Src] = append(out[e.Src], e)
 	}
 
-	// Initial normal-form state: τ-closure of the start state.
-	initSet := tauClosure(map[StateID]struct{}{d.StartEdge.Dst: {}}, out)
-	initID := normalStateID(initSet)
-
-	result := &Diagram{
-		States:    make(map[StateID]State),
-		StartEdge: StartEdge{Dst: initID, Post: d.StartEdge.Post},
-		Edges:     make([]Edge, 0),
+	// Normal-form states are keyed by stateKey of their sorted members, which
+	// is also their key for the stateIDAllocator. The initial one is the
+	// τ-closure of the start state.
+	type visible struct {
+		event       Event
+		guard, post string
 	}
-	result.States[initID] = State{ID: initID, Name: normalStateName(initSet)}
+	start := setKey(tauClosure(map[StateID]struct{}{d.StartEdge.Dst: {}}, out))
+	store, err := x.newStore()
+	if err != nil {
+		return nil, fmt.Errorf("csdf.Explorer.Normalize: %w", err)
+	}
+	defer closeStore(store, &err)
+	edges, err := bfs(store, []byte(start), func() expandFunc[visible] {
+		var buf []byte
+		return func(key []byte, _ func([]byte) bool, emit func([]byte, visible)) {
+			// The state is already τ-closed (only closures are explored), so
+			// its visible outgoing edges are exactly those of its members.
+			// Group them by event.
+			byEvent := make(map[Event][]Edge)
+			for _, s := range parseStateKey(string(key)) {
+				for _, e := range out[s] {
+					if e.Event == Tau {
+						continue
+					}
+					byEvent[e.Event] = append(byEvent[e.Event], e)
+				}
+			}
 
-	marked := map[StateID]struct{}{initID: {}}
-	queue := []map[StateID]struct{}{initSet}
+			for _, ev := range sortedEvents(byEvent) {
+				contrib := byEvent[ev]
+				dstSet := make(map[StateID]struct{}, len(contrib))
+				guards := make([]string, 0, len(contrib))
+				posts := make([]string, 0, len(contrib))
+				for _, e := range contrib {
+					dstSet[e.Dst] = struct{}{}
+					guards = append(guards, e.Guard)
+					posts = append(posts, e.Post)
+				}
 
-	for len(queue) > 0 {
-		u := queue[0]
-		queue = queue[1:]
-		uID := normalStateID(u)
-
-		// u is already τ-closed (only closures are enqueued), so its visible
-		// outgoing edges are exactly those of its members. Group them by event.
-		byEvent := make(map[Event][]Edge)
-		for s := range u {
-			for _, e := range out[s] {
-				if e.Event == Tau {
-					continue
+				v := tauClosure(dstSet, out)
+				if len(v) == 0 {
+					continue // empty sink ∅: omitted
 				}
-				byEvent[e.Event] = append(byEvent[e.Event], e)
+				buf = append(buf[:0], setKey(v)...)
+				emit(buf, visible{event: ev, guard: disjoin(guards), post: disjoin(posts)})
 			}
 		}
+	}, x.workers())
+	if err != nil {
+		return nil, fmt.Errorf("csdf.Explorer.Normalize: %w", err)
+	}
+	keys := make([]string, store.len())
+	var key []byte
+	for k := range keys {
+		if key, err = store.key(key[:0], int32(k)); err != nil {
+			return nil, fmt.Errorf("csdf.Explorer.Normalize: %w", err)
+		}
+		keys[k] = string(key)
+	}
 
-		for _, ev := range sortedEvents(byEvent) {
-			contrib := byEvent[ev]
-			dstSet := make(map[StateID]struct{}, len(contrib))
-			guards := make([]string, 0, len(contrib))
-			posts := make([]string, 0, len(contrib))
-			for _, e := range contrib {
-				dstSet[e.Dst] = struct{}{}
-				guards = append(guards, e.Guard)
-				posts = append(posts, e.Post)
-			}
-
-			v := tauClosure(dstSet, out)
-			if len(v) == 0 {
-				continue // empty sink ∅: omitted
-			}
-			vID := normalStateID(v)
-			if _, ok := result.States[vID]; !ok {
-				result.States[vID] = State{ID: vID, Name: normalStateName(v)}
-			}
-			result.Edges = append(result.Edges, Edge{
-				Src:   uID,
-				Dst:   vID,
-				Event: ev,
-				Guard: disjoin(guards),
-				Post:  disjoin(posts),
-			})
-			if _, ok := marked[vID]; !ok {
-				marked[vID] = struct{}{}
-				queue = append(queue, v)
-			}
+	ids := newStateIDAllocator()
+	result := &Diagram{
+		Name:      d.Name,
+		States:    make(map[StateID]State, len(keys)),
+		StartEdge: StartEdge{Dst: StateID(start), Post: d.StartEdge.Post},
+		Edges:     make([]Edge, 0, len(edges)),
+	}
+	for _, key := range keys {
+		set := make(map[StateID]struct{})
+		for _, s := range parseStateKey(key) {
+			set[s] = struct{}{}
 		}
+		id := ids.setID(set)
+		result.States[id] = State{ID: id, Name: normalStateName(set)}
+	}
+	for _, e := range edges {
+		result.Edges = append(result.Edges, Edge{
+			Src:   StateID(keys[e.src]),
+			Dst:   StateID(keys[e.dst]),
+			Event: e.label.event,
+			Guard: e.label.guard,
+			Post:  e.label.post,
+		})
 	}
 
+	ids.assign(result)
 	sortEdges(result.Edges)
 	return result, nil
 }
@@ -142,8 +170,9 @@ func disjoin(preds []string) string {
 	return strings.Join(disjuncts, " ∨ ")
 }
 
-// normalStateID is the canonical identifier of a normal-form state: its member
-// IDs sorted and joined with "_". Same subset ⇒ same ID.
+// normalStateID is the preferred identifier of a normal-form state: its member
+// IDs sorted and joined with "_". Same subset ⇒ same ID, but different subsets
+// may collide ({a_b} and {a, b}), so Normalize numbers colliding IDs apart (see stateIDAllocator).
 func normalStateID(set map[StateID]struct{}) StateID {
 	if len(set) == 0 {
 		return "EMPTY"
@@ -151,6 +180,21 @@ func normalStateID(set map[StateID]struct{}) StateID {
 	return StateID(strings.Join(sortedMemberStrings(set), "_"))
 }
 
+// setID returns the unambiguous ID of the normal-form state set.
+func (a *stateIDAllocator) setID(set map[StateID]struct{}) StateID {
+	return a.id(setKey(set), normalStateID(set))
+}
+
+// setKey is the key of a normal-form state: stateKey of its sorted members.
+func setKey(set map[StateID]struct{}) string {
+	members := sortedMemberStrings(set)
+	ids := make([]StateID, len(members))
+	for i, m := range members {
+		ids[i] = StateID(m)
+	}
+	return stateKey(ids...)
+}
+
 // normalStateName is the human-readable label of a normal-form state, e.g.
 // "{s0, s1}", encoding the underlying source-state set.
 func normalStateName(set map[StateID]struct{}) string {
@@ -165,3 +165,34 @@ s0_s1 --> s2 : a
 		t.Error(diff)
 	}
 }
+
+func TestNormalizeKeepsCollidingStateIDsDistinct(t *testing.T) {
+	// Setup: {s0_s1} and {s0, s1} both prefer the ID s0_s1.
+	d := mustParse(t, `@startuml
+state "s0_s1" as s0_s1
+state "s0" as s0
+state "s1" as s1
+[*] --> s0_s1
+s0_s1 --> s0 : a
+s0_s1 --> s1 : a
+@enduml
+`)
+	want := `@startuml
+state "{s0, s1}" as s0_s1
+state "{s0_s1}" as s0_s1_2
+[*] --> s0_s1_2
+s0_s1_2 --> s0_s1 : a
+@enduml
+`
+
+	// Execute
+	normalized, err := Normalize(d)
+	if err != nil {
+		t.Fatalf("Normalize() error = %v", err)
+	}
+
+	// Assert
+	if diff := cmp.Diff(want, normalized.String()); diff != "" {
+		t.Error(diff)
+	}
+}
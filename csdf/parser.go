@@ -3,6 +3,8 @@ package csdf
 import (
 	"fmt"
 	"strings"
+	"unicode"
+	"unicode/utf8"
 )
 
 const (
@@ -15,6 +17,25 @@ type Parser struct {
 	pos   int
 	line  int
 	col   int
+	// origins maps input lines to the file lines they were preprocessed from;
+	// nil when the input was not preprocessed.
+	origins []SourceLine
+	// pending holds the annotations read since they were last attached to a
+	// declaration. It is a slice rather than a map so that probes (copies of the
+	// parser) cannot modify the original's annotations.
+	pending []annotation
+	// floating holds the annotations of the diagram itself: pending annotations
+	// move here when followed by a blank line.
+	floating []annotation
+	// newlines counts the line breaks skipped since the last annotation comment.
+	newlines int
+}
+
+// annotation is one "@key value" line of an annotation comment.
+type annotation struct {
+	key   string
+	value string
+	where string
 }
 
 func NewParser(input string) *Parser {
@@ -26,33 +47,53 @@ func NewParser(input string) *Parser {
 	}
 }
 
+// NewSourceParser returns a parser for preprocessed text whose error positions
+// refer to the files the lines came from. Columns refer to the line after
+// macro substitution.
+func NewSourceParser(source *Source) *Parser {
+	p := NewParser(source.Text)
+	p.origins = source.Lines
+	return p
+}
+
 func (p *Parser) Parse() (*Diagram, error) {
 	diagram := &Diagram{
 		States: make(map[StateID]State),
 		Edges:  []Edge{},
 	}
+	p.pending = nil
+	p.floating = nil
 
 	if !p.expectString("@startuml") {
-		return nil, fmt.Errorf("csdf.Parser.Parse: expected @startuml at line %d, col %d", p.line, p.col)
+		return nil, fmt.Errorf("csdf.Parser.Parse: expected @startuml at %s", p.position())
 	}
 
 	if err := p.skipInlineTrivia(); err != nil {
 		return nil, fmt.Errorf("csdf.Parser.Parse: %w", err)
 	}
 	if p.peek() == '"' {
-		if _, err := p.parseStateName(); err != nil {
+		name, err := p.parseStateName()
+		if err != nil {
+			return nil, fmt.Errorf("csdf.Parser.Parse: %w", err)
+		}
+		diagram.Name = name
+		if err := p.skipInlineTrivia(); err != nil {
 			return nil, fmt.Errorf("csdf.Parser.Parse: %w", err)
 		}
+	} else if !p.isAtEnd() && !isInlineSpaceOrNewline(p.peek()) && !p.peekString("/'") {
+		var name strings.Builder
+		for !p.isAtEnd() && !isInlineSpaceOrNewline(p.peek()) && !p.peekString("/'") {
+			name.WriteByte(p.advance())
+		}
+		diagram.Name = name.String()
 		if err := p.skipInlineTrivia(); err != nil {
 			return nil, fmt.Errorf("csdf.Parser.Parse: %w", err)
 		}
 	}
 	if !p.expectNewlines() {
-		return nil, fmt.Errorf("csdf.Parser.Parse: expected newline after @startuml at line %d, col %d", p.line, p.col)
-	}
-	if err := p.skipTrivia(); err != nil {
-		return nil, fmt.Errorf("csdf.Parser.Parse: %w", err)
+		return nil, fmt.Errorf("csdf.Parser.Parse: expected newline after @startuml at %s", p.position())
 	}
+	p.floating = append(p.floating, p.takeAnnotations()...)
 
 	// Parse all content until @enduml
 	for !p.isAtEnd() && !p.peekString("@enduml") {
@@ -60,18 +101,31 @@ func (p *Parser) Parse() (*Diagram, error) {
 			return nil, fmt.Errorf("csdf.Parser.Parse: %w", err)
 		}
 		if p.isAtEnd() || p.peekString("@enduml") {
+			// Annotations followed by @enduml describe the diagram.
+			p.floating = append(p.floating, p.takeAnnotations()...)
 			break
 		}
 		if diagram.EndEdge != nil {
-			return nil, fmt.Errorf("csdf.Parser.Parse: expected @enduml after end edge at line %d, col %d", p.line, p.col)
+			return nil, fmt.Errorf("csdf.Parser.Parse: expected @enduml after end edge at %s", p.position())
 		}
 
-		if p.peekString("state") {
+		if p.isStateDecl() {
 			state, err := p.parseState()
 			if err != nil {
 				return nil, fmt.Errorf("csdf.Parser.Parse: %w", err)
 			}
 			diagram.States[state.ID] = state
+		} else if id, ok, err := p.stateLineID(); err != nil {
+			return nil, fmt.Errorf("csdf.Parser.Parse: %w", err)
+		} else if ok {
+			state, declared := diagram.States[id]
+			if !declared {
+				return nil, fmt.Errorf("csdf.Parser.Parse: state %s is not declared at %s", id, p.position())
+			}
+			if err := p.parseStateLine(&state); err != nil {
+				return nil, fmt.Errorf("csdf.Parser.Parse: %w", err)
+			}
+			diagram.States[id] = state
 		} else if p.peekString("[*]") {
 			startEdge, err := p.parseStartEdge()
 			if err != nil {
@@ -84,7 +138,7 @@ func (p *Parser) Parse() (*Diagram, error) {
 				return nil, fmt.Errorf("csdf.Parser.Parse: %w", err)
 			}
 			if !isEdge {
-				return nil, fmt.Errorf("csdf.Parser.Parse: unexpected syntax at line %d, col %d", p.line, p.col)
+				return nil, fmt.Errorf("csdf.Parser.Parse: unexpected syntax at %s", p.position())
 			}
 
 			isEndEdge, err := p.isEndEdge()
@@ -108,96 +162,190 @@ func (p *Parser) Parse() (*Diagram, error) {
 	}
 
 	if !p.expectString("@enduml") {
-		return nil, fmt.Errorf("csdf.Parser.Parse: expected @enduml at line %d, col %d", p.line, p.col)
+		return nil, fmt.Errorf("csdf.Parser.Parse: expected @enduml at %s", p.position())
 	}
 
+	annotations, err := mergeAnnotations(nil, p.floating)
+	if err != nil {
+		return nil, fmt.Errorf("csdf.Parser.Parse: %w", err)
+	}
+	diagram.Annotations = annotations
 	return diagram, nil
 }
 
+// takeAnnotations returns and clears the pending annotations.
+func (p *Parser) takeAnnotations() []annotation {
+	taken := p.pending
+	p.pending = nil
+	return taken
+}
+
+// mergeAnnotations adds annotations to into (allocated when nil). A key may
+// appear only once per diagram, state or edge.
+func mergeAnnotations(into Annotations, annotations []annotation) (Annotations, error) {
+	for _, a := range annotations {
+		if into == nil {
+			into = make(Annotations)
+		}
+		if _, ok := into[a.key]; ok {
+			return nil, fmt.Errorf("csdf.mergeAnnotations: duplicate annotation @%s at %s", a.key, a.where)
+		}
+		into[a.key] = a.value
+	}
+	return into, nil
+}
+
+// rejectAnnotations reports an error when annotations are attached to a
+// declaration that cannot carry them.
+func (p *Parser) rejectAnnotations(declaration string) error {
+	if len(p.pending) == 0 {
+		return nil
+	}
+	return fmt.Errorf("csdf.Parser.rejectAnnotations: %s cannot be annotated (annotation @%s at %s); separate the annotation with a blank line to annotate the diagram", declaration, p.pending[0].key, p.pending[0].where)
+}
+
+// ParseAll parses every "@startuml ... @enduml" block of the input in order.
+// As in PlantUML, text outside the blocks is ignored (PlantUML itself appends
+// its version after @enduml in PNG metadata). At least one block is required.
+func (p *Parser) ParseAll() ([]*Diagram, error) {
+	var diagrams []*Diagram
+	for {
+		p.skipToStartuml()
+		if p.isAtEnd() && len(diagrams) > 0 {
+			return diagrams, nil
+		}
+		diagram, err := p.Parse()
+		if err != nil {
+			return nil, fmt.Errorf("csdf.Parser.ParseAll: %w", err)
+		}
+		diagrams = append(diagrams, diagram)
+	}
+}
+
+// skipToStartuml skips lines up to the next line starting with @startuml
+// (after optional horizontal whitespace), or to the end of the input.
+func (p *Parser) skipToStartuml() {
+	for !p.isAtEnd() {
+		probe := *p
+		for probe.peek() == ' ' || probe.peek() == '\t' {
+			probe.advance()
+		}
+		if probe.peekString("@startuml") {
+			*p = probe
+			return
+		}
+		p.skipLine()
+	}
+}
+
 func (p *Parser) parseState() (State, error) {
 	if !p.expectString("state") {
-		return State{}, fmt.Errorf("csdf.Parser.parseState: expected 'state' at line %d, col %d", p.line, p.col)
+		return State{}, fmt.Errorf("csdf.Parser.parseState: expected 'state' at %s", p.position())
 	}
 	if err := p.skipInlineTrivia(); err != nil {
 		return State{}, fmt.Errorf("csdf.Parser.parseState: %w", err)
 	}
 
-	name, err := p.parseStateName()
-	if err != nil {
-		return State{}, fmt.Errorf("csdf.Parser.parseState: %w", err)
+	var state State
+	if p.peek() == '"' {
+		name, err := p.parseStateName()
+		if err != nil {
+			return State{}, fmt.Errorf("csdf.Parser.parseState: %w", err)
+		}
+		if err := p.skipInlineTrivia(); err != nil {
+			return State{}, fmt.Errorf("csdf.Parser.parseState: %w", err)
+		}
+
+		if !p.expectString("as") {
+			return State{}, fmt.Errorf("csdf.Parser.parseState: expected 'as' at %s", p.position())
+		}
+		if err := p.skipInlineTrivia(); err != nil {
+			return State{}, fmt.Errorf("csdf.Parser.parseState: %w", err)
+		}
+
+		id, err := p.parseID()
+		if err != nil {
+			return State{}, fmt.Errorf("csdf.Parser.parseState: %w", err)
+		}
+		state = State{ID: StateID(id), Name: name}
+	} else {
+		// PlantUML shorthand "state X": the ID doubles as the name.
+		id, err := p.parseID()
+		if err != nil {
+			return State{}, fmt.Errorf("csdf.Parser.parseState: expected '\"' or identifier at %s", p.position())
+		}
+		state = State{ID: StateID(id), Name: id}
 	}
+	state.Vars = []StateVar{}
+
 	if err := p.skipInlineTrivia(); err != nil {
 		return State{}, fmt.Errorf("csdf.Parser.parseState: %w", err)
 	}
-
-	if !p.expectString("as") {
-		return State{}, fmt.Errorf("csdf.Parser.parseState: expected 'as' at line %d, col %d", p.line, p.col)
-	}
-	if err := p.skipInlineTrivia(); err != nil {
+	if err := p.parseStateDecorations(&state); err != nil {
 		return State{}, fmt.Errorf("csdf.Parser.parseState: %w", err)
 	}
-
-	id, err := p.parseID()
+	annotations, err := mergeAnnotations(nil, p.takeAnnotations())
 	if err != nil {
 		return State{}, fmt.Errorf("csdf.Parser.parseState: %w", err)
 	}
-
-	state := State{
-		ID:   StateID(id),
-		Name: name,
-		Vars: []StateVar{},
+	state.Annotations = annotations
+	if !p.expectNewlines() {
+		return State{}, fmt.Errorf("csdf.Parser.parseState: expected newline after state declaration at %s", p.position())
 	}
+	return state, nil
+}
 
+// parseStateLine consumes a "stateID : text" line into state, whose ID it
+// must start with. text is a variable declaration when it reads as one (see
+// isVarDecl) and a PlantUML description otherwise.
+func (p *Parser) parseStateLine(state *State) error {
+	if _, err := p.parseID(); err != nil {
+		return fmt.Errorf("csdf.Parser.parseStateLine: %w", err)
+	}
 	if err := p.skipInlineTrivia(); err != nil {
-		return State{}, fmt.Errorf("csdf.Parser.parseState: %w", err)
+		return fmt.Errorf("csdf.Parser.parseStateLine: %w", err)
 	}
-	if !p.expectNewlines() {
-		return State{}, fmt.Errorf("csdf.Parser.parseState: expected newline after state declaration at line %d, col %d", p.line, p.col)
+	if !p.expectChar(':') {
+		return fmt.Errorf("csdf.Parser.parseStateLine: expected ':' after state ID at %s", p.position())
 	}
-	if err := p.skipTrivia(); err != nil {
-		return State{}, fmt.Errorf("csdf.Parser.parseState: %w", err)
+	if err := p.skipInlineTrivia(); err != nil {
+		return fmt.Errorf("csdf.Parser.parseStateLine: %w", err)
 	}
 
-	for !p.isAtEnd() {
-		isStateVar, err := p.isStateVar(state.ID)
+	isVarDecl, err := p.isVarDecl()
+	if err != nil {
+		return fmt.Errorf("csdf.Parser.parseStateLine: %w", err)
+	}
+	if !isVarDecl {
+		// Anything that is not "var [; type]" is a PlantUML description line.
+		description, err := p.parseUntilNewline()
 		if err != nil {
-			return State{}, fmt.Errorf("csdf.Parser.parseState: %w", err)
+			return fmt.Errorf("csdf.Parser.parseStateLine: %w", err)
 		}
-		if !isStateVar {
-			break
-		}
-
-		if _, err := p.parseID(); err != nil {
-			return State{}, fmt.Errorf("csdf.Parser.parseState: %w", err)
-		}
-		if err := p.skipInlineTrivia(); err != nil {
-			return State{}, fmt.Errorf("csdf.Parser.parseState: %w", err)
-		}
-		if !p.expectChar(':') {
-			return State{}, fmt.Errorf("csdf.Parser.parseState: expected ':' after state ID in variable declaration at line %d, col %d", p.line, p.col)
-		}
-		if err := p.skipInlineTrivia(); err != nil {
-			return State{}, fmt.Errorf("csdf.Parser.parseState: %w", err)
+		if description == "" {
+			return fmt.Errorf("csdf.Parser.parseStateLine: expected variable or description after ':' at %s", p.position())
 		}
+		state.Descriptions = append(state.Descriptions, description)
+	} else {
 		varName, err := p.parseID()
 		if err != nil {
-			return State{}, fmt.Errorf("csdf.Parser.parseState: %w", err)
+			return fmt.Errorf("csdf.Parser.parseStateLine: %w", err)
 		}
 		if err := p.skipInlineTrivia(); err != nil {
-			return State{}, fmt.Errorf("csdf.Parser.parseState: %w", err)
+			return fmt.Errorf("csdf.Parser.parseStateLine: %w", err)
 		}
 
 		var varType string
 		if p.expectChar(';') {
 			if err := p.skipInlineTrivia(); err != nil {
-				return State{}, fmt.Errorf("csdf.Parser.parseState: %w", err)
+				return fmt.Errorf("csdf.Parser.parseStateLine: %w", err)
 			}
 			varType, err = p.parseUntilSemicolon()
 			if err != nil {
-				return State{}, fmt.Errorf("csdf.Parser.parseState: %w", err)
+				return fmt.Errorf("csdf.Parser.parseStateLine: %w", err)
 			}
 			if p.peek() == ';' {
-				return State{}, fmt.Errorf("csdf.Parser.parseState: unexpected ';' in variable type at line %d, col %d", p.line, p.col)
+				return fmt.Errorf("csdf.Parser.parseStateLine: unexpected ';' in variable type at %s", p.position())
 			}
 		}
 
@@ -205,20 +353,59 @@ func (p *Parser) parseState() (State, error) {
 			Name: Var(varName),
 			Type: varType,
 		})
-		if !p.expectNewlines() {
-			return State{}, fmt.Errorf("csdf.Parser.parseState: expected newline after variable declaration at line %d, col %d", p.line, p.col)
+	}
+	// Annotations on variable and description lines belong to the state.
+	state.Annotations, err = mergeAnnotations(state.Annotations, p.takeAnnotations())
+	if err != nil {
+		return fmt.Errorf("csdf.Parser.parseStateLine: %w", err)
+	}
+	if !p.expectNewlines() {
+		return fmt.Errorf("csdf.Parser.parseStateLine: expected newline after variable declaration at %s", p.position())
+	}
+	return nil
+}
+
+// parseStateDecorations consumes the optional "<<stereotype>>" and "#color"
+// suffixes of a state declaration, in either order, into state.
+func (p *Parser) parseStateDecorations(state *State) error {
+	for {
+		switch {
+		case state.Stereotype == "" && p.peekString("<<"):
+			startLine := p.line
+			startCol := p.col
+			p.expectString("<<")
+			var result strings.Builder
+			for !p.isAtEnd() && p.peek() != '\n' && !p.peekString(">>") {
+				result.WriteByte(p.advance())
+			}
+			if !p.expectString(">>") {
+				return fmt.Errorf("csdf.Parser.parseStateDecorations: unterminated stereotype at %s", p.positionAt(startLine, startCol))
+			}
+			state.Stereotype = strings.TrimSpace(result.String())
+			if state.Stereotype == "" {
+				return fmt.Errorf("csdf.Parser.parseStateDecorations: empty stereotype at %s", p.positionAt(startLine, startCol))
+			}
+		case state.Color == "" && p.peek() == '#':
+			var result strings.Builder
+			for !p.isAtEnd() && !isInlineSpaceOrNewline(p.peek()) && !p.peekString("/'") {
+				result.WriteByte(p.advance())
+			}
+			if result.Len() == 1 {
+				return fmt.Errorf("csdf.Parser.parseStateDecorations: expected color after '#' at %s", p.position())
+			}
+			state.Color = result.String()
+		default:
+			return nil
 		}
-		if err := p.skipTrivia(); err != nil {
-			return State{}, fmt.Errorf("csdf.Parser.parseState: %w", err)
+		if err := p.skipInlineTrivia(); err != nil {
+			return fmt.Errorf("csdf.Parser.parseStateDecorations: %w", err)
 		}
 	}
-
-	return state, nil
 }
 
 func (p *Parser) parseStateName() (string, error) {
 	if !p.expectChar('"') {
-		return "", fmt.Errorf("csdf.Parser.parseStateName: expected '\"' at line %d, col %d", p.line, p.col)
+		return "", fmt.Errorf("csdf.Parser.parseStateName: expected '\"' at %s", p.position())
 	}
 
 	var result strings.Builder
@@ -226,7 +413,7 @@ func (p *Parser) parseStateName() (string, error) {
 		if p.peek() == '\\' {
 			p.advance()
 			if p.isAtEnd() {
-				return "", fmt.Errorf("csdf.Parser.parseStateName: unexpected end of input in string at line %d, col %d", p.line, p.col)
+				return "", fmt.Errorf("csdf.Parser.parseStateName: unexpected end of input in string at %s", p.position())
 			}
 			switch p.peek() {
 			case '\\':
@@ -244,7 +431,7 @@ func (p *Parser) parseStateName() (string, error) {
 	}
 
 	if !p.expectChar('"') {
-		return "", fmt.Errorf("csdf.Parser.parseStateName: expected closing '\"' at line %d, col %d", p.line, p.col)
+		return "", fmt.Errorf("csdf.Parser.parseStateName: expected closing '\"' at %s", p.position())
 	}
 
 	return result.String(), nil
@@ -252,14 +439,14 @@ func (p *Parser) parseStateName() (string, error) {
 
 func (p *Parser) parseStartEdge() (StartEdge, error) {
 	if !p.expectString("[*]") {
-		return StartEdge{}, fmt.Errorf("csdf.Parser.parseStartEdge: expected '[*]' at line %d, col %d", p.line, p.col)
+		return StartEdge{}, fmt.Errorf("csdf.Parser.parseStartEdge: expected '[*]' at %s", p.position())
 	}
 	if err := p.skipInlineTrivia(); err != nil {
 		return StartEdge{}, fmt.Errorf("csdf.Parser.parseStartEdge: %w", err)
 	}
 
-	if !p.expectString("-->") {
-		return StartEdge{}, fmt.Errorf("csdf.Parser.parseStartEdge: expected '-->' at line %d, col %d", p.line, p.col)
+	if _, err := p.parseArrow(); err != nil {
+		return StartEdge{}, fmt.Errorf("csdf.Parser.parseStartEdge: %w", err)
 	}
 	if err := p.skipInlineTrivia(); err != nil {
 		return StartEdge{}, fmt.Errorf("csdf.Parser.parseStartEdge: %w", err)
@@ -267,7 +454,7 @@ func (p *Parser) parseStartEdge() (StartEdge, error) {
 
 	dst, err := p.parseID()
 	if err != nil {
-		return StartEdge{}, fmt.Errorf("csdf.Parser.parseStartEdge: expected destination state ID after '-->' in start edge at line %d, col %d", p.line, p.col)
+		return StartEdge{}, fmt.Errorf("csdf.Parser.parseStartEdge: expected destination state ID after arrow in start edge at %s", p.position())
 	}
 	if err := p.skipInlineTrivia(); err != nil {
 		return StartEdge{}, fmt.Errorf("csdf.Parser.parseStartEdge: %w", err)
@@ -285,8 +472,11 @@ func (p *Parser) parseStartEdge() (StartEdge, error) {
 		}
 	}
 
+	if err := p.rejectAnnotations("the start edge"); err != nil {
+		return StartEdge{}, fmt.Errorf("csdf.Parser.parseStartEdge: %w", err)
+	}
 	if !p.expectNewlines() {
-		return StartEdge{}, fmt.Errorf("csdf.Parser.parseStartEdge: expected newline after start edge declaration at line %d, col %d", p.line, p.col)
+		return StartEdge{}, fmt.Errorf("csdf.Parser.parseStartEdge: expected newline after start edge declaration at %s", p.position())
 	}
 
 	return StartEdge{
@@ -304,8 +494,9 @@ func (p *Parser) parseEdge() (Edge, error) {
 		return Edge{}, fmt.Errorf("csdf.Parser.parseEdge: %w", err)
 	}
 
-	if !p.expectString("-->") {
-		return Edge{}, fmt.Errorf("csdf.Parser.parseEdge: expected '-->' at line %d, col %d", p.line, p.col)
+	style, err := p.parseArrow()
+	if err != nil {
+		return Edge{}, fmt.Errorf("csdf.Parser.parseEdge: %w", err)
 	}
 	if err := p.skipInlineTrivia(); err != nil {
 		return Edge{}, fmt.Errorf("csdf.Parser.parseEdge: %w", err)
@@ -320,7 +511,7 @@ func (p *Parser) parseEdge() (Edge, error) {
 	}
 
 	if !p.expectChar(':') {
-		return Edge{}, fmt.Errorf("csdf.Parser.parseEdge: expected ':' at line %d, col %d", p.line, p.col)
+		return Edge{}, fmt.Errorf("csdf.Parser.parseEdge: expected ':' at %s", p.position())
 	}
 	if err := p.skipInlineTrivia(); err != nil {
 		return Edge{}, fmt.Errorf("csdf.Parser.parseEdge: %w", err)
@@ -362,16 +553,22 @@ func (p *Parser) parseEdge() (Edge, error) {
 		}
 	}
 
+	annotations, err := mergeAnnotations(nil, p.takeAnnotations())
+	if err != nil {
+		return Edge{}, fmt.Errorf("csdf.Parser.parseEdge: %w", err)
+	}
 	if !p.expectNewlines() {
-		return Edge{}, fmt.Errorf("csdf.Parser.parseEdge: expected newline after edge declaration at line %d, col %d", p.line, p.col)
+		return Edge{}, fmt.Errorf("csdf.Parser.parseEdge: expected newline after edge declaration at %s", p.position())
 	}
 
 	return Edge{
-		Src:   StateID(src),
-		Dst:   StateID(dst),
-		Event: event,
-		Guard: guard,
-		Post:  post,
+		Src:         StateID(src),
+		Dst:         StateID(dst),
+		Event:       event,
+		Guard:       guard,
+		Post:        post,
+		Style:       style,
+		Annotations: annotations,
 	}, nil
 }
 
@@ -381,7 +578,7 @@ func (p *Parser) parseEvent() (Event, error) {
 		return "", fmt.Errorf("csdf.Parser.parseEvent: %w", err)
 	}
 	if event == "" {
-		return "", fmt.Errorf("csdf.Parser.parseEvent: expected event after ':' in edge at line %d, col %d", p.line, p.col)
+		return "", fmt.Errorf("csdf.Parser.parseEvent: expected event after ':' in edge at %s", p.position())
 	}
 	return Event(event), nil
 }
@@ -389,18 +586,87 @@ func (p *Parser) parseEvent() (Event, error) {
 func (p *Parser) parseID() (string, error) {
 	var result strings.Builder
 
-	if p.isAtEnd() || !p.isIDChar(p.peek()) {
-		return "", fmt.Errorf("csdf.Parser.parseID: expected identifier at line %d, col %d", p.line, p.col)
+	if p.isAtEnd() || !p.isIDRuneAt(p.pos) {
+		return "", fmt.Errorf("csdf.Parser.parseID: expected identifier at %s", p.position())
 	}
 
-	for !p.isAtEnd() && p.isIDChar(p.peek()) {
-		result.WriteByte(p.peek())
-		p.advance()
+	for !p.isAtEnd() && p.isIDRuneAt(p.pos) {
+		_, size := utf8.DecodeRuneInString(p.input[p.pos:])
+		for i := 0; i < size; i++ {
+			result.WriteByte(p.advance())
+		}
 	}
 
 	return result.String(), nil
 }
 
+// arrowDirections are the PlantUML arrow direction keywords, longest first so
+// that "-down->" is not read as "-d" followed by "own->".
+var arrowDirections = []string{"down", "left", "right", "up", "do", "le", "ri", "d", "l", "r", "u"}
+
+// parseArrow consumes a PlantUML transition arrow such as "-->", "->",
+// "-down->", "-[#red]->" or "-[#red]-down->" and returns the text inside its
+// brackets ("#red"), or "" when the arrow has no style. The style may come
+// before or after the direction, and dashes may separate them. Directions only
+// affect layout and are discarded.
+func (p *Parser) parseArrow() (string, error) {
+	startLine := p.line
+	startCol := p.col
+	if !p.expectChar('-') {
+		return "", fmt.Errorf("csdf.Parser.parseArrow: expected arrow at %s", p.positionAt(startLine, startCol))
+	}
+	p.skipDashes()
+
+	var style string
+	hasStyle := false
+	if p.peek() == '[' {
+		var err error
+		if style, err = p.parseArrowStyle(); err != nil {
+			return "", fmt.Errorf("csdf.Parser.parseArrow: %w", err)
+		}
+		hasStyle = true
+		p.skipDashes()
+	}
+	for _, direction := range arrowDirections {
+		if p.expectString(direction) {
+			p.skipDashes()
+			if !hasStyle && p.peek() == '[' {
+				var err error
+				if style, err = p.parseArrowStyle(); err != nil {
+					return "", fmt.Errorf("csdf.Parser.parseArrow: %w", err)
+				}
+				p.skipDashes()
+			}
+			break
+		}
+	}
+
+	if !p.expectChar('>') {
+		return "", fmt.Errorf("csdf.Parser.parseArrow: expected '>' to close arrow starting at %s", p.positionAt(startLine, startCol))
+	}
+	return style, nil
+}
+
+func (p *Parser) skipDashes() {
+	for p.peek() == '-' {
+		p.advance()
+	}
+}
+
+func (p *Parser) parseArrowStyle() (string, error) {
+	startLine := p.line
+	startCol := p.col
+	p.expectChar('[')
+	var result strings.Builder
+	for !p.isAtEnd() && p.peek() != ']' && p.peek() != '\n' {
+		result.WriteByte(p.advance())
+	}
+	if !p.expectChar(']') {
+		return "", fmt.Errorf("csdf.Parser.parseArrowStyle: unterminated arrow style at %s", p.positionAt(startLine, startCol))
+	}
+	return strings.TrimSpace(result.String()), nil
+}
+
 func (p *Parser) parseUntilSemicolon() (string, error) {
 	return p.parseUntil(';', '\n')
 }
@@ -449,8 +715,57 @@ func (p *Parser) parseUntil(stops ...byte) (string, error) {
 	return strings.TrimSpace(result.String()), nil
 }
 
-func (p *Parser) isIDChar(c byte) bool {
-	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') || c == '_' || c == '-'
+// isIDRuneAt reports whether the rune starting at byte offset pos is an ID
+// character: a Unicode letter or decimal digit, '_' or '-' (docs/SYNTAX.md).
+func (p *Parser) isIDRuneAt(pos int) bool {
+	if pos >= len(p.input) {
+		return false
+	}
+	r, _ := utf8.DecodeRuneInString(p.input[pos:])
+	return isIDRune(r)
+}
+
+// isIDRune reports whether r may appear in a state ID or variable name.
+// Combining marks are excluded because PlantUML does not accept them in
+// identifiers; write precomposed (NFC) text instead.
+func isIDRune(r rune) bool {
+	return r == '_' || r == '-' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+func isInlineSpaceOrNewline(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\r' || c == '\n'
+}
+
+// isStateDecl reports whether a state declaration starts here: the "state"
+// keyword not followed by further identifier characters (so that an edge from
+// a state with an ID such as "stateA" is not mistaken for a declaration).
+func (p *Parser) isStateDecl() bool {
+	if !p.peekString("state") {
+		return false
+	}
+	next := p.pos + len("state")
+	return !p.isIDRuneAt(next)
+}
+
+// isVarDecl reports whether the text after "ID :" is a state-variable
+// declaration (an identifier optionally followed by "; type") rather than a
+// PlantUML description line.
+func (p *Parser) isVarDecl() (bool, error) {
+	probe := *p
+	if _, err := probe.parseID(); err != nil {
+		return false, nil
+	}
+	if err := probe.skipInlineTrivia(); err != nil {
+		return false, fmt.Errorf("csdf.Parser.isVarDecl: %w", err)
+	}
+	return probe.isAtEnd() || probe.peek() == ';' || probe.peek() == '\r' || probe.peek() == '\n', nil
+}
+
+// readsAsVarDecl reports whether text, written as "stateID : text", is read as
+// a variable declaration. Such a text cannot be a description.
+func readsAsVarDecl(text string) bool {
+	isVarDecl, err := NewParser(text).isVarDecl()
+	return err == nil && isVarDecl
 }
 
 func (p *Parser) isEdge() (bool, error) {
@@ -462,7 +777,8 @@ func (p *Parser) isEdge() (bool, error) {
 	if err := probe.skipInlineTrivia(); err != nil {
 		return false, fmt.Errorf("csdf.Parser.isEdge: %w", err)
 	}
-	return probe.peekString("-->"), nil
+	_, err = probe.parseArrow()
+	return err == nil, nil
 }
 
 func (p *Parser) isEndEdge() (bool, error) {
@@ -474,7 +790,7 @@ func (p *Parser) isEndEdge() (bool, error) {
 	if err := probe.skipInlineTrivia(); err != nil {
 		return false, fmt.Errorf("csdf.Parser.isEndEdge: %w", err)
 	}
-	if !probe.expectString("-->") {
+	if _, err := probe.parseArrow(); err != nil {
 		return false, nil
 	}
 	if err := probe.skipInlineTrivia(); err != nil {
@@ -483,16 +799,33 @@ func (p *Parser) isEndEdge() (bool, error) {
 	return probe.peekString("[*]"), nil
 }
 
-func (p *Parser) isStateVar(stateID StateID) (bool, error) {
+// stateLineID returns the ID of the state a "stateID : text" line starts with,
+// and whether such a line starts here.
+func (p *Parser) stateLineID() (StateID, bool, error) {
 	probe := *p
 	id, err := probe.parseID()
-	if err != nil || StateID(id) != stateID {
-		return false, nil
+	if err != nil {
+		return "", false, nil
 	}
 	if err := probe.skipInlineTrivia(); err != nil {
-		return false, fmt.Errorf("csdf.Parser.isStateVar: %w", err)
+		return "", false, fmt.Errorf("csdf.Parser.stateLineID: %w", err)
 	}
-	return probe.peek() == ':', nil
+	return StateID(id), probe.peek() == ':', nil
+}
+
+func (p *Parser) position() string {
+	return p.positionAt(p.line, p.col)
+}
+
+func (p *Parser) positionAt(line, col int) string {
+	if line < 1 || line > len(p.origins) {
+		return fmt.Sprintf("line %d, col %d", line, col)
+	}
+	origin := p.origins[line-1]
+	if origin.File == "" {
+		return fmt.Sprintf("line %d, col %d", origin.Line, col)
+	}
+	return fmt.Sprintf("line %d, col %d of %q", origin.Line, col, origin.File)
 }
 
 func (p *Parser) peek() byte {
@@ -511,7 +844,8 @@ func (p *Parser) advance() byte {
 	if c == '\n' {
 		p.line++
 		p.col = 1
-	} else {
+	} else if utf8.RuneStart(c) {
+		// Columns count characters: UTF-8 continuation bytes do not advance.
 		p.col++
 	}
 	return c
@@ -552,6 +886,14 @@ func (p *Parser) peekString(expected string) bool {
 func (p *Parser) skipTrivia() error {
 	for {
 		for !p.isAtEnd() && (p.peek() == ' ' || p.peek() == '\t' || p.peek() == '\n' || p.peek() == '\r') {
+			if p.peek() == '\n' {
+				p.newlines++
+				if p.newlines > 1 {
+					// Annotations followed by a blank line describe the diagram
+					// rather than the next declaration.
+					p.floating = append(p.floating, p.takeAnnotations()...)
+				}
+			}
 			p.advance()
 		}
 		if p.peekString("/'") {
@@ -600,7 +942,7 @@ func (p *Parser) skipIgnoreRegion() error {
 		}
 		p.skipLine()
 	}
-	return fmt.Errorf("csdf.Parser.skipIgnoreRegion: unterminated CSDF-IGNORE region at line %d, col %d", startLine, startCol)
+	return fmt.Errorf("csdf.Parser.skipIgnoreRegion: unterminated CSDF-IGNORE region at %s", p.positionAt(startLine, startCol))
 }
 
 func (p *Parser) skipInlineTrivia() error {
@@ -621,15 +963,76 @@ func (p *Parser) skipBlockComment() error {
 	startLine := p.line
 	startCol := p.col
 	p.expectString("/'")
+	bodyStart := p.pos
 	for !p.isAtEnd() && !p.peekString("'/") {
 		p.advance()
 	}
+	body := p.input[bodyStart:p.pos]
 	if !p.expectString("'/") {
-		return fmt.Errorf("csdf.Parser.skipBlockComment: unterminated block comment at line %d, col %d", startLine, startCol)
+		return fmt.Errorf("csdf.Parser.skipBlockComment: unterminated block comment at %s", p.positionAt(startLine, startCol))
+	}
+	if err := p.readAnnotations(body, p.positionAt(startLine, startCol)); err != nil {
+		return fmt.Errorf("csdf.Parser.skipBlockComment: %w", err)
+	}
+	return nil
+}
+
+// readAnnotations appends the annotations of a block comment body to
+// p.pending. A comment with an "@key [value]" line is an annotation comment
+// (docs/SYNTAX.md, "Annotations"), and each of its non-blank lines must be
+// one. Comments without such a line, even ones starting with '@' such as
+// /'@see: x'/, are plain comments and ignored.
+func (p *Parser) readAnnotations(body, where string) error {
+	var annotations []annotation
+	malformed := ""
+	for _, line := range strings.Split(body, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		key, value, ok := parseAnnotation(line)
+		if !ok {
+			if malformed == "" {
+				malformed = line
+			}
+			continue
+		}
+		annotations = append(annotations, annotation{key: key, value: value, where: where})
+	}
+	if len(annotations) == 0 {
+		return nil
+	}
+	if malformed != "" {
+		return fmt.Errorf("csdf.Parser.readAnnotations: expected '@key [value]' in annotation comment at %s, got %q", where, malformed)
 	}
+	p.pending = append(p.pending, annotations...)
+	p.newlines = 0
 	return nil
 }
 
+// parseAnnotation splits "@key value" into its key and trimmed value.
+func parseAnnotation(line string) (string, string, bool) {
+	if !strings.HasPrefix(line, "@") {
+		return "", "", false
+	}
+	rest := line[1:]
+	end := strings.IndexFunc(rest, func(r rune) bool { return !isAnnotationKeyRune(r) })
+	if end < 0 {
+		end = len(rest)
+	}
+	if end == 0 {
+		return "", "", false
+	}
+	if end < len(rest) && rest[end] != ' ' && rest[end] != '\t' {
+		return "", "", false
+	}
+	return rest[:end], strings.TrimSpace(rest[end:]), true
+}
+
+func isAnnotationKeyRune(r rune) bool {
+	return isIDRune(r) || r == '.'
+}
+
 func (p *Parser) expectNewlines() bool {
 	count := 0
 	for !p.isAtEnd() && (p.peek() == '\n' || p.peek() == '\r') {
@@ -659,15 +1062,15 @@ func (p *Parser) parseEndEdge() (EndEdge, error) {
 		return EndEdge{}, fmt.Errorf("csdf.Parser.parseEndEdge: %w", err)
 	}
 
-	if !p.expectString("-->") {
-		return EndEdge{}, fmt.Errorf("csdf.Parser.parseEndEdge: expected '-->' at line %d, col %d", p.line, p.col)
+	if _, err := p.parseArrow(); err != nil {
+		return EndEdge{}, fmt.Errorf("csdf.Parser.parseEndEdge: %w", err)
 	}
 	if err := p.skipInlineTrivia(); err != nil {
 		return EndEdge{}, fmt.Errorf("csdf.Parser.parseEndEdge: %w", err)
 	}
 
 	if !p.expectString("[*]") {
-		return EndEdge{}, fmt.Errorf("csdf.Parser.parseEndEdge: expected '[*]' at line %d, col %d", p.line, p.col)
+		return EndEdge{}, fmt.Errorf("csdf.Parser.parseEndEdge: expected '[*]' at %s", p.position())
 	}
 	if err := p.skipInlineTrivia(); err != nil {
 		return EndEdge{}, fmt.Errorf("csdf.Parser.parseEndEdge: %w", err)
@@ -683,12 +1086,15 @@ func (p *Parser) parseEndEdge() (EndEdge, error) {
 			return EndEdge{}, fmt.Errorf("csdf.Parser.parseEndEdge: %w", err)
 		}
 		if p.peek() == ';' {
-			return EndEdge{}, fmt.Errorf("csdf.Parser.parseEndEdge: unexpected ';' in end edge guard at line %d, col %d", p.line, p.col)
+			return EndEdge{}, fmt.Errorf("csdf.Parser.parseEndEdge: unexpected ';' in end edge guard at %s", p.position())
 		}
 	}
 
+	if err := p.rejectAnnotations("the end edge"); err != nil {
+		return EndEdge{}, fmt.Errorf("csdf.Parser.parseEndEdge: %w", err)
+	}
 	if !p.expectNewlines() {
-		return EndEdge{}, fmt.Errorf("csdf.Parser.parseEndEdge: expected newline after end edge declaration at line %d, col %d", p.line, p.col)
+		return EndEdge{}, fmt.Errorf("csdf.Parser.parseEndEdge: expected newline after end edge declaration at %s", p.position())
 	}
 
 	return EndEdge{
@@ -5,6 +5,8 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+
+	"github.com/google/go-cmp/cmp"
 )
 
 func TestParseValidExamples(t *testing.T) {
@@ -571,3 +573,437 @@ s0: ready ; bool ; extra
 
 	// Teardown: no resources to release.
 }
+
+func TestParsePlantUMLArrowVariants(t *testing.T) {
+	tests := []struct {
+		name      string
+		arrow     string
+		wantStyle string
+	}{
+		{name: "single dash", arrow: "->"},
+		{name: "double dash", arrow: "-->"},
+		{name: "long dash", arrow: "--->"},
+		{name: "direction", arrow: "-down->"},
+		{name: "short direction", arrow: "-l->"},
+		{name: "color", arrow: "-[#red]->", wantStyle: "#red"},
+		{name: "color and direction", arrow: "-[#red,dashed]up->", wantStyle: "#red,dashed"},
+		{name: "direction and color", arrow: "-right[bold]->", wantStyle: "bold"},
+		{name: "color, dash and direction", arrow: "-[#red]-down->", wantStyle: "#red"},
+		{name: "direction, dash and color", arrow: "-down-[#red]->", wantStyle: "#red"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Setup
+			parser := NewParser(`@startuml
+state "Initial" as s0
+state "Done" as s1
+[*] ` + tt.arrow + ` s0
+s0 ` + tt.arrow + ` s1 : finish
+s1 ` + tt.arrow + ` [*]
+@enduml
+`)
+
+			// Execute
+			diagram, err := parser.Parse()
+
+			// Assert
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if diagram.StartEdge.Dst != "s0" {
+				t.Errorf("Parse() start edge dst = %q, want s0", diagram.StartEdge.Dst)
+			}
+			if len(diagram.Edges) != 1 {
+				t.Fatalf("Parse() edges = %#v, want one edge", diagram.Edges)
+			}
+			want := Edge{Src: "s0", Dst: "s1", Event: "finish", Guard: True, Post: True, Style: tt.wantStyle}
+			if diff := cmp.Diff(want, diagram.Edges[0]); diff != "" {
+				t.Errorf("Parse() edge mismatch (-want +got):\n%s", diff)
+			}
+			if diagram.EndEdge == nil || diagram.EndEdge.Src != "s1" {
+				t.Errorf("Parse() end edge = %#v, want src s1", diagram.EndEdge)
+			}
+
+			// Teardown: no resources to release.
+		})
+	}
+}
+
+func TestParseRejectsMalformedArrows(t *testing.T) {
+	tests := []struct {
+		name  string
+		arrow string
+	}{
+		{name: "missing head", arrow: "--"},
+		{name: "unknown direction", arrow: "-sideways->"},
+		{name: "unterminated style", arrow: "-[#red->"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Setup
+			parser := NewParser(`@startuml
+state "Initial" as s0
+[*] --> s0
+s0 ` + tt.arrow + ` s0 : retry
+@enduml
+`)
+
+			// Execute
+			diagram, err := parser.Parse()
+
+			// Assert
+			if err == nil {
+				t.Fatal("Parse() error = nil, want malformed arrow rejection")
+			}
+			if diagram != nil {
+				t.Errorf("Parse() diagram = %#v, want nil", diagram)
+			}
+
+			// Teardown: no resources to release.
+		})
+	}
+}
+
+func TestParseStateMetadata(t *testing.T) {
+	// Setup
+	parser := NewParser(`@startuml
+state "Idle" as idle <<waiting>> #pink
+idle: coins ; int
+idle : Waiting for a coin
+idle: ready
+state busy #lightblue <<service>>
+state stateA
+[*] --> idle
+stateA --> idle : reset
+@enduml
+`)
+
+	// Execute
+	diagram, err := parser.Parse()
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	idle := diagram.States["idle"]
+	if idle.Name != "Idle" || idle.Color != "#pink" || idle.Stereotype != "waiting" {
+		t.Errorf("Parse() idle = %#v", idle)
+	}
+	wantVars := []StateVar{{Name: "coins", Type: "int"}, {Name: "ready"}}
+	if len(idle.Vars) != len(wantVars) {
+		t.Fatalf("Parse() vars = %#v, want %#v", idle.Vars, wantVars)
+	}
+	for i, want := range wantVars {
+		if idle.Vars[i] != want {
+			t.Errorf("Parse() vars[%d] = %#v, want %#v", i, idle.Vars[i], want)
+		}
+	}
+	if len(idle.Descriptions) != 1 || idle.Descriptions[0] != "Waiting for a coin" {
+		t.Errorf("Parse() descriptions = %#v, want [Waiting for a coin]", idle.Descriptions)
+	}
+	busy := diagram.States["busy"]
+	if busy.Name != "busy" || busy.Color != "#lightblue" || busy.Stereotype != "service" {
+		t.Errorf("Parse() busy = %#v", busy)
+	}
+	if diagram.States["stateA"].Name != "stateA" {
+		t.Errorf("Parse() stateA = %#v", diagram.States["stateA"])
+	}
+	if len(diagram.Edges) != 1 || diagram.Edges[0].Src != "stateA" {
+		t.Errorf("Parse() edges = %#v, want one edge from stateA", diagram.Edges)
+	}
+
+	// Teardown: no resources to release.
+}
+
+func TestParseStateLinesAfterOtherDeclarations(t *testing.T) {
+	// Setup: description and variable lines of idle after other declarations.
+	parser := NewParser(`@startuml
+state "Idle" as idle
+state "Busy" as busy
+idle : Waiting for a coin
+[*] --> idle
+idle --> busy : coin
+idle: coins ; int
+busy : Brewing tea
+@enduml
+`)
+	want := map[StateID]State{
+		"idle": {ID: "idle", Name: "Idle", Vars: []StateVar{{Name: "coins", Type: "int"}}, Descriptions: []string{"Waiting for a coin"}},
+		"busy": {ID: "busy", Name: "Busy", Vars: []StateVar{}, Descriptions: []string{"Brewing tea"}},
+	}
+
+	// Execute
+	diagram, err := parser.Parse()
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if diff := cmp.Diff(want, diagram.States); diff != "" {
+		t.Error(diff)
+	}
+
+	// Teardown: no resources to release.
+}
+
+func TestParseRejectsLinesOfUndeclaredStates(t *testing.T) {
+	// Setup
+	parser := NewParser(`@startuml
+state "Idle" as idle
+[*] --> idle
+busy : Brewing tea
+@enduml
+`)
+
+	// Execute
+	_, err := parser.Parse()
+
+	// Assert
+	if err == nil || !strings.Contains(err.Error(), "state busy is not declared at line 4, col 1") {
+		t.Errorf("Parse() error = %v, want state busy is not declared", err)
+	}
+
+	// Teardown: no resources to release.
+}
+
+func TestParseAllParsesEveryBlock(t *testing.T) {
+	// Setup
+	parser := NewParser(`' leading comment
+@startuml first
+state "A" as a
+[*] --> a
+@enduml
+text between blocks is ignored, as in PlantUML
+@startuml "second diagram"
+state "B" as b
+[*] --> b
+@enduml
+1.2026.2
+`)
+
+	// Execute
+	diagrams, err := parser.ParseAll()
+
+	// Assert
+	if err != nil {
+		t.Fatalf("ParseAll() error = %v", err)
+	}
+	if len(diagrams) != 2 {
+		t.Fatalf("ParseAll() diagrams = %d, want 2", len(diagrams))
+	}
+	if diagrams[0].Name != "first" || diagrams[0].StartEdge.Dst != "a" {
+		t.Errorf("ParseAll() first = %#v", diagrams[0])
+	}
+	if diagrams[1].Name != "second diagram" || diagrams[1].StartEdge.Dst != "b" {
+		t.Errorf("ParseAll() second = %#v", diagrams[1])
+	}
+
+	// Teardown: no resources to release.
+}
+
+func TestParseAllRejectsInputWithoutDiagram(t *testing.T) {
+	// Setup
+	parser := NewParser("no diagram here\n")
+
+	// Execute
+	diagrams, err := parser.ParseAll()
+
+	// Assert
+	if err == nil {
+		t.Fatalf("ParseAll() error = nil, want missing @startuml error; diagrams = %#v", diagrams)
+	}
+
+	// Teardown: no resources to release.
+}
+
+func TestParseUnicodeIdentifiers(t *testing.T) {
+	// Setup
+	input := `@startuml
+state "Ausführung" as ausführung_1
+state "注文受付" as 受付
+受付: 数量 ; int
+[*] --> 受付 : / 数量 = 0
+受付 --> ausführung_1 : 注文 [数量 > 0]
+@enduml
+`
+
+	// Execute
+	diagram, err := NewParser(input).Parse()
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if got := diagram.States["受付"].Vars; len(got) != 1 || got[0] != (StateVar{Name: "数量", Type: "int"}) {
+		t.Errorf("Parse() vars = %#v, want [数量 ; int]", got)
+	}
+	if diff := cmp.Diff(input, diagram.String()); diff != "" {
+		t.Error(diff)
+	}
+
+	// Teardown: no resources to release.
+}
+
+func TestParseRejectsNonIdentifierRunes(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		wantErr string
+	}{
+		{
+			name:    "combining mark",
+			input:   "@startuml\nstate \"E\" as é\n@enduml\n",
+			wantErr: "at line 2, col 15",
+		},
+		{
+			name:    "symbol",
+			input:   "@startuml\nstate \"注文\" as 注文→\n@enduml\n",
+			wantErr: "at line 2, col 17",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Execute
+			_, err := NewParser(tt.input).Parse()
+
+			// Assert
+			if err == nil {
+				t.Fatal("Parse() error = nil, want error")
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Parse() error = %q, want %q", err, tt.wantErr)
+			}
+
+			// Teardown: no resources to release.
+		})
+	}
+}
+
+func TestParseAnnotations(t *testing.T) {
+	// Setup
+	parser := NewParser(`@startuml order /'@version 2'/
+/'@owner team-a'/
+
+/'@progress'/
+state "Idle" as idle
+idle: count ; int /'@unit items'/
+state "Busy" as busy /'@owner team-b'/
+[*] --> idle
+/'
+  @formal count > 0
+  @ticket ORD-12
+'/
+idle --> busy : start /'@note comment text is not part of the event'/
+' plain comments are not annotations
+/' neither is this @one'/
+busy --> idle : stop
+@enduml
+`)
+	want := &Diagram{
+		Name: "order",
+		States: map[StateID]State{
+			"idle": {ID: "idle", Name: "Idle", Vars: []StateVar{{Name: "count", Type: "int"}}, Annotations: Annotations{"progress": "", "unit": "items"}},
+			"busy": {ID: "busy", Name: "Busy", Vars: []StateVar{}, Annotations: Annotations{"owner": "team-b"}},
+		},
+		StartEdge: StartEdge{Dst: "idle", Post: True},
+		Edges: []Edge{
+			{Src: "idle", Dst: "busy", Event: "start", Guard: True, Post: True, Annotations: Annotations{"formal": "count > 0", "ticket": "ORD-12", "note": "comment text is not part of the event"}},
+			{Src: "busy", Dst: "idle", Event: "stop", Guard: True, Post: True},
+		},
+		Annotations: Annotations{"version": "2", "owner": "team-a"},
+	}
+
+	// Execute
+	diagram, err := parser.Parse()
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if diff := cmp.Diff(want, diagram); diff != "" {
+		t.Error(diff)
+	}
+
+	// Teardown: no resources to release.
+}
+
+func TestParseIgnoresCommentsThatAreNotAnnotations(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{name: "missing key", input: "@startuml\n/'@ owner'/\nstate \"A\" as a\n[*] --> a\n@enduml\n"},
+		{name: "colon after the key", input: "@startuml\nstate \"A\" as a /'@see: x'/\n[*] --> a\n@enduml\n"},
+	}
+	want := &Diagram{
+		States:    map[StateID]State{"a": {ID: "a", Name: "A", Vars: []StateVar{}}},
+		StartEdge: StartEdge{Dst: "a", Post: True},
+		Edges:     []Edge{},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Execute
+			diagram, err := NewParser(tt.input).Parse()
+
+			// Assert
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if diff := cmp.Diff(want, diagram); diff != "" {
+				t.Error(diff)
+			}
+
+			// Teardown: no resources to release.
+		})
+	}
+}
+
+func TestParseRejectsMalformedAnnotations(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		wantErr string
+	}{
+		{
+			name:    "duplicate key",
+			input:   "@startuml\n/'@owner a'/\nstate \"A\" as a /'@owner b'/\n[*] --> a\n@enduml\n",
+			wantErr: "duplicate annotation @owner at line 3, col 16",
+		},
+		{
+			name:    "missing key",
+			input:   "@startuml\n/'@owner a\n@ team'/\nstate \"A\" as a\n[*] --> a\n@enduml\n",
+			wantErr: "expected '@key [value]' in annotation comment at line 2, col 1",
+		},
+		{
+			name:    "non-annotation line",
+			input:   "@startuml\n/'@owner a\nfree text'/\nstate \"A\" as a\n[*] --> a\n@enduml\n",
+			wantErr: "got \"free text\"",
+		},
+		{
+			name:    "start edge",
+			input:   "@startuml\nstate \"A\" as a\n/'@owner a'/\n[*] --> a\n@enduml\n",
+			wantErr: "the start edge cannot be annotated",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Execute
+			_, err := NewParser(tt.input).Parse()
+
+			// Assert
+			if err == nil {
+				t.Fatal("Parse() error = nil, want error")
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Parse() error = %q, want %q", err, tt.wantErr)
+			}
+
+			// Teardown: no resources to release.
+		})
+	}
+}
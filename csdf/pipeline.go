@@ -0,0 +1,184 @@
+package csdf
+
+import (
+	"fmt"
+	"slices"
+)
+
+// CompositionStep is a step of ComposeMinimized: the hiding and minimization
+// of one diagram, or the composition and minimization of two results of
+// earlier steps.
+type CompositionStep struct {
+	// Components are the indexes of the diagrams making up the result of the
+	// step, in increasing order.
+	Components []int
+	// States is the number of states before minimization, and Minimized after.
+	States    int
+	Minimized int
+}
+
+// ComposeMinimized composes diagrams like ComposeParallel, hiding the events
+// that are neither synchronized nor visible, and minimizing (see Minimize) on
+// the way to keep the intermediate results small. The result is equivalent to
+// the minimized composition of the diagrams with those events hidden.
+//
+// First, each diagram gets its events private to it hidden, and the edges of
+// the synchronized events that some diagram never takes dropped, since they
+// can never be taken, and is minimized. Then, until one result is left, the two
+// results whose composition has the fewest states after minimization are
+// composed and minimized. The variables are renamed as by ComposeParallel, and
+// the steps are returned in order.
+//
+// Picking the pair costs n(n-1)/2 compositions of two results in the first
+// round for n diagrams, and one per remaining result in each later round,
+// since compositions of unchanged results are reused: O(n²) in all. Each
+// composition is of two minimized results, which is cheap when the
+// minimization pays off, but for many diagrams that barely shrink, composing
+// them in order with ComposeParallel may be faster.
+//
+// Minimize merges states whatever their variables, keeping those of the
+// first member, so the states of the result may not have the variables of
+// all the component states they stand for. Use the result for the events,
+// deadlocks and τ-cycles, and ComposeParallel for the variables.
+func ComposeMinimized(diagrams []*Diagram, syncEvents, visible []Event) (*Diagram, []CompositionStep, error) {
+	return (&Explorer{}).ComposeMinimized(diagrams, syncEvents, visible)
+}
+
+// ComposeMinimized is the function ComposeMinimized run on the workers of x.
+func (x *Explorer) ComposeMinimized(diagrams []*Diagram, syncEvents, visible []Event) (*Diagram, []CompositionStep, error) {
+	if len(diagrams) < 1 {
+		return nil, nil, fmt.Errorf("csdf.Explorer.ComposeMinimized: at least one diagram is required")
+	}
+
+	keep := make(map[Event]struct{}, len(syncEvents)+len(visible))
+	for _, event := range syncEvents {
+		keep[event] = struct{}{}
+	}
+	for _, event := range visible {
+		keep[event] = struct{}{}
+	}
+	alphabets := make([]map[Event]struct{}, len(diagrams))
+	for i, d := range diagrams {
+		alphabets[i] = make(map[Event]struct{})
+		for _, e := range d.Edges {
+			alphabets[i][e.Event] = struct{}{}
+		}
+	}
+	blocked := make(map[Event]struct{})
+	for _, event := range syncEvents {
+		for _, alphabet := range alphabets {
+			if _, ok := alphabet[event]; !ok {
+				blocked[event] = struct{}{}
+			}
+		}
+	}
+
+	type result struct {
+		components []int
+		d          *Diagram
+	}
+	var steps []CompositionStep
+	results := make([]result, len(diagrams))
+	for i, d := range diagrams {
+		var hidden []Event
+		for event := range alphabets[i] {
+			if _, ok := keep[event]; !ok && event != Tau {
+				hidden = append(hidden, event)
+			}
+		}
+		d = Hide(withoutEvents(namespaced(d, fmt.Sprintf("p%d", i+1)), blocked), hidden)
+		minimized, err := Minimize(d)
+		if err != nil {
+			return nil, nil, fmt.Errorf("csdf.Explorer.ComposeMinimized: %w", err)
+		}
+		results[i] = result{components: []int{i}, d: minimized}
+		steps = append(steps, CompositionStep{Components: []int{i}, States: len(d.States), Minimized: len(minimized.States)})
+	}
+
+	// compositions caches the composition of results a and b, a < b, until
+	// either is composed.
+	type pair struct{ a, b *Diagram }
+	type composition struct {
+		step CompositionStep
+		d    *Diagram
+	}
+	compositions := make(map[pair]composition)
+	compose := func(a, b result) (composition, error) {
+		if c, ok := compositions[pair{a.d, b.d}]; ok {
+			return c, nil
+		}
+		p, err := x.explore([]*Diagram{a.d, b.d}, syncEvents, noReduction, false)
+		if err != nil {
+			return composition{}, err
+		}
+		composite := p.diagram()
+		minimized, err := Minimize(composite)
+		if err != nil {
+			return composition{}, err
+		}
+		components := slices.Concat(a.components, b.components)
+		slices.Sort(components)
+		c := composition{
+			step: CompositionStep{Components: components, States: len(composite.States), Minimized: len(minimized.States)},
+			d:    minimized,
+		}
+		compositions[pair{a.d, b.d}] = c
+		return c, nil
+	}
+	for len(results) > 1 {
+		var best composition
+		bestA, bestB := -1, -1
+		for a := range results {
+			for b := a + 1; b < len(results); b++ {
+				c, err := compose(results[a], results[b])
+				if err != nil {
+					return nil, nil, fmt.Errorf("csdf.Explorer.ComposeMinimized: %w", err)
+				}
+				if bestA < 0 || c.step.Minimized < best.step.Minimized {
+					best, bestA, bestB = c, a, b
+				}
+			}
+		}
+		for k := range compositions {
+			if k.a == results[bestA].d || k.b == results[bestA].d || k.a == results[bestB].d || k.b == results[bestB].d {
+				delete(compositions, k)
+			}
+		}
+		results[bestA] = result{components: best.step.Components, d: best.d}
+		results = slices.Delete(results, bestB, bestB+1)
+		steps = append(steps, best.step)
+	}
+	return results[0].d, steps, nil
+}
+
+// namespaced returns d with its variables renamed to namespace_<name>.
+func namespaced(d *Diagram, namespace string) *Diagram {
+	c := newComponent(d, namespace)
+	out := &Diagram{
+		Name:      d.Name,
+		States:    make(map[StateID]State, len(c.ids)),
+		StartEdge: StartEdge{Dst: d.StartEdge.Dst, Post: c.startPost},
+		EndEdge:   d.EndEdge,
+		Edges:     make([]Edge, len(d.Edges)),
+	}
+	for n, id := range c.ids {
+		out.States[id] = State{ID: id, Name: c.names[n], Vars: c.vars[n]}
+	}
+	for i, e := range d.Edges {
+		out.Edges[i] = c.edge(int32(i))
+		out.Edges[i].Src, out.Edges[i].Dst = e.Src, e.Dst
+	}
+	return out
+}
+
+// withoutEvents returns d without the edges of events.
+func withoutEvents(d *Diagram, events map[Event]struct{}) *Diagram {
+	out := *d
+	out.Edges = nil
+	for _, e := range d.Edges {
+		if _, ok := events[e.Event]; !ok {
+			out.Edges = append(out.Edges, e)
+		}
+	}
+	return &out
+}
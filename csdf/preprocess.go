@@ -0,0 +1,371 @@
+package csdf
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ReadFileFunc reads a file named by an !include directive.
+type ReadFileFunc func(path string) ([]byte, error)
+
+// ReadFileWithin returns a ReadFileFunc that reads only files inside dir,
+// where symbolic links cannot lead out of dir either. With dir "", it reads
+// no file at all, so that content of unknown origin cannot include any.
+func ReadFileWithin(dir string) ReadFileFunc {
+	return func(path string) ([]byte, error) {
+		if dir == "" {
+			return nil, errors.New("csdf.ReadFileWithin: !include is not allowed here")
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil || !filepath.IsLocal(rel) {
+			return nil, fmt.Errorf("csdf.ReadFileWithin: %q is outside %q", path, dir)
+		}
+		root, err := os.OpenRoot(dir)
+		if err != nil {
+			return nil, fmt.Errorf("csdf.ReadFileWithin: %w", err)
+		}
+		defer func() { _ = root.Close() }()
+		f, err := root.Open(rel)
+		if err != nil {
+			return nil, fmt.Errorf("csdf.ReadFileWithin: %w", err)
+		}
+		defer func() { _ = f.Close() }()
+		bs, err := io.ReadAll(f)
+		if err != nil {
+			return nil, fmt.Errorf("csdf.ReadFileWithin: %w", err)
+		}
+		return bs, nil
+	}
+}
+
+// SourceLine is the origin of one line of preprocessed text. File is "" for
+// lines of the root input and the resolved include path otherwise.
+type SourceLine struct {
+	File string
+	Line int
+}
+
+// Source is preprocessed text together with the origin of each of its lines,
+// so that parse errors can be reported against the file the line came from.
+type Source struct {
+	Text  string
+	Lines []SourceLine
+}
+
+// Preprocess expands the supported subset of the PlantUML preprocessor ahead
+// of Parser (docs/SYNTAX.md, "Preprocessor"):
+//
+//   - "!include path" inlines another file, resolved relative to the directory
+//     of the including file (path "" means the working directory). The
+//     @startuml/@enduml lines of an included file are dropped. Include cycles
+//     are rejected.
+//   - "!define NAME [value]" and "!undef NAME" manage macros substituted for
+//     whole-word occurrences of NAME in subsequent lines.
+//   - "!$name = value" (and "?=", assigning only when undefined) manages
+//     variables substituted for occurrences of $name. Quoted values are unquoted.
+//   - "!ifdef NAME", "!ifndef NAME", "!else" and "!endif" select lines; NAME may
+//     be a macro or a $variable.
+//
+// Substitution is a single pass: substituted text is not expanded again. Like
+// the PlantUML preprocessor, it is textual and also applies inside quoted
+// strings and comments. Lines
+// inside CSDF-IGNORE regions are passed through verbatim, and any other
+// directive is an error.
+func Preprocess(input, path string, readFile ReadFileFunc) (*Source, error) {
+	pp := &preprocessor{
+		readFile: readFile,
+		defines:  make(map[string]string),
+		vars:     make(map[string]string),
+	}
+	if err := pp.run(input, path, "", nil); err != nil {
+		return nil, fmt.Errorf("csdf.Preprocess: %w", err)
+	}
+	return &Source{Text: pp.out.String(), Lines: pp.lines}, nil
+}
+
+type preprocessor struct {
+	readFile ReadFileFunc
+	defines  map[string]string
+	vars     map[string]string
+	out      strings.Builder
+	lines    []SourceLine
+}
+
+type condFrame struct {
+	active       bool // lines in the current branch are emitted
+	parentActive bool // the enclosing branch is emitted
+	seenElse     bool
+}
+
+// run preprocesses text read from path. label is the SourceLine.File recorded
+// for its lines and chain is the include chain leading to it.
+func (pp *preprocessor) run(text, path, label string, chain []string) error {
+	chain = append(chain, filepath.Clean(path))
+	included := len(chain) > 1
+	var conds []condFrame
+	inIgnore := false
+
+	rawLines := strings.Split(text, "\n")
+	if rawLines[len(rawLines)-1] == "" {
+		rawLines = rawLines[:len(rawLines)-1]
+	}
+	for i, raw := range rawLines {
+		lineNo := i + 1
+		where := positionOf(label, lineNo)
+		trimmed := strings.TrimSpace(raw)
+		active := len(conds) == 0 || conds[len(conds)-1].active
+
+		if inIgnore {
+			if active {
+				pp.emit(raw, label, lineNo)
+			}
+			if isMarkerComment(trimmed, ignoreEndMarker) {
+				inIgnore = false
+			}
+			continue
+		}
+		if isMarkerComment(trimmed, ignoreBeginMarker) {
+			if active {
+				pp.emit(raw, label, lineNo)
+			}
+			inIgnore = true
+			continue
+		}
+
+		if !strings.HasPrefix(trimmed, "!") {
+			if !active {
+				continue
+			}
+			if included && (strings.HasPrefix(trimmed, "@startuml") || strings.HasPrefix(trimmed, "@enduml")) {
+				continue
+			}
+			pp.emit(pp.substitute(raw), label, lineNo)
+			continue
+		}
+
+		directive, arg := splitDirective(trimmed)
+		switch directive {
+		case "!ifdef", "!ifndef":
+			if arg == "" {
+				return fmt.Errorf("csdf.preprocessor.run: %s requires a name at %s", directive, where)
+			}
+			defined := pp.isDefined(arg)
+			if directive == "!ifndef" {
+				defined = !defined
+			}
+			conds = append(conds, condFrame{active: active && defined, parentActive: active})
+			continue
+		case "!else":
+			if len(conds) == 0 {
+				return fmt.Errorf("csdf.preprocessor.run: !else without !ifdef at %s", where)
+			}
+			top := &conds[len(conds)-1]
+			if top.seenElse {
+				return fmt.Errorf("csdf.preprocessor.run: duplicate !else at %s", where)
+			}
+			top.seenElse = true
+			top.active = top.parentActive && !top.active
+			continue
+		case "!endif":
+			if len(conds) == 0 {
+				return fmt.Errorf("csdf.preprocessor.run: !endif without !ifdef at %s", where)
+			}
+			conds = conds[:len(conds)-1]
+			continue
+		}
+		if !active {
+			continue
+		}
+
+		switch {
+		case directive == "!include":
+			if err := pp.include(arg, path, where, chain); err != nil {
+				return fmt.Errorf("csdf.preprocessor.run: %w", err)
+			}
+		case directive == "!define":
+			name, value, _ := strings.Cut(arg, " ")
+			if !isMacroName(name) {
+				return fmt.Errorf("csdf.preprocessor.run: invalid macro name %q at %s", name, where)
+			}
+			pp.defines[name] = strings.TrimSpace(value)
+		case directive == "!undef":
+			delete(pp.defines, arg)
+			delete(pp.vars, strings.TrimPrefix(arg, "$"))
+		case strings.HasPrefix(directive, "!$"):
+			if err := pp.assign(strings.TrimPrefix(trimmed, "!$"), where); err != nil {
+				return fmt.Errorf("csdf.preprocessor.run: %w", err)
+			}
+		default:
+			return fmt.Errorf("csdf.preprocessor.run: unsupported preprocessor directive %q at %s", directive, where)
+		}
+	}
+	if inIgnore {
+		// Leave the unterminated region for the parser to report.
+		return nil
+	}
+	if len(conds) > 0 {
+		return fmt.Errorf("csdf.preprocessor.run: missing !endif at end of %s", describeFile(label))
+	}
+	return nil
+}
+
+func (pp *preprocessor) emit(line, label string, lineNo int) {
+	pp.out.WriteString(line)
+	pp.out.WriteByte('\n')
+	pp.lines = append(pp.lines, SourceLine{File: label, Line: lineNo})
+}
+
+func (pp *preprocessor) include(target, from, where string, chain []string) error {
+	if target == "" {
+		return fmt.Errorf("csdf.preprocessor.include: !include requires a path at %s", where)
+	}
+	if strings.HasPrefix(target, "<") || strings.Contains(target, "://") {
+		return fmt.Errorf("csdf.preprocessor.include: only relative or absolute file paths can be included at %s: %q", where, target)
+	}
+	resolved := target
+	if !filepath.IsAbs(target) {
+		resolved = filepath.Join(filepath.Dir(from), target)
+	}
+	for _, ancestor := range chain {
+		if ancestor == filepath.Clean(resolved) {
+			cycle := append(append([]string{}, chain...), filepath.Clean(resolved))
+			return fmt.Errorf("csdf.preprocessor.include: include cycle at %s: %s", where, strings.Join(cycle, " -> "))
+		}
+	}
+	bs, err := pp.readFile(resolved)
+	if err != nil {
+		return fmt.Errorf("csdf.preprocessor.include: cannot include %q at %s: %w", target, where, err)
+	}
+	if err := pp.run(string(bs), resolved, resolved, chain); err != nil {
+		return fmt.Errorf("csdf.preprocessor.include: %w", err)
+	}
+	return nil
+}
+
+// assign handles "name = value" and "name ?= value" (the text after "!$").
+func (pp *preprocessor) assign(text, where string) error {
+	name, value, ok := strings.Cut(text, "=")
+	if !ok {
+		return fmt.Errorf("csdf.preprocessor.assign: expected '=' in variable assignment at %s", where)
+	}
+	name = strings.TrimSpace(name)
+	onlyIfUndefined := strings.HasSuffix(name, "?")
+	name = strings.TrimSpace(strings.TrimSuffix(name, "?"))
+	if !isMacroName(name) {
+		return fmt.Errorf("csdf.preprocessor.assign: invalid variable name %q at %s", "$"+name, where)
+	}
+	if _, defined := pp.vars[name]; onlyIfUndefined && defined {
+		return nil
+	}
+	pp.vars[name] = unquote(strings.TrimSpace(value))
+	return nil
+}
+
+func (pp *preprocessor) isDefined(name string) bool {
+	if strings.HasPrefix(name, "$") {
+		_, ok := pp.vars[name[1:]]
+		return ok
+	}
+	_, ok := pp.defines[name]
+	return ok
+}
+
+// substitute replaces whole-word macro names and $variables in line.
+func (pp *preprocessor) substitute(line string) string {
+	if len(pp.defines) == 0 && len(pp.vars) == 0 {
+		return line
+	}
+	var sb strings.Builder
+	for i := 0; i < len(line); {
+		c := line[i]
+		if !isMacroChar(c) && c != '$' {
+			sb.WriteByte(c)
+			i++
+			continue
+		}
+		// Identifier characters following another identifier character are
+		// not the start of a word.
+		if i > 0 && isMacroChar(line[i-1]) {
+			sb.WriteByte(c)
+			i++
+			continue
+		}
+		start := i
+		if c == '$' {
+			i++
+		}
+		for i < len(line) && isMacroChar(line[i]) {
+			i++
+		}
+		word := line[start:i]
+		if c == '$' {
+			if value, ok := pp.vars[word[1:]]; ok && len(word) > 1 {
+				sb.WriteString(value)
+				continue
+			}
+		} else if value, ok := pp.defines[word]; ok {
+			sb.WriteString(value)
+			continue
+		}
+		sb.WriteString(word)
+	}
+	return sb.String()
+}
+
+func splitDirective(trimmed string) (string, string) {
+	if strings.HasPrefix(trimmed, "!$") {
+		return "!$", ""
+	}
+	i := strings.IndexAny(trimmed, " \t")
+	if i < 0 {
+		return trimmed, ""
+	}
+	return trimmed[:i], strings.TrimSpace(trimmed[i+1:])
+}
+
+// isMarkerComment reports whether trimmed is a line comment whose body is
+// exactly marker.
+func isMarkerComment(trimmed, marker string) bool {
+	return strings.HasPrefix(trimmed, "'") && strings.TrimSpace(trimmed[1:]) == marker
+}
+
+func isMacroName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i := 0; i < len(name); i++ {
+		if !isMacroChar(name[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func isMacroChar(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') || c == '_'
+}
+
+func unquote(value string) string {
+	if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+		return value[1 : len(value)-1]
+	}
+	return value
+}
+
+func positionOf(label string, line int) string {
+	if label == "" {
+		return fmt.Sprintf("line %d", line)
+	}
+	return fmt.Sprintf("line %d of %q", line, label)
+}
+
+func describeFile(label string) string {
+	if label == "" {
+		return "input"
+	}
+	return fmt.Sprintf("%q", label)
+}
@@ -0,0 +1,210 @@
+package csdf
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func stubReadFile(files map[string]string) ReadFileFunc {
+	return func(path string) ([]byte, error) {
+		content, ok := files[path]
+		if !ok {
+			return nil, fmt.Errorf("open %s: %w", path, os.ErrNotExist)
+		}
+		return []byte(content), nil
+	}
+}
+
+func TestPreprocessExpandsDirectives(t *testing.T) {
+	// Setup
+	files := map[string]string{
+		"specs/common/states.puml": `@startuml
+state "Idle" as IDLE
+!$busyName = "Busy"
+@enduml
+`,
+	}
+	input := `@startuml
+!define IDLE idle
+!include common/states.puml
+state "$busyName" as busy
+!ifdef IDLE
+[*] --> IDLE
+!else
+[*] --> busy
+!endif
+!ifndef $undefined
+IDLE --> busy : start
+!endif
+@enduml
+`
+	want := `@startuml
+state "Idle" as idle
+state "Busy" as busy
+[*] --> idle
+idle --> busy : start
+@enduml
+`
+	wantLines := []SourceLine{
+		{Line: 1},
+		{File: "specs/common/states.puml", Line: 2},
+		{Line: 4},
+		{Line: 6},
+		{Line: 11},
+		{Line: 13},
+	}
+
+	// Execute
+	source, err := Preprocess(input, "specs/main.puml", stubReadFile(files))
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Preprocess() error = %v", err)
+	}
+	if diff := cmp.Diff(want, source.Text); diff != "" {
+		t.Error(diff)
+	}
+	if diff := cmp.Diff(wantLines, source.Lines); diff != "" {
+		t.Error(diff)
+	}
+
+	// Teardown: no resources to release.
+}
+
+func TestPreprocessSubstitutesWholeWordsOnly(t *testing.T) {
+	// Setup
+	input := "!define s0 start\n!$v = 1\ns0 s0x xs0 $v $vv\n"
+
+	// Execute
+	source, err := Preprocess(input, "", stubReadFile(nil))
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Preprocess() error = %v", err)
+	}
+	if want := "start s0x xs0 1 $vv\n"; source.Text != want {
+		t.Errorf("Preprocess() text = %q, want %q", source.Text, want)
+	}
+
+	// Teardown: no resources to release.
+}
+
+func TestPreprocessKeepsIgnoreRegionsVerbatim(t *testing.T) {
+	// Setup
+	input := "!define X y\n' CSDF-IGNORE-BEGIN\n!theme plain\nX\n' CSDF-IGNORE-END\nX\n"
+
+	// Execute
+	source, err := Preprocess(input, "", stubReadFile(nil))
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Preprocess() error = %v", err)
+	}
+	if want := "' CSDF-IGNORE-BEGIN\n!theme plain\nX\n' CSDF-IGNORE-END\ny\n"; source.Text != want {
+		t.Errorf("Preprocess() text = %q, want %q", source.Text, want)
+	}
+
+	// Teardown: no resources to release.
+}
+
+func TestPreprocessRejects(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   map[string]string
+		path    string
+		input   string
+		wantErr string
+	}{
+		{
+			name:    "include cycle",
+			files:   map[string]string{"a.puml": "!include b.puml\n", "b.puml": "!include a.puml\n"},
+			input:   "!include a.puml\n",
+			wantErr: "include cycle at line 1 of \"b.puml\": . -> a.puml -> b.puml -> a.puml",
+		},
+		{
+			name:    "self include",
+			files:   map[string]string{"dir/main.puml": "!include main.puml\n"},
+			path:    "dir/main.puml",
+			input:   "!include main.puml\n",
+			wantErr: "include cycle",
+		},
+		{
+			name:    "missing include",
+			input:   "@startuml\n!include missing.puml\n",
+			wantErr: "cannot include \"missing.puml\" at line 2",
+		},
+		{
+			name:    "unsupported directive",
+			input:   "@startuml\n!theme plain\n",
+			wantErr: "unsupported preprocessor directive \"!theme\" at line 2",
+		},
+		{
+			name:    "standard library include",
+			input:   "!include <C4/C4_Container>\n",
+			wantErr: "only relative or absolute file paths can be included",
+		},
+		{
+			name:    "missing endif",
+			input:   "!ifdef X\n",
+			wantErr: "missing !endif",
+		},
+		{
+			name:    "endif without ifdef",
+			input:   "!endif\n",
+			wantErr: "!endif without !ifdef at line 1",
+		},
+		{
+			name:    "duplicate else",
+			input:   "!ifdef X\n!else\n!else\n!endif\n",
+			wantErr: "duplicate !else at line 3",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Execute
+			_, err := Preprocess(tt.input, tt.path, stubReadFile(tt.files))
+
+			// Assert
+			if err == nil {
+				t.Fatal("Preprocess() error = nil, want error")
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Preprocess() error = %q, want %q", err, tt.wantErr)
+			}
+
+			// Teardown: no resources to release.
+		})
+	}
+}
+
+func TestParseErrorPositionsMapToIncludedFile(t *testing.T) {
+	// Setup
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "states.puml"), []byte("state \"Idle\" as idle\nstate \"Broken\" broken\n"), 0o644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	main := filepath.Join(dir, "main.puml")
+	if err := os.WriteFile(main, []byte("@startuml\n!include states.puml\n[*] --> idle\n@enduml\n"), 0o644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	// Execute
+	_, err := LoadDiagrams([]string{main})
+
+	// Assert
+	if err == nil {
+		t.Fatal("LoadDiagrams() error = nil, want parse error")
+	}
+	want := fmt.Sprintf("expected 'as' at line 2, col 16 of %q", filepath.Join(dir, "states.puml"))
+	if !strings.Contains(err.Error(), want) {
+		t.Errorf("LoadDiagrams() error = %q, want %q", err, want)
+	}
+
+	// Teardown: t.TempDir is removed automatically.
+}
@@ -0,0 +1,318 @@
+// Package promela converts Composable State Diagrams to Promela, the modeling
+// language of the SPIN model checker.
+package promela
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Kuniwak/puml-parallel/csdf"
+)
+
+// Export writes a Promela model of the interface parallel composition of
+// diagrams over sync:
+//
+//   - every diagram becomes an active proctype Di whose states are labels and
+//     whose edges are the options of an if statement,
+//   - every synchronization event becomes a rendezvous channel: D0 sends and D1
+//     receives, so both take the step together,
+//   - other events and τ-edges are local steps,
+//   - a state without edges blocks, which SPIN reports as an invalid end state,
+//     and an end edge jumps to the end of the proctype, a valid end state.
+//
+// Rendezvous synchronizes two processes, so more than two diagrams with a
+// non-empty sync set are composed with csdf.ComposeParallel first and become a
+// single proctype D0. Guards and post-conditions are natural language and are
+// kept as comments, and a never claim placeholder shows where to put the
+// property.
+func Export(diagrams []*csdf.Diagram, sync []csdf.Event) (string, error) {
+	if len(diagrams) == 0 {
+		return "", fmt.Errorf("promela.Export: no diagrams")
+	}
+	syncSet := make(map[csdf.Event]struct{}, len(sync))
+	for _, event := range sync {
+		if event == csdf.Tau {
+			return "", fmt.Errorf("promela.Export: tau cannot be a synchronization event")
+		}
+		syncSet[event] = struct{}{}
+	}
+	if len(diagrams) > 2 && len(syncSet) > 0 {
+		composed, err := csdf.ComposeParallel(diagrams, sync)
+		if err != nil {
+			return "", fmt.Errorf("promela.Export: %w", err)
+		}
+		diagrams = []*csdf.Diagram{composed}
+	}
+
+	names := newNamer()
+	events := make([]csdf.Event, 0, len(syncSet))
+	if len(diagrams) == 2 {
+		// A single diagram has no partner to synchronize with.
+		for event := range syncSet {
+			events = append(events, event)
+		}
+	}
+	sort.Slice(events, func(i, j int) bool { return events[i] < events[j] })
+	channels := make(map[csdf.Event]string, len(events))
+	for _, event := range events {
+		channels[event] = names.name(string(event), "e")
+	}
+	processes := make([]string, len(diagrams))
+	for i := range diagrams {
+		processes[i] = names.name(fmt.Sprintf("D%d", i), "P")
+	}
+
+	var sb strings.Builder
+	if len(events) > 0 {
+		sb.WriteString("/* Synchronization events: " + processes[0] + " sends, " + processes[1] + " receives. */\n")
+		for _, event := range events {
+			note := ""
+			if channels[event] != string(event) {
+				note = " " + comment(string(event))
+			}
+			sb.WriteString("chan " + channels[event] + " = [0] of { bit };" + note + "\n")
+		}
+	}
+
+	for i, d := range diagrams {
+		operation := ""
+		if len(diagrams) == 2 {
+			operation = []string{"!", "?"}[i]
+		}
+		if len(events) > 0 || i > 0 {
+			sb.WriteString("\n")
+		}
+		writeProctype(&sb, d, processes[i], operation, channels)
+	}
+
+	sb.WriteString(`
+/*
+ * Property placeholder: replace this comment with a never claim, e.g. the
+ * output of spin -f '!([] <> p)', or with an ltl block. State labels can be
+ * referred to as in ` + processes[0] + `@label.
+ *
+ * never {
+ *     ...
+ * }
+ */
+`)
+	return sb.String(), nil
+}
+
+// writeProctype writes d as the active proctype process. operation is "!" or
+// "?" for the sender and the receiver of the rendezvous, and "" without a
+// partner.
+func writeProctype(sb *strings.Builder, d *csdf.Diagram, process, operation string, channels map[csdf.Event]string) {
+	if d.Name != "" {
+		sb.WriteString(comment(d.Name) + "\n")
+	}
+	sb.WriteString("active proctype " + process + "() {\n")
+
+	// A state only referred to by edges gets a label too.
+	seen := make(map[csdf.StateID]bool, len(d.States))
+	stateIDs := make([]csdf.StateID, 0, len(d.States))
+	add := func(id csdf.StateID) {
+		if !seen[id] {
+			seen[id] = true
+			stateIDs = append(stateIDs, id)
+		}
+	}
+	for id := range d.States {
+		add(id)
+	}
+	add(d.StartEdge.Dst)
+	for _, e := range d.Edges {
+		add(e.Src)
+		add(e.Dst)
+	}
+	if d.EndEdge != nil {
+		add(d.EndEdge.Src)
+	}
+	sort.Slice(stateIDs, func(i, j int) bool { return stateIDs[i] < stateIDs[j] })
+	labels := newNamer()
+	labels.reserve("terminated")
+	stateLabels := make(map[csdf.StateID]string, len(stateIDs))
+	for _, id := range stateIDs {
+		stateLabels[id] = labels.label(string(id))
+	}
+
+	outgoing := make(map[csdf.StateID][]csdf.Edge)
+	for _, e := range d.Edges {
+		outgoing[e.Src] = append(outgoing[e.Src], e)
+	}
+
+	sb.WriteString("    goto " + stateLabels[d.StartEdge.Dst] + ";" + conditionComment("", d.StartEdge.Post) + "\n")
+	for _, id := range stateIDs {
+		state, ok := d.States[id]
+		if !ok {
+			state = csdf.State{ID: id, Name: string(id)}
+		}
+		sb.WriteString(stateLabels[id] + ":")
+		var notes []string
+		if state.Name != string(id) {
+			notes = append(notes, state.Name)
+		}
+		if len(state.Vars) > 0 {
+			vars := make([]string, len(state.Vars))
+			for i, v := range state.Vars {
+				vars[i] = string(v.Name)
+			}
+			notes = append(notes, "vars: "+strings.Join(vars, ", "))
+		}
+		if len(notes) > 0 {
+			sb.WriteString(" " + comment(strings.Join(notes, "; ")))
+		}
+		sb.WriteString("\n")
+
+		var options []string
+		for _, e := range outgoing[id] {
+			step := "skip"
+			if e.Event != csdf.Tau {
+				if channel, ok := channels[e.Event]; ok {
+					step = rendezvous(channel, operation)
+				}
+			}
+			options = append(options, step+" -> goto "+stateLabels[e.Dst]+";"+edgeComment(e))
+		}
+		if d.EndEdge != nil && d.EndEdge.Src == id {
+			options = append(options, "skip -> goto terminated;"+conditionComment(d.EndEdge.Guard, ""))
+		}
+		if len(options) == 0 {
+			sb.WriteString("    false;\n")
+			continue
+		}
+		sb.WriteString("    if\n")
+		for _, option := range options {
+			sb.WriteString("    :: " + option + "\n")
+		}
+		sb.WriteString("    fi;\n")
+	}
+	if d.EndEdge != nil {
+		sb.WriteString("terminated:\n    skip\n")
+	}
+	sb.WriteString("}\n")
+}
+
+// rendezvous is the send or the receive of a synchronization event. Without a
+// partner process (a single diagram), the event is a local step.
+func rendezvous(channel, operation string) string {
+	switch operation {
+	case "!":
+		return channel + "!0"
+	case "?":
+		return channel + "?0"
+	default:
+		return "skip"
+	}
+}
+
+// edgeComment names the event of e and its non-trivial conditions.
+func edgeComment(e csdf.Edge) string {
+	text := string(e.Event)
+	if cond := conditionText(e.Guard, e.Post); cond != "" {
+		text += " " + cond
+	}
+	return " " + comment(text)
+}
+
+func conditionComment(guard, post string) string {
+	text := conditionText(guard, post)
+	if text == "" {
+		return ""
+	}
+	return " " + comment(text)
+}
+
+func conditionText(guard, post string) string {
+	var parts []string
+	if guard != "" && guard != csdf.True {
+		parts = append(parts, "["+guard+"]")
+	}
+	if post != "" && post != csdf.True {
+		parts = append(parts, "/ "+post)
+	}
+	return strings.Join(parts, " ")
+}
+
+// comment writes text as a Promela comment.
+func comment(text string) string {
+	return "/* " + strings.ReplaceAll(text, "*/", "* /") + " */"
+}
+
+// keywords are the Promela reserved words that generated identifiers must
+// avoid.
+var keywords = []string{
+	"active", "assert", "atomic", "bit", "bool", "break", "byte", "chan", "d_step",
+	"D_proctype", "do", "else", "empty", "enabled", "eval", "false", "fi", "full",
+	"get_priority", "goto", "hidden", "if", "init", "inline", "int", "len", "local",
+	"ltl", "mtype", "nempty", "never", "nfull", "notrace", "np_", "od", "of",
+	"pc_value", "pid", "print", "printf", "printm", "priority", "proctype",
+	"provided", "run", "select", "set_priority", "short", "show", "skip", "timeout",
+	"trace", "true", "typedef", "unless", "unsigned", "xr", "xs", "c_code",
+	"c_decl", "c_expr", "c_state", "c_track", "for", "in", "always", "eventually",
+	"until", "weakuntil", "stronguntil", "implies", "equivalent", "release",
+}
+
+// namer allocates distinct Promela identifiers.
+type namer struct {
+	taken map[string]struct{}
+}
+
+func newNamer() *namer {
+	n := &namer{taken: make(map[string]struct{})}
+	n.reserve(keywords...)
+	return n
+}
+
+func (n *namer) reserve(names ...string) {
+	for _, name := range names {
+		n.taken[name] = struct{}{}
+	}
+}
+
+// label is name for a state label. SPIN gives labels starting with "end",
+// "accept" and "progress" a meaning, so such states are prefixed.
+func (n *namer) label(text string) string {
+	for _, prefix := range []string{"end", "accept", "progress"} {
+		if strings.HasPrefix(text, prefix) {
+			return n.name("S_"+text, "S")
+		}
+	}
+	return n.name(text, "S")
+}
+
+// name returns an unused identifier close to text. Characters other than ASCII
+// letters, digits and '_' become '_', and prefix is prepended when the result
+// would not start with a letter.
+func (n *namer) name(text, prefix string) string {
+	var sb strings.Builder
+	for _, r := range text {
+		if isIdentRune(r) {
+			sb.WriteRune(r)
+		} else {
+			sb.WriteByte('_')
+		}
+	}
+	base := strings.TrimRight(sb.String(), "_")
+	if base == "" || !isLetter(rune(base[0])) {
+		base = prefix + "_" + base
+	}
+	candidate := base
+	for i := 2; ; i++ {
+		if _, ok := n.taken[candidate]; !ok {
+			break
+		}
+		candidate = fmt.Sprintf("%s_%d", base, i)
+	}
+	n.taken[candidate] = struct{}{}
+	return candidate
+}
+
+func isLetter(r rune) bool {
+	return (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z')
+}
+
+func isIdentRune(r rune) bool {
+	return isLetter(r) || (r >= '0' && r <= '9') || r == '_'
+}
@@ -0,0 +1,182 @@
+package promela
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Kuniwak/puml-parallel/csdf"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestExportSynchronizesOverRendezvous(t *testing.T) {
+	// Setup
+	diagrams := csdf.MustLoadDiagrams("../../examples/valid/in.puml", "../../examples/valid/out.puml")
+	want := `/* Synchronization events: D0 sends, D1 receives. */
+chan sync = [0] of { bit };
+
+active proctype D0() {
+    goto s0;
+s0:
+    if
+    :: skip -> goto s1; /* in */
+    fi;
+s1:
+    if
+    :: sync!0 -> goto s2; /* sync */
+    fi;
+s2:
+    false;
+}
+
+active proctype D1() {
+    goto s0;
+s0:
+    if
+    :: sync?0 -> goto s1; /* sync */
+    fi;
+s1:
+    if
+    :: skip -> goto s2; /* out */
+    fi;
+s2:
+    false;
+}
+
+/*
+ * Property placeholder: replace this comment with a never claim, e.g. the
+ * output of spin -f '!([] <> p)', or with an ltl block. State labels can be
+ * referred to as in D0@label.
+ *
+ * never {
+ *     ...
+ * }
+ */
+`
+
+	// Execute
+	got, err := Export(diagrams, []csdf.Event{"sync"})
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Error(diff)
+	}
+
+	// Teardown: no resources to release.
+}
+
+func TestExportKeepsConditionsAsComments(t *testing.T) {
+	// Setup: the state IDs "end" and "do" would be an end-state label and a
+	// keyword in Promela.
+	d, err := csdf.ParseDiagram([]byte(`@startuml
+state "Idle" as do
+do: count ; int
+state "end" as end
+[*] --> do : count = 0
+do --> end : stop ; count > 0 ; count' = 0 */
+do --> do : tau
+end --> [*]
+@enduml
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `active proctype D0() {
+    goto do_2; /* / count = 0 */
+do_2: /* Idle; vars: count */
+    if
+    :: skip -> goto S_end; /* stop [count > 0] / count' = 0 * / */
+    :: skip -> goto do_2; /* tau */
+    fi;
+S_end:
+    if
+    :: skip -> goto terminated;
+    fi;
+terminated:
+    skip
+}
+`
+
+	// Execute
+	got, err := Export([]*csdf.Diagram{d}, []csdf.Event{"stop"})
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+	if !strings.HasPrefix(got, want) {
+		t.Errorf("want prefix:\n%s\ngot:\n%s", want, got)
+	}
+
+	// Teardown: no resources to release.
+}
+
+func TestExportComposesMultiPartySynchronization(t *testing.T) {
+	// Setup: sync is taken by the three diagrams together.
+	diagrams := csdf.MustLoadDiagrams("../../examples/valid/in.puml", "../../examples/valid/out.puml", "../../examples/valid/in.puml")
+	want := `active proctype D0() {
+    goto s0_s0_s0;
+s0_s0_s0: /* (s0, s0, s0) */
+    if
+    :: skip -> goto s1_s0_s0; /* in */
+    :: skip -> goto s0_s0_s1; /* in */
+    fi;
+`
+
+	// Execute
+	got, err := Export(diagrams, []csdf.Event{"sync"})
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+	if !strings.HasPrefix(got, want) {
+		t.Errorf("want prefix:\n%s\ngot:\n%s", want, got)
+	}
+	if !strings.Contains(got, "s1_s0_s1: /* (s1, s0, s1) */\n    if\n    :: skip -> goto s2_s1_s2; /* sync */\n    fi;\n") {
+		t.Errorf("want the synchronized edge, got:\n%s", got)
+	}
+
+	// Teardown: no resources to release.
+}
+
+func TestExportLabelsStatesOnlyReferredToByEdges(t *testing.T) {
+	// Setup: b is not declared.
+	d, err := csdf.ParseDiagram([]byte(`@startuml
+state "A" as a
+[*] --> a
+a --> b : go
+b --> a : back
+@enduml
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `active proctype D0() {
+    goto a;
+a: /* A */
+    if
+    :: skip -> goto b; /* go */
+    fi;
+b:
+    if
+    :: skip -> goto a; /* back */
+    fi;
+}
+`
+
+	// Execute
+	got, err := Export([]*csdf.Diagram{d}, nil)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+	if !strings.HasPrefix(got, want) {
+		t.Errorf("want prefix:\n%s\ngot:\n%s", want, got)
+	}
+
+	// Teardown: no resources to release.
+}
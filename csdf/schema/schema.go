@@ -0,0 +1,373 @@
+// Package schema holds the JSON Schemas of the JSON interchange format: the
+// diagrams written by csdfparse, the compositions written by csdfparallel
+// -provenance, runtime states and the messages of the animation protocol. Validate checks documents against them.
+package schema
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+//go:embed *.schema.json
+var files embed.FS
+
+// Names are the names of the schemas, each stored as <name>.schema.json.
+var Names = []string{"diagram", "composition", "runtime-state", "proto"}
+
+// Schema returns the JSON Schema document called name.
+func Schema(name string) ([]byte, error) {
+	bs, err := files.ReadFile(fileName(name))
+	if err != nil {
+		return nil, fmt.Errorf("schema.Schema: unknown schema %q (schemas: %s)", name, strings.Join(Names, ", "))
+	}
+	return bs, nil
+}
+
+func fileName(name string) string {
+	return name + ".schema.json"
+}
+
+// Validate checks a single JSON document against the schema called name and
+// reports every violation with the JSON Pointer of the offending value.
+//
+// The validator implements the keywords the shipped schemas use: $ref (to
+// $defs and to the other schemas), type, const, enum, pattern, minimum,
+// properties, required, additionalProperties, propertyNames, items, anyOf and
+// oneOf. Other keywords, such as description, are annotations.
+func Validate(name string, document []byte) error {
+	if _, err := Schema(name); err != nil {
+		return fmt.Errorf("schema.Validate: %w", err)
+	}
+	value, err := decode(document)
+	if err != nil {
+		return fmt.Errorf("schema.Validate: %w", err)
+	}
+	v := &validator{schemas: make(map[string]any)}
+	root, err := v.load(fileName(name))
+	if err != nil {
+		return fmt.Errorf("schema.Validate: %w", err)
+	}
+	v.validate(fileName(name), root, value, "")
+	if len(v.problems) > 0 {
+		return fmt.Errorf("schema.Validate: %w", &Error{Schema: name, Problems: v.problems})
+	}
+	return nil
+}
+
+// Problem is a violation of a schema by the value at Pointer.
+type Problem struct {
+	Pointer string
+	Message string
+}
+
+func (p Problem) String() string {
+	pointer := p.Pointer
+	if pointer == "" {
+		pointer = "(root)"
+	}
+	return pointer + ": " + p.Message
+}
+
+// Error lists the problems of a document.
+type Error struct {
+	Schema   string
+	Problems []Problem
+}
+
+func (e *Error) Error() string {
+	lines := make([]string, len(e.Problems))
+	for i, p := range e.Problems {
+		lines[i] = p.String()
+	}
+	return fmt.Sprintf("the document does not match the %s schema:\n%s", e.Schema, strings.Join(lines, "\n"))
+}
+
+// decode reads a single JSON value, keeping numbers as json.Number.
+func decode(document []byte) (any, error) {
+	decoder := json.NewDecoder(bytes.NewReader(document))
+	decoder.UseNumber()
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	if _, err := decoder.Token(); !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("unexpected data after the document at offset %d", decoder.InputOffset())
+	}
+	return value, nil
+}
+
+type validator struct {
+	schemas  map[string]any
+	problems []Problem
+}
+
+func (v *validator) load(file string) (any, error) {
+	if schema, ok := v.schemas[file]; ok {
+		return schema, nil
+	}
+	bs, err := files.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("no schema %s", file)
+	}
+	schema, err := decode(bs)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	v.schemas[file] = schema
+	return schema, nil
+}
+
+// resolve finds the schema of a $ref in file: "#/$defs/x", "other.schema.json"
+// or "other.schema.json#/$defs/x".
+func (v *validator) resolve(file, ref string) (string, any, error) {
+	target, fragment, _ := strings.Cut(ref, "#")
+	if target != "" {
+		file = path.Base(target)
+	}
+	schema, err := v.load(file)
+	if err != nil {
+		return "", nil, err
+	}
+	if fragment == "" {
+		return file, schema, nil
+	}
+	for _, token := range strings.Split(strings.TrimPrefix(fragment, "/"), "/") {
+		token = strings.NewReplacer("~1", "/", "~0", "~").Replace(token)
+		object, ok := schema.(map[string]any)
+		if !ok {
+			return "", nil, fmt.Errorf("unresolvable $ref %q in %s", ref, file)
+		}
+		if schema, ok = object[token]; !ok {
+			return "", nil, fmt.Errorf("unresolvable $ref %q in %s", ref, file)
+		}
+	}
+	return file, schema, nil
+}
+
+func (v *validator) report(pointer, format string, args ...any) {
+	v.problems = append(v.problems, Problem{Pointer: pointer, Message: fmt.Sprintf(format, args...)})
+}
+
+// validate checks value at pointer against schema, a schema of file.
+func (v *validator) validate(file string, schema, value any, pointer string) {
+	switch s := schema.(type) {
+	case bool:
+		if !s {
+			v.report(pointer, "no value is allowed here")
+		}
+		return
+	case map[string]any:
+		v.validateObject(file, s, value, pointer)
+	}
+}
+
+func (v *validator) validateObject(file string, s map[string]any, value any, pointer string) {
+	if ref, ok := s["$ref"].(string); ok {
+		refFile, refSchema, err := v.resolve(file, ref)
+		if err != nil {
+			v.report(pointer, "%v", err)
+			return
+		}
+		v.validate(refFile, refSchema, value, pointer)
+	}
+	if t, ok := s["type"]; ok && !matchesType(t, value) {
+		v.report(pointer, "expected %s, got %s", describeType(t), typeOf(value))
+		return
+	}
+	if c, ok := s["const"]; ok && !reflect.DeepEqual(c, value) {
+		v.report(pointer, "expected %s, got %s", encode(c), encode(value))
+	}
+	if enum, ok := s["enum"].([]any); ok && !contains(enum, value) {
+		options := make([]string, len(enum))
+		for i, option := range enum {
+			options[i] = encode(option)
+		}
+		v.report(pointer, "expected one of %s, got %s", strings.Join(options, ", "), encode(value))
+	}
+	if text, ok := value.(string); ok {
+		if pattern, ok := s["pattern"].(string); ok {
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				v.report(pointer, "invalid pattern %q in %s", pattern, file)
+			} else if !re.MatchString(text) {
+				v.report(pointer, "%s does not match %s", encode(text), pattern)
+			}
+		}
+	}
+	if n, ok := value.(json.Number); ok {
+		if minimum, ok := s["minimum"].(json.Number); ok {
+			if x, y := n.String(), minimum.String(); compareNumbers(x, y) < 0 {
+				v.report(pointer, "%s is less than %s", x, y)
+			}
+		}
+	}
+	if object, ok := value.(map[string]any); ok {
+		v.validateProperties(file, s, object, pointer)
+	}
+	if array, ok := value.([]any); ok {
+		if items, ok := s["items"]; ok {
+			for i, item := range array {
+				v.validate(file, items, item, fmt.Sprintf("%s/%d", pointer, i))
+			}
+		}
+	}
+	if anyOf, ok := s["anyOf"].([]any); ok {
+		if v.countMatches(file, anyOf, value) == 0 {
+			v.report(pointer, "%s matches none of the %d alternatives", typeOf(value), len(anyOf))
+		}
+	}
+	if oneOf, ok := s["oneOf"].([]any); ok {
+		switch n := v.countMatches(file, oneOf, value); n {
+		case 0:
+			v.report(pointer, "%s matches none of the %d alternatives", typeOf(value), len(oneOf))
+		case 1:
+		default:
+			v.report(pointer, "%s matches %d alternatives, want exactly one", typeOf(value), n)
+		}
+	}
+}
+
+func (v *validator) validateProperties(file string, s map[string]any, object map[string]any, pointer string) {
+	if required, ok := s["required"].([]any); ok {
+		for _, name := range required {
+			if key, ok := name.(string); ok {
+				if _, ok := object[key]; !ok {
+					v.report(pointer, "missing property %q", key)
+				}
+			}
+		}
+	}
+	properties, _ := s["properties"].(map[string]any)
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		child := pointer + "/" + strings.NewReplacer("~", "~0", "/", "~1").Replace(key)
+		if names, ok := s["propertyNames"]; ok {
+			v.validate(file, names, key, child)
+		}
+		if property, ok := properties[key]; ok {
+			v.validate(file, property, object[key], child)
+			continue
+		}
+		additional, ok := s["additionalProperties"]
+		if !ok {
+			continue
+		}
+		if allowed, ok := additional.(bool); ok && !allowed {
+			v.report(pointer, "unknown property %q", key)
+			continue
+		}
+		v.validate(file, additional, object[key], child)
+	}
+}
+
+// countMatches counts the alternatives value matches.
+func (v *validator) countMatches(file string, alternatives []any, value any) int {
+	n := 0
+	for _, alternative := range alternatives {
+		branch := &validator{schemas: v.schemas}
+		branch.validate(file, alternative, value, "")
+		if len(branch.problems) == 0 {
+			n++
+		}
+	}
+	return n
+}
+
+func typeOf(value any) string {
+	switch x := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case json.Number:
+		if isInteger(x) {
+			return "integer"
+		}
+		return "number"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
+
+func isInteger(n json.Number) bool {
+	return !strings.ContainsAny(n.String(), ".eE")
+}
+
+func matchesType(t, value any) bool {
+	actual := typeOf(value)
+	matches := func(name any) bool {
+		return name == actual || (name == "number" && actual == "integer")
+	}
+	if names, ok := t.([]any); ok {
+		for _, name := range names {
+			if matches(name) {
+				return true
+			}
+		}
+		return false
+	}
+	return matches(t)
+}
+
+func describeType(t any) string {
+	names, ok := t.([]any)
+	if !ok {
+		return fmt.Sprint(t)
+	}
+	parts := make([]string, len(names))
+	for i, name := range names {
+		parts[i] = fmt.Sprint(name)
+	}
+	return strings.Join(parts, " or ")
+}
+
+func contains(values []any, value any) bool {
+	for _, candidate := range values {
+		if reflect.DeepEqual(candidate, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// compareNumbers compares two JSON numbers.
+func compareNumbers(x, y string) int {
+	var a, b float64
+	_, _ = fmt.Sscan(x, &a)
+	_, _ = fmt.Sscan(y, &b)
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func encode(value any) string {
+	bs, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(bs)
+}
@@ -0,0 +1,289 @@
+package schema
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/Kuniwak/puml-parallel/csdf"
+	"github.com/Kuniwak/puml-parallel/csdf/animation"
+	"github.com/Kuniwak/puml-parallel/csdf/animation/proto"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestSchemaRefsResolve(t *testing.T) {
+	for _, name := range Names {
+		t.Run(name, func(t *testing.T) {
+			// Setup
+			v := &validator{schemas: make(map[string]any)}
+			root, err := v.load(fileName(name))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// Execute
+			var refs []string
+			var walk func(node any)
+			walk = func(node any) {
+				switch x := node.(type) {
+				case map[string]any:
+					if ref, ok := x["$ref"].(string); ok {
+						refs = append(refs, ref)
+					}
+					for _, child := range x {
+						walk(child)
+					}
+				case []any:
+					for _, child := range x {
+						walk(child)
+					}
+				}
+			}
+			walk(root)
+
+			// Assert
+			for _, ref := range refs {
+				if _, _, err := v.resolve(fileName(name), ref); err != nil {
+					t.Error(err)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateAcceptsCsdfparseOutput(t *testing.T) {
+	paths := []string{
+		"../../examples/valid/vending_machine.puml",
+		"../../examples/valid/test_input2.puml",
+		"../../examples/valid/tea_machine.csp",
+		"../../examples/valid/door.scxml",
+		"../../examples/valid/turnstile.md",
+	}
+
+	for _, path := range paths {
+		t.Run(path, func(t *testing.T) {
+			// Setup
+			document, err := json.Marshal(csdf.NewJSONDocument(csdf.MustLoadDiagrams(path)[0]))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// Execute
+			err = Validate("diagram", document)
+
+			// Assert
+			if err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestValidateAcceptsCompositions(t *testing.T) {
+	// Setup
+	composition, err := csdf.Compose(
+		csdf.MustLoadDiagrams("../../examples/valid/in.puml", "../../examples/valid/out.puml"),
+		[]csdf.Event{"sync"},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	document, err := json.Marshal(csdf.NewCompositionDocument(composition))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Execute
+	err = Validate("composition", document)
+
+	// Assert
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestValidateReportsProblems(t *testing.T) {
+	testCases := map[string]struct {
+		schema   string
+		document string
+		want     []Problem
+	}{
+		"missing and unknown properties": {
+			schema:   "diagram",
+			document: `{"states": {}, "edge": []}`,
+			want: []Problem{
+				{Pointer: "", Message: `missing property "start_edge"`},
+				{Pointer: "", Message: `unknown property "edge"`},
+			},
+		},
+		"wrong types deep inside": {
+			schema:   "diagram",
+			document: `{"states": {"a": {"name": "A", "vars": [{"name": 1}]}}, "start_edge": {"dst": "a"}, "end_edge": {"src": "a", "guard": 2}}`,
+			want: []Problem{
+				{Pointer: "/end_edge", Message: "object matches none of the 2 alternatives"},
+				{Pointer: "/states/a/vars/0/name", Message: "expected string, got integer"},
+			},
+		},
+		"patterns and constants": {
+			schema:   "diagram",
+			document: `{"format_version": "2", "states": {"a b": {"name": "A"}}, "start_edge": {"dst": "a"}}`,
+			want: []Problem{
+				{Pointer: "/format_version", Message: `expected "1", got "2"`},
+				{Pointer: "/states/a b", Message: `"a b" does not match ^[\p{L}\p{Nd}_-]+$`},
+			},
+		},
+		"null runtime value": {
+			schema:   "runtime-state",
+			document: `{"state_id": "s0", "state_name": "Initial", "values": [{"name": "count", "value": null}]}`,
+			want: []Problem{
+				{Pointer: "/values/0/value", Message: "expected boolean or number or string or array or object, got null"},
+			},
+		},
+		"unknown command": {
+			schema:   "proto",
+			document: `{"command": "reboot"}`,
+			want: []Problem{
+				{Pointer: "", Message: "object matches none of the 2 alternatives"},
+			},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Execute
+			err := Validate(tc.schema, []byte(tc.document))
+
+			// Assert
+			var schemaErr *Error
+			if !errors.As(err, &schemaErr) {
+				t.Fatalf("Validate() error = %v, want a schema.Error", err)
+			}
+			if diff := cmp.Diff(tc.want, schemaErr.Problems); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestValidateAcceptsProtoMessages(t *testing.T) {
+	// Setup: drive a session through every command and validate what is sent
+	// and received.
+	diagram := "@startuml\nstate \"Initial\" as s0\ns0: count ; number\n[*] --> s0\ns0 --> s0 : tick ; true ; count' = count + 1\n@enduml\n"
+	index := 0
+	requests := []proto.Request{
+		{Command: proto.CommandServerVersion},
+		{Command: proto.CommandSessionNew, Path: "a.puml", Content: []byte(diagram)},
+		{Command: proto.CommandRead},
+		{Command: proto.CommandStatevar, Values: "[0]"},
+		{Command: proto.CommandSelect, Index: &index},
+		{Command: proto.CommandStatevar, Values: "[1]"},
+		{Command: proto.CommandTrace},
+		{Command: proto.CommandHistory},
+		{Command: proto.CommandJump, Index: &index},
+		{Command: proto.CommandSessionList},
+		{Command: proto.CommandSessionRm, Session: "1"},
+		{Command: proto.CommandRead, Session: "9"},
+	}
+	service := proto.NewService("dev", false)
+
+	for _, request := range requests {
+		// Execute
+		response := service.Handle(request)
+
+		// Assert
+		for _, message := range []any{request, response} {
+			document, err := json.Marshal(message)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := Validate("proto", document); err != nil {
+				t.Errorf("%s: %v", document, err)
+			}
+		}
+	}
+
+	// Teardown: no resources to release.
+}
+
+func TestSchemaPropertiesMatchGoTypes(t *testing.T) {
+	testCases := map[string]struct {
+		schema string
+		ref    string
+		value  any
+	}{
+		"Diagram":       {schema: "diagram", ref: "#", value: csdf.JSONDocument{}},
+		"State":         {schema: "diagram", ref: "#/$defs/state", value: csdf.State{}},
+		"StateVar":      {schema: "diagram", ref: "#/$defs/state_var", value: csdf.StateVar{}},
+		"StartEdge":     {schema: "diagram", ref: "#/$defs/start_edge", value: csdf.StartEdge{}},
+		"Edge":          {schema: "diagram", ref: "#/$defs/edge", value: csdf.Edge{}},
+		"EndEdge":       {schema: "diagram", ref: "#/$defs/end_edge", value: csdf.EndEdge{}},
+		"Composition":   {schema: "composition", ref: "#", value: csdf.CompositionDocument{}},
+		"ComponentEdge": {schema: "composition", ref: "#/$defs/component_edge", value: csdf.ComponentEdge{}},
+		"RuntimeState": {
+			schema: "runtime-state", ref: "#", value: csdf.RuntimeState{},
+		},
+		"StateValue":      {schema: "runtime-state", ref: "#/$defs/state_value", value: csdf.StateValue{}},
+		"Request":         {schema: "proto", ref: "#/$defs/request", value: proto.Request{}},
+		"Response":        {schema: "proto", ref: "#/$defs/response", value: proto.Response{}},
+		"View":            {schema: "proto", ref: "#/$defs/view", value: proto.View{}},
+		"Transition":      {schema: "proto", ref: "#/$defs/transition", value: proto.Transition{}},
+		"Pending":         {schema: "proto", ref: "#/$defs/pending", value: proto.Pending{}},
+		"SessionRef":      {schema: "proto", ref: "#/$defs/session_ref", value: proto.SessionRef{}},
+		"VersionData":     {schema: "proto", ref: "#/$defs/version_data", value: proto.VersionData{}},
+		"TraceData":       {schema: "proto", ref: "#/$defs/trace_data", value: proto.TraceData{}},
+		"HistoryData":     {schema: "proto", ref: "#/$defs/history_data", value: proto.HistoryData{}},
+		"HistoryEntry":    {schema: "proto", ref: "#/$defs/history_entry", value: animation.HistoryEntry{}},
+		"SessionListData": {schema: "proto", ref: "#/$defs/session_list_data", value: proto.SessionListData{}},
+		"SessionInfo":     {schema: "proto", ref: "#/$defs/session_info", value: proto.SessionInfo{}},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Setup
+			v := &validator{schemas: make(map[string]any)}
+			_, node, err := v.resolve(fileName(tc.schema), tc.ref)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for key := range node.(map[string]any)["properties"].(map[string]any) {
+				got = append(got, key)
+			}
+			sort.Strings(got)
+
+			// Execute
+			want := jsonFields(reflect.TypeOf(tc.value))
+
+			// Assert
+			if diff := cmp.Diff(want, got); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+// jsonFields lists the JSON keys of a struct type, including those of embedded
+// structs.
+func jsonFields(t reflect.Type) []string {
+	var fields []string
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Anonymous {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			fields = append(fields, jsonFields(embedded)...)
+			continue
+		}
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name != "" && name != "-" {
+			fields = append(fields, name)
+		}
+	}
+	sort.Strings(fields)
+	return fields
+}
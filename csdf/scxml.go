@@ -0,0 +1,330 @@
+package csdf
+
+import (
+	"encoding/xml"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// SCXMLNamespace is the namespace of SCXML elements.
+const SCXMLNamespace = "http://www.w3.org/2005/07/scxml"
+
+// SCXMLExtensionNamespace is the namespace of the attributes keeping what
+// SCXML has no place for: csdf:name on states, csdf:vars listing the
+// variables of a state, csdf:type on data elements and csdf:post on
+// transitions.
+const SCXMLExtensionNamespace = "https://github.com/Kuniwak/puml-parallel/csdf"
+
+// xmlNode is an XML element with its attributes and child elements in
+// document order.
+type xmlNode struct {
+	XMLName  xml.Name
+	Attrs    []xml.Attr `xml:",any,attr"`
+	Children []xmlNode  `xml:",any"`
+}
+
+func (n *xmlNode) attr(space, local string) (string, bool) {
+	for _, a := range n.Attrs {
+		if a.Name.Local == local && a.Name.Space == space {
+			return a.Value, true
+		}
+	}
+	return "", false
+}
+
+// scxmlState is a <state> or a <final> before final states are resolved.
+type scxmlState struct {
+	state       State
+	final       bool
+	localVars   []StateVar
+	transitions []scxmlTransition
+}
+
+type scxmlTransition struct {
+	src, dst StateID
+	event    Event // "" for an eventless transition
+	cond     string
+	post     string
+}
+
+// ParseSCXML reads a flat SCXML state chart:
+//
+//   - <state> elements are states and <final> elements end the diagram,
+//   - <transition event="e" cond="guard" target="dst"> is an edge with the
+//     event, guard and csdf:post post-condition; a transition without an event
+//     is τ, one without a target loops, and one listing several events is one
+//     edge per event,
+//   - the initial attribute, an <initial> element or else the first state is
+//     the start state,
+//   - <data> elements are variables: a state has the variables its csdf:vars
+//     attribute names, or else those of the document and its own datamodel.
+//
+// A <final> entered by a single eventless transition becomes the end edge of
+// the source of that transition; any other <final> becomes a state with an end
+// edge. A diagram has one end edge, so a chart ending in more ways is rejected,
+// as are compound and parallel states, history and invokes.
+func ParseSCXML(input string) (*Diagram, error) {
+	var root xmlNode
+	if err := xml.Unmarshal([]byte(input), &root); err != nil {
+		return nil, fmt.Errorf("csdf.ParseSCXML: %w", err)
+	}
+	if !isSCXMLElement(root, "scxml") {
+		return nil, fmt.Errorf("csdf.ParseSCXML: expected <scxml>, got <%s>", root.XMLName.Local)
+	}
+
+	name, _ := root.attr("", "name")
+	initial, _ := root.attr("", "initial")
+	startPost := True
+	var documentVars []StateVar
+	types := make(map[Var]string)
+	var states []scxmlState
+	seen := make(map[StateID]struct{})
+	for _, child := range root.Children {
+		switch {
+		case isSCXMLElement(child, "datamodel"):
+			documentVars = append(documentVars, scxmlVars(child, types)...)
+		case isSCXMLElement(child, "initial"):
+			target, post, err := scxmlInitial(child)
+			if err != nil {
+				return nil, fmt.Errorf("csdf.ParseSCXML: %w", err)
+			}
+			initial, startPost = target, post
+		case isSCXMLElement(child, "state"), isSCXMLElement(child, "final"):
+			s, err := parseSCXMLState(child, types)
+			if err != nil {
+				return nil, fmt.Errorf("csdf.ParseSCXML: %w", err)
+			}
+			if _, ok := seen[s.state.ID]; ok {
+				return nil, fmt.Errorf("csdf.ParseSCXML: duplicate state id %q", s.state.ID)
+			}
+			seen[s.state.ID] = struct{}{}
+			states = append(states, s)
+		case isSCXMLNamespace(child):
+			return nil, fmt.Errorf("csdf.ParseSCXML: <%s> is not supported", child.XMLName.Local)
+		}
+	}
+	if len(states) == 0 {
+		return nil, fmt.Errorf("csdf.ParseSCXML: no states")
+	}
+
+	if initial == "" {
+		initial = string(states[0].state.ID)
+	}
+	if strings.ContainsAny(initial, " \t\r\n") {
+		return nil, fmt.Errorf("csdf.ParseSCXML: several initial states %q are not supported", initial)
+	}
+
+	diagram := &Diagram{
+		Name:      name,
+		States:    make(map[StateID]State, len(states)),
+		StartEdge: StartEdge{Dst: StateID(initial), Post: startPost},
+		Edges:     make([]Edge, 0),
+	}
+	finals := make(map[StateID]bool)
+	entries := make(map[StateID][]scxmlTransition)
+	for _, s := range states {
+		state := s.state
+		if state.Vars == nil {
+			// Without csdf:vars a state sees the data of the whole document.
+			state.Vars = append(append([]StateVar{}, documentVars...), s.localVars...)
+		}
+		for i, v := range state.Vars {
+			if v.Type == "" {
+				state.Vars[i].Type = types[v.Name]
+			}
+		}
+		diagram.States[state.ID] = state
+		finals[state.ID] = s.final
+	}
+	for _, s := range states {
+		for _, t := range s.transitions {
+			if _, ok := diagram.States[t.dst]; !ok {
+				return nil, fmt.Errorf("csdf.ParseSCXML: transition from %q to unknown state %q", t.src, t.dst)
+			}
+			entries[t.dst] = append(entries[t.dst], t)
+		}
+	}
+	if _, ok := diagram.States[diagram.StartEdge.Dst]; !ok {
+		return nil, fmt.Errorf("csdf.ParseSCXML: unknown initial state %q", initial)
+	}
+	if finals[diagram.StartEdge.Dst] {
+		return nil, fmt.Errorf("csdf.ParseSCXML: the initial state %q is final", initial)
+	}
+
+	// A final entered only by one eventless transition is that transition's end
+	// edge; other finals stay states and end from there.
+	folded := make(map[StateID]bool)
+	for _, s := range states {
+		if !s.final {
+			continue
+		}
+		in := entries[s.state.ID]
+		var end EndEdge
+		if len(in) == 1 && in[0].event == "" && in[0].post == True {
+			folded[s.state.ID] = true
+			end = EndEdge{Src: in[0].src}
+			if in[0].cond != True {
+				end.Guard = in[0].cond
+			}
+			delete(diagram.States, s.state.ID)
+		} else {
+			end = EndEdge{Src: s.state.ID}
+		}
+		if diagram.EndEdge != nil {
+			return nil, fmt.Errorf("csdf.ParseSCXML: a diagram has one end edge, but the chart ends from %q and from %q", diagram.EndEdge.Src, end.Src)
+		}
+		diagram.EndEdge = &end
+	}
+	for _, s := range states {
+		for _, t := range s.transitions {
+			if folded[t.dst] {
+				continue
+			}
+			event := t.event
+			if event == "" {
+				event = Tau
+			}
+			diagram.Edges = append(diagram.Edges, Edge{Src: t.src, Dst: t.dst, Event: event, Guard: t.cond, Post: t.post})
+		}
+	}
+	return diagram, nil
+}
+
+func isSCXMLNamespace(n xmlNode) bool {
+	return n.XMLName.Space == SCXMLNamespace || n.XMLName.Space == ""
+}
+
+func isSCXMLElement(n xmlNode, local string) bool {
+	return n.XMLName.Local == local && isSCXMLNamespace(n)
+}
+
+// scxmlInitial reads an <initial> element holding a single transition.
+func scxmlInitial(n xmlNode) (string, string, error) {
+	if len(n.Children) != 1 || !isSCXMLElement(n.Children[0], "transition") {
+		return "", "", fmt.Errorf("<initial> must hold a single <transition>")
+	}
+	target, _ := n.Children[0].attr("", "target")
+	post, ok := n.Children[0].attr(SCXMLExtensionNamespace, "post")
+	if !ok {
+		post = True
+	}
+	return target, post, nil
+}
+
+// scxmlVars reads the <data> elements of a <datamodel>, recording their
+// csdf:type attributes in types.
+func scxmlVars(n xmlNode, types map[Var]string) []StateVar {
+	var vars []StateVar
+	for _, data := range n.Children {
+		if !isSCXMLElement(data, "data") {
+			continue
+		}
+		id, _ := data.attr("", "id")
+		v := StateVar{Name: Var(id)}
+		if t, ok := data.attr(SCXMLExtensionNamespace, "type"); ok {
+			v.Type = t
+			types[v.Name] = t
+		}
+		vars = append(vars, v)
+	}
+	return vars
+}
+
+func parseSCXMLState(n xmlNode, types map[Var]string) (scxmlState, error) {
+	id, ok := n.attr("", "id")
+	if !ok || id == "" {
+		return scxmlState{}, fmt.Errorf("<%s> without an id", n.XMLName.Local)
+	}
+	s := scxmlState{
+		state: State{ID: StateID(id), Name: id},
+		final: n.XMLName.Local == "final",
+	}
+	if name, ok := n.attr(SCXMLExtensionNamespace, "name"); ok {
+		s.state.Name = name
+	}
+	if names, ok := n.attr(SCXMLExtensionNamespace, "vars"); ok {
+		s.state.Vars = []StateVar{}
+		for _, name := range strings.Fields(names) {
+			s.state.Vars = append(s.state.Vars, StateVar{Name: Var(name)})
+		}
+	}
+	for _, child := range n.Children {
+		switch {
+		case isSCXMLElement(child, "datamodel"):
+			s.localVars = append(s.localVars, scxmlVars(child, types)...)
+		case isSCXMLElement(child, "transition"):
+			if s.final {
+				return scxmlState{}, fmt.Errorf("final state %q has a transition", id)
+			}
+			ts, err := parseSCXMLTransition(child, s.state.ID)
+			if err != nil {
+				return scxmlState{}, fmt.Errorf("state %q: %w", id, err)
+			}
+			s.transitions = append(s.transitions, ts...)
+		case isSCXMLElement(child, "onentry"), isSCXMLElement(child, "onexit"), isSCXMLElement(child, "donedata"):
+			// Executable content has no counterpart in a diagram.
+		case isSCXMLNamespace(child):
+			return scxmlState{}, fmt.Errorf("state %q: <%s> is not supported; flatten compound and parallel states first", id, child.XMLName.Local)
+		}
+	}
+	return s, nil
+}
+
+// parseSCXMLTransition reads a transition of src, one per event it lists.
+func parseSCXMLTransition(n xmlNode, src StateID) ([]scxmlTransition, error) {
+	target, _ := n.attr("", "target")
+	targets := strings.Fields(target)
+	if len(targets) > 1 {
+		return nil, fmt.Errorf("a transition to several targets %q is not supported", target)
+	}
+	dst := src
+	if len(targets) == 1 {
+		dst = StateID(targets[0])
+	}
+	cond, ok := n.attr("", "cond")
+	if !ok || strings.TrimSpace(cond) == "" {
+		cond = True
+	}
+	post, ok := n.attr(SCXMLExtensionNamespace, "post")
+	if !ok || strings.TrimSpace(post) == "" {
+		post = True
+	}
+	event, _ := n.attr("", "event")
+	events := strings.Fields(event)
+	if len(events) == 0 {
+		return []scxmlTransition{{src: src, dst: dst, cond: cond, post: post}}, nil
+	}
+	ts := make([]scxmlTransition, len(events))
+	for i, e := range events {
+		ts[i] = scxmlTransition{src: src, dst: dst, event: Event(e), cond: cond, post: post}
+	}
+	return ts, nil
+}
+
+// isSCXMLSource reports whether a source read from path is an SCXML document:
+// by the .scxml extension, or, for other paths and standard input, by an
+// <scxml> root element.
+func isSCXMLSource(path, text string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".scxml":
+		return true
+	case ".puml", ".plantuml", ".png", ".svg", ".csp", ".cspm", ".aut":
+		return false
+	}
+	decoder := xml.NewDecoder(strings.NewReader(text))
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return false
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			return t.Name.Local == "scxml"
+		case xml.CharData:
+			if strings.TrimSpace(string(t)) != "" {
+				return false
+			}
+		}
+	}
+}
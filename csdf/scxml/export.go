@@ -0,0 +1,169 @@
+// Package scxml writes Composable State Diagrams as SCXML state charts.
+// csdf.ParseSCXML reads them back.
+package scxml
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Kuniwak/puml-parallel/csdf"
+)
+
+// Export writes d as a flat SCXML document:
+//
+//   - every state is a <state> whose csdf:name keeps its name and whose
+//     csdf:vars lists its variables, all declared in the document datamodel,
+//   - every edge is a <transition> with the event, the guard as cond and the
+//     post-condition as csdf:post; τ-edges have no event,
+//   - the end edge is an eventless transition to a <final> state,
+//   - the start state is the initial attribute, or the target of an <initial>
+//     transition carrying a non-trivial start post-condition.
+//
+// SCXML events are separated by spaces, so events containing spaces are
+// rejected, and so are variables declared with different types.
+func Export(d *csdf.Diagram) (string, error) {
+	// A state only referred to by edges is a <state> too.
+	seen := make(map[csdf.StateID]bool, len(d.States))
+	stateIDs := make([]csdf.StateID, 0, len(d.States))
+	add := func(id csdf.StateID) {
+		if !seen[id] {
+			seen[id] = true
+			stateIDs = append(stateIDs, id)
+		}
+	}
+	for id := range d.States {
+		add(id)
+	}
+	add(d.StartEdge.Dst)
+	for _, e := range d.Edges {
+		add(e.Src)
+		add(e.Dst)
+	}
+	if d.EndEdge != nil {
+		add(d.EndEdge.Src)
+	}
+	sort.Slice(stateIDs, func(i, j int) bool { return stateIDs[i] < stateIDs[j] })
+
+	var vars []csdf.Var
+	types := make(map[csdf.Var]string)
+	for _, id := range stateIDs {
+		for _, v := range d.States[id].Vars {
+			t, ok := types[v.Name]
+			if !ok {
+				vars = append(vars, v.Name)
+				types[v.Name] = v.Type
+				continue
+			}
+			if t != v.Type {
+				return "", fmt.Errorf("scxml.Export: variable %q has the types %q and %q", v.Name, t, v.Type)
+			}
+		}
+	}
+	sort.Slice(vars, func(i, j int) bool { return vars[i] < vars[j] })
+
+	outgoing := make(map[csdf.StateID][]csdf.Edge)
+	for _, e := range d.Edges {
+		if e.Event != csdf.Tau && strings.ContainsAny(string(e.Event), " \t\r\n") {
+			return "", fmt.Errorf("scxml.Export: event %q of edge %s -> %s contains spaces", e.Event, e.Src, e.Dst)
+		}
+		outgoing[e.Src] = append(outgoing[e.Src], e)
+	}
+
+	final := "final"
+	for i := 2; ; i++ {
+		if !seen[csdf.StateID(final)] {
+			break
+		}
+		final = fmt.Sprintf("final_%d", i)
+	}
+
+	var sb strings.Builder
+	sb.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n")
+	sb.WriteString(`<scxml xmlns="` + csdf.SCXMLNamespace + `" xmlns:csdf="` + csdf.SCXMLExtensionNamespace + `" version="1.0"`)
+	startPost := d.StartEdge.Post != "" && d.StartEdge.Post != csdf.True
+	if !startPost {
+		sb.WriteString(` initial="` + escape(string(d.StartEdge.Dst)) + `"`)
+	}
+	if d.Name != "" {
+		sb.WriteString(` name="` + escape(d.Name) + `"`)
+	}
+	sb.WriteString(">\n")
+
+	if len(vars) > 0 {
+		sb.WriteString("  <datamodel>\n")
+		for _, v := range vars {
+			sb.WriteString(`    <data id="` + escape(string(v)) + `"`)
+			if types[v] != "" {
+				sb.WriteString(` csdf:type="` + escape(types[v]) + `"`)
+			}
+			sb.WriteString("/>\n")
+		}
+		sb.WriteString("  </datamodel>\n")
+	}
+	if startPost {
+		sb.WriteString("  <initial>\n")
+		sb.WriteString(`    <transition target="` + escape(string(d.StartEdge.Dst)) + `" csdf:post="` + escape(d.StartEdge.Post) + `"/>` + "\n")
+		sb.WriteString("  </initial>\n")
+	}
+
+	for _, id := range stateIDs {
+		state, ok := d.States[id]
+		if !ok {
+			state = csdf.State{ID: id, Name: string(id)}
+		}
+		sb.WriteString(`  <state id="` + escape(string(id)) + `"`)
+		if state.Name != string(id) {
+			sb.WriteString(` csdf:name="` + escape(state.Name) + `"`)
+		}
+		names := make([]string, len(state.Vars))
+		for i, v := range state.Vars {
+			names[i] = string(v.Name)
+		}
+		sb.WriteString(` csdf:vars="` + escape(strings.Join(names, " ")) + `"`)
+
+		var transitions []string
+		for _, e := range outgoing[id] {
+			transitions = append(transitions, transition(string(e.Event), e.Guard, e.Post, string(e.Dst)))
+		}
+		if d.EndEdge != nil && d.EndEdge.Src == id {
+			transitions = append(transitions, transition(string(csdf.Tau), d.EndEdge.Guard, "", final))
+		}
+		if len(transitions) == 0 {
+			sb.WriteString("/>\n")
+			continue
+		}
+		sb.WriteString(">\n")
+		for _, t := range transitions {
+			sb.WriteString("    " + t + "\n")
+		}
+		sb.WriteString("  </state>\n")
+	}
+	if d.EndEdge != nil {
+		sb.WriteString(`  <final id="` + final + `"/>` + "\n")
+	}
+	sb.WriteString("</scxml>\n")
+	return sb.String(), nil
+}
+
+func transition(event, guard, post, target string) string {
+	var sb strings.Builder
+	sb.WriteString("<transition")
+	if event != string(csdf.Tau) {
+		sb.WriteString(` event="` + escape(event) + `"`)
+	}
+	if guard != "" && guard != csdf.True {
+		sb.WriteString(` cond="` + escape(guard) + `"`)
+	}
+	sb.WriteString(` target="` + escape(target) + `"`)
+	if post != "" && post != csdf.True {
+		sb.WriteString(` csdf:post="` + escape(post) + `"`)
+	}
+	sb.WriteString("/>")
+	return sb.String()
+}
+
+// escape writes s as XML attribute text.
+func escape(s string) string {
+	return strings.NewReplacer(`&`, "&amp;", `<`, "&lt;", `>`, "&gt;", `"`, "&quot;", "\n", "&#xA;", "\t", "&#x9;").Replace(s)
+}
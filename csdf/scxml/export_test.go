@@ -0,0 +1,157 @@
+package scxml
+
+import (
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/Kuniwak/puml-parallel/csdf"
+	"github.com/google/go-cmp/cmp"
+)
+
+func mustParse(t *testing.T, src string) *csdf.Diagram {
+	t.Helper()
+	d, err := csdf.ParseDiagram([]byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+func TestExport(t *testing.T) {
+	// Setup
+	d := mustParse(t, `@startuml tea
+state "Ready" as ready
+ready : cups ; int
+state "Brewing" as brewing
+brewing : cups ; int
+[*] --> ready : cups = 0
+ready --> brewing : coin
+brewing --> ready : tea ; cups < 3 ; cups' = cups + 1
+brewing --> brewing : tau
+ready --> [*] : cups >= 3
+@enduml
+`)
+	want := `<?xml version="1.0" encoding="UTF-8"?>
+<scxml xmlns="http://www.w3.org/2005/07/scxml" xmlns:csdf="https://github.com/Kuniwak/puml-parallel/csdf" version="1.0" name="tea">
+  <datamodel>
+    <data id="cups" csdf:type="int"/>
+  </datamodel>
+  <initial>
+    <transition target="ready" csdf:post="cups = 0"/>
+  </initial>
+  <state id="brewing" csdf:name="Brewing" csdf:vars="cups">
+    <transition event="tea" cond="cups &lt; 3" target="ready" csdf:post="cups' = cups + 1"/>
+    <transition target="brewing"/>
+  </state>
+  <state id="ready" csdf:name="Ready" csdf:vars="cups">
+    <transition event="coin" target="brewing"/>
+    <transition cond="cups &gt;= 3" target="final"/>
+  </state>
+  <final id="final"/>
+</scxml>
+`
+
+	// Execute
+	got, err := Export(d)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Error(diff)
+	}
+
+	// Teardown: no resources to release.
+}
+
+func TestExportWritesStatesOnlyReferredToByEdges(t *testing.T) {
+	// Setup: b is not declared.
+	d := mustParse(t, `@startuml
+state "A" as a
+[*] --> a
+a --> b : go
+b --> a : back
+@enduml
+`)
+	want := `<?xml version="1.0" encoding="UTF-8"?>
+<scxml xmlns="http://www.w3.org/2005/07/scxml" xmlns:csdf="https://github.com/Kuniwak/puml-parallel/csdf" version="1.0" initial="a">
+  <state id="a" csdf:name="A" csdf:vars="">
+    <transition event="go" target="b"/>
+  </state>
+  <state id="b" csdf:vars="">
+    <transition event="back" target="a"/>
+  </state>
+</scxml>
+`
+
+	// Execute
+	got, err := Export(d)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Error(diff)
+	}
+
+	// Teardown: no resources to release.
+}
+
+func TestExportRoundTrips(t *testing.T) {
+	// Setup: SCXML groups transitions by their source state, so edges are
+	// compared in a canonical order.
+	paths := []string{
+		"../../examples/valid/vending_machine.puml",
+		"../../examples/valid/tea_machine.csp",
+		"../../examples/valid/door.scxml",
+	}
+
+	for _, path := range paths {
+		t.Run(path, func(t *testing.T) {
+			want := csdf.MustLoadDiagrams(path)[0]
+
+			// Execute
+			text, err := Export(want)
+			if err != nil {
+				t.Fatalf("Export() error = %v", err)
+			}
+			got, err := csdf.ParseSCXML(text)
+
+			// Assert
+			if err != nil {
+				t.Fatalf("ParseSCXML() error = %v\n%s", err, text)
+			}
+			sortEdges(want)
+			sortEdges(got)
+			if diff := cmp.Diff(want, got); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestExportRejectsEventsWithSpaces(t *testing.T) {
+	// Setup
+	d := &csdf.Diagram{
+		States:    map[csdf.StateID]csdf.State{"a": {ID: "a", Name: "a"}},
+		StartEdge: csdf.StartEdge{Dst: "a", Post: csdf.True},
+		Edges:     []csdf.Edge{{Src: "a", Dst: "a", Event: "insert coin", Guard: csdf.True, Post: csdf.True}},
+	}
+
+	// Execute
+	_, err := Export(d)
+
+	// Assert
+	if err == nil || !strings.Contains(err.Error(), "contains spaces") {
+		t.Errorf("Export() error = %v, want an error about spaces", err)
+	}
+
+	// Teardown: no resources to release.
+}
+
+func sortEdges(d *csdf.Diagram) {
+	sort.SliceStable(d.Edges, func(i, j int) bool { return d.Edges[i].Src < d.Edges[j].Src })
+}
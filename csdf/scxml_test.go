@@ -0,0 +1,197 @@
+package csdf
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseSCXML(t *testing.T) {
+	// Setup
+	input := `<?xml version="1.0" encoding="UTF-8"?>
+<scxml xmlns="http://www.w3.org/2005/07/scxml" xmlns:csdf="https://github.com/Kuniwak/puml-parallel/csdf" version="1.0" name="tea">
+  <datamodel>
+    <data id="cups" csdf:type="int"/>
+  </datamodel>
+  <initial>
+    <transition target="ready" csdf:post="cups = 0"/>
+  </initial>
+  <state id="brewing" csdf:vars="">
+    <transition event="tea" cond="hot" target="ready" csdf:post="cups' = cups + 1"/>
+    <transition/>
+  </state>
+  <state id="ready" csdf:name="Ready">
+    <transition event="coin" target="brewing"/>
+    <transition cond="cups &gt; 2" target="done"/>
+  </state>
+  <final id="done"/>
+</scxml>
+`
+	want := &Diagram{
+		Name: "tea",
+		States: map[StateID]State{
+			"brewing": {ID: "brewing", Name: "brewing", Vars: []StateVar{}},
+			"ready":   {ID: "ready", Name: "Ready", Vars: []StateVar{{Name: "cups", Type: "int"}}},
+		},
+		StartEdge: StartEdge{Dst: "ready", Post: "cups = 0"},
+		Edges: []Edge{
+			{Src: "brewing", Dst: "ready", Event: "tea", Guard: "hot", Post: "cups' = cups + 1"},
+			{Src: "brewing", Dst: "brewing", Event: Tau, Guard: True, Post: True},
+			{Src: "ready", Dst: "brewing", Event: "coin", Guard: True, Post: True},
+		},
+		EndEdge: &EndEdge{Src: "ready", Guard: "cups > 2"},
+	}
+
+	// Execute
+	got, err := ParseSCXML(input)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("ParseSCXML() error = %v", err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Error(diff)
+	}
+
+	// Teardown: no resources to release.
+}
+
+func TestParseSCXMLRejects(t *testing.T) {
+	testCases := map[string]struct {
+		input string
+		want  string
+	}{
+		"not SCXML": {
+			input: `<svg/>`,
+			want:  "expected <scxml>",
+		},
+		"compound state": {
+			input: `<scxml xmlns="http://www.w3.org/2005/07/scxml"><state id="a"><state id="b"/></state></scxml>`,
+			want:  "<state> is not supported",
+		},
+		"parallel state": {
+			input: `<scxml xmlns="http://www.w3.org/2005/07/scxml"><state id="a"/><parallel id="p"/></scxml>`,
+			want:  "<parallel> is not supported",
+		},
+		"several targets": {
+			input: `<scxml xmlns="http://www.w3.org/2005/07/scxml"><state id="a"><transition target="a b"/></state><state id="b"/></scxml>`,
+			want:  "several targets",
+		},
+		"unknown target": {
+			input: `<scxml xmlns="http://www.w3.org/2005/07/scxml"><state id="a"><transition event="e" target="b"/></state></scxml>`,
+			want:  `unknown state "b"`,
+		},
+		"two ends": {
+			input: `<scxml xmlns="http://www.w3.org/2005/07/scxml"><state id="a"><transition target="x"/><transition event="e" target="y"/></state><final id="x"/><final id="y"/></scxml>`,
+			want:  "a diagram has one end edge",
+		},
+		"final initial state": {
+			input: `<scxml xmlns="http://www.w3.org/2005/07/scxml" initial="x"><state id="a"/><final id="x"/></scxml>`,
+			want:  `the initial state "x" is final`,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Execute
+			_, err := ParseSCXML(tc.input)
+
+			// Assert
+			if err == nil {
+				t.Fatal("ParseSCXML() error = nil, want error")
+			}
+			if !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("ParseSCXML() error = %q, want it to contain %q", err.Error(), tc.want)
+			}
+		})
+	}
+}
+
+func TestLoadDiagramsReadsSCXML(t *testing.T) {
+	// Setup: the final state "removed" is entered by an event, so it stays a
+	// state and the end edge leaves it.
+	want := &Diagram{
+		Name: "door",
+		States: map[StateID]State{
+			"closed":  {ID: "closed", Name: "closed", Vars: []StateVar{{Name: "locked"}}},
+			"opened":  {ID: "opened", Name: "opened", Vars: []StateVar{{Name: "locked"}}},
+			"removed": {ID: "removed", Name: "removed", Vars: []StateVar{{Name: "locked"}}},
+		},
+		StartEdge: StartEdge{Dst: "closed", Post: True},
+		Edges: []Edge{
+			{Src: "closed", Dst: "opened", Event: "open", Guard: "!locked", Post: True},
+			{Src: "closed", Dst: "closed", Event: "lock", Guard: True, Post: True},
+			{Src: "closed", Dst: "closed", Event: "unlock", Guard: True, Post: True},
+			{Src: "closed", Dst: "removed", Event: "remove", Guard: True, Post: True},
+			{Src: "opened", Dst: "closed", Event: "close", Guard: True, Post: True},
+			{Src: "opened", Dst: "closed", Event: Tau, Guard: "timedOut", Post: True},
+		},
+		EndEdge: &EndEdge{Src: "removed"},
+	}
+
+	// Execute
+	got, err := LoadDiagrams([]string{"../examples/valid/door.scxml"})
+
+	// Assert
+	if err != nil {
+		t.Fatalf("LoadDiagrams() error = %v", err)
+	}
+	if diff := cmp.Diff(want, got[0]); diff != "" {
+		t.Error(diff)
+	}
+
+	// Teardown: no resources to release.
+}
+
+func TestLoadDiagramsSelectsSCXMLByName(t *testing.T) {
+	tests := []struct {
+		name    string
+		ref     string
+		wantErr string
+	}{
+		{name: "document name", ref: "../examples/valid/door.scxml#door"},
+		{name: "other name", ref: "../examples/valid/door.scxml#window", wantErr: `no diagram named "window" (named diagrams: "door")`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Execute
+			got, err := LoadDiagrams([]string{tt.ref})
+
+			// Assert
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("LoadDiagrams() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadDiagrams() error = %v", err)
+			}
+			if got[0].Name != "door" {
+				t.Errorf("LoadDiagrams() name = %q, want door", got[0].Name)
+			}
+
+			// Teardown: no resources to release.
+		})
+	}
+}
+
+func TestParseDiagramDetectsSCXMLByContent(t *testing.T) {
+	// Setup
+	input := []byte("<?xml version=\"1.0\"?>\n<scxml xmlns=\"http://www.w3.org/2005/07/scxml\"><state id=\"a\"/></scxml>\n")
+
+	// Execute
+	d, err := ParseDiagram(input)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("ParseDiagram() error = %v", err)
+	}
+	if _, ok := d.States["a"]; !ok {
+		t.Errorf("ParseDiagram() states = %v, want a", d.States)
+	}
+
+	// Teardown: no resources to release.
+}
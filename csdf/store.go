@@ -0,0 +1,297 @@
+package csdf
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"hash/maphash"
+	"io"
+	"os"
+	"sync"
+	"sync/atomic"
+)
+
+// StoreKind selects how an Explorer stores the states it has found.
+type StoreKind string
+
+const (
+	// MemoryStore keeps the states in Go maps. It is the default.
+	MemoryStore StoreKind = "memory"
+	// CompactStore packs the states into a single buffer indexed by their
+	// 64-bit hashes. It takes a fraction of the memory of MemoryStore, which
+	// keeps a string and a map entry per state. States sharing a hash are told
+	// apart by comparing them, so no state is missed.
+	CompactStore StoreKind = "compact"
+	// DiskStore writes the states of the visited set to a temporary file and
+	// keeps only their hashes and offsets in memory. States sharing a hash
+	// are told apart by reading them back, so no state is missed. Only the
+	// visited set moves to disk: the edges found and the resulting diagram
+	// still grow in memory with the state space.
+	DiskStore StoreKind = "disk"
+)
+
+// StoreKinds are the kinds of state stores.
+var StoreKinds = []StoreKind{MemoryStore, CompactStore, DiskStore}
+
+// stateStore numbers the states found by bfs and keeps their keys. find and
+// key may be called concurrently, but not concurrently with add.
+type stateStore interface {
+	// find returns the number of the state with key, if it was added.
+	find(key []byte) (int32, bool, error)
+	// add stores the state with key, which find did not find, as the state
+	// numbered len().
+	add(key []byte) error
+	// key appends the key of the state numbered n to buf.
+	key(buf []byte, n int32) ([]byte, error)
+	len() int
+	// close releases the resources of the store.
+	close() error
+}
+
+func (x *Explorer) newStore() (stateStore, error) {
+	if x == nil {
+		return newMemoryStore(), nil
+	}
+	switch x.Store {
+	case "", MemoryStore:
+		return newMemoryStore(), nil
+	case CompactStore:
+		return newCompactStore(), nil
+	case DiskStore:
+		s, err := newDiskStore(x.StoreDir)
+		if err != nil {
+			return nil, fmt.Errorf("csdf.Explorer.newStore: %w", err)
+		}
+		return s, nil
+	default:
+		return nil, fmt.Errorf("csdf.Explorer.newStore: unknown store %q", x.Store)
+	}
+}
+
+// closeStore closes s and reports its error through err, unless err already
+// holds one. It is meant to be deferred.
+func closeStore(s stateStore, err *error) {
+	if closeErr := s.close(); closeErr != nil && *err == nil {
+		*err = closeErr
+	}
+}
+
+type memoryStore struct {
+	numbers map[string]int32
+	keys    []string
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{numbers: make(map[string]int32)}
+}
+
+func (s *memoryStore) find(key []byte) (int32, bool, error) {
+	n, ok := s.numbers[string(key)]
+	return n, ok, nil
+}
+
+func (s *memoryStore) add(key []byte) error {
+	k := string(key)
+	s.numbers[k] = int32(len(s.keys))
+	s.keys = append(s.keys, k)
+	return nil
+}
+
+func (s *memoryStore) key(buf []byte, n int32) ([]byte, error) {
+	return append(buf, s.keys[n]...), nil
+}
+
+func (s *memoryStore) len() int { return len(s.keys) }
+
+func (s *memoryStore) close() error { return nil }
+
+// hashTable maps 64-bit hashes to state numbers by open addressing. Several
+// states may share a hash; lookup visits all of them.
+type hashTable struct {
+	hashes  []uint64 // 0 marks an empty slot
+	numbers []int32
+	count   int
+}
+
+func newHashTable() *hashTable {
+	return &hashTable{hashes: make([]uint64, 1024), numbers: make([]int32, 1024)}
+}
+
+// lookup calls match with the number of each state stored with hash h until
+// it returns true.
+func (t *hashTable) lookup(h uint64, match func(n int32) (bool, error)) (int32, bool, error) {
+	h = nonZero(h)
+	mask := uint64(len(t.hashes) - 1)
+	for i := h & mask; t.hashes[i] != 0; i = (i + 1) & mask {
+		if t.hashes[i] != h {
+			continue
+		}
+		ok, err := match(t.numbers[i])
+		if err != nil {
+			return 0, false, err
+		}
+		if ok {
+			return t.numbers[i], true, nil
+		}
+	}
+	return 0, false, nil
+}
+
+func (t *hashTable) insert(h uint64, n int32) {
+	if 2*(t.count+1) > len(t.hashes) {
+		t.grow()
+	}
+	h = nonZero(h)
+	mask := uint64(len(t.hashes) - 1)
+	i := h & mask
+	for t.hashes[i] != 0 {
+		i = (i + 1) & mask
+	}
+	t.hashes[i], t.numbers[i] = h, n
+	t.count++
+}
+
+func (t *hashTable) grow() {
+	old := *t
+	t.hashes = make([]uint64, 2*len(old.hashes))
+	t.numbers = make([]int32, 2*len(old.numbers))
+	t.count = 0
+	for i, h := range old.hashes {
+		if h != 0 {
+			t.insert(h, old.numbers[i])
+		}
+	}
+}
+
+func nonZero(h uint64) uint64 {
+	if h == 0 {
+		return 1
+	}
+	return h
+}
+
+type compactStore struct {
+	seed  maphash.Seed
+	table *hashTable
+	// arena holds the keys one after another; the key numbered n is
+	// arena[offsets[n]:offsets[n+1]].
+	arena   []byte
+	offsets []int64
+}
+
+func newCompactStore() *compactStore {
+	return &compactStore{seed: maphash.MakeSeed(), table: newHashTable(), offsets: []int64{0}}
+}
+
+func (s *compactStore) find(key []byte) (int32, bool, error) {
+	return s.table.lookup(maphash.Bytes(s.seed, key), func(n int32) (bool, error) {
+		return bytes.Equal(key, s.arena[s.offsets[n]:s.offsets[n+1]]), nil
+	})
+}
+
+func (s *compactStore) add(key []byte) error {
+	s.table.insert(maphash.Bytes(s.seed, key), int32(s.len()))
+	s.arena = append(s.arena, key...)
+	s.offsets = append(s.offsets, int64(len(s.arena)))
+	return nil
+}
+
+func (s *compactStore) key(buf []byte, n int32) ([]byte, error) {
+	return append(buf, s.arena[s.offsets[n]:s.offsets[n+1]]...), nil
+}
+
+func (s *compactStore) len() int { return len(s.offsets) - 1 }
+
+func (s *compactStore) close() error { return nil }
+
+type diskStore struct {
+	seed    maphash.Seed
+	table   *hashTable
+	file    *os.File
+	offsets []int64
+
+	// mu guards w; flushed is how much of the file has been written out, so
+	// that keys below it can be read without the lock.
+	mu      sync.Mutex
+	w       *bufio.Writer
+	flushed atomic.Int64
+}
+
+func newDiskStore(dir string) (*diskStore, error) {
+	file, err := os.CreateTemp(dir, "csdf-states-*")
+	if err != nil {
+		return nil, fmt.Errorf("csdf.newDiskStore: %w", err)
+	}
+	return &diskStore{
+		seed:    maphash.MakeSeed(),
+		table:   newHashTable(),
+		file:    file,
+		offsets: []int64{0},
+		w:       bufio.NewWriterSize(file, 1<<20),
+	}, nil
+}
+
+func (s *diskStore) find(key []byte) (int32, bool, error) {
+	var buf []byte
+	n, ok, err := s.table.lookup(maphash.Bytes(s.seed, key), func(n int32) (bool, error) {
+		var err error
+		buf, err = s.key(buf[:0], n)
+		if err != nil {
+			return false, err
+		}
+		return bytes.Equal(buf, key), nil
+	})
+	if err != nil {
+		return 0, false, fmt.Errorf("csdf.diskStore.find: %w", err)
+	}
+	return n, ok, nil
+}
+
+func (s *diskStore) add(key []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.w.Write(key); err != nil {
+		return fmt.Errorf("csdf.diskStore.add: %w", err)
+	}
+	s.table.insert(maphash.Bytes(s.seed, key), int32(s.len()))
+	s.offsets = append(s.offsets, s.offsets[len(s.offsets)-1]+int64(len(key)))
+	return nil
+}
+
+func (s *diskStore) key(buf []byte, n int32) ([]byte, error) {
+	lo, hi := s.offsets[n], s.offsets[n+1]
+	if hi > s.flushed.Load() {
+		if err := s.flush(); err != nil {
+			return nil, fmt.Errorf("csdf.diskStore.key: %w", err)
+		}
+	}
+	start := len(buf)
+	buf = append(buf, make([]byte, hi-lo)...)
+	if _, err := s.file.ReadAt(buf[start:], lo); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("csdf.diskStore.key: %w", err)
+	}
+	return buf, nil
+}
+
+func (s *diskStore) flush() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.w.Flush(); err != nil {
+		return err
+	}
+	s.flushed.Store(s.offsets[len(s.offsets)-1])
+	return nil
+}
+
+func (s *diskStore) len() int { return len(s.offsets) - 1 }
+
+func (s *diskStore) close() error {
+	closeErr := s.file.Close()
+	if err := os.Remove(s.file.Name()); err != nil {
+		return fmt.Errorf("csdf.diskStore.close: %w", err)
+	}
+	if closeErr != nil {
+		return fmt.Errorf("csdf.diskStore.close: %w", closeErr)
+	}
+	return nil
+}
@@ -0,0 +1,144 @@
+package csdf
+
+import (
+	"fmt"
+	"hash/maphash"
+	"os"
+	"testing"
+)
+
+func TestStoresNumberStatesInOrderOfAddition(t *testing.T) {
+	for _, kind := range StoreKinds {
+		t.Run(string(kind), func(t *testing.T) {
+			// Setup
+			store, err := (&Explorer{Store: kind, StoreDir: t.TempDir()}).newStore()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer store.close()
+			keys := make([][]byte, 5000)
+			for i := range keys {
+				keys[i] = []byte(fmt.Sprintf("state-%d", i*7))
+			}
+
+			// Execute
+			for i, key := range keys {
+				if _, ok, err := store.find(key); err != nil || ok {
+					t.Fatalf("find(%q) before add = (%v, %v), want (false, nil)", key, ok, err)
+				}
+				if err := store.add(key); err != nil {
+					t.Fatal(err)
+				}
+				if store.len() != i+1 {
+					t.Fatalf("len() = %d, want %d", store.len(), i+1)
+				}
+			}
+
+			// Assert
+			for i, key := range keys {
+				n, ok, err := store.find(key)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !ok || n != int32(i) {
+					t.Errorf("find(%q) = (%d, %v), want (%d, true)", key, n, ok, i)
+				}
+				got, err := store.key([]byte("prefix:"), int32(i))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if want := "prefix:" + string(key); string(got) != want {
+					t.Errorf("key(%d) = %q, want %q", i, got, want)
+				}
+			}
+			if _, ok, err := store.find([]byte("missing")); err != nil || ok {
+				t.Errorf("find(missing) = (%v, %v), want (false, nil)", ok, err)
+			}
+		})
+	}
+}
+
+func TestCompactStoreTellsApartStatesSharingAHash(t *testing.T) {
+	// Setup: the hash of "b" is made to point at "a", as on a collision.
+	store := newCompactStore()
+	if err := store.add([]byte("a")); err != nil {
+		t.Fatal(err)
+	}
+	store.table.insert(maphash.Bytes(store.seed, []byte("b")), 0)
+
+	// Execute
+	_, ok, err := store.find([]byte("b"))
+
+	// Assert
+	if err != nil || ok {
+		t.Errorf("find(b) = (%v, %v), want (false, nil)", ok, err)
+	}
+
+	// Teardown: no resources to release.
+}
+
+func TestDiskStoreRemovesItsFileOnClose(t *testing.T) {
+	// Setup
+	dir := t.TempDir()
+	store, err := newDiskStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.add([]byte("s0")); err != nil {
+		t.Fatal(err)
+	}
+
+	// Execute
+	if err := store.close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Assert
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("entries left in the store directory: %v", entries)
+	}
+}
+
+func TestTupleCodecRoundTrips(t *testing.T) {
+	// Setup
+	components := []*component{
+		{ids: make([]StateID, 3)},
+		{ids: make([]StateID, 300)},
+		{ids: make([]StateID, 70000)},
+	}
+	codec := newTupleCodec(components)
+	tuple := []int32{2, 299, 69999}
+
+	// Execute
+	key := codec.encode(nil, tuple)
+	got := make([]int32, len(tuple))
+	codec.decode(got, key)
+
+	// Assert
+	if len(key) != 1+2+3 {
+		t.Errorf("len(key) = %d, want %d", len(key), 1+2+3)
+	}
+	for i := range tuple {
+		if got[i] != tuple[i] {
+			t.Errorf("decode(encode(%v)) = %v", tuple, got)
+			break
+		}
+	}
+}
+
+func TestExplorerRejectsUnknownStore(t *testing.T) {
+	// Setup
+	x := &Explorer{Store: "tape"}
+
+	// Execute
+	_, err := x.Normalize(mustParse(t, "@startuml\n[*] --> s0\n@enduml\n"))
+
+	// Assert
+	if err == nil {
+		t.Error("Normalize() error = nil, want an unknown store error")
+	}
+}
@@ -0,0 +1,262 @@
+// Package tla converts Composable State Diagrams to TLA+ modules whose
+// control skeleton TLC can check.
+package tla
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Kuniwak/puml-parallel/csdf"
+)
+
+// Annotation keys giving formal TLA+ text for the natural-language parts of a
+// diagram (docs/SYNTAX.md, "Annotations").
+const (
+	// GuardKey on an edge is the formal enabling condition of its action.
+	GuardKey = "tla.guard"
+	// PostKey on an edge is the formal next-state relation of the variables of
+	// VariablesKey, which it must all determine.
+	PostKey = "tla.post"
+	// VariablesKey on the diagram lists the variables besides state,
+	// separated by commas.
+	VariablesKey = "tla.variables"
+	// InitKey on the diagram is the formal initial condition of the variables.
+	InitKey = "tla.init"
+	// ExtendsKey on the diagram lists the modules to extend, such as Naturals.
+	ExtendsKey = "tla.extends"
+)
+
+// Terminated is the value of state after the end edge.
+const Terminated = "[*]"
+
+// Export writes d as a TLA+ module named module:
+//
+//   - the variable state holds the ID of the current state as a string,
+//   - Init sets state to the start state,
+//   - every edge is an action named after its event (numbered when events
+//     repeat), and Next is their disjunction,
+//   - the end edge sets state to "[*]", where Terminating stutters,
+//   - Spec is Init /\ [][Next]_vars.
+//
+// Guards and post-conditions are natural language and become comments, unless
+// annotations give their formal text (see GuardKey and the other keys).
+func Export(d *csdf.Diagram, module string) (string, error) {
+	names := newNamer()
+	if module == "" {
+		return "", fmt.Errorf("tla.Export: no module name")
+	}
+	if names.sanitize(module) != module {
+		return "", fmt.Errorf("tla.Export: module name %q is not a TLA+ identifier", module)
+	}
+	names.reserve(module, "state", "vars", "States", "TypeOK", "Init", "Next", "Spec", "Terminating")
+
+	variables := []string{"state"}
+	for _, v := range splitList(d.Annotations[VariablesKey]) {
+		if names.sanitize(v) != v {
+			return "", fmt.Errorf("tla.Export: variable %q is not a TLA+ identifier", v)
+		}
+		names.reserve(v)
+		variables = append(variables, v)
+	}
+	others := variables[1:]
+
+	var sb strings.Builder
+	header := "MODULE " + module
+	sb.WriteString("---- " + header + " ----\n")
+	if d.Name != "" {
+		sb.WriteString("\\* " + d.Name + "\n")
+	}
+	if extends := splitList(d.Annotations[ExtendsKey]); len(extends) > 0 {
+		sb.WriteString("EXTENDS " + strings.Join(extends, ", ") + "\n")
+	}
+	sb.WriteString("\nVARIABLES " + strings.Join(variables, ", ") + "\n")
+	sb.WriteString("vars == <<" + strings.Join(variables, ", ") + ">>\n")
+
+	// A state only referred to by edges is a value of state too.
+	seen := make(map[csdf.StateID]bool, len(d.States))
+	stateIDs := make([]csdf.StateID, 0, len(d.States))
+	add := func(id csdf.StateID) {
+		if !seen[id] {
+			seen[id] = true
+			stateIDs = append(stateIDs, id)
+		}
+	}
+	for id := range d.States {
+		add(id)
+	}
+	add(d.StartEdge.Dst)
+	for _, e := range d.Edges {
+		add(e.Src)
+		add(e.Dst)
+	}
+	if d.EndEdge != nil {
+		add(d.EndEdge.Src)
+	}
+	sort.Slice(stateIDs, func(i, j int) bool { return stateIDs[i] < stateIDs[j] })
+	values := make([]string, len(stateIDs))
+	for i, id := range stateIDs {
+		values[i] = quote(string(id))
+	}
+	sb.WriteString("\nStates == {" + strings.Join(values, ", ") + "}\n")
+	if d.EndEdge != nil {
+		sb.WriteString("\nTypeOK == state \\in States \\cup {" + quote(Terminated) + "}\n")
+	} else {
+		sb.WriteString("\nTypeOK == state \\in States\n")
+	}
+
+	sb.WriteString("\nInit ==\n    /\\ state = " + quote(string(d.StartEdge.Dst)) + "\n")
+	if init := d.Annotations[InitKey]; init != "" {
+		sb.WriteString("    /\\ " + init + "\n")
+	} else if d.StartEdge.Post != "" && d.StartEdge.Post != csdf.True {
+		sb.WriteString("    \\* post: " + d.StartEdge.Post + "\n")
+	}
+
+	var actions []string
+	for _, e := range d.Edges {
+		action := names.name(string(e.Event))
+		actions = append(actions, action)
+		sb.WriteString(fmt.Sprintf("\n\\* %s --%s--> %s\n", e.Src, e.Event, e.Dst))
+		sb.WriteString(action + " ==\n")
+		sb.WriteString("    /\\ state = " + quote(string(e.Src)) + "\n")
+		if guard, ok := e.Annotations[GuardKey]; ok {
+			sb.WriteString("    /\\ " + guard + "\n")
+		} else if e.Guard != "" && e.Guard != csdf.True {
+			sb.WriteString("    \\* guard: " + e.Guard + "\n")
+		}
+		sb.WriteString("    /\\ state' = " + quote(string(e.Dst)) + "\n")
+		post, formal := e.Annotations[PostKey]
+		if formal {
+			sb.WriteString("    /\\ " + post + "\n")
+		} else if e.Post != "" && e.Post != csdf.True {
+			sb.WriteString("    \\* post: " + e.Post + "\n")
+		}
+		if !formal && len(others) > 0 {
+			sb.WriteString("    /\\ UNCHANGED <<" + strings.Join(others, ", ") + ">>\n")
+		}
+	}
+
+	if d.EndEdge != nil {
+		action := names.name("end")
+		actions = append(actions, action)
+		sb.WriteString(fmt.Sprintf("\n\\* %s --> [*]\n", d.EndEdge.Src))
+		sb.WriteString(action + " ==\n")
+		sb.WriteString("    /\\ state = " + quote(string(d.EndEdge.Src)) + "\n")
+		if d.EndEdge.Guard != "" && d.EndEdge.Guard != csdf.True {
+			sb.WriteString("    \\* guard: " + d.EndEdge.Guard + "\n")
+		}
+		sb.WriteString("    /\\ state' = " + quote(Terminated) + "\n")
+		if len(others) > 0 {
+			sb.WriteString("    /\\ UNCHANGED <<" + strings.Join(others, ", ") + ">>\n")
+		}
+
+		sb.WriteString("\n\\* The end edge was taken; stuttering keeps TLC from reporting a deadlock.\n")
+		sb.WriteString("Terminating ==\n    /\\ state = " + quote(Terminated) + "\n    /\\ UNCHANGED vars\n")
+		actions = append(actions, "Terminating")
+	}
+
+	switch len(actions) {
+	case 0:
+		sb.WriteString("\nNext == FALSE\n")
+	default:
+		sb.WriteString("\nNext ==\n")
+		for _, action := range actions {
+			sb.WriteString("    \\/ " + action + "\n")
+		}
+	}
+	sb.WriteString("\nSpec == Init /\\ [][Next]_vars\n")
+	sb.WriteString("\n" + strings.Repeat("=", len(header)+10) + "\n")
+	return sb.String(), nil
+}
+
+// ModuleName is the default module name for d: its name made an identifier,
+// or "System" when it has none.
+func ModuleName(d *csdf.Diagram) string {
+	if d.Name == "" {
+		return "System"
+	}
+	return newNamer().sanitize(d.Name)
+}
+
+func splitList(s string) []string {
+	var list []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// quote writes s as a TLA+ string.
+func quote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// keywords are the TLA+ reserved words and the operators of the standard
+// modules that generated identifiers must avoid.
+var keywords = []string{
+	"ASSUME", "ASSUMPTION", "AXIOM", "CASE", "CHOOSE", "CONSTANT", "CONSTANTS",
+	"DOMAIN", "ELSE", "ENABLED", "EXCEPT", "EXTENDS", "IF", "IN", "INSTANCE",
+	"LET", "LOCAL", "MODULE", "OTHER", "SF_", "SUBSET", "THEN", "THEOREM",
+	"UNCHANGED", "UNION", "VARIABLE", "VARIABLES", "WF_", "WITH", "TRUE", "FALSE",
+	"BOOLEAN", "STRING", "Nat", "Int", "Real", "Seq", "Len", "Append", "Head",
+	"Tail", "Cardinality", "LAMBDA", "RECURSIVE", "PROOF", "BY", "QED", "OBVIOUS",
+}
+
+// namer allocates distinct TLA+ identifiers.
+type namer struct {
+	taken map[string]struct{}
+}
+
+func newNamer() *namer {
+	n := &namer{taken: make(map[string]struct{})}
+	n.reserve(keywords...)
+	return n
+}
+
+func (n *namer) reserve(names ...string) {
+	for _, name := range names {
+		n.taken[name] = struct{}{}
+	}
+}
+
+// sanitize replaces the characters other than ASCII letters, digits and '_'
+// with '_', and prefixes "a_" when the result would not start with a letter.
+func (n *namer) sanitize(text string) string {
+	var sb strings.Builder
+	for _, r := range text {
+		if isIdentRune(r) {
+			sb.WriteRune(r)
+		} else {
+			sb.WriteByte('_')
+		}
+	}
+	base := strings.TrimRight(sb.String(), "_")
+	if base == "" || !isLetter(rune(base[0])) {
+		base = "a_" + base
+	}
+	return base
+}
+
+// name returns an unused identifier close to text.
+func (n *namer) name(text string) string {
+	base := n.sanitize(text)
+	candidate := base
+	for i := 2; ; i++ {
+		if _, ok := n.taken[candidate]; !ok {
+			break
+		}
+		candidate = fmt.Sprintf("%s_%d", base, i)
+	}
+	n.taken[candidate] = struct{}{}
+	return candidate
+}
+
+func isLetter(r rune) bool {
+	return (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z')
+}
+
+func isIdentRune(r rune) bool {
+	return isLetter(r) || (r >= '0' && r <= '9') || r == '_'
+}
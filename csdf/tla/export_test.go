@@ -0,0 +1,191 @@
+package tla
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Kuniwak/puml-parallel/csdf"
+	"github.com/google/go-cmp/cmp"
+)
+
+func mustParse(t *testing.T, src string) *csdf.Diagram {
+	t.Helper()
+	d, err := csdf.ParseDiagram([]byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+func TestExportUsesFormalAnnotations(t *testing.T) {
+	// Setup
+	d := mustParse(t, `@startuml tea
+/'@tla.variables cups
+@tla.init cups = 0
+@tla.extends Naturals'/
+
+state "Ready" as ready
+state "Brewing" as brewing
+[*] --> ready : no cups
+ready --> brewing : coin
+/'@tla.guard cups < 3
+@tla.post cups' = cups + 1'/
+brewing --> ready : tea ; fewer than three cups ; one more cup
+brewing --> brewing : tau
+ready --> [*]
+@enduml
+`)
+	want := `---- MODULE Tea ----
+\* tea
+EXTENDS Naturals
+
+VARIABLES state, cups
+vars == <<state, cups>>
+
+States == {"brewing", "ready"}
+
+TypeOK == state \in States \cup {"[*]"}
+
+Init ==
+    /\ state = "ready"
+    /\ cups = 0
+
+\* ready --coin--> brewing
+coin ==
+    /\ state = "ready"
+    /\ state' = "brewing"
+    /\ UNCHANGED <<cups>>
+
+\* brewing --tea--> ready
+tea ==
+    /\ state = "brewing"
+    /\ cups < 3
+    /\ state' = "ready"
+    /\ cups' = cups + 1
+
+\* brewing --tau--> brewing
+tau ==
+    /\ state = "brewing"
+    /\ state' = "brewing"
+    /\ UNCHANGED <<cups>>
+
+\* ready --> [*]
+end ==
+    /\ state = "ready"
+    /\ state' = "[*]"
+    /\ UNCHANGED <<cups>>
+
+\* The end edge was taken; stuttering keeps TLC from reporting a deadlock.
+Terminating ==
+    /\ state = "[*]"
+    /\ UNCHANGED vars
+
+Next ==
+    \/ coin
+    \/ tea
+    \/ tau
+    \/ end
+    \/ Terminating
+
+Spec == Init /\ [][Next]_vars
+
+====================
+`
+
+	// Execute
+	got, err := Export(d, "Tea")
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Error(diff)
+	}
+
+	// Teardown: no resources to release.
+}
+
+func TestExportKeepsConditionsAsComments(t *testing.T) {
+	// Setup: the two insert(coin) edges become the actions insert_coin and
+	// insert_coin_2.
+	d := csdf.MustLoadDiagrams("../../examples/valid/vending_machine.puml")[0]
+	wants := []string{
+		"VARIABLES state\n",
+		"Init ==\n    /\\ state = \"vmIdle\"\n    \\* post: availableProducts' are products initially in the vending machine\n",
+		"insert_coin ==\n    /\\ state = \"vmIdle\"\n",
+		"insert_coin_2 ==\n    /\\ state = \"vmWaitingChoosing\"\n",
+		"choose_product ==\n    /\\ state = \"vmWaitingChoosing\"\n    \\* guard: availableProducts contains product\n    /\\ state' = \"vmDropping\"\n    \\* post: product' is product\n",
+	}
+
+	// Execute
+	got, err := Export(d, "VendingMachine")
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+	for _, want := range wants {
+		if !strings.Contains(got, want) {
+			t.Errorf("want %q in:\n%s", want, got)
+		}
+	}
+
+	// Teardown: no resources to release.
+}
+
+func TestExportIncludesStatesOnlyReferredToByEdges(t *testing.T) {
+	// Setup: b is not declared.
+	d := mustParse(t, `@startuml
+state "A" as a
+[*] --> a
+a --> b : go
+b --> a : back
+@enduml
+`)
+	want := "\nStates == {\"a\", \"b\"}\n"
+
+	// Execute
+	got, err := Export(d, "System")
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+	if !strings.Contains(got, want) {
+		t.Errorf("want %q in:\n%s", want, got)
+	}
+
+	// Teardown: no resources to release.
+}
+
+func TestExportRejectsInvalidNames(t *testing.T) {
+	testCases := map[string]struct {
+		module string
+		src    string
+	}{
+		"module name": {
+			module: "vending machine",
+			src:    "@startuml\nstate \"s0\" as s0\n[*] --> s0\n@enduml\n",
+		},
+		"variable": {
+			module: "M",
+			src:    "@startuml\n/'@tla.variables cups, coin-count'/\n\nstate \"s0\" as s0\n[*] --> s0\n@enduml\n",
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Setup
+			d := mustParse(t, tc.src)
+
+			// Execute
+			_, err := Export(d, tc.module)
+
+			// Assert
+			if err == nil {
+				t.Error("Export() error = nil, want error")
+			}
+		})
+	}
+}
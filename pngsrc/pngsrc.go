@@ -1,8 +1,12 @@
-// Package pngsrc extracts PlantUML source from raw input bytes.
+// Package pngsrc extracts PlantUML source from raw input bytes and embeds it
+// into PNG images.
 //
 // If the input begins with the PNG signature, Extract reads the embedded
 // PlantUML source from a tEXt/zTXt/iTXt chunk whose keyword is "plantuml".
-// Otherwise Extract returns the input as a UTF-8 string.
+// If the input is an SVG image, Extract decodes the source from the SRC=[...]
+// comment PlantUML writes into it. Otherwise Extract returns the input as a
+// UTF-8 string. Embed writes a PNG text chunk, so that a rendered diagram
+// carries its own source.
 //
 // PNGTextChunks is exposed as a general-purpose iterator over a PNG image's
 // text chunks; Extract is a thin wrapper around it.
@@ -14,8 +18,11 @@ import (
 	"encoding/binary"
 	"errors"
 	"fmt"
+	"hash/crc32"
 	"io"
 	"iter"
+
+	"github.com/Kuniwak/puml-parallel/pumlenc"
 )
 
 const pngSignature = "\x89PNG\r\n\x1a\n"
@@ -29,7 +36,8 @@ const maxChunkLen = 0x7FFFFFFF
 
 // MaxDecompressedSize bounds the size of a decompressed text chunk, guarding
 // against deflate-bomb inputs. PlantUML diagrams in practice are far smaller.
-const MaxDecompressedSize = 16 << 20 // 16 MiB
+// It is the limit of the SRC=[...] comments of SVG images too.
+const MaxDecompressedSize = pumlenc.MaxDecodedSize
 
 // ErrNoPlantUMLChunk is returned when raw is a PNG that does not contain a
 // text chunk with the "plantuml" keyword.
@@ -43,11 +51,23 @@ type PNGTextChunk struct {
 
 // Extract returns the PlantUML source contained in raw.
 //
-// When raw is not a PNG, the bytes are returned verbatim as a string.
 // When raw is a PNG, the first tEXt/zTXt/iTXt chunk with keyword "plantuml"
 // is decoded and returned. PNG inputs without such a chunk yield
 // ErrNoPlantUMLChunk.
+//
+// When the root element of raw is an SVG element, the source is decoded from
+// the first SRC=[...] comment, in the text encoding of package pumlenc. SVG
+// inputs without such a comment yield ErrNoPlantUMLComment.
+//
+// Other bytes are returned verbatim as a string.
 func Extract(raw []byte) (string, error) {
+	if isSVG(raw) {
+		source, err := extractSVG(raw)
+		if err != nil {
+			return "", fmt.Errorf("pngsrc.Extract: %w", err)
+		}
+		return source, nil
+	}
 	if !bytes.HasPrefix(raw, []byte(pngSignature)) {
 		return string(raw), nil
 	}
@@ -214,3 +234,93 @@ func InflateBounded(compressed []byte, max int64) (string, error) {
 	}
 	return string(out), nil
 }
+
+// Embed returns a copy of the PNG image png whose "plantuml" text chunk holds
+// source. The chunk replaces the first existing "plantuml" chunk, whatever its
+// type, and further ones are dropped; without one, it is inserted before IEND.
+// Every other chunk is copied byte for byte.
+//
+// The chunk is a compressed iTXt chunk, since PlantUML source is UTF-8 while
+// tEXt and zTXt are Latin-1. png must be a well-formed PNG ending in IEND.
+func Embed(png []byte, source string) ([]byte, error) {
+	if !bytes.HasPrefix(png, []byte(pngSignature)) {
+		return nil, errors.New("pngsrc.Embed: not a PNG image")
+	}
+	chunk, err := encodeITXt(plantumlKeyword, source)
+	if err != nil {
+		return nil, fmt.Errorf("pngsrc.Embed: %w", err)
+	}
+
+	var out bytes.Buffer
+	out.Grow(len(png) + len(chunk))
+	out.WriteString(pngSignature)
+	embedded := false
+	body := png[len(pngSignature):]
+	for pos := 0; pos < len(body); {
+		if len(body)-pos < 8 {
+			return nil, fmt.Errorf("pngsrc.Embed: truncated PNG chunk header at offset %d", pos)
+		}
+		length := binary.BigEndian.Uint32(body[pos : pos+4])
+		if length > maxChunkLen {
+			return nil, fmt.Errorf("pngsrc.Embed: chunk length %d exceeds PNG maximum", length)
+		}
+		typ := string(body[pos+4 : pos+8])
+		end := uint64(pos) + 8 + uint64(length) + 4
+		if end > uint64(len(body)) {
+			return nil, fmt.Errorf("pngsrc.Embed: chunk %q length %d overruns input", typ, length)
+		}
+		raw := body[pos:end]
+		data := raw[8 : 8+length]
+		pos = int(end)
+
+		switch {
+		case typ == "IEND":
+			if !embedded {
+				out.Write(chunk)
+			}
+			out.Write(raw)
+			return out.Bytes(), nil
+		case isTextChunk(typ) && hasKeyword(data, plantumlKeyword):
+			if !embedded {
+				out.Write(chunk)
+				embedded = true
+			}
+		default:
+			out.Write(raw)
+		}
+	}
+	return nil, errors.New("pngsrc.Embed: missing IEND chunk")
+}
+
+func isTextChunk(typ string) bool {
+	return typ == "tEXt" || typ == "zTXt" || typ == "iTXt"
+}
+
+func hasKeyword(data []byte, keyword string) bool {
+	return bytes.HasPrefix(data, append([]byte(keyword), 0))
+}
+
+// encodeITXt returns a whole compressed iTXt chunk: length, type, data and CRC.
+func encodeITXt(keyword, text string) ([]byte, error) {
+	var data bytes.Buffer
+	data.WriteString(keyword)
+	// NUL separator, compression flag 1, compression method 0 (zlib), then an
+	// empty language tag and an empty translated keyword.
+	data.Write([]byte{0, 1, 0, 0, 0})
+	w := zlib.NewWriter(&data)
+	if _, err := io.WriteString(w, text); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	if data.Len() > maxChunkLen {
+		return nil, fmt.Errorf("the compressed source is %d bytes, more than a PNG chunk holds", data.Len())
+	}
+
+	chunk := make([]byte, 0, 12+data.Len())
+	chunk = binary.BigEndian.AppendUint32(chunk, uint32(data.Len()))
+	chunk = append(chunk, "iTXt"...)
+	chunk = append(chunk, data.Bytes()...)
+	return binary.BigEndian.AppendUint32(chunk, crc32.ChecksumIEEE(chunk[4:])), nil
+}
@@ -5,6 +5,7 @@ import (
 	"compress/zlib"
 	"encoding/binary"
 	"hash/crc32"
+	"image/png"
 	"os"
 	"strings"
 	"testing"
@@ -289,6 +290,139 @@ func TestExtractFromRealPlantUMLPNG(t *testing.T) {
 	}
 }
 
+func TestEmbed(t *testing.T) {
+	header := rawChunk{typ: "IHDR", data: bytes.Repeat([]byte{0}, 13)}
+	cases := []struct {
+		name  string
+		input []byte
+		want  []PNGTextChunk
+	}{
+		{
+			name:  "inserts the chunk before IEND",
+			input: buildPNG(header, tEXt("Author", "alice"), iendChunk()),
+			want: []PNGTextChunk{
+				{Keyword: "Author", Text: "alice"},
+				{Keyword: "plantuml", Text: "@startuml\n[*] --> 状態\n@enduml\n"},
+			},
+		},
+		{
+			name:  "replaces a zTXt chunk in place",
+			input: buildPNG(header, zTXt("plantuml", "OLD"), tEXt("Author", "alice"), iendChunk()),
+			want: []PNGTextChunk{
+				{Keyword: "plantuml", Text: "@startuml\n[*] --> 状態\n@enduml\n"},
+				{Keyword: "Author", Text: "alice"},
+			},
+		},
+		{
+			name: "drops further plantuml chunks",
+			input: buildPNG(
+				header,
+				tEXt("plantuml", "FIRST"),
+				tEXt("Author", "alice"),
+				iTXt("plantuml", true, "SECOND"),
+				iendChunk(),
+			),
+			want: []PNGTextChunk{
+				{Keyword: "plantuml", Text: "@startuml\n[*] --> 状態\n@enduml\n"},
+				{Keyword: "Author", Text: "alice"},
+			},
+		},
+		{
+			name:  "keeps chunks whose keyword only starts with plantuml",
+			input: buildPNG(header, tEXt("plantuml-version", "1.2024"), iendChunk()),
+			want: []PNGTextChunk{
+				{Keyword: "plantuml-version", Text: "1.2024"},
+				{Keyword: "plantuml", Text: "@startuml\n[*] --> 状態\n@enduml\n"},
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := Embed(tc.input, "@startuml\n[*] --> 状態\n@enduml\n")
+			if err != nil {
+				t.Fatalf("Embed: unexpected error: %v", err)
+			}
+			assertCRCs(t, got)
+			var chunks []PNGTextChunk
+			for c, err := range PNGTextChunks(got) {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				chunks = append(chunks, c)
+			}
+			if len(chunks) != len(tc.want) {
+				t.Fatalf("got %d chunks, want %d (got=%+v)", len(chunks), len(tc.want), chunks)
+			}
+			for i := range tc.want {
+				if chunks[i] != tc.want[i] {
+					t.Errorf("chunk %d: got %+v, want %+v", i, chunks[i], tc.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestEmbedRejectsMalformedPNG(t *testing.T) {
+	cases := []struct {
+		name  string
+		input []byte
+	}{
+		{name: "not a PNG", input: []byte("@startuml\n@enduml\n")},
+		{name: "missing IEND", input: buildPNG(tEXt("Author", "alice"))},
+		{name: "truncated chunk", input: buildPNG(rawChunk{typ: "IHDR", data: bytes.Repeat([]byte{0}, 13), lenOverride: 0xFFFFFFF0})},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got, err := Embed(tc.input, "@startuml\n@enduml\n"); err == nil {
+				t.Fatalf("Embed: want error, got nil (result=%d bytes)", len(got))
+			}
+		})
+	}
+}
+
+func TestEmbedIntoRealPlantUMLPNG(t *testing.T) {
+	pngBytes, err := os.ReadFile("../examples/valid/client.png")
+	if err != nil {
+		t.Fatalf("read .png fixture: %v", err)
+	}
+	source := "@startuml\nstate \"Replaced\" as r\n[*] --> r\n@enduml\n"
+
+	got, err := Embed(pngBytes, source)
+	if err != nil {
+		t.Fatalf("Embed: %v", err)
+	}
+
+	// image/png verifies the CRC of every chunk it reads, including text
+	// chunks it skips.
+	if _, err := png.Decode(bytes.NewReader(got)); err != nil {
+		t.Fatalf("png.Decode(Embed(...)): %v", err)
+	}
+	extracted, err := Extract(got)
+	if err != nil {
+		t.Fatalf("Extract(Embed(...)): %v", err)
+	}
+	if extracted != source {
+		t.Fatalf("Extract(Embed(...)): got %q, want %q", extracted, source)
+	}
+}
+
+// assertCRCs fails the test unless every chunk of raw has a correct CRC.
+func assertCRCs(t *testing.T, raw []byte) {
+	t.Helper()
+	body := raw[len(pngSignature):]
+	for pos := 0; pos < len(body); {
+		length := int(binary.BigEndian.Uint32(body[pos : pos+4]))
+		typeAndData := body[pos+4 : pos+8+length]
+		got := binary.BigEndian.Uint32(body[pos+8+length : pos+12+length])
+		if want := crc32.ChecksumIEEE(typeAndData); got != want {
+			t.Errorf("chunk %q: CRC %08x, want %08x", typeAndData[:4], got, want)
+		}
+		pos += 12 + length
+	}
+}
+
 // --- test helpers: build minimal PNG byte sequences ---
 
 type rawChunk struct {
@@ -0,0 +1,77 @@
+package pngsrc
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/Kuniwak/puml-parallel/pumlenc"
+)
+
+// ErrNoPlantUMLComment is returned when raw is an SVG image without the
+// SRC=[...] comment holding its PlantUML source.
+var ErrNoPlantUMLComment = errors.New("pngsrc: no SRC=[...] comment found in SVG")
+
+// isSVG reports whether the root element of raw is an SVG element. Comments,
+// processing instructions and a DOCTYPE may precede it.
+func isSVG(raw []byte) bool {
+	text := strings.TrimPrefix(string(raw), "\ufeff")
+	if !strings.HasPrefix(strings.TrimSpace(text), "<") {
+		return false
+	}
+	decoder := xml.NewDecoder(strings.NewReader(text))
+	decoder.Strict = false
+	// Only the name of the root element matters, so any declared encoding,
+	// such as the us-ascii of PlantUML, is read as is.
+	decoder.CharsetReader = func(_ string, input io.Reader) (io.Reader, error) { return input, nil }
+	for {
+		token, err := decoder.RawToken()
+		if err != nil {
+			return false
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			return t.Name.Local == "svg"
+		case xml.CharData:
+			if strings.TrimSpace(string(t)) != "" {
+				return false
+			}
+		}
+	}
+}
+
+// extractSVG decodes the PlantUML source of an SVG image from the SRC=[...]
+// comment PlantUML writes into it. The source is encoded, so the comment
+// holds it exactly even though XML comments cannot contain the "--" of
+// arrows.
+func extractSVG(raw []byte) (string, error) {
+	for rest := string(raw); ; {
+		start := strings.Index(rest, "<!--")
+		if start < 0 {
+			return "", ErrNoPlantUMLComment
+		}
+		rest = rest[start+len("<!--"):]
+		end := strings.Index(rest, "-->")
+		if end < 0 {
+			return "", errors.New("pngsrc.extractSVG: unterminated comment")
+		}
+		comment := rest[:end]
+		rest = rest[end+len("-->"):]
+
+		_, after, ok := strings.Cut(comment, "SRC=[")
+		if !ok {
+			continue
+		}
+		encoded, _, ok := strings.Cut(after, "]")
+		if !ok {
+			return "", errors.New("pngsrc.extractSVG: unterminated SRC=[...]")
+		}
+		source, err := pumlenc.Decode(encoded)
+		if err != nil {
+			return "", fmt.Errorf("pngsrc.extractSVG: SRC: %w", err)
+		}
+		return source, nil
+	}
+}
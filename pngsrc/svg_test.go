@@ -0,0 +1,115 @@
+package pngsrc
+
+import (
+	"errors"
+	"testing"
+)
+
+// inSource is examples/valid/in.puml; inSRC is its encoded form.
+const (
+	inSource = "@startuml\n\nstate \"s0\" as s0\nstate \"s1\" as s1\nstate \"s2\" as s2\n\n[*] --> s0\ns0 --> s1 : in\ns1 --> s2 : sync\n\n@enduml\n"
+	inSRC    = "SoWkIImgAStDuU82iafI5PIA3PGK4eiLYWtW0eOG0KEuW154m8YBArehLa5NrmwisW32CbImKiZCumBIOAuHa5jScPVCnUMGcfS2iWW0"
+)
+
+func TestExtractSVG(t *testing.T) {
+	cases := []struct {
+		name    string
+		input   string
+		want    string
+		wantErr error
+	}{
+		{
+			name:  "SRC comment at the end of the drawing",
+			input: `<?xml version="1.0" encoding="us-ascii" standalone="no"?><svg xmlns="http://www.w3.org/2000/svg" width="100" height="200"><defs/><g><ellipse cx="10" cy="10" rx="5" ry="5"/><!--SRC=[` + inSRC + `]--></g></svg>`,
+			want:  inSource,
+		},
+		{
+			name: "DOCTYPE, comments and other comments before the SRC comment",
+			input: `<?xml version="1.0"?>
+<!DOCTYPE svg PUBLIC "-//W3C//DTD SVG 1.1//EN" "http://www.w3.org/Graphics/SVG/1.1/DTD/svg11.dtd">
+<!-- generated -->
+<svg xmlns="http://www.w3.org/2000/svg"><!--MD5=[8d5298e8a5aa3e8d]--><g><!--SRC=[SyfFKj2rKt3CoKnELR1Io4ZDoSa70000]--></g></svg>
+`,
+			want: "Bob -> Alice : hello",
+		},
+		{
+			name:  "hexadecimal SRC",
+			input: `<svg xmlns="http://www.w3.org/2000/svg"><!--SRC=[~h407374617274756d6c0a40656e64756d6c0a]--></svg>`,
+			want:  "@startuml\n@enduml\n",
+		},
+		{
+			name:    "SVG without a SRC comment",
+			input:   `<svg xmlns="http://www.w3.org/2000/svg"><!-- drawn by hand --><g/></svg>`,
+			wantErr: ErrNoPlantUMLComment,
+		},
+		{
+			name:    "unterminated comment",
+			input:   `<svg xmlns="http://www.w3.org/2000/svg"><!--SRC=[` + inSRC + `]`,
+			wantErr: errAny,
+		},
+		{
+			name:    "corrupt SRC",
+			input:   `<svg xmlns="http://www.w3.org/2000/svg"><!--SRC=[SyfFKj2rKt3CoKnELR1Io4]--></svg>`,
+			wantErr: errAny,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := Extract([]byte(tc.input))
+			if tc.wantErr != nil {
+				if err == nil {
+					t.Fatalf("Extract: want error, got nil (result=%q)", got)
+				}
+				if tc.wantErr != errAny && !errors.Is(err, tc.wantErr) {
+					t.Fatalf("Extract: got %v, want %v", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Extract: unexpected error: %v", err)
+			}
+			if got != tc.want {
+				t.Fatalf("Extract: got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestExtractPassesEncodedTextThrough(t *testing.T) {
+	// Setup: server links are decoded by csdf.ReadDiagramRef, not here.
+	for _, input := range []string{
+		"https://www.plantuml.com/plantuml/png/" + inSRC + "\n",
+		inSRC,
+		"tokenlike",
+	} {
+		// Execute
+		got, err := Extract([]byte(input))
+
+		// Assert
+		if err != nil {
+			t.Fatalf("Extract(%q): unexpected error: %v", input, err)
+		}
+		if got != input {
+			t.Fatalf("Extract(%q): got %q, want the input", input, got)
+		}
+	}
+}
+
+func TestExtractPassesOtherXMLThrough(t *testing.T) {
+	input := `<?xml version="1.0"?>
+<!-- SRC=[SyfFKj2rKt3CoKnELR1Io4ZDoSa70000] -->
+<scxml xmlns="http://www.w3.org/2005/07/scxml" version="1.0" initial="a"><state id="a"/></scxml>
+`
+
+	got, err := Extract([]byte(input))
+	if err != nil {
+		t.Fatalf("Extract: unexpected error: %v", err)
+	}
+	if got != input {
+		t.Fatalf("Extract: got %q, want the input", got)
+	}
+}
+
+// errAny marks test cases that expect some error.
+var errAny = errors.New("any error")
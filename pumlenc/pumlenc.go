@@ -0,0 +1,170 @@
+// Package pumlenc implements the text encoding of PlantUML servers, which
+// deflates the diagram source and writes it in a base64 variant with the
+// alphabet 0-9A-Za-z-_. PlantUML also writes this encoding into the
+// SRC=[...] comment of its SVG output.
+//
+// Links to a server have the form <server>/<format>/<encoded>, such as
+// https://www.plantuml.com/plantuml/png/SyfFKj2rKt3CoKnELR1Io4ZDoSa70000.
+// Nothing here accesses the network: the source is in the link itself.
+package pumlenc
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// DefaultServer is the public PlantUML server.
+const DefaultServer = "https://www.plantuml.com/plantuml"
+
+// Formats are the path elements before the encoded text in server links:
+// images, ASCII art, and the web editor (uml).
+var Formats = []string{"png", "svg", "txt", "uml"}
+
+// otherFormats are further formats servers serve, accepted in links.
+var otherFormats = []string{"img", "eps", "epstext", "pdf", "map", "check", "proxy"}
+
+const alphabet = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz-_"
+
+// MaxDecodedSize bounds the size of a decoded source, guarding against
+// deflate-bomb inputs.
+const MaxDecodedSize = 16 << 20 // 16 MiB
+
+// Decode returns the PlantUML source encoded in s. Besides deflated text, it
+// reads the "~h" form, the source in hexadecimal. Brotli-compressed text,
+// marked by "~1", is not supported.
+func Decode(s string) (string, error) {
+	switch {
+	case strings.HasPrefix(s, "~h"):
+		bs, err := hex.DecodeString(s[len("~h"):])
+		if err != nil {
+			return "", fmt.Errorf("pumlenc.Decode: %w", err)
+		}
+		return string(bs), nil
+	case strings.HasPrefix(s, "~1"):
+		return "", errors.New("pumlenc.Decode: Brotli-compressed text (\"~1\") is not supported")
+	}
+
+	compressed, err := decode64(s)
+	if err != nil {
+		return "", fmt.Errorf("pumlenc.Decode: %w", err)
+	}
+	r := flate.NewReader(bytes.NewReader(compressed))
+	defer func() { _ = r.Close() }()
+	out, err := io.ReadAll(io.LimitReader(r, MaxDecodedSize+1))
+	if err != nil {
+		return "", fmt.Errorf("pumlenc.Decode: not deflated text: %w", err)
+	}
+	if len(out) > MaxDecodedSize {
+		return "", fmt.Errorf("pumlenc.Decode: decoded text exceeds %d bytes", MaxDecodedSize)
+	}
+	return string(out), nil
+}
+
+// Encode returns the text encoding of source: the source deflated at the best
+// compression, in the base64 variant of Decode.
+func Encode(source string) string {
+	var compressed bytes.Buffer
+	w, err := flate.NewWriter(&compressed, flate.BestCompression)
+	if err != nil {
+		panic(err) // unreachable: the level is valid
+	}
+	_, _ = io.WriteString(w, source)
+	_ = w.Close()
+	return encode64(compressed.Bytes())
+}
+
+// URL returns the link to source on server, rendered in format (see Formats).
+func URL(server, format, source string) string {
+	return strings.TrimSuffix(server, "/") + "/" + format + "/" + Encode(source)
+}
+
+// ParseRef returns the encoded text of ref when ref is a server link
+// (http or https, with the encoded text after a format path element) or
+// encoded text itself. Surrounding white space is ignored. ParseRef only
+// checks the syntax; Decode reports whether the text decodes.
+func ParseRef(ref string) (string, bool) {
+	ref = strings.TrimSpace(ref)
+	if strings.ContainsAny(ref, " \t\r\n") {
+		return "", false
+	}
+	if strings.Contains(ref, "://") {
+		u, err := url.Parse(ref)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			return "", false
+		}
+		elements := strings.Split(strings.Trim(u.Path, "/"), "/")
+		if len(elements) < 2 {
+			return "", false
+		}
+		format, encoded := elements[len(elements)-2], elements[len(elements)-1]
+		if !slices.Contains(Formats, format) && !slices.Contains(otherFormats, format) {
+			return "", false
+		}
+		if !isEncoded(encoded) {
+			return "", false
+		}
+		return encoded, true
+	}
+	if !isEncoded(ref) {
+		return "", false
+	}
+	return ref, true
+}
+
+// isEncoded reports whether s consists of the characters of an encoded text.
+func isEncoded(s string) bool {
+	s = strings.TrimPrefix(strings.TrimPrefix(s, "~h"), "~1")
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if strings.IndexByte(alphabet, s[i]) < 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// encode64 writes every group of 3 bytes as 4 characters, padding the last
+// group with zeros.
+func encode64(data []byte) string {
+	var sb strings.Builder
+	sb.Grow((len(data) + 2) / 3 * 4)
+	for i := 0; i < len(data); i += 3 {
+		var group [3]byte
+		copy(group[:], data[i:])
+		n := uint32(group[0])<<16 | uint32(group[1])<<8 | uint32(group[2])
+		for shift := 18; shift >= 0; shift -= 6 {
+			sb.WriteByte(alphabet[n>>shift&0x3F])
+		}
+	}
+	return sb.String()
+}
+
+// decode64 reads the base64 variant. Encoders pad the last group of 3 bytes
+// with zeros, which the inflater ignores as data after the final block.
+func decode64(s string) ([]byte, error) {
+	out := make([]byte, 0, len(s)*3/4)
+	var acc uint32
+	bits := 0
+	for i := 0; i < len(s); i++ {
+		v := strings.IndexByte(alphabet, s[i])
+		if v < 0 {
+			return nil, fmt.Errorf("invalid character %q at offset %d", s[i], i)
+		}
+		acc = acc<<6 | uint32(v)
+		bits += 6
+		if bits >= 8 {
+			bits -= 8
+			out = append(out, byte(acc>>bits))
+		}
+	}
+	return out, nil
+}
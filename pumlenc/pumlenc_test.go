@@ -0,0 +1,161 @@
+package pumlenc
+
+import (
+	"strings"
+	"testing"
+
+	"pgregory.net/rapid"
+)
+
+func TestDecode(t *testing.T) {
+	cases := []struct {
+		name    string
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{
+			name:  "deflated text from the PlantUML documentation",
+			input: "SyfFKj2rKt3CoKnELR1Io4ZDoSa70000",
+			want:  "Bob -> Alice : hello",
+		},
+		{
+			name:  "hexadecimal text",
+			input: "~h407374617274756d6c0a40656e64756d6c0a",
+			want:  "@startuml\n@enduml\n",
+		},
+		{
+			name:    "Brotli-compressed text is not supported",
+			input:   "~1UDfSa70000",
+			wantErr: true,
+		},
+		{
+			name:    "character outside the alphabet",
+			input:   "SyfFKj2r+t3CoKnELR1Io4ZDoSa70000",
+			wantErr: true,
+		},
+		{
+			name:    "truncated deflate stream",
+			input:   "SyfFKj2rKt3CoKnE",
+			wantErr: true,
+		},
+		{
+			name:    "odd hexadecimal text",
+			input:   "~h407",
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := Decode(tc.input)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("Decode: want error, got nil (result=%q)", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Decode: unexpected error: %v", err)
+			}
+			if got != tc.want {
+				t.Fatalf("Decode: got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestEncodeRoundTrips(t *testing.T) {
+	rapid.Check(t, func(t *rapid.T) {
+		source := rapid.String().Draw(t, "source")
+
+		encoded := Encode(source)
+
+		if strings.Trim(encoded, alphabet) != "" {
+			t.Fatalf("Encode(%q) = %q, which has characters outside the alphabet", source, encoded)
+		}
+		got, err := Decode(encoded)
+		if err != nil {
+			t.Fatalf("Decode(Encode(%q)): unexpected error: %v", source, err)
+		}
+		if got != source {
+			t.Fatalf("Decode(Encode(%q)) = %q", source, got)
+		}
+	})
+}
+
+func TestURL(t *testing.T) {
+	got := URL("https://plantuml.example.com/plantuml/", "svg", "Bob -> Alice : hello")
+
+	want := "https://plantuml.example.com/plantuml/svg/" + Encode("Bob -> Alice : hello")
+	if got != want {
+		t.Fatalf("URL: got %q, want %q", got, want)
+	}
+}
+
+func TestParseRef(t *testing.T) {
+	cases := []struct {
+		name   string
+		input  string
+		want   string
+		wantOK bool
+	}{
+		{
+			name:   "link to a PNG on the public server",
+			input:  "https://www.plantuml.com/plantuml/png/SyfFKj2rKt3CoKnELR1Io4ZDoSa70000",
+			want:   "SyfFKj2rKt3CoKnELR1Io4ZDoSa70000",
+			wantOK: true,
+		},
+		{
+			name:   "link to the editor of a private server with a query",
+			input:  "http://wiki.example.com:8080/uml/SyfFKj2rKt3CoKnELR1Io4ZDoSa70000?version=1\n",
+			want:   "SyfFKj2rKt3CoKnELR1Io4ZDoSa70000",
+			wantOK: true,
+		},
+		{
+			name:   "encoded text with surrounding white space",
+			input:  "  SyfFKj2rKt3CoKnELR1Io4ZDoSa70000\n",
+			want:   "SyfFKj2rKt3CoKnELR1Io4ZDoSa70000",
+			wantOK: true,
+		},
+		{
+			name:   "hexadecimal text",
+			input:  "~h407374617274756d6c",
+			want:   "~h407374617274756d6c",
+			wantOK: true,
+		},
+		{
+			name:  "link without a format",
+			input: "https://www.plantuml.com/SyfFKj2rKt3CoKnELR1Io4ZDoSa70000",
+		},
+		{
+			name:  "link to another site",
+			input: "https://example.com/docs/diagram.puml",
+		},
+		{
+			name:  "ftp link",
+			input: "ftp://www.plantuml.com/plantuml/png/SyfFKj2rKt3CoKnELR1Io4ZDoSa70000",
+		},
+		{
+			name:  "PlantUML source",
+			input: "@startuml\n[*] --> a\n@enduml\n",
+		},
+		{
+			name:  "file name",
+			input: "diagram.puml",
+		},
+		{
+			name:  "empty",
+			input: "",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, ok := ParseRef(tc.input)
+			if ok != tc.wantOK || got != tc.want {
+				t.Fatalf("ParseRef(%q) = (%q, %v), want (%q, %v)", tc.input, got, ok, tc.want, tc.wantOK)
+			}
+		})
+	}
+}
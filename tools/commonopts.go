@@ -5,9 +5,10 @@ import (
 	"fmt"
 	"io"
 	"log/slog"
-	"os"
+	"strings"
 
 	"github.com/Kuniwak/puml-parallel/cli"
+	"github.com/Kuniwak/puml-parallel/csdf"
 	"github.com/Kuniwak/puml-parallel/slograw"
 )
 
@@ -64,32 +65,52 @@ func NewLogger(logLevel slog.Level, w io.Writer) *slog.Logger {
 	return slog.New(slograw.NewHandler(w, logLevel))
 }
 
-func ValidateArgsAsFilePath(args []string, inout *cli.ProcInout) ([]byte, error) {
+// ValidateArgsAsFilePath reads the single input named by args: a diagram
+// reference (a file path optionally followed by "#name", or a PlantUML server
+// link), or standard input when args is empty or "-". It returns the
+// reference ("" for standard input) for csdf.ParseDiagramFile, which resolves
+// !include directives and the diagram name from it.
+func ValidateArgsAsFilePath(args []string, inout *cli.ProcInout) (string, []byte, error) {
 	switch len(args) {
 	case 0:
 		bs, err := io.ReadAll(inout.Stdin)
 		if err != nil {
-			return nil, fmt.Errorf("cannot read from stdin: %v", err)
+			return "", nil, fmt.Errorf("cannot read from stdin: %v", err)
 		}
-		return bs, nil
+		return "", bs, nil
 
 	case 1:
 		file := args[0]
 		if file == "-" {
 			bs, err := io.ReadAll(inout.Stdin)
 			if err != nil {
-				return nil, fmt.Errorf("cannot read from stdin: %v", err)
+				return "", nil, fmt.Errorf("cannot read from stdin: %v", err)
 			}
-			return bs, nil
+			return "", bs, nil
 		}
 
-		bs, err := os.ReadFile(file)
+		ref, bs, err := csdf.ReadDiagramRef(file)
 		if err != nil {
-			return nil, fmt.Errorf("cannot read file: %v", err)
+			return "", nil, fmt.Errorf("cannot read file: %v", err)
 		}
-		return bs, nil
+		return ref, bs, nil
 
 	default:
-		return nil, fmt.Errorf("too many arguments")
+		return "", nil, fmt.Errorf("too many arguments")
 	}
 }
+
+// ParseSyncEvents parses the semicolon-separated event list of a -sync flag.
+func ParseSyncEvents(s string) []csdf.Event {
+	if s == "" {
+		return nil
+	}
+	var events []csdf.Event
+	for _, event := range strings.Split(s, ";") {
+		trimmed := strings.TrimSpace(event)
+		if trimmed != "" {
+			events = append(events, csdf.Event(trimmed))
+		}
+	}
+	return events
+}
@@ -0,0 +1,47 @@
+package csdf2autcmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/Kuniwak/puml-parallel/cli"
+	"github.com/Kuniwak/puml-parallel/csdf"
+	"github.com/Kuniwak/puml-parallel/csdf/aut"
+	"github.com/Kuniwak/puml-parallel/version"
+)
+
+func NewMainFunc() cli.MainFunc[*Options] {
+	return func(opts *Options, inout *cli.ProcInout) error {
+		if opts.Common.Help {
+			return nil
+		}
+		if opts.Common.Version {
+			fmt.Fprintln(inout.Stdout, version.Version)
+			return nil
+		}
+
+		diagram, err := csdf.ParseDiagramFile(opts.Path, opts.Bytes)
+		if err != nil {
+			return fmt.Errorf("csdf2autcmd.NewMainFunc: %w", err)
+		}
+
+		lts, table, err := aut.Export(diagram, &aut.ExportOptions{Tau: opts.Tau, Conditions: opts.Conditions})
+		if err != nil {
+			return fmt.Errorf("csdf2autcmd.NewMainFunc: %w", err)
+		}
+
+		if opts.StatesFile != "" {
+			bs, err := json.MarshalIndent(table, "", "  ")
+			if err != nil {
+				return fmt.Errorf("csdf2autcmd.NewMainFunc: %w", err)
+			}
+			if err := os.WriteFile(opts.StatesFile, append(bs, '\n'), 0o644); err != nil {
+				return fmt.Errorf("csdf2autcmd.NewMainFunc: cannot write state table: %w", err)
+			}
+		}
+
+		fmt.Fprint(inout.Stdout, lts)
+		return nil
+	}
+}
@@ -0,0 +1,64 @@
+package csdf2autcmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Kuniwak/puml-parallel/cli"
+	"github.com/Kuniwak/puml-parallel/tools"
+	"github.com/Kuniwak/puml-parallel/version"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestNewMainFuncExportsWithStateTable(t *testing.T) {
+	// Arrange
+	cmdFunc := tools.NewCommandFunc(NewParseOptionsFunc(), NewMainFunc())
+	spy := cli.SpyProcInout()
+	statesFile := filepath.Join(t.TempDir(), "vending_machine.states.json")
+	wantAut, err := os.ReadFile("../../../examples/valid/vending_machine.aut")
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantStates, err := os.ReadFile("../../../examples/valid/vending_machine.states.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Act
+	exitStatus := cmdFunc([]string{"-conditions", "-states", statesFile, "../../../examples/valid/vending_machine.puml"}, spy.New())
+
+	// Assert
+	if exitStatus != 0 {
+		t.Log(spy.Stderr.String())
+		t.Errorf("want 0, got %d", exitStatus)
+	}
+	if diff := cmp.Diff(string(wantAut), spy.Stdout.String()); diff != "" {
+		t.Error(diff)
+	}
+	gotStates, err := os.ReadFile(statesFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(string(wantStates), string(gotStates)); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestNewMainFuncVersion(t *testing.T) {
+	// Arrange
+	cmdFunc := tools.NewCommandFunc(NewParseOptionsFunc(), NewMainFunc())
+	spy := cli.SpyProcInout()
+
+	// Act
+	exitStatus := cmdFunc([]string{"-v"}, spy.New())
+
+	// Assert
+	if exitStatus != 0 {
+		t.Log(spy.Stderr.String())
+		t.Errorf("want 0, got %d", exitStatus)
+	}
+	if diff := cmp.Diff(version.Version+"\n", spy.Stdout.String()); diff != "" {
+		t.Error(diff)
+	}
+}
@@ -0,0 +1,89 @@
+package csdf2autcmd
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+
+	"github.com/Kuniwak/puml-parallel/cli"
+	"github.com/Kuniwak/puml-parallel/csdf/aut"
+	"github.com/Kuniwak/puml-parallel/tools"
+)
+
+type Options struct {
+	Common     *tools.CommonOptions
+	Tau        string
+	Conditions bool
+	// StatesFile is where the state table is written; "" to discard it.
+	StatesFile string
+	Path       string // "" when reading standard input
+	Bytes      []byte
+}
+
+// CommonOptions returns the parsed common options.
+func (o *Options) CommonOptions() *tools.CommonOptions { return o.Common }
+
+func NewParseOptionsFunc() cli.ParseOptionsFunc[*Options] {
+	return func(args []string, inout *cli.ProcInout) (*Options, error) {
+		flags := flag.NewFlagSet("csdf2aut", flag.ContinueOnError)
+		flags.SetOutput(inout.Stderr)
+		flags.Usage = func() {
+			w := flags.Output()
+			fmt.Fprintf(w, `Usage: csdf2aut [options] [file.puml|file.png]
+
+Exports a Composable State Diagram as an Aldebaran (.aut) LTS for CADP and mCRL2.
+States are numbered from the start state 0. State IDs, names and variables, the start
+post-condition and the end edge are kept in a JSON state table (-states); tools read
+model.aut together with model.states.json.
+A file argument, a "-" argument, and standard input are all equivalent.
+
+Options:
+`)
+			flags.PrintDefaults()
+			fmt.Fprintf(w, `
+Examples:
+  $ csdf2aut -states model.states.json model.puml > model.aut
+  $ csdfparallel a.puml b.puml | csdf2aut -tau tau - > ab.aut
+`)
+		}
+
+		var commonRawOpts tools.CommonRawOptions
+		tools.DeclareCommonOptions(flags, &commonRawOpts)
+		tauFlag := flags.String("tau", "i", "label of tau-edges: i (CADP) or tau (mCRL2)")
+		conditionsFlag := flags.Bool("conditions", false, "append guards and post-conditions to labels as 'event ; guard ; post'")
+		statesFlag := flags.String("states", "", "file to write the JSON state table to")
+
+		if err := flags.Parse(args); err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				return &Options{Common: tools.CommonOptionsHelp}, nil
+			}
+			return nil, fmt.Errorf("csdf2autcmd.NewParseOptionsFunc: parse failed: %w", err)
+		}
+
+		commonOpts, err := tools.ValidateCommonOptions(&commonRawOpts)
+		if err != nil {
+			return nil, fmt.Errorf("csdf2autcmd.NewParseOptionsFunc: validate common options failed: %w", err)
+		}
+		if commonOpts.Version {
+			return &Options{Common: tools.CommonOptionsVersion}, nil
+		}
+
+		tau, err := aut.ParseTau(*tauFlag)
+		if err != nil {
+			return nil, fmt.Errorf("csdf2autcmd.NewParseOptionsFunc: %w", err)
+		}
+
+		path, bs, err := tools.ValidateArgsAsFilePath(flags.Args(), inout)
+		if err != nil {
+			return nil, fmt.Errorf("csdf2autcmd.NewParseOptionsFunc: validate arguments failed: %w", err)
+		}
+		return &Options{
+			Common:     commonOpts,
+			Tau:        tau,
+			Conditions: *conditionsFlag,
+			StatesFile: *statesFlag,
+			Path:       path,
+			Bytes:      bs,
+		}, nil
+	}
+}
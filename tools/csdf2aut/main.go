@@ -0,0 +1,13 @@
+package main
+
+import (
+	"github.com/Kuniwak/puml-parallel/tools"
+	"github.com/Kuniwak/puml-parallel/tools/csdf2aut/csdf2autcmd"
+)
+
+func main() {
+	tools.NewCommandFunc(
+		csdf2autcmd.NewParseOptionsFunc(),
+		csdf2autcmd.NewMainFunc(),
+	).Run()
+}
@@ -0,0 +1,49 @@
+package csdf2cspmcmd
+
+import (
+	"fmt"
+
+	"github.com/Kuniwak/puml-parallel/cli"
+	"github.com/Kuniwak/puml-parallel/csdf"
+	"github.com/Kuniwak/puml-parallel/csdf/cspm"
+	"github.com/Kuniwak/puml-parallel/version"
+)
+
+func NewMainFunc() cli.MainFunc[*Options] {
+	return func(opts *Options, inout *cli.ProcInout) error {
+		if opts.Common.Help {
+			return nil
+		}
+		if opts.Common.Version {
+			fmt.Fprintln(inout.Stdout, version.Version)
+			return nil
+		}
+
+		diagrams, err := csdf.LoadDiagrams(opts.Files)
+		if err != nil {
+			return fmt.Errorf("csdf2cspmcmd.NewMainFunc: cannot parse diagrams: %w", err)
+		}
+
+		exportOpts := &cspm.ExportOptions{
+			Sync:           opts.Sync,
+			DeadlockFree:   opts.DeadlockFree,
+			DivergenceFree: opts.DivergenceFree,
+			Model:          opts.Model,
+		}
+		if opts.SpecFile != "" {
+			specs, err := csdf.LoadDiagrams([]string{opts.SpecFile})
+			if err != nil {
+				return fmt.Errorf("csdf2cspmcmd.NewMainFunc: cannot parse specification: %w", err)
+			}
+			exportOpts.Spec = specs[0]
+		}
+
+		script, err := cspm.Export(diagrams, exportOpts)
+		if err != nil {
+			return fmt.Errorf("csdf2cspmcmd.NewMainFunc: %w", err)
+		}
+
+		fmt.Fprint(inout.Stdout, script)
+		return nil
+	}
+}
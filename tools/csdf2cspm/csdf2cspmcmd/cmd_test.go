@@ -0,0 +1,86 @@
+package csdf2cspmcmd
+
+import (
+	"testing"
+
+	"github.com/Kuniwak/puml-parallel/cli"
+	"github.com/Kuniwak/puml-parallel/tools"
+	"github.com/Kuniwak/puml-parallel/version"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestNewMainFuncExports(t *testing.T) {
+	// Arrange
+	cmdFunc := tools.NewCommandFunc(NewParseOptionsFunc(), NewMainFunc())
+	spy := cli.SpyProcInout()
+	want := `-- Channels
+channel in, out, sync
+
+D0_s0 =
+    in -> D0_s1
+D0_s1 =
+    sync -> D0_s2
+D0_s2 =
+    STOP
+D0 = D0_s0
+
+D1_s0 =
+    sync -> D1_s1
+D1_s1 =
+    out -> D1_s2
+D1_s2 =
+    STOP
+D1 = D1_s0
+
+SYSTEM = D0 [| {sync} |] D1
+
+SPEC_s0 =
+    in -> SPEC_s1
+SPEC_s1 =
+    sync -> SPEC_s2
+SPEC_s2 =
+    STOP
+SPEC = SPEC_s0
+
+assert SYSTEM :[deadlock free [F]]
+assert SPEC [F= SYSTEM
+`
+
+	// Act
+	exitStatus := cmdFunc([]string{
+		"-sync", "sync",
+		"-deadlock",
+		"-spec", "../../../examples/valid/in.puml",
+		"-model", "F",
+		"../../../examples/valid/in.puml",
+		"../../../examples/valid/out.puml",
+	}, spy.New())
+
+	// Assert
+	if exitStatus != 0 {
+		t.Log(spy.Stderr.String())
+		t.Errorf("want 0, got %d", exitStatus)
+	}
+	if diff := cmp.Diff(want, spy.Stdout.String()); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestNewMainFuncVersion(t *testing.T) {
+	// Arrange
+	cmdFunc := tools.NewCommandFunc(NewParseOptionsFunc(), NewMainFunc())
+	spy := cli.SpyProcInout()
+
+	// Act
+	exitStatus := cmdFunc([]string{"-v"}, spy.New())
+
+	// Assert
+	if exitStatus != 0 {
+		t.Log(spy.Stderr.String())
+		t.Errorf("want 0, got %d", exitStatus)
+	}
+	want := version.Version + "\n"
+	if diff := cmp.Diff(want, spy.Stdout.String()); diff != "" {
+		t.Error(diff)
+	}
+}
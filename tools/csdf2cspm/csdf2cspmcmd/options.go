@@ -0,0 +1,93 @@
+package csdf2cspmcmd
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+
+	"github.com/Kuniwak/puml-parallel/cli"
+	"github.com/Kuniwak/puml-parallel/csdf"
+	"github.com/Kuniwak/puml-parallel/csdf/cspm"
+	"github.com/Kuniwak/puml-parallel/tools"
+)
+
+type Options struct {
+	Common         *tools.CommonOptions
+	Sync           []csdf.Event
+	DeadlockFree   bool
+	DivergenceFree bool
+	// SpecFile is the diagram of the refinement specification; "" for none.
+	SpecFile string
+	Model    cspm.Model
+	Files    []string
+}
+
+// CommonOptions returns the parsed common options.
+func (o *Options) CommonOptions() *tools.CommonOptions { return o.Common }
+
+func NewParseOptionsFunc() cli.ParseOptionsFunc[*Options] {
+	return func(args []string, inout *cli.ProcInout) (*Options, error) {
+		flags := flag.NewFlagSet("csdf2cspm", flag.ContinueOnError)
+		flags.SetOutput(inout.Stderr)
+		flags.Usage = func() {
+			w := flags.Output()
+			fmt.Fprintf(w, `Usage: csdf2cspm [options] <file1.puml> [file2.puml] ...
+
+Exports the interface parallel composition of Composable State Diagrams as a CSPm script for FDR.
+The composed process is named SYSTEM; tau-edges are hidden.
+
+Options:
+`)
+			flags.PrintDefaults()
+			fmt.Fprintf(w, `
+Examples:
+  $ csdf2cspm a.puml > a.csp
+  $ csdf2cspm -sync 'insert;choose;drop' -deadlock -livelock a.puml b.puml
+  $ csdf2cspm -spec spec.puml -model FD a.puml b.puml
+`)
+		}
+
+		var commonRawOpts tools.CommonRawOptions
+		tools.DeclareCommonOptions(flags, &commonRawOpts)
+		syncFlag := flags.String("sync", "", "semicolon-separated list of synchronization events")
+		deadlockFlag := flags.Bool("deadlock", false, "add a deadlock freedom assertion")
+		livelockFlag := flags.Bool("livelock", false, "add a livelock (divergence) freedom assertion")
+		specFlag := flags.String("spec", "", "diagram of a specification SYSTEM must refine")
+		modelFlag := flags.String("model", string(cspm.Traces), "semantic model of the -spec refinement: T, F or FD")
+
+		if err := flags.Parse(args); err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				return &Options{Common: tools.CommonOptionsHelp}, nil
+			}
+			return nil, fmt.Errorf("csdf2cspmcmd.NewParseOptionsFunc: parse failed: %w", err)
+		}
+
+		commonOpts, err := tools.ValidateCommonOptions(&commonRawOpts)
+		if err != nil {
+			return nil, fmt.Errorf("csdf2cspmcmd.NewParseOptionsFunc: validate common options failed: %w", err)
+		}
+		if commonOpts.Version {
+			return &Options{Common: tools.CommonOptionsVersion}, nil
+		}
+
+		model, err := cspm.ParseModel(*modelFlag)
+		if err != nil {
+			return nil, fmt.Errorf("csdf2cspmcmd.NewParseOptionsFunc: %w", err)
+		}
+
+		files := flags.Args()
+		if len(files) < 1 {
+			return nil, fmt.Errorf("csdf2cspmcmd.NewParseOptionsFunc: too few arguments")
+		}
+
+		return &Options{
+			Common:         commonOpts,
+			Sync:           tools.ParseSyncEvents(*syncFlag),
+			DeadlockFree:   *deadlockFlag,
+			DivergenceFree: *livelockFlag,
+			SpecFile:       *specFlag,
+			Model:          model,
+			Files:          files,
+		}, nil
+	}
+}
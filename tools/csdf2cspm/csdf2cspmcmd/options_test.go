@@ -0,0 +1,98 @@
+package csdf2cspmcmd
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/Kuniwak/puml-parallel/cli"
+	"github.com/Kuniwak/puml-parallel/csdf"
+	"github.com/Kuniwak/puml-parallel/csdf/cspm"
+	"github.com/Kuniwak/puml-parallel/tools"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestNewParseOptionsFuncOK(t *testing.T) {
+	type testCase struct {
+		Args     []string
+		Expected *Options
+	}
+
+	testCases := map[string]testCase{
+		"-h (representative value)": {
+			Args:     []string{"-h"},
+			Expected: &Options{Common: tools.CommonOptionsHelp},
+		},
+		"-v (representative value)": {
+			Args:     []string{"-v"},
+			Expected: &Options{Common: tools.CommonOptionsVersion},
+		},
+		"single file (lower boundary value)": {
+			Args:     []string{"a.puml"},
+			Expected: &Options{Common: tools.NewCommonOptionsDefault(), Model: cspm.Traces, Files: []string{"a.puml"}},
+		},
+		"all assertions (representative value)": {
+			Args: []string{"-sync", "x;y", "-deadlock", "-livelock", "-spec", "s.puml", "-model", "FD", "a.puml", "b.puml"},
+			Expected: &Options{
+				Common:         tools.NewCommonOptionsDefault(),
+				Sync:           []csdf.Event{"x", "y"},
+				DeadlockFree:   true,
+				DivergenceFree: true,
+				SpecFile:       "s.puml",
+				Model:          cspm.FailuresDivergences,
+				Files:          []string{"a.puml", "b.puml"},
+			},
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Arrange
+			parseOptions := NewParseOptionsFunc()
+			spy := cli.SpyProcInout()
+
+			// Act
+			opts, err := parseOptions(testCase.Args, spy.New())
+			if err != nil {
+				t.Log(spy.Stderr.String())
+				t.Errorf("want nil, got %#v", err)
+			}
+
+			// Assert
+			if !reflect.DeepEqual(testCase.Expected, opts) {
+				t.Error(cmp.Diff(testCase.Expected, opts))
+			}
+		})
+	}
+}
+
+func TestNewParseOptionsFuncNG(t *testing.T) {
+	type testCase struct {
+		Args []string
+	}
+
+	testCases := map[string]testCase{
+		"too few arguments (representative value)": {
+			Args: []string{},
+		},
+		"unknown model (representative value)": {
+			Args: []string{"-model", "X", "a.puml"},
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Arrange
+			parseOptions := NewParseOptionsFunc()
+			spy := cli.SpyProcInout()
+
+			// Act
+			opts, err := parseOptions(testCase.Args, spy.New())
+
+			// Assert
+			if err == nil {
+				t.Log(opts)
+				t.Error("want not nil, got nil")
+			}
+		})
+	}
+}
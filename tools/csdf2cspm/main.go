@@ -0,0 +1,13 @@
+package main
+
+import (
+	"github.com/Kuniwak/puml-parallel/tools"
+	"github.com/Kuniwak/puml-parallel/tools/csdf2cspm/csdf2cspmcmd"
+)
+
+func main() {
+	tools.NewCommandFunc(
+		csdf2cspmcmd.NewParseOptionsFunc(),
+		csdf2cspmcmd.NewMainFunc(),
+	).Run()
+}
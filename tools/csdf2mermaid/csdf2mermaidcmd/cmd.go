@@ -0,0 +1,38 @@
+package csdf2mermaidcmd
+
+import (
+	"fmt"
+
+	"github.com/Kuniwak/puml-parallel/cli"
+	"github.com/Kuniwak/puml-parallel/csdf"
+	"github.com/Kuniwak/puml-parallel/csdf/mermaid"
+	"github.com/Kuniwak/puml-parallel/version"
+)
+
+func NewMainFunc() cli.MainFunc[*Options] {
+	return func(opts *Options, inout *cli.ProcInout) error {
+		if opts.Common.Help {
+			return nil
+		}
+		if opts.Common.Version {
+			fmt.Fprintln(inout.Stdout, version.Version)
+			return nil
+		}
+
+		diagram, err := csdf.ParseDiagramFile(opts.Path, opts.Bytes)
+		if err != nil {
+			return fmt.Errorf("csdf2mermaidcmd.NewMainFunc: %w", err)
+		}
+
+		text, err := mermaid.Export(diagram)
+		if err != nil {
+			return fmt.Errorf("csdf2mermaidcmd.NewMainFunc: %w", err)
+		}
+
+		if opts.Markdown {
+			text = "```mermaid\n" + text + "```\n"
+		}
+		fmt.Fprint(inout.Stdout, text)
+		return nil
+	}
+}
@@ -0,0 +1,55 @@
+package csdf2mermaidcmd
+
+import (
+	"testing"
+
+	"github.com/Kuniwak/puml-parallel/cli"
+	"github.com/Kuniwak/puml-parallel/tools"
+	"github.com/Kuniwak/puml-parallel/version"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestNewMainFuncExports(t *testing.T) {
+	// Arrange
+	cmdFunc := tools.NewCommandFunc(NewParseOptionsFunc(), NewMainFunc())
+	spy := cli.SpyProcInout()
+	want := "```mermaid" + `
+stateDiagram-v2
+    s0
+    s1
+    s2
+    [*] --> s0
+    s0 --> s1 : in
+    s1 --> s2 : sync
+` + "```\n"
+
+	// Act
+	exitStatus := cmdFunc([]string{"-markdown", "../../../examples/valid/in.puml"}, spy.New())
+
+	// Assert
+	if exitStatus != 0 {
+		t.Log(spy.Stderr.String())
+		t.Errorf("want 0, got %d", exitStatus)
+	}
+	if diff := cmp.Diff(want, spy.Stdout.String()); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestNewMainFuncVersion(t *testing.T) {
+	// Arrange
+	cmdFunc := tools.NewCommandFunc(NewParseOptionsFunc(), NewMainFunc())
+	spy := cli.SpyProcInout()
+
+	// Act
+	exitStatus := cmdFunc([]string{"-v"}, spy.New())
+
+	// Assert
+	if exitStatus != 0 {
+		t.Log(spy.Stderr.String())
+		t.Errorf("want 0, got %d", exitStatus)
+	}
+	if diff := cmp.Diff(version.Version+"\n", spy.Stdout.String()); diff != "" {
+		t.Error(diff)
+	}
+}
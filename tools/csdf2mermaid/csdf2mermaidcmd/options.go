@@ -0,0 +1,70 @@
+package csdf2mermaidcmd
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+
+	"github.com/Kuniwak/puml-parallel/cli"
+	"github.com/Kuniwak/puml-parallel/tools"
+)
+
+type Options struct {
+	Common *tools.CommonOptions
+	// Markdown wraps the diagram in a ```mermaid fenced block.
+	Markdown bool
+	Path     string // "" when reading standard input
+	Bytes    []byte
+}
+
+// CommonOptions returns the parsed common options.
+func (o *Options) CommonOptions() *tools.CommonOptions { return o.Common }
+
+func NewParseOptionsFunc() cli.ParseOptionsFunc[*Options] {
+	return func(args []string, inout *cli.ProcInout) (*Options, error) {
+		flags := flag.NewFlagSet("csdf2mermaid", flag.ContinueOnError)
+		flags.SetOutput(inout.Stderr)
+		flags.Usage = func() {
+			w := flags.Output()
+			fmt.Fprintf(w, `Usage: csdf2mermaid [options] [file.puml|file.png]
+
+Exports a Composable State Diagram as a Mermaid stateDiagram-v2, which GitHub renders in
+Markdown. Edges are labeled "event [guard] / post" and variables are listed in notes.
+A file argument, a "-" argument, and standard input are all equivalent.
+
+Options:
+`)
+			flags.PrintDefaults()
+			fmt.Fprintf(w, `
+Examples:
+  $ csdf2mermaid vending_machine.puml > vending_machine.mmd
+  $ csdfparallel a.puml b.puml | csdf2mermaid -markdown - >> README.md
+`)
+		}
+
+		var commonRawOpts tools.CommonRawOptions
+		tools.DeclareCommonOptions(flags, &commonRawOpts)
+		markdownFlag := flags.Bool("markdown", false, "wrap the diagram in a ```mermaid block for Markdown")
+
+		if err := flags.Parse(args); err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				return &Options{Common: tools.CommonOptionsHelp}, nil
+			}
+			return nil, fmt.Errorf("csdf2mermaidcmd.NewParseOptionsFunc: parse failed: %w", err)
+		}
+
+		commonOpts, err := tools.ValidateCommonOptions(&commonRawOpts)
+		if err != nil {
+			return nil, fmt.Errorf("csdf2mermaidcmd.NewParseOptionsFunc: validate common options failed: %w", err)
+		}
+		if commonOpts.Version {
+			return &Options{Common: tools.CommonOptionsVersion}, nil
+		}
+
+		path, bs, err := tools.ValidateArgsAsFilePath(flags.Args(), inout)
+		if err != nil {
+			return nil, fmt.Errorf("csdf2mermaidcmd.NewParseOptionsFunc: validate arguments failed: %w", err)
+		}
+		return &Options{Common: commonOpts, Markdown: *markdownFlag, Path: path, Bytes: bs}, nil
+	}
+}
@@ -0,0 +1,122 @@
+package csdf2mermaidcmd
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Kuniwak/puml-parallel/cli"
+	"github.com/Kuniwak/puml-parallel/tools"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestNewParseOptionsFuncOK(t *testing.T) {
+	type testCase struct {
+		Stdin    string
+		Args     []string
+		Expected *Options
+	}
+
+	testCases := map[string]testCase{
+		"-h (representative value)": {
+			Args:     []string{"-h"},
+			Expected: &Options{Common: tools.CommonOptionsHelp},
+		},
+		"--help (representative value)": {
+			Args:     []string{"--help"},
+			Expected: &Options{Common: tools.CommonOptionsHelp},
+		},
+		"-v (representative value)": {
+			Args:     []string{"-v"},
+			Expected: &Options{Common: tools.CommonOptionsVersion},
+		},
+		"--version (representative value)": {
+			Args:     []string{"--version"},
+			Expected: &Options{Common: tools.CommonOptionsVersion},
+		},
+		"no args means stdin (representative value)": {
+			Stdin: "@startuml\n@enduml\n",
+			Args:  []string{},
+			Expected: &Options{
+				Common: tools.NewCommonOptionsDefault(),
+				Bytes:  []byte("@startuml\n@enduml\n"),
+			},
+		},
+		"dash means stdin (representative value)": {
+			Stdin: "@startuml\n@enduml\n",
+			Args:  []string{"-"},
+			Expected: &Options{
+				Common: tools.NewCommonOptionsDefault(),
+				Bytes:  []byte("@startuml\n@enduml\n"),
+			},
+		},
+		"markdown (representative value)": {
+			Args: []string{"-markdown", filepath.Join("testdata", "a.puml")},
+			Expected: &Options{
+				Common:   tools.NewCommonOptionsDefault(),
+				Markdown: true,
+				Path:     filepath.Join("testdata", "a.puml"),
+				Bytes:    []byte("@startuml\n@enduml\n"),
+			},
+		},
+		"file argument (representative value)": {
+			Args: []string{filepath.Join("testdata", "a.puml")},
+			Expected: &Options{
+				Common: tools.NewCommonOptionsDefault(),
+				Path:   filepath.Join("testdata", "a.puml"),
+				Bytes:  []byte("@startuml\n@enduml\n"),
+			},
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Arrange
+			parseOptions := NewParseOptionsFunc()
+			spy := cli.SpyProcInout()
+			spy.Stdin = cli.StubStdin(strings.NewReader(testCase.Stdin))
+
+			// Act
+			opts, err := parseOptions(testCase.Args, spy.New())
+			if err != nil {
+				t.Log(spy.Stderr.String())
+				t.Errorf("want nil, got %#v", err)
+			}
+
+			// Assert
+			if !reflect.DeepEqual(testCase.Expected, opts) {
+				t.Error(cmp.Diff(testCase.Expected, opts))
+			}
+		})
+	}
+}
+
+func TestNewParseOptionsFuncNG(t *testing.T) {
+	type testCase struct {
+		Args []string
+	}
+
+	testCases := map[string]testCase{
+		"too many arguments (representative value)": {
+			Args: []string{"a.puml", "b.puml"},
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Arrange
+			parseOptions := NewParseOptionsFunc()
+			spy := cli.SpyProcInout()
+
+			// Act
+			opts, err := parseOptions(testCase.Args, spy.New())
+
+			// Assert
+			if err == nil {
+				t.Log(opts)
+				t.Error("want not nil, got nil")
+			}
+		})
+	}
+}
@@ -0,0 +1,13 @@
+package main
+
+import (
+	"github.com/Kuniwak/puml-parallel/tools"
+	"github.com/Kuniwak/puml-parallel/tools/csdf2mermaid/csdf2mermaidcmd"
+)
+
+func main() {
+	tools.NewCommandFunc(
+		csdf2mermaidcmd.NewParseOptionsFunc(),
+		csdf2mermaidcmd.NewMainFunc(),
+	).Run()
+}
@@ -0,0 +1,35 @@
+package csdf2pmlcmd
+
+import (
+	"fmt"
+
+	"github.com/Kuniwak/puml-parallel/cli"
+	"github.com/Kuniwak/puml-parallel/csdf"
+	"github.com/Kuniwak/puml-parallel/csdf/promela"
+	"github.com/Kuniwak/puml-parallel/version"
+)
+
+func NewMainFunc() cli.MainFunc[*Options] {
+	return func(opts *Options, inout *cli.ProcInout) error {
+		if opts.Common.Help {
+			return nil
+		}
+		if opts.Common.Version {
+			fmt.Fprintln(inout.Stdout, version.Version)
+			return nil
+		}
+
+		diagrams, err := csdf.LoadDiagrams(opts.Files)
+		if err != nil {
+			return fmt.Errorf("csdf2pmlcmd.NewMainFunc: cannot parse diagrams: %w", err)
+		}
+
+		model, err := promela.Export(diagrams, opts.Sync)
+		if err != nil {
+			return fmt.Errorf("csdf2pmlcmd.NewMainFunc: %w", err)
+		}
+
+		fmt.Fprint(inout.Stdout, model)
+		return nil
+	}
+}
@@ -0,0 +1,76 @@
+package csdf2pmlcmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Kuniwak/puml-parallel/cli"
+	"github.com/Kuniwak/puml-parallel/tools"
+	"github.com/Kuniwak/puml-parallel/version"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestNewMainFuncExports(t *testing.T) {
+	// Arrange
+	cmdFunc := tools.NewCommandFunc(NewParseOptionsFunc(), NewMainFunc())
+	spy := cli.SpyProcInout()
+
+	// Act
+	exitStatus := cmdFunc([]string{
+		"-sync", "sync",
+		"../../../examples/valid/in.puml",
+		"../../../examples/valid/out.puml",
+	}, spy.New())
+
+	// Assert
+	if exitStatus != 0 {
+		t.Log(spy.Stderr.String())
+		t.Errorf("want 0, got %d", exitStatus)
+	}
+	for _, want := range []string{"chan sync = [0] of { bit };", ":: sync!0 -> goto s2;", ":: sync?0 -> goto s1;"} {
+		if !strings.Contains(spy.Stdout.String(), want) {
+			t.Errorf("want %q in output, got %q", want, spy.Stdout.String())
+		}
+	}
+}
+
+func TestNewMainFuncComposesMultiPartySynchronization(t *testing.T) {
+	// Arrange
+	cmdFunc := tools.NewCommandFunc(NewParseOptionsFunc(), NewMainFunc())
+	spy := cli.SpyProcInout()
+
+	// Act
+	exitStatus := cmdFunc([]string{
+		"-sync", "sync",
+		"../../../examples/valid/in.puml",
+		"../../../examples/valid/out.puml",
+		"../../../examples/valid/in_out.puml",
+	}, spy.New())
+
+	// Assert
+	if exitStatus != 0 {
+		t.Log(spy.Stderr.String())
+		t.Errorf("want 0, got %d", exitStatus)
+	}
+	if !strings.Contains(spy.Stdout.String(), "active proctype D0() {\n    goto s0_s0_s0_s0;\n") {
+		t.Errorf("want a single composed proctype, got %q", spy.Stdout.String())
+	}
+}
+
+func TestNewMainFuncVersion(t *testing.T) {
+	// Arrange
+	cmdFunc := tools.NewCommandFunc(NewParseOptionsFunc(), NewMainFunc())
+	spy := cli.SpyProcInout()
+
+	// Act
+	exitStatus := cmdFunc([]string{"-v"}, spy.New())
+
+	// Assert
+	if exitStatus != 0 {
+		t.Log(spy.Stderr.String())
+		t.Errorf("want 0, got %d", exitStatus)
+	}
+	if diff := cmp.Diff(version.Version+"\n", spy.Stdout.String()); diff != "" {
+		t.Error(diff)
+	}
+}
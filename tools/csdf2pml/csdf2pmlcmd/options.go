@@ -0,0 +1,75 @@
+package csdf2pmlcmd
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+
+	"github.com/Kuniwak/puml-parallel/cli"
+	"github.com/Kuniwak/puml-parallel/csdf"
+	"github.com/Kuniwak/puml-parallel/tools"
+)
+
+type Options struct {
+	Common *tools.CommonOptions
+	Sync   []csdf.Event
+	Files  []string
+}
+
+// CommonOptions returns the parsed common options.
+func (o *Options) CommonOptions() *tools.CommonOptions { return o.Common }
+
+func NewParseOptionsFunc() cli.ParseOptionsFunc[*Options] {
+	return func(args []string, inout *cli.ProcInout) (*Options, error) {
+		flags := flag.NewFlagSet("csdf2pml", flag.ContinueOnError)
+		flags.SetOutput(inout.Stderr)
+		flags.Usage = func() {
+			w := flags.Output()
+			fmt.Fprintf(w, `Usage: csdf2pml [options] <file1.puml> [file2.puml] ...
+
+Exports the interface parallel composition of Composable State Diagrams as a Promela model for SPIN.
+Each diagram becomes a proctype; synchronization events become rendezvous channels, which
+connect two proctypes, so more than two diagrams with synchronization events are composed
+into a single proctype first.
+
+Options:
+`)
+			flags.PrintDefaults()
+			fmt.Fprintf(w, `
+Examples:
+  $ csdf2pml a.puml > a.pml
+  $ csdf2pml -sync 'insert;choose;drop' a.puml b.puml > ab.pml
+`)
+		}
+
+		var commonRawOpts tools.CommonRawOptions
+		tools.DeclareCommonOptions(flags, &commonRawOpts)
+		syncFlag := flags.String("sync", "", "semicolon-separated list of synchronization events")
+
+		if err := flags.Parse(args); err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				return &Options{Common: tools.CommonOptionsHelp}, nil
+			}
+			return nil, fmt.Errorf("csdf2pmlcmd.NewParseOptionsFunc: parse failed: %w", err)
+		}
+
+		commonOpts, err := tools.ValidateCommonOptions(&commonRawOpts)
+		if err != nil {
+			return nil, fmt.Errorf("csdf2pmlcmd.NewParseOptionsFunc: validate common options failed: %w", err)
+		}
+		if commonOpts.Version {
+			return &Options{Common: tools.CommonOptionsVersion}, nil
+		}
+
+		files := flags.Args()
+		if len(files) < 1 {
+			return nil, fmt.Errorf("csdf2pmlcmd.NewParseOptionsFunc: too few arguments")
+		}
+
+		return &Options{
+			Common: commonOpts,
+			Sync:   tools.ParseSyncEvents(*syncFlag),
+			Files:  files,
+		}, nil
+	}
+}
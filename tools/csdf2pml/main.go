@@ -0,0 +1,13 @@
+package main
+
+import (
+	"github.com/Kuniwak/puml-parallel/tools"
+	"github.com/Kuniwak/puml-parallel/tools/csdf2pml/csdf2pmlcmd"
+)
+
+func main() {
+	tools.NewCommandFunc(
+		csdf2pmlcmd.NewParseOptionsFunc(),
+		csdf2pmlcmd.NewMainFunc(),
+	).Run()
+}
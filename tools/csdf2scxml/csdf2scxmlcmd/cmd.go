@@ -0,0 +1,35 @@
+package csdf2scxmlcmd
+
+import (
+	"fmt"
+
+	"github.com/Kuniwak/puml-parallel/cli"
+	"github.com/Kuniwak/puml-parallel/csdf"
+	"github.com/Kuniwak/puml-parallel/csdf/scxml"
+	"github.com/Kuniwak/puml-parallel/version"
+)
+
+func NewMainFunc() cli.MainFunc[*Options] {
+	return func(opts *Options, inout *cli.ProcInout) error {
+		if opts.Common.Help {
+			return nil
+		}
+		if opts.Common.Version {
+			fmt.Fprintln(inout.Stdout, version.Version)
+			return nil
+		}
+
+		diagram, err := csdf.ParseDiagramFile(opts.Path, opts.Bytes)
+		if err != nil {
+			return fmt.Errorf("csdf2scxmlcmd.NewMainFunc: %w", err)
+		}
+
+		text, err := scxml.Export(diagram)
+		if err != nil {
+			return fmt.Errorf("csdf2scxmlcmd.NewMainFunc: %w", err)
+		}
+
+		fmt.Fprint(inout.Stdout, text)
+		return nil
+	}
+}
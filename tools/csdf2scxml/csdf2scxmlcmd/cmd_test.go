@@ -0,0 +1,72 @@
+package csdf2scxmlcmd
+
+import (
+	"testing"
+
+	"github.com/Kuniwak/puml-parallel/cli"
+	"github.com/Kuniwak/puml-parallel/tools"
+	"github.com/Kuniwak/puml-parallel/version"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestNewMainFuncExports(t *testing.T) {
+	// Arrange
+	cmdFunc := tools.NewCommandFunc(NewParseOptionsFunc(), NewMainFunc())
+	spy := cli.SpyProcInout()
+	want := `<?xml version="1.0" encoding="UTF-8"?>
+<scxml xmlns="http://www.w3.org/2005/07/scxml" xmlns:csdf="https://github.com/Kuniwak/puml-parallel/csdf" version="1.0" initial="s0">
+  <state id="s0" csdf:vars="">
+    <transition event="in" target="s1"/>
+  </state>
+  <state id="s1" csdf:vars="">
+    <transition event="sync" target="s2"/>
+  </state>
+  <state id="s2" csdf:vars=""/>
+</scxml>
+`
+
+	// Act
+	exitStatus := cmdFunc([]string{"../../../examples/valid/in.puml"}, spy.New())
+
+	// Assert
+	if exitStatus != 0 {
+		t.Log(spy.Stderr.String())
+		t.Errorf("want 0, got %d", exitStatus)
+	}
+	if diff := cmp.Diff(want, spy.Stdout.String()); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestNewMainFuncReadsSCXML(t *testing.T) {
+	// Arrange
+	cmdFunc := tools.NewCommandFunc(NewParseOptionsFunc(), NewMainFunc())
+	spy := cli.SpyProcInout()
+
+	// Act
+	exitStatus := cmdFunc([]string{"../../../examples/valid/door.scxml"}, spy.New())
+
+	// Assert
+	if exitStatus != 0 {
+		t.Log(spy.Stderr.String())
+		t.Errorf("want 0, got %d", exitStatus)
+	}
+}
+
+func TestNewMainFuncVersion(t *testing.T) {
+	// Arrange
+	cmdFunc := tools.NewCommandFunc(NewParseOptionsFunc(), NewMainFunc())
+	spy := cli.SpyProcInout()
+
+	// Act
+	exitStatus := cmdFunc([]string{"-v"}, spy.New())
+
+	// Assert
+	if exitStatus != 0 {
+		t.Log(spy.Stderr.String())
+		t.Errorf("want 0, got %d", exitStatus)
+	}
+	if diff := cmp.Diff(version.Version+"\n", spy.Stdout.String()); diff != "" {
+		t.Error(diff)
+	}
+}
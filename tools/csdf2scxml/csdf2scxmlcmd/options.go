@@ -0,0 +1,67 @@
+package csdf2scxmlcmd
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+
+	"github.com/Kuniwak/puml-parallel/cli"
+	"github.com/Kuniwak/puml-parallel/tools"
+)
+
+type Options struct {
+	Common *tools.CommonOptions
+	Path   string // "" when reading standard input
+	Bytes  []byte
+}
+
+// CommonOptions returns the parsed common options.
+func (o *Options) CommonOptions() *tools.CommonOptions { return o.Common }
+
+func NewParseOptionsFunc() cli.ParseOptionsFunc[*Options] {
+	return func(args []string, inout *cli.ProcInout) (*Options, error) {
+		flags := flag.NewFlagSet("csdf2scxml", flag.ContinueOnError)
+		flags.SetOutput(inout.Stderr)
+		flags.Usage = func() {
+			w := flags.Output()
+			fmt.Fprintf(w, `Usage: csdf2scxml [options] [file.puml|file.png]
+
+Exports a Composable State Diagram as a flat SCXML state chart. Names, variables and
+post-conditions are kept in csdf: attributes, so every tool reads the chart back.
+A file argument, a "-" argument, and standard input are all equivalent.
+
+Options:
+`)
+			flags.PrintDefaults()
+			fmt.Fprintf(w, `
+Examples:
+  $ csdf2scxml vending_machine.puml > vending_machine.scxml
+  $ csdfparallel a.puml b.puml | csdf2scxml - > system.scxml
+`)
+		}
+
+		var commonRawOpts tools.CommonRawOptions
+		tools.DeclareCommonOptions(flags, &commonRawOpts)
+
+		if err := flags.Parse(args); err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				return &Options{Common: tools.CommonOptionsHelp}, nil
+			}
+			return nil, fmt.Errorf("csdf2scxmlcmd.NewParseOptionsFunc: parse failed: %w", err)
+		}
+
+		commonOpts, err := tools.ValidateCommonOptions(&commonRawOpts)
+		if err != nil {
+			return nil, fmt.Errorf("csdf2scxmlcmd.NewParseOptionsFunc: validate common options failed: %w", err)
+		}
+		if commonOpts.Version {
+			return &Options{Common: tools.CommonOptionsVersion}, nil
+		}
+
+		path, bs, err := tools.ValidateArgsAsFilePath(flags.Args(), inout)
+		if err != nil {
+			return nil, fmt.Errorf("csdf2scxmlcmd.NewParseOptionsFunc: validate arguments failed: %w", err)
+		}
+		return &Options{Common: commonOpts, Path: path, Bytes: bs}, nil
+	}
+}
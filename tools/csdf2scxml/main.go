@@ -0,0 +1,13 @@
+package main
+
+import (
+	"github.com/Kuniwak/puml-parallel/tools"
+	"github.com/Kuniwak/puml-parallel/tools/csdf2scxml/csdf2scxmlcmd"
+)
+
+func main() {
+	tools.NewCommandFunc(
+		csdf2scxmlcmd.NewParseOptionsFunc(),
+		csdf2scxmlcmd.NewMainFunc(),
+	).Run()
+}
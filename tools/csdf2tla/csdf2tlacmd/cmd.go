@@ -0,0 +1,39 @@
+package csdf2tlacmd
+
+import (
+	"fmt"
+
+	"github.com/Kuniwak/puml-parallel/cli"
+	"github.com/Kuniwak/puml-parallel/csdf"
+	"github.com/Kuniwak/puml-parallel/csdf/tla"
+	"github.com/Kuniwak/puml-parallel/version"
+)
+
+func NewMainFunc() cli.MainFunc[*Options] {
+	return func(opts *Options, inout *cli.ProcInout) error {
+		if opts.Common.Help {
+			return nil
+		}
+		if opts.Common.Version {
+			fmt.Fprintln(inout.Stdout, version.Version)
+			return nil
+		}
+
+		diagram, err := csdf.ParseDiagramFile(opts.Path, opts.Bytes)
+		if err != nil {
+			return fmt.Errorf("csdf2tlacmd.NewMainFunc: %w", err)
+		}
+
+		module := opts.Module
+		if module == "" {
+			module = tla.ModuleName(diagram)
+		}
+		text, err := tla.Export(diagram, module)
+		if err != nil {
+			return fmt.Errorf("csdf2tlacmd.NewMainFunc: %w", err)
+		}
+
+		fmt.Fprint(inout.Stdout, text)
+		return nil
+	}
+}
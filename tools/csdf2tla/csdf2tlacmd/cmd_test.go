@@ -0,0 +1,96 @@
+package csdf2tlacmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Kuniwak/puml-parallel/cli"
+	"github.com/Kuniwak/puml-parallel/tools"
+	"github.com/Kuniwak/puml-parallel/version"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestNewMainFuncExports(t *testing.T) {
+	// Arrange
+	cmdFunc := tools.NewCommandFunc(NewParseOptionsFunc(), NewMainFunc())
+	spy := cli.SpyProcInout()
+	want := `---- MODULE InOut ----
+
+VARIABLES state
+vars == <<state>>
+
+States == {"s0", "s1", "s2"}
+
+TypeOK == state \in States
+
+Init ==
+    /\ state = "s0"
+
+\* s0 --in--> s1
+in ==
+    /\ state = "s0"
+    /\ state' = "s1"
+
+\* s1 --sync--> s2
+sync ==
+    /\ state = "s1"
+    /\ state' = "s2"
+
+Next ==
+    \/ in
+    \/ sync
+
+Spec == Init /\ [][Next]_vars
+
+======================
+`
+
+	// Act
+	exitStatus := cmdFunc([]string{"-module", "InOut", "../../../examples/valid/in.puml"}, spy.New())
+
+	// Assert
+	if exitStatus != 0 {
+		t.Log(spy.Stderr.String())
+		t.Errorf("want 0, got %d", exitStatus)
+	}
+	if diff := cmp.Diff(want, spy.Stdout.String()); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestNewMainFuncNamesModuleAfterDiagram(t *testing.T) {
+	// Arrange
+	cmdFunc := tools.NewCommandFunc(NewParseOptionsFunc(), NewMainFunc())
+	spy := cli.SpyProcInout()
+	spy.Stdin = cli.StubStdin(strings.NewReader("@startuml my-machine\nstate \"s0\" as s0\n[*] --> s0\n@enduml\n"))
+
+	// Act
+	exitStatus := cmdFunc([]string{}, spy.New())
+
+	// Assert
+	if exitStatus != 0 {
+		t.Log(spy.Stderr.String())
+		t.Errorf("want 0, got %d", exitStatus)
+	}
+	if !strings.HasPrefix(spy.Stdout.String(), "---- MODULE my_machine ----\n") {
+		t.Errorf("want module my_machine, got %q", spy.Stdout.String())
+	}
+}
+
+func TestNewMainFuncVersion(t *testing.T) {
+	// Arrange
+	cmdFunc := tools.NewCommandFunc(NewParseOptionsFunc(), NewMainFunc())
+	spy := cli.SpyProcInout()
+
+	// Act
+	exitStatus := cmdFunc([]string{"-v"}, spy.New())
+
+	// Assert
+	if exitStatus != 0 {
+		t.Log(spy.Stderr.String())
+		t.Errorf("want 0, got %d", exitStatus)
+	}
+	if diff := cmp.Diff(version.Version+"\n", spy.Stdout.String()); diff != "" {
+		t.Error(diff)
+	}
+}
@@ -0,0 +1,71 @@
+package csdf2tlacmd
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+
+	"github.com/Kuniwak/puml-parallel/cli"
+	"github.com/Kuniwak/puml-parallel/tools"
+)
+
+type Options struct {
+	Common *tools.CommonOptions
+	// Module is the TLA+ module name; "" for the diagram name.
+	Module string
+	Path   string // "" when reading standard input
+	Bytes  []byte
+}
+
+// CommonOptions returns the parsed common options.
+func (o *Options) CommonOptions() *tools.CommonOptions { return o.Common }
+
+func NewParseOptionsFunc() cli.ParseOptionsFunc[*Options] {
+	return func(args []string, inout *cli.ProcInout) (*Options, error) {
+		flags := flag.NewFlagSet("csdf2tla", flag.ContinueOnError)
+		flags.SetOutput(inout.Stderr)
+		flags.Usage = func() {
+			w := flags.Output()
+			fmt.Fprintf(w, `Usage: csdf2tla [options] [file.puml|file.png]
+
+Exports a Composable State Diagram as a TLA+ module with a state control variable and
+one action per edge. Guards and post-conditions become comments unless tla.guard and
+tla.post annotations give their formal text.
+A file argument, a "-" argument, and standard input are all equivalent.
+
+Options:
+`)
+			flags.PrintDefaults()
+			fmt.Fprintf(w, `
+Examples:
+  $ csdf2tla -module VendingMachine vending_machine.puml > VendingMachine.tla
+  $ csdfparallel a.puml b.puml | csdf2tla -module System - > System.tla
+`)
+		}
+
+		var commonRawOpts tools.CommonRawOptions
+		tools.DeclareCommonOptions(flags, &commonRawOpts)
+		moduleFlag := flags.String("module", "", "module name (default: the diagram name, or System)")
+
+		if err := flags.Parse(args); err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				return &Options{Common: tools.CommonOptionsHelp}, nil
+			}
+			return nil, fmt.Errorf("csdf2tlacmd.NewParseOptionsFunc: parse failed: %w", err)
+		}
+
+		commonOpts, err := tools.ValidateCommonOptions(&commonRawOpts)
+		if err != nil {
+			return nil, fmt.Errorf("csdf2tlacmd.NewParseOptionsFunc: validate common options failed: %w", err)
+		}
+		if commonOpts.Version {
+			return &Options{Common: tools.CommonOptionsVersion}, nil
+		}
+
+		path, bs, err := tools.ValidateArgsAsFilePath(flags.Args(), inout)
+		if err != nil {
+			return nil, fmt.Errorf("csdf2tlacmd.NewParseOptionsFunc: validate arguments failed: %w", err)
+		}
+		return &Options{Common: commonOpts, Module: *moduleFlag, Path: path, Bytes: bs}, nil
+	}
+}
@@ -0,0 +1,13 @@
+package main
+
+import (
+	"github.com/Kuniwak/puml-parallel/tools"
+	"github.com/Kuniwak/puml-parallel/tools/csdf2tla/csdf2tlacmd"
+)
+
+func main() {
+	tools.NewCommandFunc(
+		csdf2tlacmd.NewParseOptionsFunc(),
+		csdf2tlacmd.NewMainFunc(),
+	).Run()
+}
@@ -0,0 +1,45 @@
+package csdfcomposecmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Kuniwak/puml-parallel/cli"
+	"github.com/Kuniwak/puml-parallel/csdf"
+	"github.com/Kuniwak/puml-parallel/tools"
+	"github.com/Kuniwak/puml-parallel/version"
+)
+
+func NewMainFunc() cli.MainFunc[*Options] {
+	return func(opts *Options, inout *cli.ProcInout) error {
+		if opts.Common.Help {
+			return nil
+		}
+		if opts.Common.Version {
+			fmt.Fprintln(inout.Stdout, version.Version)
+			return nil
+		}
+
+		diagrams, err := csdf.LoadDiagrams(opts.Files)
+		if err != nil {
+			return fmt.Errorf("csdfcomposecmd.NewMainFunc: cannot parse diagrams: %w", err)
+		}
+
+		composite, steps, err := opts.Explorer.ComposeMinimized(diagrams, opts.Sync, opts.Visible)
+		if err != nil {
+			return fmt.Errorf("csdfcomposecmd.NewMainFunc: %w", err)
+		}
+		for _, step := range steps {
+			names := make([]string, len(step.Components))
+			for i, c := range step.Components {
+				names[i] = opts.Files[c]
+			}
+			fmt.Fprintf(inout.Stderr, "%s: %d states, %d after minimization\n", strings.Join(names, " || "), step.States, step.Minimized)
+		}
+
+		if err := tools.WritePlantUML(inout.Stdout, composite.String(), opts.Output); err != nil {
+			return fmt.Errorf("csdfcomposecmd.NewMainFunc: %w", err)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,97 @@
+package csdfcomposecmd
+
+import (
+	"testing"
+
+	"github.com/Kuniwak/puml-parallel/cli"
+	"github.com/Kuniwak/puml-parallel/tools"
+	"github.com/Kuniwak/puml-parallel/version"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestNewMainFuncComposeMinimized(t *testing.T) {
+	// Arrange: in and out are hidden, so the composition only synchronizes.
+	cmdFunc := tools.NewCommandFunc(NewParseOptionsFunc(), NewMainFunc())
+	spy := cli.SpyProcInout()
+	want := `@startuml
+state "(s0, s0)" as s0_s0
+state "(s2, s1)" as s2_s1
+[*] --> s0_s0
+s0_s0 --> s2_s1 : sync
+@enduml
+`
+	wantReport := `../../../examples/valid/in.puml: 3 states, 2 after minimization
+../../../examples/valid/out.puml: 3 states, 2 after minimization
+../../../examples/valid/in.puml || ../../../examples/valid/out.puml: 2 states, 2 after minimization
+`
+
+	// Act
+	exitStatus := cmdFunc([]string{
+		"-sync", "sync",
+		"../../../examples/valid/in.puml",
+		"../../../examples/valid/out.puml",
+	}, spy.New())
+
+	// Assert
+	if exitStatus != 0 {
+		t.Log(spy.Stderr.String())
+		t.Errorf("want 0, got %d", exitStatus)
+	}
+	if diff := cmp.Diff(want, spy.Stdout.String()); diff != "" {
+		t.Error(diff)
+	}
+	if diff := cmp.Diff(wantReport, spy.Stderr.String()); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestNewMainFuncKeepsVisibleEvents(t *testing.T) {
+	// Arrange
+	cmdFunc := tools.NewCommandFunc(NewParseOptionsFunc(), NewMainFunc())
+	spy := cli.SpyProcInout()
+	want := `@startuml
+state "(s0, s0)" as s0_s0
+state "(s1, s0)" as s1_s0
+state "(s2, s1)" as s2_s1
+[*] --> s0_s0
+s0_s0 --> s1_s0 : in
+s1_s0 --> s2_s1 : sync
+@enduml
+`
+
+	// Act
+	exitStatus := cmdFunc([]string{
+		"-sync", "sync",
+		"-visible", "in",
+		"../../../examples/valid/in.puml",
+		"../../../examples/valid/out.puml",
+	}, spy.New())
+
+	// Assert
+	if exitStatus != 0 {
+		t.Log(spy.Stderr.String())
+		t.Errorf("want 0, got %d", exitStatus)
+	}
+	if diff := cmp.Diff(want, spy.Stdout.String()); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestNewMainFuncVersion(t *testing.T) {
+	// Arrange
+	cmdFunc := tools.NewCommandFunc(NewParseOptionsFunc(), NewMainFunc())
+	spy := cli.SpyProcInout()
+
+	// Act
+	exitStatus := cmdFunc([]string{"-v"}, spy.New())
+
+	// Assert
+	if exitStatus != 0 {
+		t.Log(spy.Stderr.String())
+		t.Errorf("want 0, got %d", exitStatus)
+	}
+	want := version.Version + "\n"
+	if diff := cmp.Diff(want, spy.Stdout.String()); diff != "" {
+		t.Error(diff)
+	}
+}
@@ -0,0 +1,100 @@
+package csdfcomposecmd
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+
+	"github.com/Kuniwak/puml-parallel/cli"
+	"github.com/Kuniwak/puml-parallel/csdf"
+	"github.com/Kuniwak/puml-parallel/tools"
+)
+
+type Options struct {
+	Common   *tools.CommonOptions
+	Explorer *csdf.Explorer
+	Sync     []csdf.Event
+	// Visible are the unsynchronized events kept visible; the others are
+	// hidden.
+	Visible []csdf.Event
+	Output  *tools.PlantUMLOutputOptions
+	Files   []string
+}
+
+// CommonOptions returns the parsed common options.
+func (o *Options) CommonOptions() *tools.CommonOptions { return o.Common }
+
+func NewParseOptionsFunc() cli.ParseOptionsFunc[*Options] {
+	return func(args []string, inout *cli.ProcInout) (*Options, error) {
+		flags := flag.NewFlagSet("csdfcompose", flag.ContinueOnError)
+		flags.SetOutput(inout.Stderr)
+		flags.Usage = func() {
+			w := flags.Output()
+			fmt.Fprintf(w, `Usage: csdfcompose [options] <file1.puml> [file2.puml] ...
+
+Composes Composable State Diagrams in parallel like csdfparallel, hiding the events
+that are neither synchronized nor listed in -visible, and minimizing on the way:
+each diagram is hidden and minimized, then the two results whose composition is
+the smallest are composed and minimized until one is left. Prints the result and
+reports the number of states of each step, before and after minimization, on
+standard error.
+
+The result has the same deadlocks and livelocks as the composition of csdfparallel
+with the same events hidden.
+
+Options:
+`)
+			flags.PrintDefaults()
+			fmt.Fprintf(w, `
+Examples:
+  $ csdfcompose -sync 'insert;choose;drop' a.puml b.puml c.puml
+  $ csdfcompose -sync 'insert;choose;drop' -visible 'showAvailable' a.puml b.puml
+  $ csdfcompose -sync 'insert' a.puml b.puml | csdfdeadlockfree -
+`)
+		}
+
+		var commonRawOpts tools.CommonRawOptions
+		tools.DeclareCommonOptions(flags, &commonRawOpts)
+		var explorer csdf.Explorer
+		tools.DeclareExploreOptions(flags, &explorer)
+		syncFlag := flags.String("sync", "", "semicolon-separated list of synchronization events")
+		visibleFlag := flags.String("visible", "", "semicolon-separated list of unsynchronized events to keep visible")
+		var outputOpts tools.PlantUMLOutputOptions
+		tools.DeclarePlantUMLOutputOptions(flags, &outputOpts)
+
+		if err := flags.Parse(args); err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				return &Options{Common: tools.CommonOptionsHelp}, nil
+			}
+			return nil, fmt.Errorf("csdfcomposecmd.NewParseOptionsFunc: parse failed: %w", err)
+		}
+
+		commonOpts, err := tools.ValidateCommonOptions(&commonRawOpts)
+		if err != nil {
+			return nil, fmt.Errorf("csdfcomposecmd.NewParseOptionsFunc: validate common options failed: %w", err)
+		}
+		if commonOpts.Version {
+			return &Options{Common: tools.CommonOptionsVersion}, nil
+		}
+		if err := tools.ValidateExploreOptions(&explorer); err != nil {
+			return nil, fmt.Errorf("csdfcomposecmd.NewParseOptionsFunc: %w", err)
+		}
+		if err := tools.ValidatePlantUMLOutputOptions(&outputOpts); err != nil {
+			return nil, fmt.Errorf("csdfcomposecmd.NewParseOptionsFunc: %w", err)
+		}
+
+		files := flags.Args()
+		if len(files) < 1 {
+			return nil, fmt.Errorf("csdfcomposecmd.NewParseOptionsFunc: too few arguments")
+		}
+
+		return &Options{
+			Common:   commonOpts,
+			Explorer: &explorer,
+			Sync:     tools.ParseSyncEvents(*syncFlag),
+			Visible:  tools.ParseSyncEvents(*visibleFlag),
+			Output:   &outputOpts,
+			Files:    files,
+		}, nil
+	}
+}
@@ -0,0 +1,109 @@
+package csdfcomposecmd
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/Kuniwak/puml-parallel/cli"
+	"github.com/Kuniwak/puml-parallel/csdf"
+	"github.com/Kuniwak/puml-parallel/pumlenc"
+	"github.com/Kuniwak/puml-parallel/tools"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestNewParseOptionsFuncOK(t *testing.T) {
+	type testCase struct {
+		Args     []string
+		Expected *Options
+	}
+
+	testCases := map[string]testCase{
+		"-h (representative value)": {
+			Args:     []string{"-h"},
+			Expected: &Options{Common: tools.CommonOptionsHelp},
+		},
+		"-v (representative value)": {
+			Args:     []string{"-v"},
+			Expected: &Options{Common: tools.CommonOptionsVersion},
+		},
+		"single file (lower boundary value)": {
+			Args:     []string{"a.puml"},
+			Expected: &Options{Common: tools.NewCommonOptionsDefault(), Explorer: &csdf.Explorer{}, Output: &tools.PlantUMLOutputOptions{Server: pumlenc.DefaultServer}, Files: []string{"a.puml"}},
+		},
+		"-sync and -visible with three files (representative value)": {
+			Args: []string{"-sync", "x;y", "-visible", "z", "a.puml", "b.puml", "c.puml"},
+			Expected: &Options{
+				Common:   tools.NewCommonOptionsDefault(),
+				Explorer: &csdf.Explorer{},
+				Sync:     []csdf.Event{"x", "y"},
+				Visible:  []csdf.Event{"z"},
+				Output:   &tools.PlantUMLOutputOptions{Server: pumlenc.DefaultServer},
+				Files:    []string{"a.puml", "b.puml", "c.puml"},
+			},
+		},
+		"-workers (representative value)": {
+			Args: []string{"-workers", "2", "a.puml"},
+			Expected: &Options{
+				Common:   tools.NewCommonOptionsDefault(),
+				Explorer: &csdf.Explorer{Workers: 2},
+				Output:   &tools.PlantUMLOutputOptions{Server: pumlenc.DefaultServer},
+				Files:    []string{"a.puml"},
+			},
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Arrange
+			parseOptions := NewParseOptionsFunc()
+			spy := cli.SpyProcInout()
+
+			// Act
+			opts, err := parseOptions(testCase.Args, spy.New())
+			if err != nil {
+				t.Log(spy.Stderr.String())
+				t.Errorf("want nil, got %#v", err)
+			}
+
+			// Assert
+			if !reflect.DeepEqual(testCase.Expected, opts) {
+				t.Error(cmp.Diff(testCase.Expected, opts))
+			}
+		})
+	}
+}
+
+func TestNewParseOptionsFuncNG(t *testing.T) {
+	type testCase struct {
+		Args []string
+	}
+
+	testCases := map[string]testCase{
+		"too few arguments (representative value)": {
+			Args: []string{},
+		},
+		"unknown -link format (representative value)": {
+			Args: []string{"-link", "jpeg", "a.puml"},
+		},
+		"negative -workers (representative value)": {
+			Args: []string{"-workers", "-1", "a.puml"},
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Arrange
+			parseOptions := NewParseOptionsFunc()
+			spy := cli.SpyProcInout()
+
+			// Act
+			opts, err := parseOptions(testCase.Args, spy.New())
+
+			// Assert
+			if err == nil {
+				t.Log(opts)
+				t.Error("want not nil, got nil")
+			}
+		})
+	}
+}
@@ -0,0 +1,13 @@
+package main
+
+import (
+	"github.com/Kuniwak/puml-parallel/tools"
+	"github.com/Kuniwak/puml-parallel/tools/csdfcompose/csdfcomposecmd"
+)
+
+func main() {
+	tools.NewCommandFunc(
+		csdfcomposecmd.NewParseOptionsFunc(),
+		csdfcomposecmd.NewMainFunc(),
+	).Run()
+}
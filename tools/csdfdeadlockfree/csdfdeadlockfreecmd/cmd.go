@@ -0,0 +1,58 @@
+package csdfdeadlockfreecmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/Kuniwak/puml-parallel/cli"
+	"github.com/Kuniwak/puml-parallel/csdf"
+	"github.com/Kuniwak/puml-parallel/version"
+)
+
+// ErrDeadlockDetected is returned when the diagram is not deadlock free. The CLI
+// layer turns it into a non-zero exit status; the witness is printed to stdout.
+var ErrDeadlockDetected = errors.New("deadlock detected")
+
+func NewMainFunc() cli.MainFunc[*Options] {
+	return func(opts *Options, inout *cli.ProcInout) error {
+		if opts.Common.Help {
+			return nil
+		}
+		if opts.Common.Version {
+			fmt.Fprintln(inout.Stdout, version.Version)
+			return nil
+		}
+
+		diagrams, err := loadDiagrams(opts)
+		if err != nil {
+			return fmt.Errorf("csdfdeadlockfreecmd.NewMainFunc: %w", err)
+		}
+
+		witness, ok, err := opts.Explorer.CheckComposedDeadlockFree(diagrams, opts.Sync)
+		if err != nil {
+			return fmt.Errorf("csdfdeadlockfreecmd.NewMainFunc: %w", err)
+		}
+		if ok {
+			fmt.Fprintln(inout.Stdout, "deadlock free")
+			return nil
+		}
+
+		fmt.Fprint(inout.Stdout, csdf.RenderDeadlock(witness))
+		return fmt.Errorf("csdfdeadlockfreecmd.NewMainFunc: %w", ErrDeadlockDetected)
+	}
+}
+
+func loadDiagrams(opts *Options) ([]*csdf.Diagram, error) {
+	if len(opts.Files) > 0 {
+		diagrams, err := csdf.LoadDiagrams(opts.Files)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse diagrams: %w", err)
+		}
+		return diagrams, nil
+	}
+	diagram, err := csdf.ParseDiagramFile(opts.Path, opts.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	return []*csdf.Diagram{diagram}, nil
+}
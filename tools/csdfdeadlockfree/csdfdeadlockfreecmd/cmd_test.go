@@ -0,0 +1,102 @@
+package csdfdeadlockfreecmd
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Kuniwak/puml-parallel/cli"
+	"github.com/Kuniwak/puml-parallel/tools"
+	"github.com/Kuniwak/puml-parallel/version"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestNewMainFuncReportsDeadlockFree(t *testing.T) {
+	// Arrange: a diagram whose states all lead back to the start is deadlock free.
+	cmdFunc := tools.NewCommandFunc(NewParseOptionsFunc(), NewMainFunc())
+	spy := cli.SpyProcInout()
+	want := "deadlock free\n"
+
+	// Act
+	exitStatus := cmdFunc([]string{filepath.Join("testdata", "free.puml")}, spy.New())
+
+	// Assert
+	if exitStatus != 0 {
+		t.Log(spy.Stderr.String())
+		t.Errorf("want 0, got %d", exitStatus)
+	}
+	if diff := cmp.Diff(want, spy.Stdout.String()); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestNewMainFuncDetectsDeadlockInComposition(t *testing.T) {
+	for _, args := range [][]string{{}, {"-no-reduction"}} {
+		t.Run(strings.Join(args, " "), func(t *testing.T) {
+			// Arrange: in.puml and out.puml both stop after their last event.
+			cmdFunc := tools.NewCommandFunc(NewParseOptionsFunc(), NewMainFunc())
+			spy := cli.SpyProcInout()
+			args = append(args, "-sync", "sync", "../../../examples/valid/in.puml", "../../../examples/valid/out.puml")
+			want := "s0_s0 --in--> s1_s0\ns1_s0 --sync--> s2_s1\ns2_s1 --out--> s2_s2\ndeadlock: s2_s2\n"
+
+			// Act
+			exitStatus := cmdFunc(args, spy.New())
+
+			// Assert
+			if exitStatus == 0 {
+				t.Error("want non-zero exit status, got 0")
+			}
+			if diff := cmp.Diff(want, spy.Stdout.String()); diff != "" {
+				t.Error(diff)
+			}
+			if !strings.Contains(spy.Stderr.String(), "deadlock detected") {
+				t.Errorf("want deadlock detected on stderr, got %q", spy.Stderr.String())
+			}
+		})
+	}
+}
+
+func TestNewMainFuncReadsStdin(t *testing.T) {
+	// Arrange: reading from stdin must be equivalent to a file argument.
+	input := `@startuml
+state "s0" as s0
+state "s1" as s1
+[*] --> s0
+s0 --> s1 : a
+@enduml
+`
+	cmdFunc := tools.NewCommandFunc(NewParseOptionsFunc(), NewMainFunc())
+	spy := cli.SpyProcInout()
+	spy.Stdin = cli.StubStdin(strings.NewReader(input))
+	want := "s0 --a--> s1\ndeadlock: s1\n"
+
+	// Act
+	exitStatus := cmdFunc([]string{}, spy.New())
+
+	// Assert
+	if exitStatus == 0 {
+		t.Error("want non-zero exit status, got 0")
+	}
+	if diff := cmp.Diff(want, spy.Stdout.String()); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestNewMainFuncVersion(t *testing.T) {
+	// Arrange
+	cmdFunc := tools.NewCommandFunc(NewParseOptionsFunc(), NewMainFunc())
+	spy := cli.SpyProcInout()
+
+	// Act
+	exitStatus := cmdFunc([]string{"-v"}, spy.New())
+
+	// Assert
+	if exitStatus != 0 {
+		t.Log(spy.Stderr.String())
+		t.Errorf("want 0, got %d", exitStatus)
+	}
+	want := version.Version + "\n"
+	if diff := cmp.Diff(want, spy.Stdout.String()); diff != "" {
+		t.Error(diff)
+	}
+}
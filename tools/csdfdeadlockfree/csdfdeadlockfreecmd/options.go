@@ -0,0 +1,92 @@
+package csdfdeadlockfreecmd
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+
+	"github.com/Kuniwak/puml-parallel/cli"
+	"github.com/Kuniwak/puml-parallel/csdf"
+	"github.com/Kuniwak/puml-parallel/tools"
+)
+
+type Options struct {
+	Common   *tools.CommonOptions
+	Explorer *csdf.Explorer
+	Sync     []csdf.Event
+	Path     string // "" when reading standard input
+	Bytes    []byte
+	Files    []string // the diagrams to compose, when more than one is given
+}
+
+// CommonOptions returns the parsed common options.
+func (o *Options) CommonOptions() *tools.CommonOptions { return o.Common }
+
+func NewParseOptionsFunc() cli.ParseOptionsFunc[*Options] {
+	return func(args []string, inout *cli.ProcInout) (*Options, error) {
+		flags := flag.NewFlagSet("csdfdeadlockfree", flag.ContinueOnError)
+		flags.SetOutput(inout.Stderr)
+		flags.Usage = func() {
+			w := flags.Output()
+			fmt.Fprintf(w, `Usage: csdfdeadlockfree [options] [file.puml|file.png]
+       csdfdeadlockfree [options] [-sync event1;event2;...] file1.puml file2.puml ...
+
+Verifies that a Composable State Diagram is deadlock free, i.e. every state
+reachable from the start state has an outgoing transition. Guards are not
+evaluated. Prints "deadlock free" and exits 0 when free; otherwise prints a
+trace to a deadlocked state and exits 1.
+A file argument, a "-" argument, and standard input are all equivalent.
+
+Given several files, checks their interface parallel composition as composed by
+csdfparallel. The composition is built with partial-order reduction, which skips
+interleavings of unsynchronized events that cannot hide a deadlock, and then
+checked; -no-reduction turns the reduction off.
+
+Options:
+`)
+			flags.PrintDefaults()
+			fmt.Fprintf(w, `
+Examples:
+  $ csdfdeadlockfree path/to/file.puml
+  $ csdfdeadlockfree < path/to/file.puml
+  $ csdfparallel a.puml b.puml | csdfdeadlockfree -
+  $ csdfdeadlockfree -sync 'insert;choose' a.puml b.puml
+`)
+		}
+
+		var commonRawOpts tools.CommonRawOptions
+		tools.DeclareCommonOptions(flags, &commonRawOpts)
+		var explorer csdf.Explorer
+		tools.DeclareExploreOptions(flags, &explorer)
+		syncFlag := flags.String("sync", "", "semicolon-separated list of synchronization events of the composed files")
+		flags.BoolVar(&explorer.NoReduction, "no-reduction", false, "explore every interleaving of the composed files; the verdict does not depend on it")
+
+		if err := flags.Parse(args); err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				return &Options{Common: tools.CommonOptionsHelp}, nil
+			}
+			return nil, fmt.Errorf("csdfdeadlockfreecmd.NewParseOptionsFunc: parse failed: %w", err)
+		}
+
+		commonOpts, err := tools.ValidateCommonOptions(&commonRawOpts)
+		if err != nil {
+			return nil, fmt.Errorf("csdfdeadlockfreecmd.NewParseOptionsFunc: validate common options failed: %w", err)
+		}
+		if commonOpts.Version {
+			return &Options{Common: tools.CommonOptionsVersion}, nil
+		}
+		if err := tools.ValidateExploreOptions(&explorer); err != nil {
+			return nil, fmt.Errorf("csdfdeadlockfreecmd.NewParseOptionsFunc: %w", err)
+		}
+
+		sync := tools.ParseSyncEvents(*syncFlag)
+		if files := flags.Args(); len(files) > 1 {
+			return &Options{Common: commonOpts, Explorer: &explorer, Sync: sync, Files: files}, nil
+		}
+		path, bs, err := tools.ValidateArgsAsFilePath(flags.Args(), inout)
+		if err != nil {
+			return nil, fmt.Errorf("csdfdeadlockfreecmd.NewParseOptionsFunc: validate arguments failed: %w", err)
+		}
+		return &Options{Common: commonOpts, Explorer: &explorer, Sync: sync, Path: path, Bytes: bs}, nil
+	}
+}
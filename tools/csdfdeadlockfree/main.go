@@ -0,0 +1,13 @@
+package main
+
+import (
+	"github.com/Kuniwak/puml-parallel/tools"
+	"github.com/Kuniwak/puml-parallel/tools/csdfdeadlockfree/csdfdeadlockfreecmd"
+)
+
+func main() {
+	tools.NewCommandFunc(
+		csdfdeadlockfreecmd.NewParseOptionsFunc(),
+		csdfdeadlockfreecmd.NewMainFunc(),
+	).Run()
+}
@@ -0,0 +1,39 @@
+package csdfdotcmd
+
+import (
+	"fmt"
+
+	"github.com/Kuniwak/puml-parallel/cli"
+	"github.com/Kuniwak/puml-parallel/csdf"
+	"github.com/Kuniwak/puml-parallel/csdf/dot"
+	"github.com/Kuniwak/puml-parallel/version"
+)
+
+func NewMainFunc() cli.MainFunc[*Options] {
+	return func(opts *Options, inout *cli.ProcInout) error {
+		if opts.Common.Help {
+			return nil
+		}
+		if opts.Common.Version {
+			fmt.Fprintln(inout.Stdout, version.Version)
+			return nil
+		}
+
+		diagram, err := csdf.ParseDiagramFile(opts.Path, opts.Bytes)
+		if err != nil {
+			return fmt.Errorf("csdfdotcmd.NewMainFunc: %w", err)
+		}
+
+		exportOpts := &dot.ExportOptions{Tau: opts.Tau, Clusters: opts.Clusters}
+		if opts.Livelock {
+			// A livelock-free diagram has no witness to draw.
+			exportOpts.Livelock, _, err = opts.Explorer.CheckLivelockFree(diagram)
+			if err != nil {
+				return fmt.Errorf("csdfdotcmd.NewMainFunc: %w", err)
+			}
+		}
+
+		fmt.Fprint(inout.Stdout, dot.Export(diagram, exportOpts))
+		return nil
+	}
+}
@@ -0,0 +1,77 @@
+package csdfdotcmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Kuniwak/puml-parallel/cli"
+	"github.com/Kuniwak/puml-parallel/tools"
+	"github.com/Kuniwak/puml-parallel/version"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestNewMainFuncRendersDiagram(t *testing.T) {
+	// Arrange
+	cmdFunc := tools.NewCommandFunc(NewParseOptionsFunc(), NewMainFunc())
+	spy := cli.SpyProcInout()
+	want := `digraph "" {
+  node [shape=box, style=rounded];
+  "[*]" [shape=point, width=0.2, label=""];
+  "s0" [label="s0"];
+  "s1" [label="s1"];
+  "s2" [label="s2"];
+  "[*]" -> "s0";
+  "s0" -> "s1" [label="in"];
+  "s1" -> "s2" [label="sync"];
+}
+`
+
+	// Act
+	exitStatus := cmdFunc([]string{"../../../examples/valid/in.puml"}, spy.New())
+
+	// Assert
+	if exitStatus != 0 {
+		t.Log(spy.Stderr.String())
+		t.Errorf("want 0, got %d", exitStatus)
+	}
+	if diff := cmp.Diff(want, spy.Stdout.String()); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestNewMainFuncHighlightsLivelock(t *testing.T) {
+	// Arrange: user.puml has a tau self-loop on userIdle (a livelock).
+	cmdFunc := tools.NewCommandFunc(NewParseOptionsFunc(), NewMainFunc())
+	spy := cli.SpyProcInout()
+	want := `"userIdle" -> "userIdle" [label="tau / wanted' is non-empty", style="dashed", color="red", fontcolor="red", penwidth=2];`
+
+	// Act
+	exitStatus := cmdFunc([]string{"-livelock", "-tau", "../../../examples/valid/user.puml"}, spy.New())
+
+	// Assert
+	if exitStatus != 0 {
+		t.Log(spy.Stderr.String())
+		t.Errorf("want 0, got %d", exitStatus)
+	}
+	if !strings.Contains(spy.Stdout.String(), want) {
+		t.Errorf("want %q in output, got %q", want, spy.Stdout.String())
+	}
+}
+
+func TestNewMainFuncVersion(t *testing.T) {
+	// Arrange
+	cmdFunc := tools.NewCommandFunc(NewParseOptionsFunc(), NewMainFunc())
+	spy := cli.SpyProcInout()
+
+	// Act
+	exitStatus := cmdFunc([]string{"-v"}, spy.New())
+
+	// Assert
+	if exitStatus != 0 {
+		t.Log(spy.Stderr.String())
+		t.Errorf("want 0, got %d", exitStatus)
+	}
+	if diff := cmp.Diff(version.Version+"\n", spy.Stdout.String()); diff != "" {
+		t.Error(diff)
+	}
+}
@@ -0,0 +1,88 @@
+package csdfdotcmd
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+
+	"github.com/Kuniwak/puml-parallel/cli"
+	"github.com/Kuniwak/puml-parallel/csdf"
+	"github.com/Kuniwak/puml-parallel/tools"
+)
+
+type Options struct {
+	Common   *tools.CommonOptions
+	Explorer *csdf.Explorer
+	Livelock bool
+	Tau      bool
+	Clusters bool
+	Path     string // "" when reading standard input
+	Bytes    []byte
+}
+
+// CommonOptions returns the parsed common options.
+func (o *Options) CommonOptions() *tools.CommonOptions { return o.Common }
+
+func NewParseOptionsFunc() cli.ParseOptionsFunc[*Options] {
+	return func(args []string, inout *cli.ProcInout) (*Options, error) {
+		flags := flag.NewFlagSet("csdfdot", flag.ContinueOnError)
+		flags.SetOutput(inout.Stderr)
+		flags.Usage = func() {
+			w := flags.Output()
+			fmt.Fprintf(w, `Usage: csdfdot [options] [file.puml|file.png]
+
+Renders a Composable State Diagram in the Graphviz DOT language.
+Flags select the analysis results drawn over the diagram.
+A file argument, a "-" argument, and standard input are all equivalent.
+
+Options:
+`)
+			flags.PrintDefaults()
+			fmt.Fprintf(w, `
+Examples:
+  $ csdfdot path/to/file.puml | dot -Tsvg > file.svg
+  $ csdfparallel a.puml b.puml | csdfdot -livelock -tau -clusters - | dot -Tsvg > ab.svg
+`)
+		}
+
+		var commonRawOpts tools.CommonRawOptions
+		tools.DeclareCommonOptions(flags, &commonRawOpts)
+		var explorer csdf.Explorer
+		tools.DeclareExploreOptions(flags, &explorer)
+		livelockFlag := flags.Bool("livelock", false, "highlight a livelock witness in red")
+		tauFlag := flags.Bool("tau", false, "draw tau-edges dashed and in blue")
+		clustersFlag := flags.Bool("clusters", false, "group composed states by the states of their components")
+
+		if err := flags.Parse(args); err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				return &Options{Common: tools.CommonOptionsHelp}, nil
+			}
+			return nil, fmt.Errorf("csdfdotcmd.NewParseOptionsFunc: parse failed: %w", err)
+		}
+
+		commonOpts, err := tools.ValidateCommonOptions(&commonRawOpts)
+		if err != nil {
+			return nil, fmt.Errorf("csdfdotcmd.NewParseOptionsFunc: validate common options failed: %w", err)
+		}
+		if commonOpts.Version {
+			return &Options{Common: tools.CommonOptionsVersion}, nil
+		}
+		if err := tools.ValidateExploreOptions(&explorer); err != nil {
+			return nil, fmt.Errorf("csdfdotcmd.NewParseOptionsFunc: %w", err)
+		}
+
+		path, bs, err := tools.ValidateArgsAsFilePath(flags.Args(), inout)
+		if err != nil {
+			return nil, fmt.Errorf("csdfdotcmd.NewParseOptionsFunc: validate arguments failed: %w", err)
+		}
+		return &Options{
+			Common:   commonOpts,
+			Explorer: &explorer,
+			Livelock: *livelockFlag,
+			Tau:      *tauFlag,
+			Clusters: *clustersFlag,
+			Path:     path,
+			Bytes:    bs,
+		}, nil
+	}
+}
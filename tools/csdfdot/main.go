@@ -0,0 +1,13 @@
+package main
+
+import (
+	"github.com/Kuniwak/puml-parallel/tools"
+	"github.com/Kuniwak/puml-parallel/tools/csdfdot/csdfdotcmd"
+)
+
+func main() {
+	tools.NewCommandFunc(
+		csdfdotcmd.NewParseOptionsFunc(),
+		csdfdotcmd.NewMainFunc(),
+	).Run()
+}
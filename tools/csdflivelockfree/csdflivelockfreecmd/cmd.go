@@ -23,12 +23,15 @@ func NewMainFunc() cli.MainFunc[*Options] {
 			return nil
 		}
 
-		diagram, err := csdf.ParseDiagram(opts.Bytes)
+		diagrams, err := loadDiagrams(opts)
 		if err != nil {
 			return fmt.Errorf("csdflivelockfreecmd.NewMainFunc: %w", err)
 		}
 
-		witness, ok := csdf.CheckLivelockFree(diagram)
+		witness, ok, err := opts.Explorer.CheckComposedLivelockFree(diagrams, opts.Sync)
+		if err != nil {
+			return fmt.Errorf("csdflivelockfreecmd.NewMainFunc: %w", err)
+		}
 		if ok {
 			fmt.Fprintln(inout.Stdout, "livelock free")
 			return nil
@@ -38,3 +41,18 @@ func NewMainFunc() cli.MainFunc[*Options] {
 		return fmt.Errorf("csdflivelockfreecmd.NewMainFunc: %w", ErrLivelockDetected)
 	}
 }
+
+func loadDiagrams(opts *Options) ([]*csdf.Diagram, error) {
+	if len(opts.Files) > 0 {
+		diagrams, err := csdf.LoadDiagrams(opts.Files)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse diagrams: %w", err)
+		}
+		return diagrams, nil
+	}
+	diagram, err := csdf.ParseDiagramFile(opts.Path, opts.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	return []*csdf.Diagram{diagram}, nil
+}
@@ -50,6 +50,35 @@ func TestNewMainFuncDetectsLivelock(t *testing.T) {
 	}
 }
 
+func TestNewMainFuncDetectsLivelockInComposition(t *testing.T) {
+	for _, args := range [][]string{{}, {"-no-reduction"}} {
+		t.Run(strings.Join(args, " "), func(t *testing.T) {
+			// Arrange: the machine may spin on tau once the user has paid.
+			cmdFunc := tools.NewCommandFunc(NewParseOptionsFunc(), NewMainFunc())
+			spy := cli.SpyProcInout()
+			args = append(args,
+				"-sync", "insert(coin);choose(product);drop(product)",
+				"../../../examples/valid/user.puml",
+				"../../../examples/valid/vending_machine.puml",
+			)
+
+			// Act
+			exitStatus := cmdFunc(args, spy.New())
+
+			// Assert
+			if exitStatus == 0 {
+				t.Error("want non-zero exit status, got 0")
+			}
+			if !strings.Contains(spy.Stdout.String(), "cycle:\n") {
+				t.Errorf("want witness on stdout, got %q", spy.Stdout.String())
+			}
+			if !strings.Contains(spy.Stderr.String(), "livelock detected") {
+				t.Errorf("want livelock detected on stderr, got %q", spy.Stderr.String())
+			}
+		})
+	}
+}
+
 func TestNewMainFuncReadsStdin(t *testing.T) {
 	// Arrange: reading from stdin must be equivalent to a file argument.
 	input := `@startuml
@@ -6,12 +6,17 @@ import (
 	"fmt"
 
 	"github.com/Kuniwak/puml-parallel/cli"
+	"github.com/Kuniwak/puml-parallel/csdf"
 	"github.com/Kuniwak/puml-parallel/tools"
 )
 
 type Options struct {
-	Common *tools.CommonOptions
-	Bytes  []byte
+	Common   *tools.CommonOptions
+	Explorer *csdf.Explorer
+	Sync     []csdf.Event
+	Path     string // "" when reading standard input
+	Bytes    []byte
+	Files    []string // the diagrams to compose, when more than one is given
 }
 
 // CommonOptions returns the parsed common options.
@@ -24,12 +29,18 @@ func NewParseOptionsFunc() cli.ParseOptionsFunc[*Options] {
 		flags.Usage = func() {
 			w := flags.Output()
 			fmt.Fprintf(w, `Usage: csdflivelockfree [options] [file.puml|file.png]
+       csdflivelockfree [options] [-sync event1;event2;...] file1.puml file2.puml ...
 
 Verifies that a Composable State Diagram is livelock free, i.e. has no cycle
 reachable from the start state consisting entirely of internal "tau" transitions.
 Prints "livelock free" and exits 0 when free; otherwise prints a witness and exits 1.
 A file argument, a "-" argument, and standard input are all equivalent.
 
+Given several files, checks their interface parallel composition as composed by
+csdfparallel. The composition is built with partial-order reduction, which skips
+interleavings of unsynchronized events that cannot hide a livelock, and then
+checked; -no-reduction turns the reduction off.
+
 Options:
 `)
 			flags.PrintDefaults()
@@ -38,11 +49,16 @@ Examples:
   $ csdflivelockfree path/to/file.puml
   $ csdflivelockfree < path/to/file.puml
   $ csdfparallel a.puml b.puml | csdflivelockfree -
+  $ csdflivelockfree -sync 'insert;choose' a.puml b.puml
 `)
 		}
 
 		var commonRawOpts tools.CommonRawOptions
 		tools.DeclareCommonOptions(flags, &commonRawOpts)
+		var explorer csdf.Explorer
+		tools.DeclareExploreOptions(flags, &explorer)
+		syncFlag := flags.String("sync", "", "semicolon-separated list of synchronization events of the composed files")
+		flags.BoolVar(&explorer.NoReduction, "no-reduction", false, "explore every interleaving of the composed files; the verdict does not depend on it")
 
 		if err := flags.Parse(args); err != nil {
 			if errors.Is(err, flag.ErrHelp) {
@@ -58,11 +74,18 @@ Examples:
 		if commonOpts.Version {
 			return &Options{Common: tools.CommonOptionsVersion}, nil
 		}
+		if err := tools.ValidateExploreOptions(&explorer); err != nil {
+			return nil, fmt.Errorf("csdflivelockfreecmd.NewParseOptionsFunc: %w", err)
+		}
 
-		bs, err := tools.ValidateArgsAsFilePath(flags.Args(), inout)
+		sync := tools.ParseSyncEvents(*syncFlag)
+		if files := flags.Args(); len(files) > 1 {
+			return &Options{Common: commonOpts, Explorer: &explorer, Sync: sync, Files: files}, nil
+		}
+		path, bs, err := tools.ValidateArgsAsFilePath(flags.Args(), inout)
 		if err != nil {
 			return nil, fmt.Errorf("csdflivelockfreecmd.NewParseOptionsFunc: validate arguments failed: %w", err)
 		}
-		return &Options{Common: commonOpts, Bytes: bs}, nil
+		return &Options{Common: commonOpts, Explorer: &explorer, Sync: sync, Path: path, Bytes: bs}, nil
 	}
 }
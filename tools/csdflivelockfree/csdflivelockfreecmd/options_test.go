@@ -7,6 +7,7 @@ import (
 	"testing"
 
 	"github.com/Kuniwak/puml-parallel/cli"
+	"github.com/Kuniwak/puml-parallel/csdf"
 	"github.com/Kuniwak/puml-parallel/tools"
 	"github.com/google/go-cmp/cmp"
 )
@@ -39,23 +40,36 @@ func TestNewParseOptionsFuncOK(t *testing.T) {
 			Stdin: "@startuml\n@enduml\n",
 			Args:  []string{},
 			Expected: &Options{
-				Common: tools.NewCommonOptionsDefault(),
-				Bytes:  []byte("@startuml\n@enduml\n"),
+				Common:   tools.NewCommonOptionsDefault(),
+				Explorer: &csdf.Explorer{},
+				Bytes:    []byte("@startuml\n@enduml\n"),
 			},
 		},
 		"dash means stdin (representative value)": {
 			Stdin: "@startuml\n@enduml\n",
 			Args:  []string{"-"},
 			Expected: &Options{
-				Common: tools.NewCommonOptionsDefault(),
-				Bytes:  []byte("@startuml\n@enduml\n"),
+				Common:   tools.NewCommonOptionsDefault(),
+				Explorer: &csdf.Explorer{},
+				Bytes:    []byte("@startuml\n@enduml\n"),
 			},
 		},
 		"file argument (representative value)": {
 			Args: []string{filepath.Join("testdata", "a.puml")},
 			Expected: &Options{
-				Common: tools.NewCommonOptionsDefault(),
-				Bytes:  []byte("@startuml\n@enduml\n"),
+				Common:   tools.NewCommonOptionsDefault(),
+				Explorer: &csdf.Explorer{},
+				Path:     filepath.Join("testdata", "a.puml"),
+				Bytes:    []byte("@startuml\n@enduml\n"),
+			},
+		},
+		"several files are composed (representative value)": {
+			Args: []string{"-sync", "a;b", "-no-reduction", "a.puml", "b.puml"},
+			Expected: &Options{
+				Common:   tools.NewCommonOptionsDefault(),
+				Explorer: &csdf.Explorer{NoReduction: true},
+				Sync:     []csdf.Event{"a", "b"},
+				Files:    []string{"a.puml", "b.puml"},
 			},
 		},
 	}
@@ -88,8 +102,11 @@ func TestNewParseOptionsFuncNG(t *testing.T) {
 	}
 
 	testCases := map[string]testCase{
-		"too many arguments (representative value)": {
-			Args: []string{"a.puml", "b.puml"},
+		"nonexistent file (representative value)": {
+			Args: []string{"nonexistent.puml"},
+		},
+		"unknown -store (representative value)": {
+			Args: []string{"-store", "tape", "a.puml", "b.puml"},
 		},
 	}
 
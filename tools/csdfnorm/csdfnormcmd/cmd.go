@@ -5,6 +5,7 @@ import (
 
 	"github.com/Kuniwak/puml-parallel/cli"
 	"github.com/Kuniwak/puml-parallel/csdf"
+	"github.com/Kuniwak/puml-parallel/tools"
 	"github.com/Kuniwak/puml-parallel/version"
 )
 
@@ -18,17 +19,19 @@ func NewMainFunc() cli.MainFunc[*Options] {
 			return nil
 		}
 
-		diagram, err := csdf.ParseDiagram(opts.Bytes)
+		diagram, err := csdf.ParseDiagramFile(opts.Path, opts.Bytes)
 		if err != nil {
 			return fmt.Errorf("csdfnormcmd.NewMainFunc: %w", err)
 		}
 
-		normalized, err := csdf.Normalize(diagram)
+		normalized, err := opts.Explorer.Normalize(diagram)
 		if err != nil {
 			return fmt.Errorf("csdfnormcmd.NewMainFunc: %w", err)
 		}
 
-		fmt.Fprint(inout.Stdout, normalized.String())
+		if err := tools.WritePlantUML(inout.Stdout, normalized.String(), opts.Output); err != nil {
+			return fmt.Errorf("csdfnormcmd.NewMainFunc: %w", err)
+		}
 		return nil
 	}
 }
@@ -6,12 +6,16 @@ import (
 	"fmt"
 
 	"github.com/Kuniwak/puml-parallel/cli"
+	"github.com/Kuniwak/puml-parallel/csdf"
 	"github.com/Kuniwak/puml-parallel/tools"
 )
 
 type Options struct {
-	Common *tools.CommonOptions
-	Bytes  []byte
+	Common   *tools.CommonOptions
+	Explorer *csdf.Explorer
+	Output   *tools.PlantUMLOutputOptions
+	Path     string // "" when reading standard input
+	Bytes    []byte
 }
 
 // CommonOptions returns the parsed common options.
@@ -37,11 +41,16 @@ Examples:
   $ csdfnorm path/to/file.puml
   $ csdfnorm < path/to/file.puml
   $ csdfnorm - < path/to/file.puml
+  $ csdfnorm -embed-into graph.png path/to/file.puml
 `)
 		}
 
 		var commonRawOpts tools.CommonRawOptions
 		tools.DeclareCommonOptions(flags, &commonRawOpts)
+		var explorer csdf.Explorer
+		tools.DeclareExploreOptions(flags, &explorer)
+		var outputOpts tools.PlantUMLOutputOptions
+		tools.DeclarePlantUMLOutputOptions(flags, &outputOpts)
 
 		if err := flags.Parse(args); err != nil {
 			if errors.Is(err, flag.ErrHelp) {
@@ -57,11 +66,17 @@ Examples:
 		if commonOpts.Version {
 			return &Options{Common: tools.CommonOptionsVersion}, nil
 		}
+		if err := tools.ValidateExploreOptions(&explorer); err != nil {
+			return nil, fmt.Errorf("csdfnormcmd.NewParseOptionsFunc: %w", err)
+		}
+		if err := tools.ValidatePlantUMLOutputOptions(&outputOpts); err != nil {
+			return nil, fmt.Errorf("csdfnormcmd.NewParseOptionsFunc: %w", err)
+		}
 
-		bs, err := tools.ValidateArgsAsFilePath(flags.Args(), inout)
+		path, bs, err := tools.ValidateArgsAsFilePath(flags.Args(), inout)
 		if err != nil {
 			return nil, fmt.Errorf("csdfnormcmd.NewParseOptionsFunc: validate arguments failed: %w", err)
 		}
-		return &Options{Common: commonOpts, Bytes: bs}, nil
+		return &Options{Common: commonOpts, Explorer: &explorer, Output: &outputOpts, Path: path, Bytes: bs}, nil
 	}
 }
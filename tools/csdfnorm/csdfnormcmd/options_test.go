@@ -7,6 +7,8 @@ import (
 	"testing"
 
 	"github.com/Kuniwak/puml-parallel/cli"
+	"github.com/Kuniwak/puml-parallel/csdf"
+	"github.com/Kuniwak/puml-parallel/pumlenc"
 	"github.com/Kuniwak/puml-parallel/tools"
 	"github.com/google/go-cmp/cmp"
 )
@@ -39,23 +41,40 @@ func TestNewParseOptionsFuncOK(t *testing.T) {
 			Stdin: "@startuml\n@enduml\n",
 			Args:  []string{},
 			Expected: &Options{
-				Common: tools.NewCommonOptionsDefault(),
-				Bytes:  []byte("@startuml\n@enduml\n"),
+				Common:   tools.NewCommonOptionsDefault(),
+				Explorer: &csdf.Explorer{},
+				Output:   &tools.PlantUMLOutputOptions{Server: pumlenc.DefaultServer},
+				Bytes:    []byte("@startuml\n@enduml\n"),
 			},
 		},
 		"dash means stdin (representative value)": {
 			Stdin: "@startuml\n@enduml\n",
 			Args:  []string{"-"},
 			Expected: &Options{
-				Common: tools.NewCommonOptionsDefault(),
-				Bytes:  []byte("@startuml\n@enduml\n"),
+				Common:   tools.NewCommonOptionsDefault(),
+				Explorer: &csdf.Explorer{},
+				Output:   &tools.PlantUMLOutputOptions{Server: pumlenc.DefaultServer},
+				Bytes:    []byte("@startuml\n@enduml\n"),
 			},
 		},
 		"file argument (representative value)": {
 			Args: []string{filepath.Join("testdata", "a.puml")},
 			Expected: &Options{
-				Common: tools.NewCommonOptionsDefault(),
-				Bytes:  []byte("@startuml\n@enduml\n"),
+				Common:   tools.NewCommonOptionsDefault(),
+				Explorer: &csdf.Explorer{},
+				Output:   &tools.PlantUMLOutputOptions{Server: pumlenc.DefaultServer},
+				Path:     filepath.Join("testdata", "a.puml"),
+				Bytes:    []byte("@startuml\n@enduml\n"),
+			},
+		},
+		"-embed-into (representative value)": {
+			Args: []string{"-embed-into", "a.png", filepath.Join("testdata", "a.puml")},
+			Expected: &Options{
+				Common:   tools.NewCommonOptionsDefault(),
+				Explorer: &csdf.Explorer{},
+				Output:   &tools.PlantUMLOutputOptions{EmbedInto: "a.png", Server: pumlenc.DefaultServer},
+				Path:     filepath.Join("testdata", "a.puml"),
+				Bytes:    []byte("@startuml\n@enduml\n"),
 			},
 		},
 	}
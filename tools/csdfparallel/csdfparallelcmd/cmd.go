@@ -1,10 +1,12 @@
 package csdfparallelcmd
 
 import (
+	"encoding/json"
 	"fmt"
 
 	"github.com/Kuniwak/puml-parallel/cli"
 	"github.com/Kuniwak/puml-parallel/csdf"
+	"github.com/Kuniwak/puml-parallel/tools"
 	"github.com/Kuniwak/puml-parallel/version"
 )
 
@@ -23,12 +25,25 @@ func NewMainFunc() cli.MainFunc[*Options] {
 			return fmt.Errorf("csdfparallelcmd.NewMainFunc: cannot parse diagrams: %w", err)
 		}
 
-		composite, err := csdf.ComposeParallel(diagrams, opts.Sync)
+		if opts.Provenance {
+			composition, err := opts.Explorer.Compose(diagrams, opts.Sync)
+			if err != nil {
+				return fmt.Errorf("csdfparallelcmd.NewMainFunc: %w", err)
+			}
+			if err := json.NewEncoder(inout.Stdout).Encode(csdf.NewCompositionDocument(composition)); err != nil {
+				return fmt.Errorf("csdfparallelcmd.NewMainFunc: %w", err)
+			}
+			return nil
+		}
+
+		composite, err := opts.Explorer.ComposeParallel(diagrams, opts.Sync)
 		if err != nil {
 			return fmt.Errorf("csdfparallelcmd.NewMainFunc: %w", err)
 		}
 
-		fmt.Fprint(inout.Stdout, composite.String())
+		if err := tools.WritePlantUML(inout.Stdout, composite.String(), opts.Output); err != nil {
+			return fmt.Errorf("csdfparallelcmd.NewMainFunc: %w", err)
+		}
 		return nil
 	}
 }
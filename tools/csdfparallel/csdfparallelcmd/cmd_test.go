@@ -1,9 +1,16 @@
 package csdfparallelcmd
 
 import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/Kuniwak/puml-parallel/cli"
+	"github.com/Kuniwak/puml-parallel/csdf"
+	"github.com/Kuniwak/puml-parallel/pngsrc"
+	"github.com/Kuniwak/puml-parallel/pumlenc"
 	"github.com/Kuniwak/puml-parallel/tools"
 	"github.com/Kuniwak/puml-parallel/version"
 	"github.com/google/go-cmp/cmp"
@@ -42,6 +49,182 @@ s2_s1 --> s2_s2 : out
 	}
 }
 
+func TestNewMainFuncProvenance(t *testing.T) {
+	// Arrange
+	cmdFunc := tools.NewCommandFunc(NewParseOptionsFunc(), NewMainFunc())
+	spy := cli.SpyProcInout()
+
+	// Act
+	exitStatus := cmdFunc([]string{
+		"-sync", "sync",
+		"-provenance",
+		"../../../examples/valid/in.puml",
+		"../../../examples/valid/out.puml",
+	}, spy.New())
+
+	// Assert
+	if exitStatus != 0 {
+		t.Log(spy.Stderr.String())
+		t.Fatalf("want 0, got %d", exitStatus)
+	}
+	var got struct {
+		FormatVersion string                          `json:"format_version"`
+		States        map[csdf.StateID][]csdf.StateID `json:"states"`
+		Edges         [][]csdf.ComponentEdge          `json:"edges"`
+	}
+	if err := json.Unmarshal([]byte(spy.Stdout.String()), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.FormatVersion != csdf.JSONFormatVersion {
+		t.Errorf("format_version = %q, want %q", got.FormatVersion, csdf.JSONFormatVersion)
+	}
+	if diff := cmp.Diff([]csdf.StateID{"s2", "s1"}, got.States["s2_s1"]); diff != "" {
+		t.Errorf("states[s2_s1]:\n%s", diff)
+	}
+	wantEdges := [][]csdf.ComponentEdge{
+		{{Component: 0, Edge: 0, Src: "s0", Dst: "s1", Event: "in"}},
+		{
+			{Component: 0, Edge: 1, Src: "s1", Dst: "s2", Event: "sync"},
+			{Component: 1, Edge: 0, Src: "s0", Dst: "s1", Event: "sync"},
+		},
+		{{Component: 1, Edge: 1, Src: "s1", Dst: "s2", Event: "out"}},
+	}
+	if diff := cmp.Diff(wantEdges, got.Edges); diff != "" {
+		t.Errorf("edges:\n%s", diff)
+	}
+}
+
+func TestNewMainFuncOutputDoesNotDependOnWorkers(t *testing.T) {
+	// Arrange
+	args := []string{
+		"-sync", "insert;showAvailable;showPurchasable;choose;drop",
+		"../../../examples/valid/user.puml",
+		"../../../examples/valid/vending_machine.puml",
+	}
+	outputs := make(map[string]string)
+
+	for _, workers := range []string{"1", "4"} {
+		cmdFunc := tools.NewCommandFunc(NewParseOptionsFunc(), NewMainFunc())
+		spy := cli.SpyProcInout()
+
+		// Act
+		exitStatus := cmdFunc(append([]string{"-workers", workers}, args...), spy.New())
+
+		// Assert
+		if exitStatus != 0 {
+			t.Log(spy.Stderr.String())
+			t.Fatalf("-workers %s: want 0, got %d", workers, exitStatus)
+		}
+		outputs[workers] = spy.Stdout.String()
+	}
+	if diff := cmp.Diff(outputs["1"], outputs["4"]); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestNewMainFuncEmbedInto(t *testing.T) {
+	// Arrange
+	cmdFunc := tools.NewCommandFunc(NewParseOptionsFunc(), NewMainFunc())
+	spy := cli.SpyProcInout()
+	image, err := os.ReadFile("../../../examples/valid/client.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "composite.png")
+	if err := os.WriteFile(path, image, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Act
+	exitStatus := cmdFunc([]string{
+		"-sync", "sync",
+		"-embed-into", path,
+		"../../../examples/valid/in.puml",
+		"../../../examples/valid/out.puml",
+	}, spy.New())
+
+	// Assert
+	if exitStatus != 0 {
+		t.Log(spy.Stderr.String())
+		t.Errorf("want 0, got %d", exitStatus)
+	}
+	embedded, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := pngsrc.Extract(embedded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(spy.Stdout.String(), got); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestNewMainFuncLink(t *testing.T) {
+	// Arrange
+	cmdFunc := tools.NewCommandFunc(NewParseOptionsFunc(), NewMainFunc())
+	spy := cli.SpyProcInout()
+	want := "https://www.plantuml.com/plantuml/png/" + pumlenc.Encode(`@startuml
+state "(s0, s0)" as s0_s0
+state "(s1, s0)" as s1_s0
+state "(s2, s1)" as s2_s1
+state "(s2, s2)" as s2_s2
+[*] --> s0_s0
+s0_s0 --> s1_s0 : in
+s1_s0 --> s2_s1 : sync
+s2_s1 --> s2_s2 : out
+@enduml
+`) + "\n"
+
+	// Act
+	exitStatus := cmdFunc([]string{
+		"-sync", "sync",
+		"-link", "png",
+		"../../../examples/valid/in.puml",
+		"../../../examples/valid/out.puml",
+	}, spy.New())
+
+	// Assert
+	if exitStatus != 0 {
+		t.Log(spy.Stderr.String())
+		t.Errorf("want 0, got %d", exitStatus)
+	}
+	if diff := cmp.Diff(want, spy.Stdout.String()); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestNewMainFuncReadsServerLinks(t *testing.T) {
+	// Arrange
+	cmdFunc := tools.NewCommandFunc(NewParseOptionsFunc(), NewMainFunc())
+	spy := cli.SpyProcInout()
+	in, err := os.ReadFile("../../../examples/valid/in.puml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := os.ReadFile("../../../examples/valid/out.puml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Act
+	exitStatus := cmdFunc([]string{
+		"-sync", "sync",
+		"https://www.plantuml.com/plantuml/uml/" + pumlenc.Encode(string(in)),
+		pumlenc.Encode(string(out)),
+	}, spy.New())
+
+	// Assert
+	if exitStatus != 0 {
+		t.Log(spy.Stderr.String())
+		t.Errorf("want 0, got %d", exitStatus)
+	}
+	if !strings.Contains(spy.Stdout.String(), "s2_s1 --> s2_s2 : out\n") {
+		t.Errorf("want the composition, got %q", spy.Stdout.String())
+	}
+}
+
 func TestNewMainFuncVersion(t *testing.T) {
 	// Arrange
 	cmdFunc := tools.NewCommandFunc(NewParseOptionsFunc(), NewMainFunc())
@@ -60,3 +243,33 @@ func TestNewMainFuncVersion(t *testing.T) {
 		t.Error(diff)
 	}
 }
+
+func TestNewMainFuncComposesNamedDiagramsFromOneFile(t *testing.T) {
+	// Arrange
+	cmdFunc := tools.NewCommandFunc(NewParseOptionsFunc(), NewMainFunc())
+	spy := cli.SpyProcInout()
+	want := `@startuml
+state "(Idle, Waiting)" as s0_s0
+state "(Sent, Received)" as s1_s1
+[*] --> s0_s0
+s0_s0 --> s1_s1 : sync
+s1_s1 --> s1_s1 : out
+@enduml
+`
+
+	// Act
+	exitStatus := cmdFunc([]string{
+		"-sync", "sync",
+		"../../../examples/valid/multiple_diagrams.puml#sender",
+		"../../../examples/valid/multiple_diagrams.puml#receiver",
+	}, spy.New())
+
+	// Assert
+	if exitStatus != 0 {
+		t.Log(spy.Stderr.String())
+		t.Errorf("want 0, got %d", exitStatus)
+	}
+	if diff := cmp.Diff(want, spy.Stdout.String()); diff != "" {
+		t.Error(diff)
+	}
+}
@@ -4,31 +4,21 @@ import (
 	"errors"
 	"flag"
 	"fmt"
-	"strings"
 
 	"github.com/Kuniwak/puml-parallel/cli"
 	"github.com/Kuniwak/puml-parallel/csdf"
 	"github.com/Kuniwak/puml-parallel/tools"
 )
 
-func parseSyncEvents(s string) []csdf.Event {
-	if s == "" {
-		return nil
-	}
-	var events []csdf.Event
-	for _, event := range strings.Split(s, ";") {
-		trimmed := strings.TrimSpace(event)
-		if trimmed != "" {
-			events = append(events, csdf.Event(trimmed))
-		}
-	}
-	return events
-}
-
 type Options struct {
-	Common *tools.CommonOptions
-	Sync   []csdf.Event
-	Files  []string
+	Common   *tools.CommonOptions
+	Explorer *csdf.Explorer
+	Sync     []csdf.Event
+	Output   *tools.PlantUMLOutputOptions
+	// Provenance prints the composition as JSON with the component states and
+	// edges of its states and edges, instead of PlantUML.
+	Provenance bool
+	Files      []string
 }
 
 // CommonOptions returns the parsed common options.
@@ -51,12 +41,19 @@ Options:
 Examples:
   $ csdfparallel a.puml
   $ csdfparallel -sync 'insert;choose;drop' a.puml b.puml
+  $ csdfparallel -sync 'insert' -embed-into ab.png a.puml b.puml
+  $ csdfparallel -sync 'insert' -provenance a.puml b.puml > ab.json
 `)
 		}
 
 		var commonRawOpts tools.CommonRawOptions
 		tools.DeclareCommonOptions(flags, &commonRawOpts)
+		var explorer csdf.Explorer
+		tools.DeclareExploreOptions(flags, &explorer)
 		syncFlag := flags.String("sync", "", "semicolon-separated list of synchronization events")
+		var outputOpts tools.PlantUMLOutputOptions
+		tools.DeclarePlantUMLOutputOptions(flags, &outputOpts)
+		provenanceFlag := flags.Bool("provenance", false, "print the composition as JSON with the component states and edges of every state and edge")
 
 		if err := flags.Parse(args); err != nil {
 			if errors.Is(err, flag.ErrHelp) {
@@ -72,6 +69,15 @@ Examples:
 		if commonOpts.Version {
 			return &Options{Common: tools.CommonOptionsVersion}, nil
 		}
+		if err := tools.ValidateExploreOptions(&explorer); err != nil {
+			return nil, fmt.Errorf("csdfparallelcmd.NewParseOptionsFunc: %w", err)
+		}
+		if err := tools.ValidatePlantUMLOutputOptions(&outputOpts); err != nil {
+			return nil, fmt.Errorf("csdfparallelcmd.NewParseOptionsFunc: %w", err)
+		}
+		if *provenanceFlag && (outputOpts.EmbedInto != "" || outputOpts.Link != "") {
+			return nil, fmt.Errorf("csdfparallelcmd.NewParseOptionsFunc: -provenance prints JSON and cannot be combined with -embed-into or -link")
+		}
 
 		files := flags.Args()
 		if len(files) < 1 {
@@ -79,9 +85,12 @@ Examples:
 		}
 
 		return &Options{
-			Common: commonOpts,
-			Sync:   parseSyncEvents(*syncFlag),
-			Files:  files,
+			Common:     commonOpts,
+			Explorer:   &explorer,
+			Sync:       tools.ParseSyncEvents(*syncFlag),
+			Output:     &outputOpts,
+			Provenance: *provenanceFlag,
+			Files:      files,
 		}, nil
 	}
 }
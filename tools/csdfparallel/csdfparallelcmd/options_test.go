@@ -6,6 +6,7 @@ import (
 
 	"github.com/Kuniwak/puml-parallel/cli"
 	"github.com/Kuniwak/puml-parallel/csdf"
+	"github.com/Kuniwak/puml-parallel/pumlenc"
 	"github.com/Kuniwak/puml-parallel/tools"
 	"github.com/google/go-cmp/cmp"
 )
@@ -27,14 +28,62 @@ func TestNewParseOptionsFuncOK(t *testing.T) {
 		},
 		"single file (lower boundary value)": {
 			Args:     []string{"a.puml"},
-			Expected: &Options{Common: tools.NewCommonOptionsDefault(), Files: []string{"a.puml"}},
+			Expected: &Options{Common: tools.NewCommonOptionsDefault(), Explorer: &csdf.Explorer{}, Output: &tools.PlantUMLOutputOptions{Server: pumlenc.DefaultServer}, Files: []string{"a.puml"}},
 		},
 		"sync with two files (representative value)": {
 			Args: []string{"-sync", "x;y", "a.puml", "b.puml"},
 			Expected: &Options{
-				Common: tools.NewCommonOptionsDefault(),
-				Sync:   []csdf.Event{"x", "y"},
-				Files:  []string{"a.puml", "b.puml"},
+				Common:   tools.NewCommonOptionsDefault(),
+				Explorer: &csdf.Explorer{},
+				Output:   &tools.PlantUMLOutputOptions{Server: pumlenc.DefaultServer},
+				Sync:     []csdf.Event{"x", "y"},
+				Files:    []string{"a.puml", "b.puml"},
+			},
+		},
+		"-embed-into (representative value)": {
+			Args: []string{"-embed-into", "ab.png", "a.puml", "b.puml"},
+			Expected: &Options{
+				Common:   tools.NewCommonOptionsDefault(),
+				Explorer: &csdf.Explorer{},
+				Output:   &tools.PlantUMLOutputOptions{EmbedInto: "ab.png", Server: pumlenc.DefaultServer},
+				Files:    []string{"a.puml", "b.puml"},
+			},
+		},
+		"-link with -server (representative value)": {
+			Args: []string{"-link", "svg", "-server", "https://plantuml.example.com/", "a.puml"},
+			Expected: &Options{
+				Common:   tools.NewCommonOptionsDefault(),
+				Explorer: &csdf.Explorer{},
+				Output:   &tools.PlantUMLOutputOptions{Link: "svg", Server: "https://plantuml.example.com/"},
+				Files:    []string{"a.puml"},
+			},
+		},
+		"-provenance (representative value)": {
+			Args: []string{"-provenance", "a.puml", "b.puml"},
+			Expected: &Options{
+				Common:     tools.NewCommonOptionsDefault(),
+				Explorer:   &csdf.Explorer{},
+				Output:     &tools.PlantUMLOutputOptions{Server: pumlenc.DefaultServer},
+				Provenance: true,
+				Files:      []string{"a.puml", "b.puml"},
+			},
+		},
+		"-workers (representative value)": {
+			Args: []string{"-workers", "4", "a.puml"},
+			Expected: &Options{
+				Common:   tools.NewCommonOptionsDefault(),
+				Explorer: &csdf.Explorer{Workers: 4},
+				Output:   &tools.PlantUMLOutputOptions{Server: pumlenc.DefaultServer},
+				Files:    []string{"a.puml"},
+			},
+		},
+		"-store disk -store-dir (representative value)": {
+			Args: []string{"-store", "disk", "-store-dir", "/tmp/states", "a.puml"},
+			Expected: &Options{
+				Common:   tools.NewCommonOptionsDefault(),
+				Explorer: &csdf.Explorer{Store: csdf.DiskStore, StoreDir: "/tmp/states"},
+				Output:   &tools.PlantUMLOutputOptions{Server: pumlenc.DefaultServer},
+				Files:    []string{"a.puml"},
 			},
 		},
 	}
@@ -69,6 +118,24 @@ func TestNewParseOptionsFuncNG(t *testing.T) {
 		"too few arguments (representative value)": {
 			Args: []string{},
 		},
+		"unknown -link format (representative value)": {
+			Args: []string{"-link", "jpeg", "a.puml"},
+		},
+		"-server without a scheme (representative value)": {
+			Args: []string{"-link", "png", "-server", "plantuml.example.com", "a.puml"},
+		},
+		"negative -workers (representative value)": {
+			Args: []string{"-workers", "-1", "a.puml"},
+		},
+		"unknown -store (representative value)": {
+			Args: []string{"-store", "tape", "a.puml"},
+		},
+		"-store-dir without -store disk (representative value)": {
+			Args: []string{"-store", "compact", "-store-dir", "/tmp/states", "a.puml"},
+		},
+		"-provenance with -link (representative value)": {
+			Args: []string{"-provenance", "-link", "png", "a.puml"},
+		},
 	}
 
 	for name, testCase := range testCases {
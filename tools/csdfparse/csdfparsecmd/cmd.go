@@ -19,12 +19,12 @@ func NewMainFunc() cli.MainFunc[*Options] {
 			return nil
 		}
 
-		diagram, err := csdf.ParseDiagram(opts.Bytes)
+		diagram, err := csdf.ParseDiagramFile(opts.Path, opts.Bytes)
 		if err != nil {
 			return fmt.Errorf("csdfparsecmd.NewMainFunc: %w", err)
 		}
 
-		if err := json.NewEncoder(inout.Stdout).Encode(diagram); err != nil {
+		if err := json.NewEncoder(inout.Stdout).Encode(csdf.NewJSONDocument(diagram)); err != nil {
 			return fmt.Errorf("csdfparsecmd.NewMainFunc: writing JSON: %w", err)
 		}
 		return nil
@@ -22,7 +22,7 @@ s0 --> s1 : finish(result) ; ready ; done
 s1 --> [*] : complete
 @enduml
 `
-	want := `{"states":{"s0":{"id":"s0","name":"Initial","vars":[{"name":"ready","type":"bool"},{"name":"count"}]},"s1":{"id":"s1","name":"Done","vars":[]}},"start_edge":{"dst":"s0","post":"initialize"},"edges":[{"src":"s0","dst":"s1","event":"finish(result)","guard":"ready","post":"done"}],"end_edge":{"src":"s1","guard":"complete"}}` + "\n"
+	want := `{"format_version":"1","states":{"s0":{"id":"s0","name":"Initial","vars":[{"name":"ready","type":"bool"},{"name":"count"}]},"s1":{"id":"s1","name":"Done","vars":[]}},"start_edge":{"dst":"s0","post":"initialize"},"edges":[{"src":"s0","dst":"s1","event":"finish(result)","guard":"ready","post":"done"}],"end_edge":{"src":"s1","guard":"complete"}}` + "\n"
 
 	cmdFunc := tools.NewCommandFunc(NewParseOptionsFunc(), NewMainFunc())
 	spy := cli.SpyProcInout()
@@ -46,7 +46,7 @@ s1 --> [*] : complete
 
 func TestNewMainFuncReadsFileArgument(t *testing.T) {
 	// Arrange: `csdfparse <file>` must be equivalent to reading from stdin.
-	want := `{"states":{"s0":{"id":"s0","name":"SKIP","vars":[]}},"start_edge":{"dst":"s0","post":"true"},"edges":[],"end_edge":{"src":"s0","guard":"true"}}` + "\n"
+	want := `{"format_version":"1","states":{"s0":{"id":"s0","name":"SKIP","vars":[]}},"start_edge":{"dst":"s0","post":"true"},"edges":[],"end_edge":{"src":"s0","guard":"true"}}` + "\n"
 	cmdFunc := tools.NewCommandFunc(NewParseOptionsFunc(), NewMainFunc())
 	spy := cli.SpyProcInout()
 
@@ -63,6 +63,36 @@ func TestNewMainFuncReadsFileArgument(t *testing.T) {
 	}
 }
 
+func TestNewMainFuncPrintsAnnotations(t *testing.T) {
+	// Arrange
+	input := `@startuml
+/'@owner team-a'/
+
+/'@progress'/
+state "Initial" as s0
+[*] --> s0
+s0 --> s0 : tick /'@formal x > 0'/
+@enduml
+`
+	want := `{"format_version":"1","states":{"s0":{"id":"s0","name":"Initial","vars":[],"annotations":{"progress":""}}},"start_edge":{"dst":"s0","post":"true"},"edges":[{"src":"s0","dst":"s0","event":"tick","guard":"true","post":"true","annotations":{"formal":"x \u003e 0"}}],"end_edge":null,"annotations":{"owner":"team-a"}}` + "\n"
+
+	cmdFunc := tools.NewCommandFunc(NewParseOptionsFunc(), NewMainFunc())
+	spy := cli.SpyProcInout()
+	spy.Stdin = cli.StubStdin(strings.NewReader(input))
+
+	// Act
+	exitStatus := cmdFunc([]string{}, spy.New())
+
+	// Assert
+	if exitStatus != 0 {
+		t.Log(spy.Stderr.String())
+		t.Errorf("want 0, got %d", exitStatus)
+	}
+	if diff := cmp.Diff(want, spy.Stdout.String()); diff != "" {
+		t.Error(diff)
+	}
+}
+
 func TestNewMainFuncVersion(t *testing.T) {
 	// Arrange
 	cmdFunc := tools.NewCommandFunc(NewParseOptionsFunc(), NewMainFunc())
@@ -11,6 +11,7 @@ import (
 
 type Options struct {
 	Common *tools.CommonOptions
+	Path   string // "" when reading standard input
 	Bytes  []byte
 }
 
@@ -57,10 +58,10 @@ Examples:
 			return &Options{Common: tools.CommonOptionsVersion}, nil
 		}
 
-		bs, err := tools.ValidateArgsAsFilePath(flags.Args(), inout)
+		path, bs, err := tools.ValidateArgsAsFilePath(flags.Args(), inout)
 		if err != nil {
 			return nil, fmt.Errorf("csdfparsecmd.NewParseOptionsFunc: validate arguments failed: %w", err)
 		}
-		return &Options{Common: commonOpts, Bytes: bs}, nil
+		return &Options{Common: commonOpts, Path: path, Bytes: bs}, nil
 	}
 }
@@ -55,6 +55,7 @@ func TestNewParseOptionsFuncOK(t *testing.T) {
 			Args: []string{filepath.Join("testdata", "a.puml")},
 			Expected: &Options{
 				Common: tools.NewCommonOptionsDefault(),
+				Path:   filepath.Join("testdata", "a.puml"),
 				Bytes:  []byte("@startuml\n@enduml\n"),
 			},
 		},
@@ -19,12 +19,12 @@ import (
 type HistoryEntry = animation.HistoryEntry
 
 func runWithSolver(file string, inout *cli.ProcInout, interrupts <-chan os.Signal, solver csdf.PostSolver) error {
-	bs, err := os.ReadFile(file)
+	ref, bs, err := csdf.ReadDiagramRef(file)
 	if err != nil {
 		return fmt.Errorf("csdfreplcmd.runWithSolver: cannot read the file: %w: %q", err, file)
 	}
 
-	diagram, err := csdf.ParseDiagram(bs)
+	diagram, err := csdf.ParseDiagramFile(ref, bs)
 	if err != nil {
 		return fmt.Errorf("csdfreplcmd.runWithSolver: cannot parse the file: %w: %q", err, file)
 	}
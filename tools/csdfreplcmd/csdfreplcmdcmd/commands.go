@@ -4,8 +4,10 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
 
 	"github.com/Kuniwak/puml-parallel/cli"
+	"github.com/Kuniwak/puml-parallel/csdf"
 	"github.com/Kuniwak/puml-parallel/csdf/animation/proto"
 	"github.com/Kuniwak/puml-parallel/tools"
 )
@@ -247,11 +249,21 @@ func parseSessionNew() cli.ParseOptionsFunc[*clientOptions] {
 			return nil, errors.New("session new requires exactly one file (.puml or .png)")
 		}
 		path := flags.Arg(0)
-		content, err := os.ReadFile(path)
+		ref, content, err := csdf.ReadDiagramRef(path)
 		if err != nil {
 			return nil, fmt.Errorf("session new: cannot read file: %v", err)
 		}
-		opts.req = proto.Request{Command: proto.CommandSessionNew, Path: path, Content: content}
+		// The daemon runs in its own working directory, so !include paths are
+		// resolved against the file's absolute directory. Server links have no
+		// directory.
+		dir := ""
+		if ref != "" {
+			file, _ := csdf.SplitDiagramRef(ref)
+			if dir, err = filepath.Abs(filepath.Dir(file)); err != nil {
+				return nil, fmt.Errorf("session new: cannot resolve directory: %v", err)
+			}
+		}
+		opts.req = proto.Request{Command: proto.CommandSessionNew, Path: path, Content: content, Dir: dir}
 		return opts, nil
 	}
 }
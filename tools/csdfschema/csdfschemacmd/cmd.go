@@ -0,0 +1,65 @@
+package csdfschemacmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/Kuniwak/puml-parallel/cli"
+	"github.com/Kuniwak/puml-parallel/csdf/schema"
+	"github.com/Kuniwak/puml-parallel/version"
+)
+
+func NewMainFunc() cli.MainFunc[*Options] {
+	return func(opts *Options, inout *cli.ProcInout) error {
+		if opts.Common.Help {
+			return nil
+		}
+		if opts.Common.Version {
+			fmt.Fprintln(inout.Stdout, version.Version)
+			return nil
+		}
+
+		if !opts.Validate {
+			bs, err := schema.Schema(opts.Schema)
+			if err != nil {
+				return fmt.Errorf("csdfschemacmd.NewMainFunc: %w", err)
+			}
+			_, _ = inout.Stdout.Write(bs)
+			return nil
+		}
+
+		n, err := validateAll(opts.Schema, opts.Bytes)
+		if err != nil {
+			return fmt.Errorf("csdfschemacmd.NewMainFunc: %w", err)
+		}
+		if n == 0 {
+			return fmt.Errorf("csdfschemacmd.NewMainFunc: no JSON document in the input")
+		}
+		fmt.Fprintln(inout.Stdout, "valid")
+		return nil
+	}
+}
+
+// validateAll validates every JSON value of input and returns how many there
+// were. Errors name the document with %v rather than wrap it, so that the
+// number survives UserFacingError.
+func validateAll(name string, input []byte) (int, error) {
+	decoder := json.NewDecoder(bytes.NewReader(input))
+	n := 0
+	for {
+		var document json.RawMessage
+		if err := decoder.Decode(&document); err != nil {
+			if errors.Is(err, io.EOF) {
+				return n, nil
+			}
+			return n, fmt.Errorf("document %d: %v", n+1, err)
+		}
+		n++
+		if err := schema.Validate(name, document); err != nil {
+			return n, fmt.Errorf("document %d: %v", n, err)
+		}
+	}
+}
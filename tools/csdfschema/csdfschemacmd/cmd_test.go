@@ -0,0 +1,96 @@
+package csdfschemacmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Kuniwak/puml-parallel/cli"
+	"github.com/Kuniwak/puml-parallel/csdf/schema"
+	"github.com/Kuniwak/puml-parallel/tools"
+	"github.com/Kuniwak/puml-parallel/version"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestNewMainFuncPrintsSchema(t *testing.T) {
+	// Arrange
+	cmdFunc := tools.NewCommandFunc(NewParseOptionsFunc(), NewMainFunc())
+	spy := cli.SpyProcInout()
+	want, err := schema.Schema("runtime-state")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Act
+	exitStatus := cmdFunc([]string{"-schema", "runtime-state"}, spy.New())
+
+	// Assert
+	if exitStatus != 0 {
+		t.Log(spy.Stderr.String())
+		t.Errorf("want 0, got %d", exitStatus)
+	}
+	if diff := cmp.Diff(string(want), spy.Stdout.String()); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestNewMainFuncValidatesJSONLines(t *testing.T) {
+	// Arrange
+	cmdFunc := tools.NewCommandFunc(NewParseOptionsFunc(), NewMainFunc())
+	spy := cli.SpyProcInout()
+	spy.Stdin = cli.StubStdin(strings.NewReader(`{"command":"session_new","path":"a.puml","content":""}
+{"command":"select","session":"1","index":0}
+`))
+
+	// Act
+	exitStatus := cmdFunc([]string{"-schema", "proto", "-validate"}, spy.New())
+
+	// Assert
+	if exitStatus != 0 {
+		t.Log(spy.Stderr.String())
+		t.Errorf("want 0, got %d", exitStatus)
+	}
+	if diff := cmp.Diff("valid\n", spy.Stdout.String()); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestNewMainFuncReportsProblems(t *testing.T) {
+	// Arrange
+	cmdFunc := tools.NewCommandFunc(NewParseOptionsFunc(), NewMainFunc())
+	spy := cli.SpyProcInout()
+	spy.Stdin = cli.StubStdin(strings.NewReader(`{"states":{"a":{"name":"A"}},"start_edge":{"dst":"a"}}
+{"format_version":"1","states":{"a":{"name":"A"}},"start_edge":{"dst":"a"},"edges":[{"src":"a","dst":"a"}]}
+`))
+
+	// Act
+	exitStatus := cmdFunc([]string{"-validate"}, spy.New())
+
+	// Assert
+	if exitStatus == 0 {
+		t.Error("want non-zero, got 0")
+	}
+	stderr := spy.Stderr.String()
+	for _, want := range []string{"document 2", `/edges/0: missing property "event"`} {
+		if !strings.Contains(stderr, want) {
+			t.Errorf("want %q in the error, got %q", want, stderr)
+		}
+	}
+}
+
+func TestNewMainFuncVersion(t *testing.T) {
+	// Arrange
+	cmdFunc := tools.NewCommandFunc(NewParseOptionsFunc(), NewMainFunc())
+	spy := cli.SpyProcInout()
+
+	// Act
+	exitStatus := cmdFunc([]string{"-v"}, spy.New())
+
+	// Assert
+	if exitStatus != 0 {
+		t.Log(spy.Stderr.String())
+		t.Errorf("want 0, got %d", exitStatus)
+	}
+	if diff := cmp.Diff(version.Version+"\n", spy.Stdout.String()); diff != "" {
+		t.Error(diff)
+	}
+}
@@ -0,0 +1,96 @@
+package csdfschemacmd
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/Kuniwak/puml-parallel/cli"
+	"github.com/Kuniwak/puml-parallel/csdf/schema"
+	"github.com/Kuniwak/puml-parallel/tools"
+)
+
+type Options struct {
+	Common *tools.CommonOptions
+	// Schema is the name of the schema, one of schema.Names.
+	Schema string
+	// Validate checks the input against the schema instead of printing it.
+	Validate bool
+	Path     string // "" when reading standard input
+	Bytes    []byte
+}
+
+// CommonOptions returns the parsed common options.
+func (o *Options) CommonOptions() *tools.CommonOptions { return o.Common }
+
+func NewParseOptionsFunc() cli.ParseOptionsFunc[*Options] {
+	return func(args []string, inout *cli.ProcInout) (*Options, error) {
+		flags := flag.NewFlagSet("csdfschema", flag.ContinueOnError)
+		flags.SetOutput(inout.Stderr)
+		flags.Usage = func() {
+			w := flags.Output()
+			fmt.Fprintf(w, `Usage: csdfschema [options]
+       csdfschema -validate [options] [file.json]
+
+Prints the JSON Schema of the JSON written and read by the tools, or, with -validate,
+checks JSON documents against it. The schemas are:
+
+  diagram        a diagram as printed by csdfparse and read by every tool
+  composition    a composed diagram with its provenance, as printed by csdfparallel -provenance
+  runtime-state  a state with the values of its variables, as in the animation protocol
+  proto          a request or a response exchanged by csdfreplcmd and csdfrepld
+
+With -validate, every JSON value of the input is checked, so JSON Lines work too.
+A file argument, a "-" argument, and standard input are all equivalent.
+
+Options:
+`)
+			flags.PrintDefaults()
+			fmt.Fprintf(w, `
+Examples:
+  $ csdfschema > diagram.schema.json
+  $ csdfparse vending_machine.puml | csdfschema -validate
+  $ csdfschema -schema proto -validate requests.jsonl
+`)
+		}
+
+		var commonRawOpts tools.CommonRawOptions
+		tools.DeclareCommonOptions(flags, &commonRawOpts)
+		schemaFlag := flags.String("schema", "diagram", fmt.Sprintf("schema name (%s)", strings.Join(schema.Names, ", ")))
+		validateFlag := flags.Bool("validate", false, "validate the input against the schema instead of printing it")
+
+		if err := flags.Parse(args); err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				return &Options{Common: tools.CommonOptionsHelp}, nil
+			}
+			return nil, fmt.Errorf("csdfschemacmd.NewParseOptionsFunc: parse failed: %w", err)
+		}
+
+		commonOpts, err := tools.ValidateCommonOptions(&commonRawOpts)
+		if err != nil {
+			return nil, fmt.Errorf("csdfschemacmd.NewParseOptionsFunc: validate common options failed: %w", err)
+		}
+		if commonOpts.Version {
+			return &Options{Common: tools.CommonOptionsVersion}, nil
+		}
+
+		if !slices.Contains(schema.Names, *schemaFlag) {
+			return nil, fmt.Errorf("csdfschemacmd.NewParseOptionsFunc: unknown schema %q (schemas: %s)", *schemaFlag, strings.Join(schema.Names, ", "))
+		}
+
+		if !*validateFlag {
+			if flags.NArg() > 0 {
+				return nil, fmt.Errorf("csdfschemacmd.NewParseOptionsFunc: a file is read only with -validate")
+			}
+			return &Options{Common: commonOpts, Schema: *schemaFlag}, nil
+		}
+
+		path, bs, err := tools.ValidateArgsAsFilePath(flags.Args(), inout)
+		if err != nil {
+			return nil, fmt.Errorf("csdfschemacmd.NewParseOptionsFunc: validate arguments failed: %w", err)
+		}
+		return &Options{Common: commonOpts, Schema: *schemaFlag, Validate: true, Path: path, Bytes: bs}, nil
+	}
+}
@@ -0,0 +1,129 @@
+package csdfschemacmd
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Kuniwak/puml-parallel/cli"
+	"github.com/Kuniwak/puml-parallel/tools"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestNewParseOptionsFuncOK(t *testing.T) {
+	type testCase struct {
+		Stdin    string
+		Args     []string
+		Expected *Options
+	}
+
+	testCases := map[string]testCase{
+		"-h (representative value)": {
+			Args:     []string{"-h"},
+			Expected: &Options{Common: tools.CommonOptionsHelp},
+		},
+		"--help (representative value)": {
+			Args:     []string{"--help"},
+			Expected: &Options{Common: tools.CommonOptionsHelp},
+		},
+		"-v (representative value)": {
+			Args:     []string{"-v"},
+			Expected: &Options{Common: tools.CommonOptionsVersion},
+		},
+		"--version (representative value)": {
+			Args:     []string{"--version"},
+			Expected: &Options{Common: tools.CommonOptionsVersion},
+		},
+		"no args prints the diagram schema (representative value)": {
+			Args: []string{},
+			Expected: &Options{
+				Common: tools.NewCommonOptionsDefault(),
+				Schema: "diagram",
+			},
+		},
+		"-schema (representative value)": {
+			Args: []string{"-schema", "proto"},
+			Expected: &Options{
+				Common: tools.NewCommonOptionsDefault(),
+				Schema: "proto",
+			},
+		},
+		"-validate reads stdin (representative value)": {
+			Stdin: "{}\n",
+			Args:  []string{"-validate"},
+			Expected: &Options{
+				Common:   tools.NewCommonOptionsDefault(),
+				Schema:   "diagram",
+				Validate: true,
+				Bytes:    []byte("{}\n"),
+			},
+		},
+		"-validate with a file argument (representative value)": {
+			Args: []string{"-schema", "diagram", "-validate", filepath.Join("testdata", "a.json")},
+			Expected: &Options{
+				Common:   tools.NewCommonOptionsDefault(),
+				Schema:   "diagram",
+				Validate: true,
+				Path:     filepath.Join("testdata", "a.json"),
+				Bytes:    []byte(`{"states":{"a":{"name":"A"}},"start_edge":{"dst":"a"}}` + "\n"),
+			},
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Arrange
+			parseOptions := NewParseOptionsFunc()
+			spy := cli.SpyProcInout()
+			spy.Stdin = cli.StubStdin(strings.NewReader(testCase.Stdin))
+
+			// Act
+			opts, err := parseOptions(testCase.Args, spy.New())
+			if err != nil {
+				t.Log(spy.Stderr.String())
+				t.Errorf("want nil, got %#v", err)
+			}
+
+			// Assert
+			if !reflect.DeepEqual(testCase.Expected, opts) {
+				t.Error(cmp.Diff(testCase.Expected, opts))
+			}
+		})
+	}
+}
+
+func TestNewParseOptionsFuncNG(t *testing.T) {
+	type testCase struct {
+		Args []string
+	}
+
+	testCases := map[string]testCase{
+		"unknown schema (representative value)": {
+			Args: []string{"-schema", "plantuml"},
+		},
+		"file without -validate (representative value)": {
+			Args: []string{"a.json"},
+		},
+		"too many arguments (representative value)": {
+			Args: []string{"-validate", "a.json", "b.json"},
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Arrange
+			parseOptions := NewParseOptionsFunc()
+			spy := cli.SpyProcInout()
+
+			// Act
+			opts, err := parseOptions(testCase.Args, spy.New())
+
+			// Assert
+			if err == nil {
+				t.Log(opts)
+				t.Error("want not nil, got nil")
+			}
+		})
+	}
+}
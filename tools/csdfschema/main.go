@@ -0,0 +1,13 @@
+package main
+
+import (
+	"github.com/Kuniwak/puml-parallel/tools"
+	"github.com/Kuniwak/puml-parallel/tools/csdfschema/csdfschemacmd"
+)
+
+func main() {
+	tools.NewCommandFunc(
+		csdfschemacmd.NewParseOptionsFunc(),
+		csdfschemacmd.NewMainFunc(),
+	).Run()
+}
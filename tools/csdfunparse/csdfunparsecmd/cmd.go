@@ -0,0 +1,32 @@
+package csdfunparsecmd
+
+import (
+	"fmt"
+
+	"github.com/Kuniwak/puml-parallel/cli"
+	"github.com/Kuniwak/puml-parallel/csdf"
+	"github.com/Kuniwak/puml-parallel/tools"
+	"github.com/Kuniwak/puml-parallel/version"
+)
+
+func NewMainFunc() cli.MainFunc[*Options] {
+	return func(opts *Options, inout *cli.ProcInout) error {
+		if opts.Common.Help {
+			return nil
+		}
+		if opts.Common.Version {
+			fmt.Fprintln(inout.Stdout, version.Version)
+			return nil
+		}
+
+		diagram, err := csdf.ParseDiagramFile(opts.Path, opts.Bytes)
+		if err != nil {
+			return fmt.Errorf("csdfunparsecmd.NewMainFunc: %w", err)
+		}
+
+		if err := tools.WritePlantUML(inout.Stdout, diagram.String(), opts.Output); err != nil {
+			return fmt.Errorf("csdfunparsecmd.NewMainFunc: %w", err)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,73 @@
+package csdfunparsecmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Kuniwak/puml-parallel/cli"
+	"github.com/Kuniwak/puml-parallel/tools"
+	"github.com/Kuniwak/puml-parallel/version"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestNewMainFuncUnparsesJSON(t *testing.T) {
+	// Arrange
+	cmdFunc := tools.NewCommandFunc(NewParseOptionsFunc(), NewMainFunc())
+	spy := cli.SpyProcInout()
+	spy.Stdin = cli.StubStdin(strings.NewReader(`{"name":"tea","states":{"idle":{"id":"idle","name":"Idle","vars":[{"name":"cups","type":"int"}]}},"start_edge":{"dst":"idle","post":"cups' = 0"},"edges":[{"src":"idle","dst":"idle","event":"tea","guard":"true","post":"cups' = cups + 1"}],"end_edge":null}`))
+	want := `@startuml tea
+state "Idle" as idle
+idle: cups ; int
+[*] --> idle : cups' = 0
+idle --> idle : tea ; true ; cups' = cups + 1
+@enduml
+`
+
+	// Act
+	exitStatus := cmdFunc([]string{}, spy.New())
+
+	// Assert
+	if exitStatus != 0 {
+		t.Log(spy.Stderr.String())
+		t.Errorf("want 0, got %d", exitStatus)
+	}
+	if diff := cmp.Diff(want, spy.Stdout.String()); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestNewMainFuncRejectsInvalidJSON(t *testing.T) {
+	// Arrange
+	cmdFunc := tools.NewCommandFunc(NewParseOptionsFunc(), NewMainFunc())
+	spy := cli.SpyProcInout()
+	spy.Stdin = cli.StubStdin(strings.NewReader(`{"states":{},"start_edge":{"dst":"idle"}}`))
+
+	// Act
+	exitStatus := cmdFunc([]string{}, spy.New())
+
+	// Assert
+	if exitStatus == 0 {
+		t.Error("want non-zero, got 0")
+	}
+	if !strings.Contains(spy.Stderr.String(), `undeclared state "idle"`) {
+		t.Errorf("want an undeclared state error, got %q", spy.Stderr.String())
+	}
+}
+
+func TestNewMainFuncVersion(t *testing.T) {
+	// Arrange
+	cmdFunc := tools.NewCommandFunc(NewParseOptionsFunc(), NewMainFunc())
+	spy := cli.SpyProcInout()
+
+	// Act
+	exitStatus := cmdFunc([]string{"-v"}, spy.New())
+
+	// Assert
+	if exitStatus != 0 {
+		t.Log(spy.Stderr.String())
+		t.Errorf("want 0, got %d", exitStatus)
+	}
+	if diff := cmp.Diff(version.Version+"\n", spy.Stdout.String()); diff != "" {
+		t.Error(diff)
+	}
+}
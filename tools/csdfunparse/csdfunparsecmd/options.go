@@ -0,0 +1,75 @@
+package csdfunparsecmd
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+
+	"github.com/Kuniwak/puml-parallel/cli"
+	"github.com/Kuniwak/puml-parallel/tools"
+)
+
+type Options struct {
+	Common *tools.CommonOptions
+	Output *tools.PlantUMLOutputOptions
+	Path   string // "" when reading standard input
+	Bytes  []byte
+}
+
+// CommonOptions returns the parsed common options.
+func (o *Options) CommonOptions() *tools.CommonOptions { return o.Common }
+
+func NewParseOptionsFunc() cli.ParseOptionsFunc[*Options] {
+	return func(args []string, inout *cli.ProcInout) (*Options, error) {
+		flags := flag.NewFlagSet("csdfunparse", flag.ContinueOnError)
+		flags.SetOutput(inout.Stderr)
+		flags.Usage = func() {
+			w := flags.Output()
+			fmt.Fprintf(w, `Usage: csdfunparse [options] [file.json|file.puml]
+
+Prints a diagram as Composable State Diagram PlantUML. The input is typically the JSON
+printed by csdfparse, edited or generated by a script; every other input format is
+accepted too.
+A file argument, a "-" argument, and standard input are all equivalent.
+
+Options:
+`)
+			flags.PrintDefaults()
+			fmt.Fprintf(w, `
+Examples:
+  $ csdfunparse diagram.json > diagram.puml
+  $ csdfparse vending_machine.puml | jq '.name = "vm"' | csdfunparse
+  $ csdfunparse -embed-into diagram.png diagram.json > diagram.puml
+`)
+		}
+
+		var commonRawOpts tools.CommonRawOptions
+		tools.DeclareCommonOptions(flags, &commonRawOpts)
+		var outputOpts tools.PlantUMLOutputOptions
+		tools.DeclarePlantUMLOutputOptions(flags, &outputOpts)
+
+		if err := flags.Parse(args); err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				return &Options{Common: tools.CommonOptionsHelp}, nil
+			}
+			return nil, fmt.Errorf("csdfunparsecmd.NewParseOptionsFunc: parse failed: %w", err)
+		}
+
+		commonOpts, err := tools.ValidateCommonOptions(&commonRawOpts)
+		if err != nil {
+			return nil, fmt.Errorf("csdfunparsecmd.NewParseOptionsFunc: validate common options failed: %w", err)
+		}
+		if commonOpts.Version {
+			return &Options{Common: tools.CommonOptionsVersion}, nil
+		}
+		if err := tools.ValidatePlantUMLOutputOptions(&outputOpts); err != nil {
+			return nil, fmt.Errorf("csdfunparsecmd.NewParseOptionsFunc: %w", err)
+		}
+
+		path, bs, err := tools.ValidateArgsAsFilePath(flags.Args(), inout)
+		if err != nil {
+			return nil, fmt.Errorf("csdfunparsecmd.NewParseOptionsFunc: validate arguments failed: %w", err)
+		}
+		return &Options{Common: commonOpts, Output: &outputOpts, Path: path, Bytes: bs}, nil
+	}
+}
@@ -0,0 +1,13 @@
+package main
+
+import (
+	"github.com/Kuniwak/puml-parallel/tools"
+	"github.com/Kuniwak/puml-parallel/tools/csdfunparse/csdfunparsecmd"
+)
+
+func main() {
+	tools.NewCommandFunc(
+		csdfunparsecmd.NewParseOptionsFunc(),
+		csdfunparsecmd.NewMainFunc(),
+	).Run()
+}
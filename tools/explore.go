@@ -0,0 +1,30 @@
+package tools
+
+import (
+	"flag"
+	"fmt"
+	"slices"
+
+	"github.com/Kuniwak/puml-parallel/csdf"
+)
+
+// DeclareExploreOptions declares the options of the tools that explore state
+// spaces, which configure explorer.
+func DeclareExploreOptions(flags *flag.FlagSet, explorer *csdf.Explorer) {
+	flags.IntVar(&explorer.Workers, "workers", 0, "number of goroutines exploring states, 0 for one per CPU; the output does not depend on it")
+	flags.StringVar((*string)(&explorer.Store), "store", "", "where to keep the states found: memory (the default), compact (the states packed into one buffer, indexed by hash) or disk (the visited states in a temporary file; edges stay in memory)")
+	flags.StringVar(&explorer.StoreDir, "store-dir", "", "directory of the temporary file of -store disk (default: the system temporary directory)")
+}
+
+func ValidateExploreOptions(explorer *csdf.Explorer) error {
+	if explorer.Workers < 0 {
+		return fmt.Errorf("-workers must be 0 or more, got %d", explorer.Workers)
+	}
+	if explorer.Store != "" && !slices.Contains(csdf.StoreKinds, explorer.Store) {
+		return fmt.Errorf("-store must be one of %v, got %q", csdf.StoreKinds, explorer.Store)
+	}
+	if explorer.StoreDir != "" && explorer.Store != csdf.DiskStore {
+		return fmt.Errorf("-store-dir requires -store disk")
+	}
+	return nil
+}
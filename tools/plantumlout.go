@@ -0,0 +1,97 @@
+package tools
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/Kuniwak/puml-parallel/pngsrc"
+	"github.com/Kuniwak/puml-parallel/pumlenc"
+)
+
+// PlantUMLOutputOptions are the options of the tools that print PlantUML.
+type PlantUMLOutputOptions struct {
+	// EmbedInto is a PNG image whose embedded PlantUML source is replaced by the
+	// output, or "" to leave images alone.
+	EmbedInto string
+	// Link is the format (see pumlenc.Formats) of the PlantUML server link
+	// printed instead of the source, or "" to print the source.
+	Link string
+	// Server is the PlantUML server of the link.
+	Server string
+}
+
+func DeclarePlantUMLOutputOptions(flags *flag.FlagSet, options *PlantUMLOutputOptions) {
+	flags.StringVar(&options.EmbedInto, "embed-into", "", "also embed the printed PlantUML into the \"plantuml\" text chunk of this existing PNG image")
+	flags.StringVar(&options.Link, "link", "", fmt.Sprintf("print a PlantUML server link instead of the PlantUML, showing the diagram as one of %s", strings.Join(pumlenc.Formats, ", ")))
+	flags.StringVar(&options.Server, "server", pumlenc.DefaultServer, "PlantUML server of -link; the link holds the diagram, nothing is sent")
+}
+
+func ValidatePlantUMLOutputOptions(options *PlantUMLOutputOptions) error {
+	if options.Link != "" && !slices.Contains(pumlenc.Formats, options.Link) {
+		return fmt.Errorf("unknown -link format %q (formats: %s)", options.Link, strings.Join(pumlenc.Formats, ", "))
+	}
+	if !strings.HasPrefix(options.Server, "http://") && !strings.HasPrefix(options.Server, "https://") {
+		return fmt.Errorf("-server must be an http or https URL, got %q", options.Server)
+	}
+	return nil
+}
+
+// WritePlantUML embeds source into the image of -embed-into, if any, and then
+// prints source, or its server link with -link.
+func WritePlantUML(w io.Writer, source string, options *PlantUMLOutputOptions) error {
+	if options.EmbedInto != "" {
+		if err := EmbedSourceInto(options.EmbedInto, source); err != nil {
+			return fmt.Errorf("tools.WritePlantUML: %w", err)
+		}
+	}
+	if options.Link != "" {
+		fmt.Fprintln(w, pumlenc.URL(options.Server, options.Link, source))
+		return nil
+	}
+	fmt.Fprint(w, source)
+	return nil
+}
+
+// EmbedSourceInto replaces the PlantUML source embedded in the PNG image at
+// path with source. The image is rewritten through a temporary file in the
+// same directory, so it is never left half-written.
+func EmbedSourceInto(path, source string) error {
+	image, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("tools.EmbedSourceInto: %w", err)
+	}
+	embedded, err := pngsrc.Embed(image, source)
+	if err != nil {
+		return fmt.Errorf("tools.EmbedSourceInto: %s: %w", path, err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("tools.EmbedSourceInto: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("tools.EmbedSourceInto: %w", err)
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+	if _, err := tmp.Write(embedded); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("tools.EmbedSourceInto: %w", err)
+	}
+	if err := tmp.Chmod(info.Mode().Perm()); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("tools.EmbedSourceInto: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("tools.EmbedSourceInto: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("tools.EmbedSourceInto: %w", err)
+	}
+	return nil
+}
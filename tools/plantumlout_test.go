@@ -0,0 +1,71 @@
+package tools
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Kuniwak/puml-parallel/pngsrc"
+)
+
+func TestEmbedSourceInto(t *testing.T) {
+	image, err := os.ReadFile("../examples/valid/client.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "client.png")
+	if err := os.WriteFile(path, image, 0o640); err != nil {
+		t.Fatal(err)
+	}
+	source := "@startuml\n[*] --> a\n@enduml\n"
+
+	if err := EmbedSourceInto(path, source); err != nil {
+		t.Fatalf("EmbedSourceInto: %v", err)
+	}
+
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	extracted, err := pngsrc.Extract(got)
+	if err != nil {
+		t.Fatalf("Extract: %v", err)
+	}
+	if extracted != source {
+		t.Errorf("got %q, want %q", extracted, source)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0o640 {
+		t.Errorf("mode: got %v, want %v", info.Mode().Perm(), os.FileMode(0o640))
+	}
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("want only the image left in the directory, got %d entries", len(entries))
+	}
+}
+
+func TestEmbedSourceIntoRejectsNonPNG(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "a.puml")
+	original := []byte("@startuml\n@enduml\n")
+	if err := os.WriteFile(path, original, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := EmbedSourceInto(path, "@startuml\n[*] --> a\n@enduml\n"); err == nil {
+		t.Fatal("want error, got nil")
+	}
+
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != string(original) {
+		t.Errorf("the file was modified: %q", got)
+	}
+}